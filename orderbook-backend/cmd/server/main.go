@@ -2,17 +2,26 @@ package main
 
 import (
 	"context"
+	"errors"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"orderbook-backend/internal/api"
 	"orderbook-backend/internal/config"
 	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/journal"
+	"orderbook-backend/internal/logging"
 	"orderbook-backend/internal/market"
+	"orderbook-backend/internal/snapshot"
+	"orderbook-backend/internal/state"
+	"orderbook-backend/internal/tradestore"
 	"orderbook-backend/internal/yellow"
 
+	"github.com/ethereum/go-ethereum/ethclient"
 	"github.com/joho/godotenv"
 )
 
@@ -27,23 +36,136 @@ func main() {
 
 	// Load configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+
+	// Structured logging; the standard logger writes through it as well
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		log.Printf("Invalid LOG_LEVEL %q, using info", cfg.LogLevel)
+		level = slog.LevelInfo
+	}
+	logger := logging.New(cfg.LogFormat, level)
+	slog.SetDefault(logger)
+
+	if err := engine.SetQuantityScale(uint64(cfg.QuantityScale)); err != nil {
+		log.Fatalf("Invalid QUANTITY_SCALE %d: %v", cfg.QuantityScale, err)
+	}
+	if engine.QuantityScale() > 1 {
+		log.Printf("Fractional shares enabled (%d units per share)", engine.QuantityScale())
+	}
 
 	// Initialize market orderbooks (separate YES/NO orderbooks per market)
 	marketOrderbooks := engine.NewMarketOrderbooks()
+	if cfg.MinMakerSpread > 0 {
+		marketOrderbooks.SetMinSpread(uint64(cfg.MinMakerSpread))
+	}
+	if cfg.TickSize < 0 || engine.CheckTickSize(uint64(cfg.TickSize)) != nil {
+		log.Fatalf("Invalid TICK_SIZE %d: %v", cfg.TickSize, engine.ErrInvalidTickSize)
+	}
+	marketOrderbooks.SetTickSize(uint64(cfg.TickSize))
+	minQty, err := engine.ParseQuantity(cfg.MinOrderQty)
+	if err != nil {
+		log.Fatalf("Invalid MIN_ORDER_QTY %q: %v", cfg.MinOrderQty, err)
+	}
+	marketOrderbooks.SetMinQuantity(minQty)
+	if mode, err := engine.ParseSelfTradeMode(cfg.SelfTradePrevention); err != nil {
+		log.Printf("%v, allowing self-trades", err)
+	} else {
+		marketOrderbooks.SetSelfTradePrevention(mode)
+	}
 	log.Println("Market orderbooks initialized")
 
 	// Initialize market manager (prediction markets)
 	marketManager := market.NewManager()
-	lifecycleManager := market.NewLifecycleManager(marketManager)
+	if err := marketManager.SetMaxOutcomes(cfg.MaxOutcomes); err != nil {
+		log.Fatalf("Invalid MAX_OUTCOMES %d: %v", cfg.MaxOutcomes, err)
+	}
+	if cfg.MarketIDSeed != 0 {
+		marketManager.SetIDGenerator(market.SeededIDs(int64(cfg.MarketIDSeed)))
+		log.Printf("Market IDs are deterministic (seed %d)", cfg.MarketIDSeed)
+	}
+	var oracle market.Oracle
+	if cfg.OracleURL != "" {
+		oracle = market.NewHTTPOracle(cfg.OracleURL, time.Duration(cfg.OracleTimeoutSec)*time.Second)
+		log.Printf("Locked markets resolve from oracle %s", cfg.OracleURL)
+	}
+	lifecycleManager := market.NewLifecycleManager(marketManager, oracle)
+	lifecycleManager.SetDrainWindow(time.Duration(cfg.DrainMinutes) * time.Minute)
+	marketManager.SetDisputeWindow(cfg.DisputeWindow)
+	marketManager.SetTradeCheck(marketOrderbooks.HasTraded)
 	log.Println("Market manager initialized")
 
 	// Initialize position manager
 	positions := engine.NewPositionManager()
+	positions.SetLimits(engine.Limits{
+		MaxNetPosition:   uint64(cfg.PositionLimit) * engine.QuantityScale(),
+		MaxDailyNotional: uint64(cfg.DailyNotionalLimit) * 10000, // USDC -> basis points
+	})
+	positions.SetReservedFunds(marketOrderbooks.OpenBuyNotional)
+	positions.SetReservedShares(marketOrderbooks.OpenSellQuantity)
+	positions.SetFeeSchedule(engine.FeeSchedule{MakerBps: uint64(cfg.MakerFeeBps), TakerBps: uint64(cfg.TakerFeeBps)})
+	positions.SetFeeAccount(cfg.FeeAccountID)
+	if cfg.MakerFeeBps > 0 || cfg.TakerFeeBps > 0 {
+		log.Printf("Trading fees: maker %d bps, taker %d bps, paid to %s", cfg.MakerFeeBps, cfg.TakerFeeBps, cfg.FeeAccountID)
+	}
 	log.Println("Position manager initialized")
 
+	// Restore markets, resting orders and positions saved at the last shutdown
+	if cfg.StateFile != "" {
+		if saved, err := snapshot.Load(cfg.StateFile); errors.Is(err, os.ErrNotExist) {
+			log.Printf("No state snapshot at %s, starting fresh", cfg.StateFile)
+		} else if err != nil {
+			log.Fatalf("Failed to load state snapshot: %v", err)
+		} else if err := saved.Restore(marketManager, marketOrderbooks, positions); err != nil {
+			log.Fatalf("Failed to restore state snapshot: %v", err)
+		} else {
+			log.Printf("State restored from %s (saved %s)", cfg.StateFile, saved.SavedAt.Format(time.RFC3339))
+		}
+	}
+
+	// Replay changes made since the snapshot, then journal new ones
+	var journalWriter *journal.Writer
+	if cfg.JournalFile != "" {
+		n, err := journal.Replay(cfg.JournalFile, marketManager, marketOrderbooks, positions)
+		if err != nil {
+			log.Fatalf("Failed to replay journal: %v", err)
+		}
+		if n > 0 {
+			log.Printf("Replayed %d journal entries from %s", n, cfg.JournalFile)
+		}
+		journalWriter, err = journal.Open(cfg.JournalFile)
+		if err != nil {
+			log.Fatalf("Failed to open journal: %v", err)
+		}
+		journalWriter.SetSync(cfg.JournalFsync)
+		marketManager.SetJournalCallback(journalWriter.RecordMarket)
+		marketOrderbooks.SetGlobalJournalCallback(journalWriter.Record)
+		positions.SetJournalCallback(journalWriter.Record)
+	}
+
+	if policy, err := market.ParseEmptyMarketPolicy(cfg.EmptyMarketPolicy); err != nil {
+		log.Printf("%v, keeping empty markets for manual resolution", err)
+	} else {
+		lifecycleManager.SetEmptyMarketPolicy(policy, time.Duration(cfg.EmptyMarketGraceMinutes)*time.Minute, positions.OpenInterest)
+	}
+
+	// Initialize durable trade tape (optional - only if a directory is set)
+	var tradeStore *tradestore.Store
+	if cfg.TradeStoreDir != "" {
+		store, err := tradestore.Open(cfg.TradeStoreDir, int64(cfg.TradeStoreMaxSize)*1024*1024)
+		if err != nil {
+			log.Fatalf("Failed to open trade store: %v", err)
+		}
+		tradeStore = store
+		log.Printf("Trade store initialized (%s)", cfg.TradeStoreDir)
+	}
+
 	// Initialize Yellow Network client (optional - only if private key is set)
 	var yellowClient *yellow.Client
 	var sessions *yellow.SessionManager
+	var disputer *yellow.Disputer
 
 	log.Println("Initializing Yellow SDK...")
 	if cfg.PrivateKey != "" {
@@ -52,7 +174,30 @@ func main() {
 			log.Printf("❌ Yellow SDK: Failed to initialize signer: %v", err)
 		} else {
 			log.Printf("✓ Yellow SDK: Signer initialized (address: %s)", signer.Address().Hex())
+			yellow.StateDomain = yellow.NewStateDomain(int64(cfg.ChainID), cfg.AdjudicatorAddr)
+			if cfg.ChainRPCURL != "" {
+				if chain, err := ethclient.DialContext(context.Background(), cfg.ChainRPCURL); err != nil {
+					log.Printf("❌ Yellow SDK: Chain RPC unavailable, disputes disabled: %v", err)
+				} else if disputer, err = yellow.NewDisputer(chain, signer, cfg.AdjudicatorAddr); err != nil {
+					log.Printf("❌ Yellow SDK: %v, disputes disabled", err)
+				} else {
+					log.Printf("✓ Yellow SDK: On-chain disputes via %s", cfg.ChainRPCURL)
+				}
+			}
 			yellowClient = yellow.NewClient(cfg.YellowNodeURL, signer)
+			yellowClient.SetLogger(logger)
+			if mode, err := yellow.ParseAuthSignMode(cfg.AuthSignMode); err != nil {
+				log.Printf("❌ Yellow SDK: %v, using %s", err, yellow.AuthSignEIP712)
+			} else {
+				yellowClient.SetAuthSignMode(mode)
+			}
+			if cfg.YellowTrace {
+				yellowClient.SetTrace(true, nil)
+				log.Println("  Yellow JSON-RPC frame logging enabled")
+			}
+			yellowClient.SetReconnect(cfg.YellowReconnect)
+			yellowClient.SetPingInterval(time.Duration(cfg.YellowPingIntervalSec) * time.Second)
+			yellowClient.SetMaxPingFailures(cfg.YellowPingFailures)
 
 			// Connect to Yellow Network
 			log.Printf("  Connecting to Yellow Network: %s", cfg.YellowNodeURL)
@@ -66,6 +211,11 @@ func main() {
 					log.Printf("❌ Yellow SDK: Authentication failed: %v", err)
 				} else {
 					sessions = yellow.NewSessionManager(yellowClient, signer)
+					sessions.SetCloseOptions(yellow.CloseOptions{
+						Timeout: time.Duration(cfg.SessionCloseTimeoutSec) * time.Second,
+						Retries: cfg.SessionCloseRetries,
+						Backoff: yellow.DefaultCloseOptions.Backoff,
+					})
 					log.Println("✓ Yellow SDK: Authenticated successfully")
 					log.Printf("🟢 Yellow Network: CONNECTED and ready")
 				}
@@ -77,28 +227,113 @@ func main() {
 
 	// Initialize API server
 	server := api.NewServer(cfg, marketOrderbooks, yellowClient, sessions, marketManager, positions)
+	server.SetLogger(logger)
+	if cfg.JWTPublicKeyFile != "" {
+		pemKey, err := os.ReadFile(cfg.JWTPublicKeyFile)
+		if err != nil {
+			log.Fatalf("Failed to read JWT_PUBLIC_KEY_FILE: %v", err)
+		}
+		verifier, err := yellow.NewTokenVerifier(pemKey)
+		if err != nil {
+			log.Fatalf("Invalid JWT_PUBLIC_KEY_FILE: %v", err)
+		}
+		server.SetTokenVerifier(verifier)
+	} else {
+		log.Println("No JWT_PUBLIC_KEY_FILE set, user JWTs will be rejected")
+	}
+	if cfg.AdminToken == "" {
+		log.Println("No ADMIN_TOKEN set, admin endpoints are disabled")
+	}
+	if disputer != nil {
+		server.SetDisputer(disputer)
+	}
+	if tradeStore != nil {
+		server.SetTradeStore(tradeStore)
+	}
+	if cfg.ChannelBackedBalances {
+		// Funding sessions still open at the last shutdown were checkpointed;
+		// their allocations are the funds that back existing balances
+		var checkpoints []yellow.Checkpoint
+		if cfg.CheckpointDir != "" {
+			if checkpoints, err = yellow.ReadCheckpoints(cfg.CheckpointDir); err != nil {
+				log.Fatalf("Failed to read session checkpoints: %v", err)
+			}
+		}
+		allocations, err := state.FromCheckpoints(checkpoints)
+		if err != nil {
+			log.Fatalf("Failed to restore channel allocations: %v", err)
+		}
+		server.SetAllocations(allocations)
+		log.Println("Balances are backed by state channel allocations")
+	}
 
-	// Start lifecycle manager (auto-lock markets when resolution time passes)
+	// Start lifecycle manager (auto-drain/lock markets as resolution time nears)
+	lifecycleManager.SetStatusCallback(server.BroadcastMarketStatus)
+	lifecycleManager.SetSettleFunc(server.SettleMarket)
 	ctx, cancel := context.WithCancel(context.Background())
 	lifecycleManager.Start(ctx)
 
-	// Handle graceful shutdown
+	// Start the sweeper that cancels expired good-til-date orders
+	expirySweeper := engine.NewExpirySweeper(marketOrderbooks, time.Duration(cfg.OrderExpirySweepSec)*time.Second)
+	expirySweeper.SetExpireCallback(server.OrdersExpired)
+	expirySweeper.Start(ctx)
+
+	// Handle graceful shutdown: stop taking requests first, so nothing
+	// changes while state is saved and sessions are closed
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigChan := make(chan os.Signal, 1)
 		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 		<-sigChan
 
 		log.Println("Shutting down...")
+		httpCtx, cancelHTTP := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSec)*time.Second)
+		if err := server.Shutdown(httpCtx); err != nil {
+			log.Printf("HTTP shutdown: %v", err)
+		}
+		cancelHTTP()
 		cancel()
 		lifecycleManager.Stop()
+		expirySweeper.Stop()
+		if cfg.StateFile != "" {
+			if err := snapshot.Save(cfg.StateFile, snapshot.Capture(marketManager, marketOrderbooks, positions)); err != nil {
+				log.Printf("Failed to save state snapshot: %v", err)
+			} else {
+				log.Printf("State saved to %s", cfg.StateFile)
+				if journalWriter != nil {
+					if err := journalWriter.Reset(); err != nil {
+						log.Printf("Failed to reset journal: %v", err)
+					}
+				}
+			}
+		}
+		if journalWriter != nil {
+			journalWriter.Close()
+		}
+		if sessions != nil {
+			shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeoutSec)*time.Second)
+			if err := sessions.Shutdown(shutdownCtx, yellow.ShutdownOptions{
+				Close:         cfg.ShutdownCloseSessions,
+				CheckpointDir: cfg.CheckpointDir,
+			}); err != nil {
+				log.Printf("Session shutdown: %v", err)
+			}
+			cancelShutdown()
+		}
 		if yellowClient != nil {
 			yellowClient.Close()
 		}
-		os.Exit(0)
+		if tradeStore != nil {
+			if err := tradeStore.Close(); err != nil {
+				log.Printf("Failed to close trade store: %v", err)
+			}
+		}
 	}()
 
 	// Start server
 	if err := server.Start(); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
+	<-shutdownDone
 }
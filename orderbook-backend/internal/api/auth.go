@@ -0,0 +1,61 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// callerKey is the request context key for the authenticated address
+type callerKey struct{}
+
+// authenticated requires a caller for handlers that move a user's funds or
+// orders. A Yellow JWT bearer token, once its signature checks out, attaches
+// its address to the request context, and the handler then acts for that
+// address only. The admin token may act for any user named in the request.
+// Anything else gets a 401.
+func (s *Server) authenticated(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.isAdmin(r) {
+			h(w, r)
+			return
+		}
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		session, err := s.tokens.Validate(token)
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, "invalid or expired token")
+			return
+		}
+		h(w, r.WithContext(context.WithValue(r.Context(), callerKey{}, session.Address)))
+	}
+}
+
+// authenticatedAddress returns the address attached by authenticated, or ""
+// for admin requests
+func authenticatedAddress(r *http.Request) string {
+	address, _ := r.Context().Value(callerKey{}).(string)
+	return address
+}
+
+// actingUser returns the user a request acts for: the authenticated address,
+// which requested must match if given, or for admin requests the requested
+// user. It writes an error and returns false otherwise.
+func actingUser(w http.ResponseWriter, r *http.Request, requested string) (string, bool) {
+	address := authenticatedAddress(r)
+	if address == "" {
+		if requested == "" {
+			writeError(w, http.StatusBadRequest, "user_id is required")
+			return "", false
+		}
+		return requested, true
+	}
+	if requested != "" && !strings.EqualFold(requested, address) {
+		writeError(w, http.StatusForbidden, "user_id does not match the authenticated address")
+		return "", false
+	}
+	return address, true
+}
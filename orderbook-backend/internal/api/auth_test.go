@@ -0,0 +1,108 @@
+package api
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPlaceOrderWithValidToken(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, alice, 100)
+
+	rec := ts.do(t, "POST", "/api/v1/order", ts.token(t, alice, time.Hour), map[string]interface{}{
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "buy",
+		"price":      5000,
+		"quantity":   10,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if _, ok := ts.marketOrderbooks.GetOrderbook(mkt.ID, "YES").BestBid(); !ok {
+		t.Fatal("alice's bid is not resting on the book")
+	}
+}
+
+func TestPlaceOrderWithExpiredToken(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, alice, 100)
+
+	rec := ts.do(t, "POST", "/api/v1/order", ts.token(t, alice, -time.Minute), map[string]interface{}{
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "buy",
+		"price":      5000,
+		"quantity":   10,
+	})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestPlaceOrderRejectsUserMismatch(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, bob, 100)
+
+	rec := ts.do(t, "POST", "/api/v1/order", ts.token(t, alice, time.Hour), map[string]interface{}{
+		"user_id":    bob,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "buy",
+		"price":      5000,
+		"quantity":   10,
+	})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+}
+
+func TestAuthenticatedRejectsForgedToken(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, alice, 100)
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forged := signToken(t, other, map[string]interface{}{
+		"address":    alice,
+		"expires_at": time.Now().Add(time.Hour).Unix(),
+	})
+	rec := ts.do(t, "POST", "/api/v1/order", forged, map[string]interface{}{
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "buy",
+		"price":      5000,
+		"quantity":   10,
+	})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+}
+
+func TestAuthenticatedFailsClosedWithoutAdminToken(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.AdminToken = ""
+
+	for _, bearer := range []string{"", testAdminToken} {
+		rec := ts.do(t, "POST", "/api/v1/deposit", bearer, map[string]interface{}{
+			"user_id": bob,
+			"amount":  100,
+		})
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("bearer %q: status = %d, want 401", bearer, rec.Code)
+		}
+	}
+	if balance := ts.positions.GetBalance(bob); balance != 0 {
+		t.Fatalf("bob's balance = %d, want 0", balance)
+	}
+}
@@ -0,0 +1,255 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+)
+
+// buyIdempotencyTTL is how long a completed /buy is replayed for its key
+const buyIdempotencyTTL = 24 * time.Hour
+
+var errNoComplementBids = errors.New("no bids for the other outcome at this price; nothing was bought")
+
+// BuyRequest is the request body for a one-step outcome purchase
+type BuyRequest struct {
+	UserID    string `json:"user_id"`
+	MarketID  string `json:"market_id"`
+	OutcomeID string `json:"outcome_id"` // Outcome to buy: "YES" or "NO"
+	Price     uint64 `json:"price"`      // Max price per share in basis points
+	MaxSpend  uint64 `json:"max_spend"`  // In basis points (10000 = 1 USDC)
+}
+
+// BuyResponse reports every step taken on the user's behalf
+type BuyResponse struct {
+	Order     *engine.Order    `json:"order"`
+	Trades    []*engine.Trade  `json:"trades"`
+	Deposited uint64           `json:"deposited"` // Topped up from the state channel to cover collateral
+	Minted    engine.Quantity  `json:"minted"`    // YES+NO pairs minted
+	Redeemed  engine.Quantity  `json:"redeemed"`  // Minted pairs whose other outcome didn't sell, redeemed again
+	Balance   uint64           `json:"balance"`
+	Position  *engine.Position `json:"position"`
+}
+
+// buyRecord is a completed /buy, kept so a retry with the same key gets
+// the same response instead of buying again
+type buyRecord struct {
+	request   BuyRequest
+	response  BuyResponse
+	expiresAt time.Time
+}
+
+// buyCache holds completed /buy requests by user and idempotency key
+type buyCache struct {
+	mu      sync.Mutex
+	records map[string]buyRecord
+}
+
+// get returns the unexpired record for a key
+func (c *buyCache) get(key string, now time.Time) (buyRecord, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	rec, ok := c.records[key]
+	if !ok || now.After(rec.expiresAt) {
+		return buyRecord{}, false
+	}
+	return rec, true
+}
+
+// put stores a completed buy and drops expired ones
+func (c *buyCache) put(key string, rec buyRecord, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.records == nil {
+		c.records = make(map[string]buyRecord)
+	}
+	for k, old := range c.records {
+		if now.After(old.expiresAt) {
+			delete(c.records, k)
+		}
+	}
+	c.records[key] = rec
+}
+
+// rollback undoes completed steps in reverse order and reports the steps
+// that could not be undone
+type rollback []func() error
+
+func (rb rollback) run() error {
+	var errs []error
+	for i := len(rb) - 1; i >= 0; i-- {
+		if err := rb[i](); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleBuy handles POST /api/buy
+//
+// Buying an outcome at price P is done by minting YES+NO pairs and selling
+// the other outcome at 10000-P or better, so the user pays at most P per
+// share. The sale is immediate-or-cancel: pairs whose other outcome doesn't
+// sell right away are redeemed again, so a buy never costs more than
+// max_spend, and one that sells nothing is undone. With channel-backed
+// balances, missing collateral is deposited from the user's state channel
+// first; otherwise the balance must already cover it. The steps run under
+// the user's lock, so no other request of theirs can spend the deposit or
+// the minted shares midway, and if a step fails the earlier ones are
+// undone. The Idempotency-Key header makes retries safe: a completed buy is
+// answered again instead of repeated.
+func (s *Server) handleBuy(w http.ResponseWriter, r *http.Request) {
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+	if idempotencyKey == "" {
+		writeError(w, http.StatusBadRequest, "Idempotency-Key header is required")
+		return
+	}
+
+	var req BuyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+
+	defer s.userLocks.lock(req.UserID)()
+
+	cacheKey := req.UserID + "\x00" + idempotencyKey
+	if rec, ok := s.buys.get(cacheKey, time.Now()); ok {
+		if rec.request != req {
+			writeError(w, http.StatusConflict, "Idempotency-Key was already used for a different request")
+			return
+		}
+		w.Header().Set("Idempotent-Replayed", "true")
+		writeJSON(w, http.StatusOK, rec.response)
+		return
+	}
+
+	if req.Price == 0 || req.Price >= 10000 {
+		writeError(w, http.StatusBadRequest, "price must be between 1 and 9999")
+		return
+	}
+
+	status, ok := s.marketManager.Status(req.MarketID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+	if status != market.StatusTrading {
+		writeError(w, http.StatusBadRequest, "market is not accepting orders")
+		return
+	}
+
+	// The order offers the complement of the outcome being bought
+	var complement engine.OutcomeID
+	switch req.OutcomeID {
+	case "YES":
+		complement = engine.OutcomeNO
+	case "NO":
+		complement = engine.OutcomeYES
+	default:
+		writeError(w, http.StatusBadRequest, "invalid outcome_id: must be 'YES' or 'NO'")
+		return
+	}
+
+	shares := req.MaxSpend * engine.QuantityScale() / req.Price
+	if shares == 0 {
+		writeError(w, http.StatusBadRequest, "max_spend does not cover one share at this price")
+		return
+	}
+
+	var undo rollback
+	fail := func(err error) {
+		if rbErr := undo.run(); rbErr != nil {
+			s.logger.ErrorContext(r.Context(), "buy rollback incomplete", "user_id", req.UserID, "error", err, "rollback_error", rbErr)
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("buy failed (%v) and could not be fully rolled back: %v", err, rbErr))
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error())
+	}
+
+	// Step 1: deposit whatever the mint needs beyond the available balance.
+	// Only channel-committed funds can be deposited this way.
+	collateral := engine.Collateral(shares)
+	var deposited uint64
+	if available := s.positions.AvailableBalance(req.UserID); available < collateral {
+		if !s.cfg.ChannelBackedBalances {
+			fail(engine.ErrInsufficientBalance)
+			return
+		}
+		deposited = collateral - available
+		if err := s.positions.Deposit(req.UserID, deposited); err != nil {
+			fail(err)
+			return
+		}
+		undo = append(undo, func() error { return s.positions.Withdraw(req.UserID, deposited) })
+	}
+
+	// Step 2: mint the pairs
+	if err := s.positions.MintShares(req.UserID, req.MarketID, shares); err != nil {
+		fail(err)
+		return
+	}
+	undo = append(undo, func() error { return s.positions.RedeemShares(req.UserID, req.MarketID, shares) })
+
+	// Step 3: sell the complement to whatever bids are there now
+	order := engine.NewOrder(req.UserID, req.MarketID, complement, engine.SideSell, 10000-req.Price, shares)
+	order.TimeInForce = engine.TimeInForceIOC
+	trades, err := s.placeOrder(r.Context(), order)
+	if err != nil {
+		fail(err)
+		return
+	}
+	var sold uint64
+	for _, trade := range trades {
+		if trade.SellOrderID == order.ID {
+			sold += trade.Quantity
+		}
+	}
+	if sold == 0 {
+		fail(errNoComplementBids)
+		return
+	}
+
+	// Step 4: redeem the pairs that didn't sell and hand back the part of
+	// the deposit they no longer need
+	unsold := shares - sold
+	if unsold > 0 {
+		if err := s.positions.RedeemShares(req.UserID, req.MarketID, unsold); err != nil {
+			s.logger.ErrorContext(r.Context(), "buy could not redeem unsold pairs", "user_id", req.UserID, "unsold", unsold, "error", err)
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("bought %d shares but could not redeem the %d unsold pairs: %v", sold, unsold, err))
+			return
+		}
+		if refund := min(deposited, engine.Collateral(unsold)); refund > 0 {
+			if err := s.positions.Withdraw(req.UserID, refund); err != nil {
+				s.logger.ErrorContext(r.Context(), "buy could not return unused deposit", "user_id", req.UserID, "refund", refund, "error", err)
+			} else {
+				deposited -= refund
+			}
+		}
+	}
+
+	resp := BuyResponse{
+		Order:     order,
+		Trades:    trades,
+		Deposited: deposited,
+		Minted:    engine.Quantity(shares),
+		Redeemed:  engine.Quantity(unsold),
+		Balance:   s.positions.GetBalance(req.UserID),
+		Position:  s.positions.GetPosition(req.UserID, req.MarketID),
+	}
+	now := time.Now()
+	s.buys.put(cacheKey, buyRecord{request: req, response: resp, expiresAt: now.Add(buyIdempotencyTTL)}, now)
+	writeJSON(w, http.StatusOK, resp)
+}
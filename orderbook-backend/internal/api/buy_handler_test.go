@@ -0,0 +1,211 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	"orderbook-backend/internal/state"
+	"orderbook-backend/internal/yellow"
+)
+
+// channelBacked turns on channel-backed balances and commits usdc (whole
+// units) to the user's channel
+func (ts *testServer) channelBacked(t *testing.T, userID string, usdc uint64) {
+	t.Helper()
+	ts.cfg.ChannelBackedBalances = true
+	alloc := state.NewAllocations("", ts.cfg.DefaultToken, nil)
+	addr, err := yellow.NormalizeAddress(userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	alloc.Credit(ts.cfg.DefaultToken, addr, usdc*10000)
+	ts.SetAllocations(alloc)
+}
+
+func (ts *testServer) buy(t *testing.T, key string, body map[string]interface{}) *http.Response {
+	t.Helper()
+	req := ts.newRequest(t, "POST", "/api/v1/buy", testAdminToken, body)
+	if key != "" {
+		req.Header.Set("Idempotency-Key", key)
+	}
+	return ts.serve(req).Result()
+}
+
+func TestBuyRollsBackWhenPlacingFails(t *testing.T) {
+	ts := newTestServer(t)
+	ts.marketOrderbooks.SetTickSize(100)
+	mkt := ts.createMarket(t, alice)
+	ts.channelBacked(t, alice, 100)
+
+	// The complement is offered at 10000-6050 = 3950, off the 100 bps tick
+	resp := ts.buy(t, "k1", map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"price":      6050,
+		"max_spend":  605000,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	if balance := ts.positions.GetBalance(alice); balance != 0 {
+		t.Errorf("balance = %d, want the deposit rolled back to 0", balance)
+	}
+	pos := ts.positions.GetPosition(alice, mkt.ID)
+	if pos.YesShares != 0 || pos.NoShares != 0 {
+		t.Errorf("shares = %d YES / %d NO, want the mint rolled back", pos.YesShares, pos.NoShares)
+	}
+}
+
+func TestBuyRejectsUnbackedShortfall(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+
+	resp := ts.buy(t, "k1", map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"price":      6000,
+		"max_spend":  600000,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	if balance := ts.positions.GetBalance(alice); balance != 0 {
+		t.Fatalf("balance = %d, want no free collateral", balance)
+	}
+}
+
+func TestBuyRequiresIdempotencyKey(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, alice, 100)
+
+	resp := ts.buy(t, "", map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"price":      6000,
+		"max_spend":  600000,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+}
+
+func TestBuyIsIdempotent(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 100)
+	body := map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"price":      6000,
+		"max_spend":  600000,
+	}
+
+	// Concurrent retries of the same buy mint and place it once
+	var wg sync.WaitGroup
+	orderIDs := make([]string, 4)
+	for i := range orderIDs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp := ts.buy(t, "same-key", body)
+			if resp.StatusCode != http.StatusOK {
+				t.Errorf("status = %d, want 200", resp.StatusCode)
+				return
+			}
+			var out struct {
+				Order struct {
+					ID string `json:"id"`
+				} `json:"order"`
+			}
+			if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+				t.Error(err)
+			}
+			orderIDs[i] = out.Order.ID
+		}(i)
+	}
+	wg.Wait()
+	for _, id := range orderIDs[1:] {
+		if id != orderIDs[0] {
+			t.Fatalf("order IDs %v differ, want one buy", orderIDs)
+		}
+	}
+	if pos := ts.positions.GetPosition(alice, mkt.ID); pos.YesShares != 100 {
+		t.Fatalf("YES shares = %d, want 100 minted once", pos.YesShares)
+	}
+
+	body["price"] = 5000
+	if resp := ts.buy(t, "same-key", body); resp.StatusCode != http.StatusConflict {
+		t.Fatalf("reused key: status = %d, want 409", resp.StatusCode)
+	}
+}
+
+func TestBuyRedeemsWhatDoesNotSell(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 40)
+
+	resp := ts.buy(t, "k1", map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"price":      6000,
+		"max_spend":  600000,
+	})
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	var out BuyResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	if out.Minted != 100 || out.Redeemed != 60 {
+		t.Errorf("minted %d, redeemed %d, want 100 and 60", out.Minted, out.Redeemed)
+	}
+
+	// Only the 40 NO that sold leave YES behind; the rest is collateral again
+	if spent := 1000000 - ts.positions.GetBalance(alice); spent != 40*6000 {
+		t.Errorf("spent %d, want 240000 for 40 shares at 6000", spent)
+	}
+	pos := ts.positions.GetPosition(alice, mkt.ID)
+	if pos.YesShares != 40 || pos.NoShares != 0 {
+		t.Errorf("shares = %d YES / %d NO, want 40 / 0", pos.YesShares, pos.NoShares)
+	}
+	if asks := ts.marketOrderbooks.OpenSellQuantity(alice, mkt.ID, "NO"); asks != 0 {
+		t.Errorf("alice has %d NO resting, want the unsold remainder cancelled", asks)
+	}
+}
+
+func TestBuyWithNoBidsIsUndone(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	ts.channelBacked(t, alice, 100)
+
+	resp := ts.buy(t, "k1", map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"price":      6000,
+		"max_spend":  600000,
+	})
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("status = %d, want 400", resp.StatusCode)
+	}
+	if balance := ts.positions.GetBalance(alice); balance != 0 {
+		t.Errorf("balance = %d, want the deposit returned", balance)
+	}
+	pos := ts.positions.GetPosition(alice, mkt.ID)
+	if pos.YesShares != 0 || pos.NoShares != 0 {
+		t.Errorf("shares = %d YES / %d NO, want the mint undone", pos.YesShares, pos.NoShares)
+	}
+}
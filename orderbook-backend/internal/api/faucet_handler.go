@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// FaucetRequest is the request body for a testnet faucet grant
+type FaucetRequest struct {
+	UserID string `json:"user_id"`
+}
+
+// FaucetGrant records one faucet credit for auditing
+type FaucetGrant struct {
+	UserID    string    `json:"user_id"`
+	IP        string    `json:"ip"`
+	Amount    uint64    `json:"amount"` // In basis points (10000 = 1 USDC)
+	GrantedAt time.Time `json:"granted_at"`
+}
+
+// faucet grants test funds at most once per user and per IP each UTC day
+type faucet struct {
+	mu     sync.Mutex
+	day    string          // UTC date the claims below belong to
+	claims map[string]bool // "user:<id>" / "ip:<addr>" -> claimed today
+	grants []FaucetGrant
+}
+
+// claim reserves today's grant for a user and IP, reporting false if either
+// has already claimed
+func (f *faucet) claim(userID, ip string, now time.Time) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if day := now.UTC().Format("2006-01-02"); day != f.day {
+		f.day = day
+		f.claims = make(map[string]bool)
+	}
+	if f.claims["user:"+userID] || f.claims["ip:"+ip] {
+		return false
+	}
+	f.claims["user:"+userID] = true
+	f.claims["ip:"+ip] = true
+	return true
+}
+
+// release undoes a claim whose deposit failed
+func (f *faucet) release(userID, ip string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.claims, "user:"+userID)
+	delete(f.claims, "ip:"+ip)
+}
+
+// record appends a grant to the audit log
+func (f *faucet) record(g FaucetGrant) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.grants = append(f.grants, g)
+}
+
+// history returns a copy of the audit log
+func (f *faucet) history() []FaucetGrant {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FaucetGrant{}, f.grants...)
+}
+
+// clientIP returns the host part of the request's remote address
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// handleFaucet handles POST /api/faucet
+func (s *Server) handleFaucet(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg.FaucetEnabled {
+		writeError(w, http.StatusNotFound, "faucet is disabled")
+		return
+	}
+
+	var req FaucetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	userID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+
+	ip := clientIP(r)
+	now := time.Now()
+	if !s.faucet.claim(req.UserID, ip, now) {
+		writeError(w, http.StatusTooManyRequests, "faucet already used today")
+		return
+	}
+
+	amount := uint64(s.cfg.FaucetAmount) * 10000 // USDC -> basis points
+	if err := s.positions.Deposit(req.UserID, amount); err != nil {
+		s.faucet.release(req.UserID, ip)
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.faucet.record(FaucetGrant{UserID: req.UserID, IP: ip, Amount: amount, GrantedAt: now})
+	log.Printf("Faucet: credited %d to %s (ip %s)", amount, req.UserID, ip)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":  req.UserID,
+		"credited": amount,
+		"balance":  s.positions.GetBalance(req.UserID),
+	})
+}
+
+// handleFaucetGrants handles GET /api/faucet/grants
+func (s *Server) handleFaucetGrants(w http.ResponseWriter, r *http.Request) {
+	if !s.cfg.FaucetEnabled {
+		writeError(w, http.StatusNotFound, "faucet is disabled")
+		return
+	}
+	if !s.requireAdmin(w, r) {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.faucet.history())
+}
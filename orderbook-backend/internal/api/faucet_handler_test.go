@@ -0,0 +1,65 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestFaucetGrantsOncePerDay(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.FaucetEnabled = true
+	ts.cfg.FaucetAmount = 100
+
+	if rec := ts.do(t, "POST", "/api/v1/faucet", ts.token(t, alice, time.Hour), map[string]string{}); rec.Code != http.StatusOK {
+		t.Fatalf("first grant: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := ts.positions.GetBalance(alice); got != 100*10000 {
+		t.Fatalf("balance = %d, want %d", got, 100*10000)
+	}
+
+	// Neither the same user nor another from the same IP gets a second grant
+	for _, user := range []string{alice, bob} {
+		if rec := ts.do(t, "POST", "/api/v1/faucet", ts.token(t, user, time.Hour), map[string]string{}); rec.Code != http.StatusTooManyRequests {
+			t.Errorf("%s same day: status = %d, want 429", user, rec.Code)
+		}
+	}
+	if got := ts.positions.GetBalance(alice); got != 100*10000 {
+		t.Fatalf("balance after refusal = %d, want %d", got, 100*10000)
+	}
+
+	rec := ts.do(t, "GET", "/api/v1/faucet/grants", testAdminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("grants: status = %d", rec.Code)
+	}
+	if grants := ts.faucet.history(); len(grants) != 1 || grants[0].UserID != alice {
+		t.Fatalf("grants = %+v, want alice's one", grants)
+	}
+}
+
+func TestFaucetClaimResetsEachDay(t *testing.T) {
+	var f faucet
+	day := time.Date(2026, 3, 1, 23, 0, 0, 0, time.UTC)
+	if !f.claim(alice, "10.0.0.1", day) {
+		t.Fatal("first claim refused")
+	}
+	if f.claim(alice, "10.0.0.2", day.Add(30*time.Minute)) {
+		t.Fatal("second claim the same day granted")
+	}
+	if !f.claim(alice, "10.0.0.1", day.Add(2*time.Hour)) {
+		t.Fatal("claim the next day refused")
+	}
+}
+
+func TestFaucetDisabled(t *testing.T) {
+	ts := newTestServer(t)
+	if rec := ts.do(t, "POST", "/api/v1/faucet", ts.token(t, alice, time.Hour), map[string]string{}); rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want 404", rec.Code)
+	}
+	if got := ts.positions.GetBalance(alice); got != 0 {
+		t.Fatalf("balance = %d, want 0", got)
+	}
+	if rec := ts.do(t, "GET", "/api/v1/faucet/grants", testAdminToken, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("grants: status = %d, want 404", rec.Code)
+	}
+}
@@ -1,13 +1,18 @@
 package api
 
 import (
-	"log"
+	"context"
+	"crypto/subtle"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
 
 	"orderbook-backend/internal/config"
 	"orderbook-backend/internal/engine"
 	"orderbook-backend/internal/market"
 	"orderbook-backend/internal/state"
+	"orderbook-backend/internal/tradestore"
 	"orderbook-backend/internal/yellow"
 )
 
@@ -17,10 +22,26 @@ type Server struct {
 	marketOrderbooks *engine.MarketOrderbooks
 	yellowClient     *yellow.Client
 	sessions         *yellow.SessionManager
+	disputer         *yellow.Disputer      // Submits on-chain challenges (nil = no chain access)
+	tokens           *yellow.TokenVerifier // Verifies callers' Yellow JWTs (nil = JWTs rejected)
 	allocations      *state.Allocations
 	wsHub            *Hub
 	marketManager    *market.Manager
 	positions        *engine.PositionManager
+	tradeStore       *tradestore.Store
+	tape             *engine.TradeHistory // Recent trades across every market
+	logger           *slog.Logger
+
+	httpMu     sync.Mutex
+	httpServer *http.Server
+
+	ammMu sync.RWMutex
+	amms  map[string]*engine.AMM // marketID -> house market maker
+
+	faucet faucet
+
+	userLocks userLocks // Serializes each user's fund-moving requests
+	buys      buyCache  // Completed /buy requests by idempotency key
 }
 
 // NewServer creates a new API server
@@ -40,45 +61,169 @@ func NewServer(
 		wsHub:            NewHub(),
 		marketManager:    marketManager,
 		positions:        positions,
+		amms:             make(map[string]*engine.AMM),
+		tape:             engine.NewTradeHistory(globalTapeSize),
+		logger:           slog.Default(),
 	}
 }
 
-// SetAllocations sets the allocations tracker
+// SetLogger sets the logger for request and trade logs
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// SetAllocations sets the allocations tracker. With CHANNEL_BACKED_BALANCES
+// on, deposits and withdrawals must then reconcile with it.
 func (s *Server) SetAllocations(alloc *state.Allocations) {
 	s.allocations = alloc
+	if s.cfg.ChannelBackedBalances {
+		s.positions.SetChannelBacking(s.channelBalance)
+	}
+}
+
+// channelBalance returns a user's channel-backed funds in basis points.
+// Channel allocations are keyed by checksummed address; only the default
+// token backs trading balances.
+func (s *Server) channelBalance(userID string) uint64 {
+	addr, err := yellow.NormalizeAddress(userID)
+	if err != nil {
+		return 0
+	}
+	token, err := yellow.NormalizeAddress(s.cfg.DefaultToken)
+	if err != nil {
+		return 0
+	}
+	return s.allocations.GetBalance(token, addr)
+}
+
+// SetDisputer sets the on-chain disputer used by dispute settlement
+func (s *Server) SetDisputer(d *yellow.Disputer) {
+	s.disputer = d
+}
+
+// SetTokenVerifier sets the verifier for callers' Yellow JWTs. Without one,
+// only the admin token authenticates.
+func (s *Server) SetTokenVerifier(v *yellow.TokenVerifier) {
+	s.tokens = v
+}
+
+// SetTradeStore sets the durable trade store
+func (s *Server) SetTradeStore(store *tradestore.Store) {
+	s.tradeStore = store
+}
+
+// isAdmin reports whether the request carries the admin bearer token. With
+// no ADMIN_TOKEN configured nobody is an admin.
+func (s *Server) isAdmin(r *http.Request) bool {
+	if s.cfg.AdminToken == "" {
+		return false
+	}
+	token := r.Header.Get("Authorization")
+	return subtle.ConstantTimeCompare([]byte(token), []byte("Bearer "+s.cfg.AdminToken)) == 1
+}
+
+// requireAdmin checks the admin bearer token. It writes a 401 and returns
+// false if the request isn't authorized.
+func (s *Server) requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if !s.isAdmin(r) {
+		writeError(w, http.StatusUnauthorized, "admin token required")
+		return false
+	}
+	return true
 }
 
-// RegisterRoutes registers all HTTP routes
+// callerAddress returns the address from a verified Yellow JWT bearer
+// token, or "" if the request carries no valid token
+func (s *Server) callerAddress(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	session, err := s.tokens.Validate(token)
+	if err != nil {
+		return ""
+	}
+	return session.Address
+}
+
+// RegisterRoutes registers all HTTP routes. The API is mounted under
+// /api/<version>; the current version is also served unversioned under /api
+// with a Deprecation header.
 func (s *Server) RegisterRoutes(mux *http.ServeMux) {
+	version := s.cfg.APIVersion
+	if version == "" {
+		version = defaultAPIVersion
+	}
+	mountVersion(mux, version, true, s.routesV1)
+
+	// WebSocket endpoint
+	mux.HandleFunc("GET /ws", s.handleWebSocket)
+}
+
+// routesV1 registers the v1 API
+func (s *Server) routesV1(rt *router) {
 	// Health check
-	mux.HandleFunc("GET /api/health", s.handleHealth)
+	rt.handle("GET /health", s.handleHealth)
 
 	// Market endpoints (prediction market)
-	mux.HandleFunc("POST /api/market", s.handleCreateMarket)
-	mux.HandleFunc("GET /api/markets", s.handleListMarkets)
-	mux.HandleFunc("GET /api/market/{id}", s.handleGetMarket)
-	mux.HandleFunc("POST /api/market/{id}/resolve", s.handleResolveMarket)
+	rt.handle("POST /market", s.authenticated(s.handleCreateMarket))
+	rt.handle("GET /markets", s.handleListMarkets)
+	rt.handle("GET /market/{id}", s.handleGetMarket)
+	rt.handle("PATCH /market/{id}", s.handleUpdateMarket)
+	rt.handle("GET /market/{id}/stats", s.handleMarketStats)
+	rt.handle("GET /market/{id}/ticker", s.handleGetMarketTicker)
+	rt.handle("POST /market/{id}/resolve", s.handleResolveMarket)
+	rt.handle("POST /market/{id}/dispute", s.handleDisputeMarket)
+	rt.handle("POST /market/{id}/drain", s.handleDrainMarket)
+	rt.handle("POST /market/{id}/reopen", s.handleReopenMarket)
+	rt.handle("GET /market/{id}/cost-to-price", s.handleCostToPrice)
+	rt.handle("GET /market/{id}/simulate", s.handleSimulateResolution)
+	rt.handle("GET /market/{id}/export", s.handleExportMarket)
+	rt.handle("GET /market/{id}/settlement", s.handleGetSettlement)
+	rt.handle("POST /admin/markets/resolve-batch", s.handleResolveBatch)
 
 	// Order endpoints
-	mux.HandleFunc("POST /api/order", s.handlePlaceOrder)
-	mux.HandleFunc("GET /api/orderbook", s.handleGetOrderbook)
-	mux.HandleFunc("DELETE /api/order/{id}", s.handleCancelOrder)
-	mux.HandleFunc("GET /api/trades", s.handleGetTrades)
+	rt.handle("POST /order", s.authenticated(s.handlePlaceOrder))
+	rt.handle("GET /orderbook", s.handleGetOrderbook)
+	rt.handle("GET /orderbook/replay", s.handleReplayOrderbook)
+	rt.handle("GET /ticker", s.handleGetTicker)
+	rt.handle("GET /candles", s.handleGetCandles)
+	rt.handle("GET /order/{id}", s.handleGetOrder)
+	rt.handle("PATCH /order/{id}", s.authenticated(s.handleAmendOrder))
+	rt.handle("DELETE /order/{id}", s.authenticated(s.handleCancelOrder))
+	rt.handle("DELETE /orders", s.authenticated(s.handleCancelUserOrders))
+	rt.handle("GET /trades", s.handleGetTrades)
+	rt.handle("GET /trades/recent", s.handleGetRecentTrades)
 
 	// Position endpoints
-	mux.HandleFunc("GET /api/position/{userId}", s.handleGetPosition)
-	mux.HandleFunc("POST /api/deposit", s.handleDeposit)
-	mux.HandleFunc("POST /api/mint", s.handleMintShares)
+	rt.handle("GET /position/{userId}", s.handleGetPosition)
+	rt.handle("GET /fills/{userId}", s.handleGetUserFills)
+	rt.handle("GET /pnl/{userId}", s.handleGetPnL)
+	rt.handle("GET /ledger/{userId}", s.handleGetLedger)
+	rt.handle("POST /deposit", s.authenticated(s.handleDeposit))
+	rt.handle("POST /withdraw", s.authenticated(s.handleWithdraw))
+	rt.handle("POST /mint", s.authenticated(s.handleMintShares))
+	rt.handle("POST /buy", s.authenticated(s.handleBuy))
+	rt.handle("POST /faucet", s.authenticated(s.handleFaucet))
+	rt.handle("GET /faucet/grants", s.handleFaucetGrants)
 
 	// Session endpoints
-	mux.HandleFunc("POST /api/session", s.handleCreateSession)
-	mux.HandleFunc("DELETE /api/session/{id}", s.handleCloseSession)
+	rt.handle("POST /session", s.handleCreateSession)
+	rt.handle("DELETE /session/{id}", s.handleCloseSession)
+
+	// Yellow signing metadata
+	rt.handle("GET /yellow/eip712-domain", s.handleGetEIP712Domain)
 
 	// Settlement endpoint
-	mux.HandleFunc("POST /api/settle", s.handleSettle)
+	rt.handle("POST /settle", s.handleSettle)
+}
 
-	// WebSocket endpoint
-	mux.HandleFunc("GET /ws", s.handleWebSocket)
+// onTrade is the global trade callback for every orderbook
+func (s *Server) onTrade(trade *engine.Trade) {
+	s.tape.Add(trade)
+	if s.tradeStore != nil {
+		s.tradeStore.Append(trade)
+	}
 }
 
 // Start starts the HTTP server
@@ -86,23 +231,61 @@ func (s *Server) Start() error {
 	// Start WebSocket hub
 	go s.wsHub.Run()
 
-	// Trade callbacks are set per-market when markets are created
+	// Record every trade on the global tape (and persist it) as it is matched
+	s.marketOrderbooks.SetGlobalTradeCallback(s.onTrade)
+
+	// Stream level-3 order events to subscribed WebSocket clients
+	s.marketOrderbooks.SetGlobalOrderEventCallback(s.publishOrderEvent)
+
+	// Tell authenticated WebSocket clients when their own orders change
+	s.marketOrderbooks.SetGlobalOrderUpdateCallback(s.publishOrderUpdate)
 
 	mux := http.NewServeMux()
 	s.RegisterRoutes(mux)
 
-	// Add CORS middleware
-	handler := corsMiddleware(mux)
+	// Throttle each client, then add CORS headers (to 429s as well), and
+	// tag and log every request
+	var handler http.Handler = mux
+	if s.cfg.RateLimitRPS > 0 {
+		handler = s.rateLimitMiddleware(newRateLimiter(s.cfg.RateLimitRPS, s.cfg.RateLimitBurst), handler)
+	}
+	handler = corsMiddleware(handler)
+	handler = s.requestLogMiddleware(handler)
 
 	addr := ":" + s.cfg.ServerPort
-	log.Printf("Server starting on %s", addr)
-	return http.ListenAndServe(addr, handler)
+	srv := &http.Server{Addr: addr, Handler: handler}
+	s.httpMu.Lock()
+	s.httpServer = srv
+	s.httpMu.Unlock()
+
+	s.logger.Info("server starting", "addr", addr)
+	if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting connections, waits for in-flight requests to
+// finish until ctx is done, then closes every WebSocket client and stops
+// the hub. Start returns nil once it has been called.
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.httpMu.Lock()
+	srv := s.httpServer
+	s.httpMu.Unlock()
+
+	var err error
+	if srv != nil {
+		err = srv.Shutdown(ctx)
+	}
+	s.wsHub.Stop()
+	return err
 }
 
 // handleHealth is the health check endpoint
 func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
-	writeJSON(w, http.StatusOK, map[string]string{
-		"status": "ok",
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "ok",
+		"websocket": s.wsHub.Stats(),
 	})
 }
 
@@ -110,8 +293,9 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 func corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Request-ID")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Total-Count, X-Request-ID")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsInFlightRequests(t *testing.T) {
+	ts := newTestServer(t)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	_, ts.cfg.ServerPort, _ = net.SplitHostPort(addr)
+	ln.Close()
+
+	started := make(chan error, 1)
+	go func() { started <- ts.Start() }()
+	var conn net.Conn
+	for deadline := time.Now().Add(5 * time.Second); conn == nil; {
+		if conn, err = net.Dial("tcp", addr); err != nil && time.Now().After(deadline) {
+			t.Fatalf("server never came up: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	defer conn.Close()
+
+	// Send the request line and half the body, so the request is in flight
+	body := `{"user_id":"nobody"}`
+	req := "POST /api/v1/order HTTP/1.1\r\nHost: test\r\nAuthorization: Bearer " + testAdminToken +
+		"\r\nContent-Type: application/json\r\nContent-Length: 20\r\n\r\n" + body[:10]
+	if _, err := conn.Write([]byte(req)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	shutdown := make(chan error, 1)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		shutdown <- ts.Shutdown(ctx)
+	}()
+
+	// New connections are refused while the request is still running
+	for deadline := time.Now().Add(5 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		c, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		c.Close()
+		if time.Now().After(deadline) {
+			t.Fatal("server still accepts connections during shutdown")
+		}
+	}
+	select {
+	case err := <-shutdown:
+		t.Fatalf("shutdown returned with a request in flight: %v", err)
+	default:
+	}
+
+	if _, err := conn.Write([]byte(body[10:])); err != nil {
+		t.Fatal(err)
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("in-flight request got no response: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("in-flight order: status = %d, want the handler's 404 for its missing market", resp.StatusCode)
+	}
+	if err := <-shutdown; err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+	if err := <-started; err != nil {
+		t.Fatalf("Start returned %v after shutdown, want nil", err)
+	}
+}
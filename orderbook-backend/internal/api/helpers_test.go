@@ -0,0 +1,167 @@
+package api
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/config"
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+	"orderbook-backend/internal/yellow"
+)
+
+const testAdminToken = "test-admin"
+
+// Addresses used as users in the API tests
+const (
+	alice = "0x1111111111111111111111111111111111111111"
+	bob   = "0x2222222222222222222222222222222222222222"
+)
+
+// testServer is a Server wired with in-memory components, its routes and a
+// key for issuing user tokens
+type testServer struct {
+	*Server
+	mux *http.ServeMux
+	key *ecdsa.PrivateKey
+}
+
+// newTestServer creates a server with an admin token and a JWT verifier
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	cfg := &config.Config{
+		AdminToken:     testAdminToken,
+		APIVersion:     "v1",
+		TickSize:       1,
+		MinOrderQty:    "0",
+		AllocationMode: "fifo",
+		DefaultToken:   "0x0000000000000000000000000000000000000000",
+	}
+	s := NewServer(cfg, engine.NewMarketOrderbooks(), nil, nil, market.NewManager(), engine.NewPositionManager())
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier, err := yellow.NewTokenVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetTokenVerifier(verifier)
+
+	mux := http.NewServeMux()
+	s.RegisterRoutes(mux)
+	return &testServer{Server: s, mux: mux, key: key}
+}
+
+// token issues an ES256 JWT for address that expires after ttl
+func (ts *testServer) token(t *testing.T, address string, ttl time.Duration) string {
+	t.Helper()
+	return signToken(t, ts.key, map[string]interface{}{
+		"address":    address,
+		"expires_at": time.Now().Add(ttl).Unix(),
+	})
+}
+
+// signToken signs claims as an ES256 JWT
+func signToken(t *testing.T, key *ecdsa.PrivateKey, claims map[string]interface{}) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+// do sends a request with an optional JSON body and bearer token
+func (ts *testServer) do(t *testing.T, method, path, bearer string, body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	return ts.serve(ts.newRequest(t, method, path, bearer, body))
+}
+
+// newRequest builds a request with an optional JSON body and bearer token
+func (ts *testServer) newRequest(t *testing.T, method, path, bearer string, body interface{}) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, path, &buf)
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+	return req
+}
+
+// serve runs a request through the server's routes
+func (ts *testServer) serve(req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	ts.mux.ServeHTTP(rec, req)
+	return rec
+}
+
+// createMarket creates a trading YES/NO market through the manager
+func (ts *testServer) createMarket(t *testing.T, creator string) *market.Market {
+	t.Helper()
+	mkt, err := ts.marketManager.Create(market.CreateMarketRequest{
+		Question:   "Will it rain?",
+		ResolvesAt: time.Now().Add(time.Hour),
+		CreatorID:  creator,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mkt
+}
+
+// fund deposits USDC (whole units) for a user
+func (ts *testServer) fund(t *testing.T, userID string, usdc uint64) {
+	t.Helper()
+	if err := ts.positions.Deposit(userID, usdc*10000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// placeOrder places a limit order for a user with the admin token and fails
+// the test unless it is accepted
+func (ts *testServer) placeOrder(t *testing.T, userID, marketID, outcome, side string, price, qty uint64) {
+	t.Helper()
+	rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+		"user_id":    userID,
+		"market_id":  marketID,
+		"outcome_id": outcome,
+		"side":       side,
+		"price":      price,
+		"quantity":   qty,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("place %s %s %d@%d for %s: status = %d, body %s", side, outcome, qty, price, userID, rec.Code, rec.Body)
+	}
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	"orderbook-backend/internal/engine"
@@ -11,10 +14,27 @@ import (
 
 // CreateMarketRequest is the request to create a new market
 type CreateMarketRequest struct {
-	Question    string `json:"question"`
-	Description string `json:"description,omitempty"`
-	ResolvesAt  string `json:"resolves_at"` // RFC3339 format
-	CreatorID   string `json:"creator_id"`
+	Question    string   `json:"question"`
+	Description string   `json:"description,omitempty"`
+	Outcomes    []string `json:"outcomes,omitempty"` // Defaults to ["YES", "NO"]
+	ResolvesAt  string   `json:"resolves_at"`        // RFC3339 format
+	CreatorID   string   `json:"creator_id"`         // Must match the caller's address unless admin
+
+	// FeeFreeHours overrides the configured fee-free window for this market (admin only)
+	FeeFreeHours *int `json:"fee_free_hours,omitempty"`
+
+	// Per-user limit overrides for this market (0 = unlimited, admin only)
+	PositionLimit      *uint64 `json:"position_limit,omitempty"`       // Shares
+	DailyNotionalLimit *uint64 `json:"daily_notional_limit,omitempty"` // USDC
+
+	// AMMLiquidity overrides the configured house maker liquidity (0 = no AMM, admin only)
+	AMMLiquidity *int `json:"amm_liquidity,omitempty"`
+
+	// AllocationMode overrides how same-price fills are shared ("fifo" or "pro_rata", admin only)
+	AllocationMode string `json:"allocation_mode,omitempty"`
+
+	// Resolvers may resolve the market besides admins (defaults to creator_id, admin only)
+	Resolvers []string `json:"resolvers,omitempty"`
 }
 
 // handleCreateMarket handles POST /api/market
@@ -30,29 +50,178 @@ func (s *Server) handleCreateMarket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A market is credited to its caller; only admins may name another creator
+	creatorID, ok := actingUser(w, r, req.CreatorID)
+	if !ok {
+		return
+	}
+
+	// Resolvers decide the payout, so only admins may name them
+	if len(req.Resolvers) > 0 && !s.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "resolvers requires the admin token")
+		return
+	}
+
 	resolvesAt, err := time.Parse(time.RFC3339, req.ResolvesAt)
 	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid resolves_at format, use RFC3339")
 		return
 	}
 
+	// Waiving fees costs the exchange revenue, so only admins may override it
+	feeFreeHours := s.cfg.FeeFreeHours
+	if req.FeeFreeHours != nil {
+		if !s.isAdmin(r) {
+			writeError(w, http.StatusForbidden, "fee_free_hours requires the admin token")
+			return
+		}
+		feeFreeHours = *req.FeeFreeHours
+	}
+	if feeFreeHours < 0 {
+		writeError(w, http.StatusBadRequest, "fee_free_hours must not be negative")
+		return
+	}
+
+	// The house AMM trades with house funds, so only admins may change it
+	ammLiquidity := s.cfg.AMMLiquidity
+	if req.AMMLiquidity != nil {
+		if !s.isAdmin(r) {
+			writeError(w, http.StatusForbidden, "amm_liquidity requires the admin token")
+			return
+		}
+		ammLiquidity = *req.AMMLiquidity
+	}
+	if ammLiquidity < 0 {
+		writeError(w, http.StatusBadRequest, "amm_liquidity must not be negative")
+		return
+	}
+
+	// Allocation decides which makers get filled, so only admins may change it
+	modeName := s.cfg.AllocationMode
+	if req.AllocationMode != "" {
+		if !s.isAdmin(r) {
+			writeError(w, http.StatusForbidden, "allocation_mode requires the admin token")
+			return
+		}
+		modeName = req.AllocationMode
+	}
+	allocMode, err := engine.ParseAllocationMode(modeName)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Limits are a compliance control, so only admins may override them
+	if (req.PositionLimit != nil || req.DailyNotionalLimit != nil) && !s.isAdmin(r) {
+		writeError(w, http.StatusForbidden, "position_limit and daily_notional_limit require the admin token")
+		return
+	}
+
+	outcomes := make([]market.Outcome, len(req.Outcomes))
+	for i, o := range req.Outcomes {
+		outcomes[i] = market.Outcome(o)
+	}
+
 	mkt, err := s.marketManager.Create(market.CreateMarketRequest{
-		Question:    req.Question,
-		Description: req.Description,
-		ResolvesAt:  resolvesAt,
-		CreatorID:   req.CreatorID,
+		Question:      req.Question,
+		Description:   req.Description,
+		Outcomes:      outcomes,
+		ResolvesAt:    resolvesAt,
+		CreatorID:     creatorID,
+		FeeFreeWindow: time.Duration(feeFreeHours) * time.Hour,
+		Resolvers:     req.Resolvers,
 	})
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		switch err {
+		case market.ErrTooManyOutcomes, market.ErrInvalidOutcomeSet, market.ErrNotBinary:
+			writeError(w, http.StatusBadRequest, err.Error())
+		default:
+			writeError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
+	// Waive fees during the bootstrap window
+	s.positions.SetFeeFreeWindow(mkt.ID, mkt.FeeFreeUntil)
+
+	// Override the global per-user limits if requested
+	if req.PositionLimit != nil || req.DailyNotionalLimit != nil {
+		limits := s.positions.LimitsFor(mkt.ID)
+		if req.PositionLimit != nil {
+			limits.MaxNetPosition = *req.PositionLimit * engine.QuantityScale()
+		}
+		if req.DailyNotionalLimit != nil {
+			limits.MaxDailyNotional = *req.DailyNotionalLimit * 10000
+		}
+		s.positions.SetMarketLimits(mkt.ID, limits)
+	}
+
+	// Create the YES/NO orderbooks up front so they carry the global trade
+	// callback from the start instead of being created on the first order
+	s.marketOrderbooks.GetOrCreate(mkt.ID)
+	if allocMode != engine.AllocationFIFO {
+		s.marketOrderbooks.SetAllocationMode(mkt.ID, allocMode, uint64(max(s.cfg.ProRataMinQty, 1))*engine.QuantityScale())
+	}
+
+	// Collect opening orders without matching, then uncross at one price
+	if s.cfg.OpeningAuctionSec > 0 {
+		s.startOpeningAuction(mkt.ID, time.Duration(s.cfg.OpeningAuctionSec)*time.Second)
+	}
+
+	// Seed thin books with house liquidity
+	if ammLiquidity > 0 {
+		s.startAMM(mkt.ID, float64(ammLiquidity))
+	}
+
+	// Otherwise the market's session is opened by its first trade
+	if s.cfg.SessionTrigger == "market_creation" {
+		s.openYellowSession(r.Context(), mkt.ID)
+	}
+
 	writeJSON(w, http.StatusCreated, mkt.ToJSON())
 }
 
-// handleListMarkets handles GET /api/markets
+// Page sizes for GET /api/markets
+const (
+	defaultMarketPage = 100
+	maxMarketPage     = 1000
+)
+
+// handleListMarkets handles GET /api/markets?status=&creator_id=&limit=&offset=.
+// Markets are listed newest first; X-Total-Count holds the number matching.
 func (s *Server) handleListMarkets(w http.ResponseWriter, r *http.Request) {
-	markets := s.marketManager.List()
+	query := r.URL.Query()
+	filter := market.ListFilter{
+		CreatorID: query.Get("creator_id"),
+		Limit:     defaultMarketPage,
+	}
+	if v := query.Get("status"); v != "" {
+		status, err := market.ParseMarketStatus(v)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "unknown status: "+v)
+			return
+		}
+		filter.Status = &status
+	}
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		filter.Limit = min(n, maxMarketPage)
+	}
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		filter.Offset = n
+	}
+
+	markets, total := s.marketManager.ListPage(filter)
+	w.Header().Set("X-Total-Count", strconv.Itoa(total))
 
 	result := make([]market.MarketJSON, 0, len(markets))
 	for _, m := range markets {
@@ -79,6 +248,231 @@ func (s *Server) handleGetMarket(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, mkt.ToJSON())
 }
 
+// handleMarketStats handles GET /api/market/{id}/stats
+func (s *Server) handleMarketStats(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, s.marketOrderbooks.Stats(marketID, time.Now()))
+}
+
+// UpdateMarketRequest is the request to edit a market before it trades
+type UpdateMarketRequest struct {
+	Question    *string `json:"question,omitempty"`
+	Description *string `json:"description,omitempty"`
+	ResolvesAt  *string `json:"resolves_at,omitempty"` // RFC3339 format
+}
+
+// handleUpdateMarket handles PATCH /api/market/{id}
+func (s *Server) handleUpdateMarket(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+	mkt, ok := s.marketManager.Get(marketID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	// Only admins and the market's creator may edit it
+	if !s.isAdmin(r) {
+		caller := s.callerAddress(r)
+		if caller == "" {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		if !strings.EqualFold(caller, mkt.CreatorID) {
+			writeError(w, http.StatusForbidden, "only the market's creator may edit it")
+			return
+		}
+	}
+
+	var req UpdateMarketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	update := market.UpdateMarketRequest{Question: req.Question, Description: req.Description}
+	if req.ResolvesAt != nil {
+		resolvesAt, err := time.Parse(time.RFC3339, *req.ResolvesAt)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "invalid resolves_at format, use RFC3339")
+			return
+		}
+		update.ResolvesAt = &resolvesAt
+	}
+
+	if err := s.marketManager.Update(marketID, update); err != nil {
+		if err == market.ErrInvalidTransition {
+			writeError(w, http.StatusConflict, "market can only be edited while trading and before its first trade")
+		} else {
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	// Respond with the market as edited, not the snapshot taken before it
+	updated, ok := s.marketManager.Get(marketID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, updated.ToJSON())
+}
+
+// handleCostToPrice handles GET /api/market/{id}/cost-to-price?outcome=YES&side=buy&price=7000
+func (s *Server) handleCostToPrice(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	query := r.URL.Query()
+
+	outcome := engine.OutcomeYES
+	if query.Get("outcome") == "NO" {
+		outcome = engine.OutcomeNO
+	}
+
+	var side engine.Side
+	switch query.Get("side") {
+	case "buy", "":
+		side = engine.SideBuy
+	case "sell":
+		side = engine.SideSell
+	default:
+		writeError(w, http.StatusBadRequest, "invalid side: must be 'buy' or 'sell'")
+		return
+	}
+
+	price, err := strconv.ParseUint(query.Get("price"), 10, 64)
+	if err != nil || price > 10000 {
+		writeError(w, http.StatusBadRequest, "price must be between 0 and 10000 basis points")
+		return
+	}
+
+	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
+	quantity, notional := orderbook.CostToPrice(side, price)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"market_id":    marketID,
+		"outcome":      string(outcome),
+		"side":         string(side),
+		"target_price": price,
+		"quantity":     engine.Quantity(quantity),
+		"notional":     notional,
+	})
+}
+
+// handleSimulateResolution handles GET /api/market/{id}/simulate?outcome=YES
+func (s *Server) handleSimulateResolution(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+	status, ok := s.marketManager.Status(marketID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+	if status == market.StatusResolved {
+		writeError(w, http.StatusBadRequest, market.ErrAlreadyResolved.Error())
+		return
+	}
+
+	var outcome engine.OutcomeID
+	switch r.URL.Query().Get("outcome") {
+	case "YES":
+		outcome = engine.OutcomeYES
+	case "NO":
+		outcome = engine.OutcomeNO
+	default:
+		writeError(w, http.StatusBadRequest, "outcome must be 'YES' or 'NO'")
+		return
+	}
+
+	deltas := s.positions.SimulatePayouts(marketID, outcome)
+	var totalPayout uint64
+	for _, d := range deltas {
+		totalPayout += d.Payout
+	}
+	if deltas == nil {
+		deltas = []engine.BalanceDelta{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"market_id":    marketID,
+		"outcome":      string(outcome),
+		"total_payout": totalPayout,
+		"users":        deltas,
+	})
+}
+
+// handleDrainMarket handles POST /api/market/{id}/drain
+func (s *Server) handleDrainMarket(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	marketID := r.PathValue("id")
+	if err := s.marketManager.Drain(marketID); err != nil {
+		if err == market.ErrMarketNotFound {
+			writeError(w, http.StatusNotFound, err.Error())
+		} else {
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	mkt, _ := s.marketManager.Get(marketID)
+	s.BroadcastMarketStatus(mkt)
+	writeJSON(w, http.StatusOK, mkt.ToJSON())
+}
+
+// ReopenMarketRequest is the request to return a locked market to trading
+type ReopenMarketRequest struct {
+	ResolvesAt string `json:"resolves_at"` // RFC3339 format
+}
+
+// handleReopenMarket handles POST /api/market/{id}/reopen
+func (s *Server) handleReopenMarket(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	var req ReopenMarketRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	resolvesAt, err := time.Parse(time.RFC3339, req.ResolvesAt)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid resolves_at format, use RFC3339")
+		return
+	}
+
+	marketID := r.PathValue("id")
+	if err := s.marketManager.Reopen(marketID, resolvesAt); err != nil {
+		if err == market.ErrMarketNotFound {
+			writeError(w, http.StatusNotFound, err.Error())
+		} else {
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	mkt, _ := s.marketManager.Get(marketID)
+	s.BroadcastMarketStatus(mkt)
+	writeJSON(w, http.StatusOK, mkt.ToJSON())
+}
+
+// BroadcastMarketStatus notifies WebSocket clients of a market status change
+func (s *Server) BroadcastMarketStatus(mkt *market.Market) {
+	s.wsHub.Broadcast(Message{
+		Type: "market_status",
+		Data: mkt.ToJSON(),
+	})
+}
+
 // ResolveMarketRequest is the request to resolve a market
 type ResolveMarketRequest struct {
 	Outcome string `json:"outcome"` // "YES" or "NO"
@@ -92,59 +486,305 @@ func (s *Server) handleResolveMarket(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Only admins and the market's resolvers, identified by a verified
+	// Yellow JWT, may resolve it
+	if !s.isAdmin(r) {
+		caller := s.callerAddress(r)
+		if caller == "" {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		allowed, found := s.marketManager.CanResolve(marketID, caller)
+		if !found {
+			writeError(w, http.StatusNotFound, "market not found")
+			return
+		}
+		if !allowed {
+			writeError(w, http.StatusForbidden, "not authorized to resolve this market")
+			return
+		}
+	}
+
 	var req ResolveMarketRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	var outcome market.Outcome
-	switch req.Outcome {
-	case "YES":
-		outcome = market.OutcomeYes
-	case "NO":
-		outcome = market.OutcomeNo
-	default:
+	outcome, ok := parseOutcome(req.Outcome)
+	if !ok {
 		writeError(w, http.StatusBadRequest, "outcome must be 'YES' or 'NO'")
 		return
 	}
 
-	// First lock the market
+	mkt, record, err := s.resolveMarket(marketID, outcome)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	s.BroadcastMarketStatus(mkt)
+
+	// Payouts wait for the dispute window to pass
+	if record == nil {
+		writeJSON(w, http.StatusAccepted, map[string]interface{}{
+			"market":          mkt.ToJSON(),
+			"dispute_ends_at": mkt.DisputeEndsAt,
+		})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"market":       mkt.ToJSON(),
+		"total_payout": record.Total,
+		"positions":    len(record.Entries),
+	})
+}
+
+// handleDisputeMarket handles POST /api/market/{id}/dispute. Any
+// authenticated caller may dispute a proposed resolution within its window;
+// the market then waits for a new resolution instead of paying out.
+func (s *Server) handleDisputeMarket(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+
+	disputedBy := s.callerAddress(r)
+	if disputedBy == "" {
+		if !s.isAdmin(r) {
+			writeError(w, http.StatusUnauthorized, "authentication required")
+			return
+		}
+		disputedBy = "admin"
+	} else if !s.isAdmin(r) {
+		// A dispute holds up everyone's payout, so only those with a stake
+		// in the outcome may raise one
+		canResolve, found := s.marketManager.CanResolve(marketID, disputedBy)
+		if !found {
+			writeError(w, http.StatusNotFound, "market not found")
+			return
+		}
+		pos := s.positions.GetPosition(disputedBy, marketID)
+		if !canResolve && pos.YesShares == 0 && pos.NoShares == 0 {
+			writeError(w, http.StatusForbidden, "only the market's resolvers and position holders may dispute it")
+			return
+		}
+	}
+
+	mkt, err := s.marketManager.Dispute(marketID, disputedBy)
+	if err != nil {
+		if err == market.ErrMarketNotFound {
+			writeError(w, http.StatusNotFound, err.Error())
+		} else {
+			writeError(w, http.StatusBadRequest, err.Error())
+		}
+		return
+	}
+
+	s.BroadcastMarketStatus(mkt)
+	writeJSON(w, http.StatusOK, mkt.ToJSON())
+}
+
+// BatchResolveItem is one market to resolve in a batch
+type BatchResolveItem struct {
+	MarketID string `json:"market_id"`
+	Outcome  string `json:"outcome"` // "YES" or "NO"
+}
+
+// BatchResolveResult reports what happened to one market in a batch
+type BatchResolveResult struct {
+	MarketID    string `json:"market_id"`
+	Status      string `json:"status"` // "resolved", "resolving" (in its dispute window), "skipped" (already resolved) or "failed"
+	Error       string `json:"error,omitempty"`
+	TotalPayout uint64 `json:"total_payout,omitempty"`
+}
+
+// handleResolveBatch handles POST /api/admin/markets/resolve-batch
+func (s *Server) handleResolveBatch(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	var req []BatchResolveItem
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body: expected a list of {market_id, outcome}")
+		return
+	}
+
+	// Each market is resolved on its own; one failure doesn't stop the rest
+	results := make([]BatchResolveResult, len(req))
+	var resolved, skipped, failed int
+	for i, item := range req {
+		result := BatchResolveResult{MarketID: item.MarketID}
+		outcome, ok := parseOutcome(item.Outcome)
+		if !ok {
+			result.Status = "failed"
+			result.Error = "outcome must be 'YES' or 'NO'"
+			results[i] = result
+			failed++
+			continue
+		}
+
+		_, record, err := s.resolveMarket(item.MarketID, outcome)
+		switch {
+		case err == market.ErrAlreadyResolved:
+			result.Status = "skipped"
+			result.Error = err.Error()
+			skipped++
+		case err != nil:
+			result.Status = "failed"
+			result.Error = err.Error()
+			failed++
+		case record == nil:
+			result.Status = "resolving"
+			resolved++
+		default:
+			result.Status = "resolved"
+			result.TotalPayout = record.Total
+			resolved++
+		}
+		results[i] = result
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"results":  results,
+		"resolved": resolved,
+		"skipped":  skipped,
+		"failed":   failed,
+	})
+}
+
+// resolveMarket locks a market if needed, resolves it and pays out winning
+// shares to all position holders in one step, keeping a record of each
+// payout. With a dispute window the payout happens when it is finalized.
+func (s *Server) resolveMarket(marketID string, outcome market.Outcome) (*market.Market, *market.SettlementRecord, error) {
 	if err := s.marketManager.Lock(marketID); err != nil {
 		// Market might already be locked, which is fine
 		if err != market.ErrInvalidTransition {
-			writeError(w, http.StatusBadRequest, err.Error())
-			return
+			return nil, nil, err
 		}
 	}
 
-	// Resolve the market
-	mkt, err := s.marketManager.Resolve(market.ResolveRequest{
+	return s.marketManager.ResolveAndSettle(market.ResolveRequest{
 		MarketID: marketID,
 		Outcome:  outcome,
-	})
-	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+	}, s.SettleMarket)
+}
+
+// parseOutcome converts "YES" or "NO" to a market outcome
+func parseOutcome(s string) (market.Outcome, bool) {
+	switch s {
+	case "YES":
+		return market.OutcomeYes, true
+	case "NO":
+		return market.OutcomeNo, true
 	}
+	return "", false
+}
 
-	// Payout winning shares to all position holders
-	positions := s.positions.GetAllPositions(marketID)
-	var totalPayout uint64
-	for _, pos := range positions {
-		var engineOutcome engine.OutcomeID
-		if mkt.Outcome != nil && *mkt.Outcome == market.OutcomeYes {
-			engineOutcome = engine.OutcomeYES
-		} else {
-			engineOutcome = engine.OutcomeNO
+// SettleMarket pays out a resolved market and reports each user's payout
+func (s *Server) SettleMarket(marketID string, outcome market.Outcome) []market.SettlementEntry {
+	engineOutcome := engine.OutcomeNO
+	if outcome == market.OutcomeYes {
+		engineOutcome = engine.OutcomeYES
+	}
+	settlement := s.positions.SettleMarket(marketID, engineOutcome)
+
+	entries := make([]market.SettlementEntry, len(settlement.Payouts))
+	for i, d := range settlement.Payouts {
+		shares := d.NoShares
+		if engineOutcome == engine.OutcomeYES {
+			shares = d.YesShares
 		}
-		payout := s.positions.PayoutWinningShares(pos.UserID, marketID, engineOutcome)
-		totalPayout += payout
+		entries[i] = market.SettlementEntry{UserID: d.UserID, Shares: shares, Payout: d.Payout}
+	}
+	return entries
+}
+
+// handleGetSettlement handles GET /api/market/{id}/settlement
+func (s *Server) handleGetSettlement(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	record, ok := s.marketManager.Settlement(marketID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "market has not been settled")
+		return
 	}
 
+	// Entries hold scaled share counts; report them as decimal shares
+	type entry struct {
+		market.SettlementEntry
+		Shares engine.Quantity `json:"shares"`
+	}
+	entries := make([]entry, len(record.Entries))
+	for i, e := range record.Entries {
+		entries[i] = entry{SettlementEntry: e, Shares: engine.Quantity(e.Shares)}
+	}
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"market":       mkt.ToJSON(),
-		"total_payout": totalPayout,
-		"positions":    len(positions),
+		"market_id":  record.MarketID,
+		"outcome":    record.Outcome,
+		"settled_at": record.SettledAt,
+		"entries":    entries,
+		"total":      record.Total,
 	})
 }
+
+// MarketExport is a complete, self-contained record of a market for audit
+type MarketExport struct {
+	Market     market.MarketJSON                   `json:"market"`
+	Orderbooks map[string]engine.OrderbookSnapshot `json:"orderbooks"`
+	Trades     []*engine.Trade                     `json:"trades"`
+	Positions  []*engine.Position                  `json:"positions"`
+	Settlement *engine.Settlement                  `json:"settlement,omitempty"`
+	ExportedAt string                              `json:"exported_at"`
+}
+
+// handleExportMarket handles GET /api/market/{id}/export
+func (s *Server) handleExportMarket(w http.ResponseWriter, r *http.Request) {
+	// Positions expose user identities
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
+	marketID := r.PathValue("id")
+	mkt, ok := s.marketManager.Get(marketID)
+	if !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	export := MarketExport{
+		Market:     mkt.ToJSON(),
+		Orderbooks: make(map[string]engine.OrderbookSnapshot),
+		Positions:  s.positions.GetAllPositions(marketID),
+		ExportedAt: time.Now().UTC().Format(time.RFC3339),
+	}
+
+	if obs := s.marketOrderbooks.Get(marketID); obs != nil {
+		export.Orderbooks[string(engine.OutcomeYES)] = obs.YES.GetSnapshot()
+		export.Orderbooks[string(engine.OutcomeNO)] = obs.NO.GetSnapshot()
+	}
+
+	trades, err := s.tradesBetween(marketID, time.Time{}, time.Time{})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	export.Trades = trades
+
+	if settlement, ok := s.positions.GetSettlement(marketID); ok {
+		export.Settlement = settlement
+	}
+
+	// Empty sections serialize as [] rather than null
+	if export.Trades == nil {
+		export.Trades = []*engine.Trade{}
+	}
+	if export.Positions == nil {
+		export.Positions = []*engine.Position{}
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="market-%s.json"`, marketID))
+	writeJSON(w, http.StatusOK, export)
+}
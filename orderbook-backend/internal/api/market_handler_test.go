@@ -0,0 +1,565 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+)
+
+func TestResolveByCreator(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+
+	rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", ts.token(t, alice, time.Hour), map[string]string{"outcome": "YES"})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusResolved {
+		t.Fatalf("status = %v, want resolved", status)
+	}
+}
+
+func TestResolveRejectsUnauthorizedCaller(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+
+	rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", ts.token(t, bob, time.Hour), map[string]string{"outcome": "YES"})
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", rec.Code)
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusTrading {
+		t.Fatalf("status = %v, want trading", status)
+	}
+}
+
+func TestResolveDeniedByDefaultWithoutAdminToken(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.AdminToken = ""
+	mkt := ts.createMarket(t, alice)
+
+	rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", "", map[string]string{"outcome": "YES"})
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want 401", rec.Code)
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusTrading {
+		t.Fatalf("status = %v, want trading", status)
+	}
+}
+
+func TestDrainRequiresAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+
+	for _, bearer := range []string{"", ts.token(t, alice, time.Hour)} {
+		if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/drain", bearer, nil); rec.Code != http.StatusUnauthorized {
+			t.Errorf("bearer %q: status = %d, want 401", bearer, rec.Code)
+		}
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusTrading {
+		t.Fatalf("status = %v, want trading", status)
+	}
+
+	if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/drain", testAdminToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("admin drain: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusDraining {
+		t.Fatalf("status = %v, want draining", status)
+	}
+}
+
+func TestCreateMarketFeeFreeOverrideRequiresAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	body := map[string]interface{}{
+		"question":       "Will it rain?",
+		"resolves_at":    time.Now().Add(time.Hour).Format(time.RFC3339),
+		"creator_id":     alice,
+		"fee_free_hours": 10000,
+	}
+
+	if rec := ts.do(t, "POST", "/api/v1/market", ts.token(t, alice, time.Hour), body); rec.Code != http.StatusForbidden {
+		t.Fatalf("user override: status = %d, want 403", rec.Code)
+	}
+	if n := len(ts.marketManager.List()); n != 0 {
+		t.Fatalf("%d markets created, want 0", n)
+	}
+	if rec := ts.do(t, "POST", "/api/v1/market", testAdminToken, body); rec.Code != http.StatusCreated {
+		t.Fatalf("admin override: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestCreateMarketPreparesOrderbooks(t *testing.T) {
+	ts := newTestServer(t)
+	ts.marketOrderbooks.SetGlobalTradeCallback(ts.onTrade)
+
+	rec := ts.do(t, "POST", "/api/v1/market", ts.token(t, alice, time.Hour), map[string]interface{}{
+		"question":    "Will it rain?",
+		"resolves_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+		"creator_id":  alice,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("create: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	obs := ts.marketOrderbooks.Get(created.ID)
+	if obs == nil {
+		t.Fatal("orderbooks not created with the market")
+	}
+	for _, order := range []*engine.Order{
+		engine.NewOrder(alice, created.ID, engine.OutcomeYES, engine.SideSell, 5000, 1),
+		engine.NewOrder(bob, created.ID, engine.OutcomeYES, engine.SideBuy, 5000, 1),
+	} {
+		if _, err := obs.YES.PlaceOrder(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if trades := ts.tape.Recent(10); len(trades) != 1 {
+		t.Fatalf("tape has %d trades, want the first trade recorded", len(trades))
+	}
+}
+
+func TestCreateMarketCreditsTheCaller(t *testing.T) {
+	ts := newTestServer(t)
+	create := func(bearer string, body map[string]interface{}) *httptest.ResponseRecorder {
+		body["question"] = "Will it rain?"
+		body["resolves_at"] = time.Now().Add(time.Hour).Format(time.RFC3339)
+		return ts.do(t, "POST", "/api/v1/market", bearer, body)
+	}
+	creator := func(rec *httptest.ResponseRecorder) string {
+		t.Helper()
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("create: status = %d, body %s", rec.Code, rec.Body)
+		}
+		var mkt market.MarketJSON
+		if err := json.Unmarshal(rec.Body.Bytes(), &mkt); err != nil {
+			t.Fatal(err)
+		}
+		got, _ := ts.marketManager.Get(mkt.ID)
+		return got.CreatorID
+	}
+
+	if rec := create("", map[string]interface{}{"creator_id": alice}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous create: status = %d, want 401", rec.Code)
+	}
+	if rec := create(ts.token(t, bob, time.Hour), map[string]interface{}{"creator_id": alice}); rec.Code != http.StatusForbidden {
+		t.Fatalf("create credited to someone else: status = %d, want 403", rec.Code)
+	}
+	if rec := create(ts.token(t, bob, time.Hour), map[string]interface{}{"resolvers": []string{bob}}); rec.Code != http.StatusForbidden {
+		t.Fatalf("non-admin naming resolvers: status = %d, want 403", rec.Code)
+	}
+	if got := creator(create(ts.token(t, bob, time.Hour), map[string]interface{}{})); !strings.EqualFold(got, bob) {
+		t.Fatalf("creator = %q, want the caller %s", got, bob)
+	}
+	if got := creator(create(testAdminToken, map[string]interface{}{"creator_id": alice, "resolvers": []string{bob}})); got != alice {
+		t.Fatalf("admin create: creator = %q, want %s", got, alice)
+	}
+}
+
+func TestCreateMarketAMMOverrideRequiresAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	body := map[string]interface{}{
+		"question":      "Will it rain?",
+		"resolves_at":   time.Now().Add(time.Hour).Format(time.RFC3339),
+		"creator_id":    alice,
+		"amm_liquidity": 1000,
+	}
+
+	if rec := ts.do(t, "POST", "/api/v1/market", ts.token(t, alice, time.Hour), body); rec.Code != http.StatusForbidden {
+		t.Fatalf("user override: status = %d, want 403", rec.Code)
+	}
+	if n := len(ts.marketManager.List()); n != 0 {
+		t.Fatalf("%d markets created, want 0", n)
+	}
+	if n := len(ts.amms); n != 0 {
+		t.Fatalf("%d house AMMs started, want 0", n)
+	}
+}
+
+func TestCreateMarketLimitOverrideRequiresAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	ts.positions.SetLimits(engine.Limits{MaxNetPosition: 10})
+
+	for _, field := range []string{"position_limit", "daily_notional_limit"} {
+		body := map[string]interface{}{
+			"question":    "Will it rain?",
+			"resolves_at": time.Now().Add(time.Hour).Format(time.RFC3339),
+			"creator_id":  alice,
+			field:         0,
+		}
+		if rec := ts.do(t, "POST", "/api/v1/market", ts.token(t, alice, time.Hour), body); rec.Code != http.StatusForbidden {
+			t.Fatalf("user %s override: status = %d, want 403", field, rec.Code)
+		}
+	}
+	if n := len(ts.marketManager.List()); n != 0 {
+		t.Fatalf("%d markets created, want 0", n)
+	}
+
+	rec := ts.do(t, "POST", "/api/v1/market", testAdminToken, map[string]interface{}{
+		"question":       "Will it rain?",
+		"resolves_at":    time.Now().Add(time.Hour).Format(time.RFC3339),
+		"creator_id":     alice,
+		"position_limit": 0,
+	})
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("admin override: status = %d, body %s", rec.Code, rec.Body)
+	}
+	mkt := ts.marketManager.List()[0]
+	if limits := ts.positions.LimitsFor(mkt.ID); limits.MaxNetPosition != 0 {
+		t.Fatalf("limits = %+v, want the position limit lifted", limits)
+	}
+}
+
+func TestExportResolvedMarket(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "buy", 1000, 10)
+	if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", testAdminToken, map[string]string{"outcome": "YES"}); rec.Code != http.StatusOK {
+		t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	if rec := ts.do(t, "GET", "/api/v1/market/"+mkt.ID+"/export", ts.token(t, alice, time.Hour), nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("user export: status = %d, want 401", rec.Code)
+	}
+	rec := ts.do(t, "GET", "/api/v1/market/"+mkt.ID+"/export", testAdminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("export: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var export MarketExport
+	if err := json.Unmarshal(rec.Body.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+	if export.Market.ID != mkt.ID || export.Market.Status != market.StatusResolved.String() {
+		t.Errorf("market = %s %q, want %s resolved", export.Market.ID, export.Market.Status, mkt.ID)
+	}
+	if len(export.Orderbooks) != 2 {
+		t.Errorf("orderbooks = %d, want YES and NO", len(export.Orderbooks))
+	}
+	if len(export.Trades) != 1 || !export.Trades[0].Mint {
+		t.Errorf("trades = %+v, want the one mint", export.Trades)
+	}
+	// Settlement closes out positions; what each holder had is in the payouts
+	if export.Positions == nil || len(export.Positions) != 0 {
+		t.Errorf("positions = %v, want []", export.Positions)
+	}
+	if export.Settlement == nil || len(export.Settlement.Payouts) != 2 {
+		t.Errorf("settlement = %+v, want a payout per holder", export.Settlement)
+	}
+	if export.ExportedAt == "" {
+		t.Error("exported_at missing")
+	}
+}
+
+func TestCreateMarketAllocationModeOverrideRequiresAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	body := map[string]interface{}{
+		"question":        "Will it rain?",
+		"resolves_at":     time.Now().Add(time.Hour).Format(time.RFC3339),
+		"creator_id":      alice,
+		"allocation_mode": "pro_rata",
+	}
+
+	if rec := ts.do(t, "POST", "/api/v1/market", ts.token(t, alice, time.Hour), body); rec.Code != http.StatusForbidden {
+		t.Fatalf("user override: status = %d, want 403", rec.Code)
+	}
+	if n := len(ts.marketManager.List()); n != 0 {
+		t.Fatalf("%d markets created, want 0", n)
+	}
+
+	rec := ts.do(t, "POST", "/api/v1/market", testAdminToken, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("admin override: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var mkt market.MarketJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &mkt); err != nil {
+		t.Fatal(err)
+	}
+
+	// Pro-rata: a 15 lot against three 10 lots fills each for 5
+	makers := []string{alice, bob, "0x3333333333333333333333333333333333333333"}
+	for _, user := range makers {
+		ts.fund(t, user, 10)
+		if err := ts.positions.MintShares(user, mkt.ID, 10); err != nil {
+			t.Fatal(err)
+		}
+		ts.placeOrder(t, user, mkt.ID, "YES", "sell", 6000, 10)
+	}
+	taker := "0x4444444444444444444444444444444444444444"
+	ts.fund(t, taker, 10)
+	ts.placeOrder(t, taker, mkt.ID, "YES", "buy", 6000, 15)
+	for _, user := range makers {
+		if pos := ts.positions.GetPosition(user, mkt.ID); pos.YesShares != 5 {
+			t.Errorf("maker %s has %d YES left, want 5", user, pos.YesShares)
+		}
+	}
+}
+
+func TestSettlementRecordPayoutsSumToTotal(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	if rec := ts.do(t, "GET", "/api/v1/market/"+mkt.ID+"/settlement", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("before resolution: status = %d, want 404", rec.Code)
+	}
+
+	// Alice and Bob each end up with winning YES shares
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "sell", 7000, 4)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "buy", 7000, 4)
+	if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", testAdminToken, map[string]string{"outcome": "YES"}); rec.Code != http.StatusOK {
+		t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec := ts.do(t, "GET", "/api/v1/market/"+mkt.ID+"/settlement", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("settlement: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var record struct {
+		Outcome string `json:"outcome"`
+		Entries []struct {
+			UserID string `json:"user_id"`
+			Payout uint64 `json:"payout"`
+		} `json:"entries"`
+		Total uint64 `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &record); err != nil {
+		t.Fatal(err)
+	}
+
+	payouts := make(map[string]uint64)
+	var sum uint64
+	for _, e := range record.Entries {
+		payouts[e.UserID] += e.Payout
+		sum += e.Payout
+	}
+	if record.Outcome != "YES" || sum != record.Total || record.Total != 100000 {
+		t.Fatalf("record = %+v: payouts sum to %d, want total 100000", record, sum)
+	}
+	if payouts[alice] != 60000 || payouts[bob] != 40000 {
+		t.Fatalf("payouts = %v, want alice 60000 and bob 40000", payouts)
+	}
+}
+
+func TestResolveBatchSkipsResolvedMarkets(t *testing.T) {
+	ts := newTestServer(t)
+	done, a, b := ts.createMarket(t, alice), ts.createMarket(t, alice), ts.createMarket(t, alice)
+	if rec := ts.do(t, "POST", "/api/v1/market/"+done.ID+"/resolve", testAdminToken, map[string]string{"outcome": "NO"}); rec.Code != http.StatusOK {
+		t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+	}
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	ts.placeOrder(t, alice, a.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, a.ID, "NO", "buy", 4000, 10)
+
+	batch := []BatchResolveItem{
+		{MarketID: done.ID, Outcome: "YES"},
+		{MarketID: a.ID, Outcome: "YES"},
+		{MarketID: b.ID, Outcome: "NO"},
+	}
+	if rec := ts.do(t, "POST", "/api/v1/admin/markets/resolve-batch", ts.token(t, alice, time.Hour), batch); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("non-admin: status = %d, want 401", rec.Code)
+	}
+
+	rec := ts.do(t, "POST", "/api/v1/admin/markets/resolve-batch", testAdminToken, batch)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Results  []BatchResolveResult `json:"results"`
+		Resolved int                  `json:"resolved"`
+		Skipped  int                  `json:"skipped"`
+		Failed   int                  `json:"failed"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Resolved != 2 || resp.Skipped != 1 || resp.Failed != 0 {
+		t.Fatalf("resolved %d skipped %d failed %d, want 2, 1, 0", resp.Resolved, resp.Skipped, resp.Failed)
+	}
+	want := []BatchResolveResult{
+		{MarketID: done.ID, Status: "skipped", Error: market.ErrAlreadyResolved.Error()},
+		{MarketID: a.ID, Status: "resolved", TotalPayout: 100000},
+		{MarketID: b.ID, Status: "resolved"},
+	}
+	for i, result := range resp.Results {
+		if result != want[i] {
+			t.Errorf("result %d = %+v, want %+v", i, result, want[i])
+		}
+	}
+
+	// The already-resolved market keeps its original outcome
+	if mkt, _ := ts.marketManager.Get(done.ID); mkt.Outcome == nil || *mkt.Outcome != market.OutcomeNo {
+		t.Fatalf("%s outcome = %v, want NO", done.ID, mkt.Outcome)
+	}
+	for _, id := range []string{a.ID, b.ID} {
+		if status, _ := ts.marketManager.Status(id); status != market.StatusResolved {
+			t.Fatalf("%s status = %v, want resolved", id, status)
+		}
+	}
+}
+
+func TestResolveWithinDisputeWindowDefersPayout(t *testing.T) {
+	ts := newTestServer(t)
+	ts.marketManager.SetDisputeWindow(time.Hour)
+	ts.fund(t, alice, 10)
+	ts.fund(t, bob, 10)
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+
+	rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", ts.token(t, alice, time.Hour), map[string]string{"outcome": "YES"})
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("resolve: status = %d, want 202, body %s", rec.Code, rec.Body)
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusResolving {
+		t.Fatalf("status = %v, want resolving", status)
+	}
+	if got := ts.positions.GetBalance(alice); got != 40000 {
+		t.Fatalf("alice balance = %d, want 40000: paid out inside the dispute window", got)
+	}
+
+	dispute := "/api/v1/market/" + mkt.ID + "/dispute"
+	if rec := ts.do(t, "POST", dispute, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("anonymous dispute: status = %d, want 401", rec.Code)
+	}
+	stranger := ts.token(t, "0x3333333333333333333333333333333333333333", time.Hour)
+	if rec := ts.do(t, "POST", dispute, stranger, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("dispute by a caller with no position: status = %d, want 403", rec.Code)
+	}
+	if rec := ts.do(t, "POST", "/api/v1/market/missing/dispute", stranger, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("dispute of a missing market: status = %d, want 404", rec.Code)
+	}
+	rec = ts.do(t, "POST", dispute, ts.token(t, bob, time.Hour), nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("dispute: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var got market.MarketJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.DisputedBy != bob {
+		t.Fatalf("disputed by %q, want %q", got.DisputedBy, bob)
+	}
+	if rec := ts.do(t, "POST", dispute, ts.token(t, bob, time.Hour), nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("second dispute: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestReopenMarketEndpoint(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.marketManager.Lock(mkt.ID); err != nil {
+		t.Fatal(err)
+	}
+	body := map[string]string{"resolves_at": time.Now().Add(2 * time.Hour).UTC().Format(time.RFC3339)}
+	path := "/api/v1/market/" + mkt.ID + "/reopen"
+
+	if rec := ts.do(t, "POST", path, ts.token(t, alice, time.Hour), body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("creator reopening: status = %d, want 401", rec.Code)
+	}
+	rec := ts.do(t, "POST", path, testAdminToken, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reopen: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusTrading {
+		t.Fatalf("status = %v, want trading", status)
+	}
+	if rec := ts.do(t, "POST", path, testAdminToken, body); rec.Code != http.StatusBadRequest {
+		t.Fatalf("reopening a trading market: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestListMarketsFiltersAndPages(t *testing.T) {
+	ts := newTestServer(t)
+	for i := 0; i < 3; i++ {
+		ts.createMarket(t, alice)
+	}
+	locked := ts.createMarket(t, bob)
+	if err := ts.marketManager.Lock(locked.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	list := func(query string) ([]market.MarketJSON, string) {
+		t.Helper()
+		rec := ts.do(t, "GET", "/api/v1/markets"+query, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body %s", query, rec.Code, rec.Body)
+		}
+		var markets []market.MarketJSON
+		if err := json.Unmarshal(rec.Body.Bytes(), &markets); err != nil {
+			t.Fatal(err)
+		}
+		return markets, rec.Header().Get("X-Total-Count")
+	}
+
+	if markets, total := list("?status=locked"); len(markets) != 1 || markets[0].ID != locked.ID || total != "1" {
+		t.Errorf("status=locked: %d markets, total %s, want only %s", len(markets), total, locked.ID)
+	}
+	if markets, total := list("?creator_id=" + alice + "&limit=2"); len(markets) != 2 || total != "3" {
+		t.Errorf("alice's first page: %d markets of %s, want 2 of 3", len(markets), total)
+	}
+	if markets, total := list("?offset=10"); len(markets) != 0 || total != "4" {
+		t.Errorf("offset past the end: %d markets of %s, want an empty page of 4", len(markets), total)
+	}
+	for _, bad := range []string{"?status=open", "?limit=0", "?offset=-1"} {
+		if rec := ts.do(t, "GET", "/api/v1/markets"+bad, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%s: status = %d, want 400", bad, rec.Code)
+		}
+	}
+}
+
+func TestUpdateMarketBeforeFirstTrade(t *testing.T) {
+	ts := newTestServer(t)
+	ts.marketManager.SetTradeCheck(ts.marketOrderbooks.HasTraded)
+	mkt := ts.createMarket(t, alice)
+	path := "/api/v1/market/" + mkt.ID
+	edit := map[string]string{"question": "Will it snow?"}
+
+	if rec := ts.do(t, "PATCH", path, ts.token(t, bob, time.Hour), edit); rec.Code != http.StatusForbidden {
+		t.Fatalf("stranger editing: status = %d, want 403", rec.Code)
+	}
+	rec := ts.do(t, "PATCH", path, ts.token(t, alice, time.Hour), edit)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("creator editing: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var edited market.MarketJSON
+	if err := json.Unmarshal(rec.Body.Bytes(), &edited); err != nil {
+		t.Fatal(err)
+	}
+	if edited.Question != "Will it snow?" {
+		t.Fatalf("response question = %q, want the edit", edited.Question)
+	}
+	if got, _ := ts.marketManager.Get(mkt.ID); got.Question != "Will it snow?" {
+		t.Fatalf("question = %q after the edit", got.Question)
+	}
+
+	ts.fund(t, alice, 10)
+	ts.fund(t, bob, 10)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 5)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 5)
+
+	edit["question"] = "Will it hail?"
+	if rec := ts.do(t, "PATCH", path, ts.token(t, alice, time.Hour), edit); rec.Code != http.StatusConflict {
+		t.Fatalf("editing a traded market: status = %d, want 409", rec.Code)
+	}
+	if got, _ := ts.marketManager.Get(mkt.ID); got.Question != "Will it snow?" {
+		t.Fatalf("question = %q, want the edit before trading to stand", got.Question)
+	}
+}
@@ -4,27 +4,46 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+	"orderbook-backend/internal/state"
 	"orderbook-backend/internal/yellow"
 )
 
 // PlaceOrderRequest is the request body for placing an order
 type PlaceOrderRequest struct {
-	UserID    string `json:"user_id"`
-	MarketID  string `json:"market_id"`
-	OutcomeID string `json:"outcome_id"` // "YES" or "NO"
-	Side      string `json:"side"`       // "buy" or "sell"
-	Price     uint64 `json:"price"`      // 0-10000 basis points (0-100% probability)
-	Quantity  uint64 `json:"quantity"`   // Number of shares
+	UserID    string          `json:"user_id"`
+	MarketID  string          `json:"market_id"`
+	OutcomeID string          `json:"outcome_id"` // "YES" or "NO"
+	Side      string          `json:"side"`       // "buy" or "sell"
+	Type      string          `json:"type"`       // "limit" (default) or "market"
+	Price     uint64          `json:"price"`      // 0-10000 basis points (0-100% probability); ignored for market orders
+	Quantity  engine.Quantity `json:"quantity"`   // Number of shares, fractional if QUANTITY_SCALE allows
+
+	// TimeInForce is "GTC" (default), "FOK" (fill completely now or cancel)
+	// or "IOC" (fill what is possible now, cancel the rest)
+	TimeInForce string `json:"time_in_force,omitempty"`
+
+	// PostOnly rejects the order instead of matching if it would cross the book
+	PostOnly bool `json:"post_only,omitempty"`
+
+	// ExpiresAt makes a GTC limit order good-til-date: it is cancelled once
+	// this time (RFC 3339) passes
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 }
 
 // PlaceOrderResponse is the response for a placed order
 type PlaceOrderResponse struct {
 	Order  *engine.Order   `json:"order"`
 	Trades []*engine.Trade `json:"trades"`
+
+	// CancelledQty is the part of a market or IOC order that found no liquidity
+	CancelledQty engine.Quantity `json:"cancelled_qty,omitempty"`
 }
 
 // handlePlaceOrder handles POST /api/order
@@ -34,14 +53,19 @@ func (s *Server) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	userID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
 
-	// Validate market exists and is trading
-	market, ok := s.marketManager.Get(req.MarketID)
+	// Validate market exists and is trading (or draining)
+	status, ok := s.marketManager.Status(req.MarketID)
 	if !ok {
 		writeError(w, http.StatusNotFound, "market not found")
 		return
 	}
-	if market.Status != 0 { // StatusTrading = 0
+	draining := status == market.StatusDraining
+	if status != market.StatusTrading && !draining {
 		writeError(w, http.StatusBadRequest, "market is not accepting orders")
 		return
 	}
@@ -58,6 +82,12 @@ func (s *Server) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// While draining, only orders that close existing shares are accepted
+	if draining && side != engine.SideSell {
+		writeError(w, http.StatusBadRequest, "market is draining: only reduce-only (sell) orders are accepted")
+		return
+	}
+
 	// Validate outcome
 	var outcome engine.OutcomeID
 	switch req.OutcomeID {
@@ -71,46 +101,127 @@ func (s *Server) handlePlaceOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Create order
-	order := engine.NewOrder(req.UserID, req.MarketID, outcome, side, req.Price, req.Quantity)
+	var order *engine.Order
+	switch req.Type {
+	case "", "limit":
+		order = engine.NewOrder(userID, req.MarketID, outcome, side, req.Price, uint64(req.Quantity))
+	case "market":
+		order = engine.NewMarketOrder(userID, req.MarketID, outcome, side, uint64(req.Quantity))
+	default:
+		writeError(w, http.StatusBadRequest, "invalid type: must be 'limit' or 'market'")
+		return
+	}
 
-	// Validate user can place this order (has balance/shares)
-	if err := s.positions.ValidateOrder(order); err != nil {
+	switch engine.TimeInForce(req.TimeInForce) {
+	case "", engine.TimeInForceGTC:
+	case engine.TimeInForceFOK, engine.TimeInForceIOC:
+		order.TimeInForce = engine.TimeInForce(req.TimeInForce)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid time_in_force: must be 'GTC', 'FOK' or 'IOC'")
+		return
+	}
+
+	if req.PostOnly {
+		if order.IsMarket() || order.TimeInForce != engine.TimeInForceGTC {
+			writeError(w, http.StatusBadRequest, "post_only requires a GTC limit order")
+			return
+		}
+		order.PostOnly = true
+	}
+
+	if req.ExpiresAt != nil {
+		if order.IsMarket() || order.TimeInForce != engine.TimeInForceGTC {
+			writeError(w, http.StatusBadRequest, "expires_at requires a GTC limit order")
+			return
+		}
+		if !req.ExpiresAt.After(time.Now()) {
+			writeError(w, http.StatusBadRequest, "expires_at must be in the future")
+			return
+		}
+		order.ExpiresAt = *req.ExpiresAt
+	}
+
+	unlock := s.userLocks.lock(userID)
+	trades, err := s.placeOrder(r.Context(), order)
+	unlock()
+	if err == engine.ErrWouldCross {
+		writeError(w, http.StatusBadRequest, "post-only order would cross the book and was not placed")
+		return
+	}
+	if err == engine.ErrOffTick {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("price must be a multiple of the tick size (%d bps)", s.cfg.TickSize))
+		return
+	}
+	if err == engine.ErrBelowMinQuantity {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("quantity must be at least %s", s.cfg.MinOrderQty))
+		return
+	}
+	if err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
+	resp := PlaceOrderResponse{
+		Order:  order,
+		Trades: trades,
+	}
+	if order.IsMarket() || order.TimeInForce == engine.TimeInForceIOC {
+		resp.CancelledQty = engine.Quantity(order.RemainingQty())
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// placeOrder checks an order against the user's funds and limits, matches
+// it and settles the resulting trades
+func (s *Server) placeOrder(ctx context.Context, order *engine.Order) ([]*engine.Trade, error) {
 	// Get the correct orderbook for this market and outcome
-	orderbook := s.marketOrderbooks.GetOrderbook(req.MarketID, outcome)
+	orderbook := s.marketOrderbooks.GetOrderbook(order.MarketID, order.OutcomeID)
+
+	// A market order is checked as if priced at the worst level it reaches
+	if order.IsMarket() {
+		order.Price = orderbook.SweepPrice(order.Side, order.Quantity)
+	}
+
+	// Validate user can place this order (has balance/shares)
+	if err := s.positions.ValidateOrder(order); err != nil {
+		return nil, err
+	}
+
+	// Enforce per-user compliance limits
+	if err := s.positions.CheckLimits(order, s.marketOrderbooks.GetOrCreate(order.MarketID)); err != nil {
+		return nil, err
+	}
 
 	// Place order and get trades
 	trades, err := orderbook.PlaceOrder(order)
 	if err != nil {
-		writeError(w, http.StatusBadRequest, err.Error())
-		return
+		return nil, err
 	}
 
+	return s.afterMatch(ctx, order.MarketID, trades), nil
+}
+
+// afterMatch applies the trades from an order entering a market's book and
+// broadcasts the results. It returns them along with any house requotes.
+func (s *Server) afterMatch(ctx context.Context, marketID string, trades []*engine.Trade) []*engine.Trade {
 	// Execute trades (update positions)
+	trades = s.applyTrades(ctx, trades)
 	for _, trade := range trades {
-		s.positions.ExecuteTrade(trade)
-		// Broadcast each trade to WebSocket clients
-		s.wsHub.Broadcast(Message{
-			Type: "trade",
-			Data: trade,
-		})
+		s.logTrade(ctx, trade)
 	}
 
+	// Let the house market maker requote after its inventory moved
+	trades = append(trades, s.refreshAMM(marketID, trades)...)
+
 	// Update Yellow Network state channel if connected
 	if len(trades) > 0 {
-		s.updateYellowSession(r.Context(), req.MarketID)
+		s.updateYellowSession(ctx, marketID)
 	}
 
 	// Broadcast orderbook update for this market
-	s.broadcastOrderbookForMarket(req.MarketID)
+	s.broadcastOrderbookForMarket(marketID)
 
-	writeJSON(w, http.StatusOK, PlaceOrderResponse{
-		Order:  order,
-		Trades: trades,
-	})
+	return trades
 }
 
 // handleGetOrderbook handles GET /api/orderbook?market_id=xxx&outcome=YES
@@ -126,13 +237,181 @@ func (s *Server) handleGetOrderbook(w http.ResponseWriter, r *http.Request) {
 
 	// Get orderbook for specific market and outcome
 	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
-	snapshot := orderbook.GetSnapshot()
+	snapshot := orderbook.GetDepth(s.cfg.MaxDepth)
 
 	// Add outcome info to response
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"outcome": string(outcome),
-		"bids":    snapshot.Bids,
-		"asks":    snapshot.Asks,
+		"outcome":    string(outcome),
+		"bids":       snapshot.Bids,
+		"asks":       snapshot.Asks,
+		"in_auction": orderbook.InAuction(),
+	})
+}
+
+// handleGetTicker handles GET /api/ticker?market_id=xxx&outcome=YES
+func (s *Server) handleGetTicker(w http.ResponseWriter, r *http.Request) {
+	marketID := r.URL.Query().Get("market_id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	outcome := engine.OutcomeYES
+	if r.URL.Query().Get("outcome") == "NO" {
+		outcome = engine.OutcomeNO
+	}
+
+	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
+	bid, hasBid := orderbook.BestBid()
+	ask, hasAsk := orderbook.BestAsk()
+
+	// Missing sides are null, as are spread and mid unless both exist
+	response := map[string]interface{}{
+		"market_id": marketID,
+		"outcome":   string(outcome),
+		"best_bid":  nil,
+		"best_ask":  nil,
+		"spread":    nil,
+		"mid":       nil,
+	}
+	if hasBid {
+		response["best_bid"] = bid
+	}
+	if hasAsk {
+		response["best_ask"] = ask
+	}
+	if hasBid && hasAsk {
+		response["spread"] = int64(ask.Price) - int64(bid.Price)
+		response["mid"] = (bid.Price + ask.Price) / 2
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// handleGetMarketTicker handles GET /api/market/{id}/ticker
+//
+// Last trade price and 24h change for both outcomes.
+func (s *Server) handleGetMarketTicker(w http.ResponseWriter, r *http.Request) {
+	marketID := r.PathValue("id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	now := time.Now()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"market_id": marketID,
+		"YES":       s.marketOrderbooks.GetOrderbook(marketID, engine.OutcomeYES).PriceChange(now),
+		"NO":        s.marketOrderbooks.GetOrderbook(marketID, engine.OutcomeNO).PriceChange(now),
+	})
+}
+
+// handleReplayOrderbook handles GET /api/orderbook/replay?market_id=xxx&outcome=YES&seq=N
+//
+// Rebuilds the book as it stood right after level-3 event N, for looking
+// into disputes. The live book is not touched.
+func (s *Server) handleReplayOrderbook(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	marketID := query.Get("market_id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	outcome := engine.OutcomeYES
+	if query.Get("outcome") == "NO" {
+		outcome = engine.OutcomeNO
+	}
+
+	seq, err := strconv.ParseUint(query.Get("seq"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "seq must be a non-negative integer")
+		return
+	}
+
+	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
+	replay, err := orderbook.ReplayTo(seq)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	snapshot := replay.GetSnapshot()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"market_id":  marketID,
+		"outcome":    string(outcome),
+		"seq":        seq,
+		"latest_seq": orderbook.EventSeq(),
+		"bids":       snapshot.Bids,
+		"asks":       snapshot.Asks,
+		"orders":     replay.GetL3Snapshot(),
+	})
+}
+
+// handleGetOrder handles GET /api/order/{id}?market_id=xxx&outcome=YES. An
+// order that has left the book is reported from its removal event and its
+// trades while they are still retained.
+func (s *Server) handleGetOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("id")
+	marketID := r.URL.Query().Get("market_id")
+	if marketID == "" {
+		writeError(w, http.StatusBadRequest, "market_id required")
+		return
+	}
+
+	outcome := engine.OutcomeYES
+	if r.URL.Query().Get("outcome") == "NO" {
+		outcome = engine.OutcomeNO
+	}
+
+	obs := s.marketOrderbooks.Get(marketID)
+	if obs == nil {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	// Complement fills are recorded by the incoming order's book, so an
+	// order's trades can be in either outcome's history
+	trades := append(obs.YES.OrderTrades(orderID), obs.NO.OrderTrades(orderID)...)
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Timestamp.Before(trades[j].Timestamp) })
+	var filled uint64
+	for _, trade := range trades {
+		filled += trade.Quantity
+	}
+
+	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
+	if order, err := orderbook.GetOrder(orderID); err == nil {
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"order_id":      order.ID,
+			"status":        order.Status,
+			"filled_qty":    engine.Quantity(order.FilledQty),
+			"remaining_qty": engine.Quantity(order.RemainingQty()),
+			"order":         order,
+			"trades":        trades,
+		})
+		return
+	}
+
+	var status engine.OrderStatus
+	var remaining uint64
+	if event, ok := orderbook.RemovalEvent(orderID); ok {
+		status = event.Status
+		if status == engine.StatusCancelled {
+			remaining = event.RemainingQty
+		}
+	} else if len(trades) > 0 {
+		// Never rested: it filled on arrival
+		status = engine.StatusFilled
+	} else {
+		writeError(w, http.StatusNotFound, "order not found: it is not on the book and has no retained history")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"order_id":      orderID,
+		"status":        status,
+		"filled_qty":    engine.Quantity(filled),
+		"remaining_qty": engine.Quantity(remaining),
+		"trades":        trades,
 	})
 }
 
@@ -153,6 +432,14 @@ func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
 	}
 
 	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
+	order, err := orderbook.GetOrder(orderID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if _, ok := actingUser(w, r, order.UserID); !ok {
+		return
+	}
 	if err := orderbook.CancelOrder(orderID); err != nil {
 		writeError(w, http.StatusNotFound, err.Error())
 		return
@@ -167,47 +454,422 @@ func (s *Server) handleCancelOrder(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// handleGetTrades handles GET /api/trades?market_id=xxx&outcome=YES
-func (s *Server) handleGetTrades(w http.ResponseWriter, r *http.Request) {
+// AmendOrderRequest changes a resting order. Omitted fields keep their
+// current value.
+type AmendOrderRequest struct {
+	MarketID  string           `json:"market_id"`
+	OutcomeID string           `json:"outcome_id"` // "YES" or "NO"
+	Price     *uint64          `json:"price,omitempty"`
+	Quantity  *engine.Quantity `json:"quantity,omitempty"` // New total quantity, including any filled part
+}
+
+// handleAmendOrder handles PATCH /api/order/{id}
+func (s *Server) handleAmendOrder(w http.ResponseWriter, r *http.Request) {
+	orderID := r.PathValue("id")
+	if orderID == "" {
+		writeError(w, http.StatusBadRequest, "order id required")
+		return
+	}
+
+	var req AmendOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	outcome := engine.OutcomeYES
+	if req.OutcomeID == "NO" {
+		outcome = engine.OutcomeNO
+	}
+
+	orderbook := s.marketOrderbooks.GetOrderbook(req.MarketID, outcome)
+	order, err := orderbook.GetOrder(orderID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if _, ok := actingUser(w, r, order.UserID); !ok {
+		return
+	}
+	defer s.userLocks.lock(order.UserID)()
+
+	newPrice, newQty := order.Price, order.Quantity
+	if req.Price != nil {
+		newPrice = *req.Price
+	}
+	if req.Quantity != nil {
+		newQty = uint64(*req.Quantity)
+	}
+
+	// Growing the order is checked like placing the extra size
+	if newQty > order.FilledQty && newQty-order.FilledQty > order.RemainingQty() {
+		// The resting remainder already reserves its funds or shares
+		check := *order
+		check.Price = newPrice
+		check.Quantity = newQty - order.Quantity
+		if err := s.positions.ValidateOrder(&check); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := s.positions.CheckLimits(&check, s.marketOrderbooks.GetOrCreate(req.MarketID)); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+
+	amended, trades, err := orderbook.AmendOrder(orderID, newPrice, newQty)
+	if err == engine.ErrOrderNotFound {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, PlaceOrderResponse{
+		Order:  amended,
+		Trades: s.afterMatch(r.Context(), req.MarketID, trades),
+	})
+}
+
+// handleCancelUserOrders handles DELETE /api/orders?user_id=xxx&market_id=yyy&outcome=YES.
+// Without outcome the user's orders on both outcomes are cancelled.
+func (s *Server) handleCancelUserOrders(w http.ResponseWriter, r *http.Request) {
+	userID, ok := actingUser(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
 	marketID := r.URL.Query().Get("market_id")
-	outcomeStr := r.URL.Query().Get("outcome")
+	if marketID == "" {
+		writeError(w, http.StatusBadRequest, "market_id required")
+		return
+	}
+
+	obs := s.marketOrderbooks.Get(marketID)
+	if obs == nil {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	cancelled := 0
+	switch r.URL.Query().Get("outcome") {
+	case "":
+		cancelled = obs.YES.CancelUserOrders(userID) + obs.NO.CancelUserOrders(userID)
+	case "YES":
+		cancelled = obs.YES.CancelUserOrders(userID)
+	case "NO":
+		cancelled = obs.NO.CancelUserOrders(userID)
+	default:
+		writeError(w, http.StatusBadRequest, "invalid outcome: must be 'YES' or 'NO'")
+		return
+	}
+
+	if cancelled > 0 {
+		s.broadcastOrderbookForMarket(marketID)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "cancelled",
+		"user_id":   userID,
+		"market_id": marketID,
+		"cancelled": cancelled,
+	})
+}
+
+// Page sizes for GET /api/trades
+const (
+	defaultTradePage = 100
+	maxTradePage     = 1000
+)
+
+// handleGetTrades handles GET /api/trades?market_id=xxx&outcome=YES&since=RFC3339&until=RFC3339&limit=100&offset=0
+func (s *Server) handleGetTrades(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	marketID := query.Get("market_id")
+	outcomeStr := query.Get("outcome")
 
 	outcome := engine.OutcomeYES
 	if outcomeStr == "NO" {
 		outcome = engine.OutcomeNO
 	}
 
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid since format, use RFC3339")
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid until format, use RFC3339")
+		return
+	}
+
+	limit := defaultTradePage
+	if v := query.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, maxTradePage)
+	}
+	offset := 0
+	if v := query.Get("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "offset must be a non-negative integer")
+			return
+		}
+		offset = n
+	}
+
+	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
+	trades := orderbook.QueryTrades(since, until, limit, offset)
+	writeJSON(w, http.StatusOK, trades)
+}
+
+// candleIntervals are the bucket sizes GET /api/candles accepts
+var candleIntervals = map[string]time.Duration{
+	"1m":  time.Minute,
+	"5m":  5 * time.Minute,
+	"15m": 15 * time.Minute,
+	"1h":  time.Hour,
+	"4h":  4 * time.Hour,
+	"1d":  24 * time.Hour,
+}
+
+// handleGetCandles handles GET /api/candles?market_id=xxx&outcome=YES&interval=1m&since=RFC3339
+func (s *Server) handleGetCandles(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	marketID := query.Get("market_id")
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	outcome := engine.OutcomeYES
+	if query.Get("outcome") == "NO" {
+		outcome = engine.OutcomeNO
+	}
+
+	name := query.Get("interval")
+	if name == "" {
+		name = "1m"
+	}
+	interval, ok := candleIntervals[name]
+	if !ok {
+		writeError(w, http.StatusBadRequest, "invalid interval: must be one of 1m, 5m, 15m, 1h, 4h or 1d")
+		return
+	}
+
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid since format, use RFC3339")
+		return
+	}
+
 	orderbook := s.marketOrderbooks.GetOrderbook(marketID, outcome)
-	trades := orderbook.RecentTrades(100)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"market_id": marketID,
+		"outcome":   string(outcome),
+		"interval":  name,
+		"candles":   orderbook.Candles(interval, since),
+	})
+}
+
+// globalTapeSize bounds the cross-market recent trades feed
+const globalTapeSize = 1000
+
+// handleGetRecentTrades handles GET /api/trades/recent?limit=50
+func (s *Server) handleGetRecentTrades(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = min(n, globalTapeSize)
+	}
+
+	// The tape is oldest-first; the feed is newest-first
+	trades := s.tape.Recent(limit)
+	for i, j := 0, len(trades)-1; i < j; i, j = i+1, j-1 {
+		trades[i], trades[j] = trades[j], trades[i]
+	}
 	writeJSON(w, http.StatusOK, trades)
 }
 
+// startOpeningAuction holds a market's orders unmatched for the given window
+// and schedules the uncross when it ends
+func (s *Server) startOpeningAuction(marketID string, window time.Duration) {
+	s.marketOrderbooks.StartAuction(marketID, time.Now().Add(window))
+	time.AfterFunc(window, func() {
+		s.uncrossMarket(marketID)
+	})
+	s.logger.Info("market in opening auction", "market_id", marketID, "window", window.String())
+}
+
+// uncrossMarket ends the opening auction on both outcome books and settles the fills
+func (s *Server) uncrossMarket(marketID string) {
+	obs := s.marketOrderbooks.Get(marketID)
+	if obs == nil {
+		return
+	}
+
+	var trades []*engine.Trade
+	trades = append(trades, obs.YES.Uncross()...)
+	trades = append(trades, obs.NO.Uncross()...)
+
+	trades = s.applyTrades(context.Background(), trades)
+	trades = append(trades, s.refreshAMM(marketID, trades)...)
+	if len(trades) > 0 {
+		s.updateYellowSession(context.Background(), marketID)
+	}
+
+	s.broadcastOrderbookForMarket(marketID)
+	s.logger.Info("opening auction uncrossed", "market_id", marketID, "trades", len(trades))
+}
+
+// maxAMMRequotes bounds how often the house maker requotes after one order,
+// since its new quotes can themselves cross the book
+const maxAMMRequotes = 4
+
+// startAMM attaches a house market maker to a market and places its first quotes
+func (s *Server) startAMM(marketID string, liquidity float64) {
+	amm := engine.NewAMM(marketID, engine.AMMConfig{
+		UserID:     s.cfg.HouseUserID,
+		Liquidity:  liquidity,
+		QuoteSize:  uint64(s.cfg.AMMQuoteSize) * engine.QuantityScale(),
+		HalfSpread: uint64(s.cfg.AMMHalfSpread),
+	}, s.marketOrderbooks.GetOrCreate(marketID), s.positions)
+
+	s.ammMu.Lock()
+	s.amms[marketID] = amm
+	s.ammMu.Unlock()
+
+	trades := s.applyTrades(context.Background(), amm.Refresh())
+	s.refreshAMM(marketID, trades)
+	s.logger.Info("house AMM started", "market_id", marketID, "liquidity", liquidity)
+}
+
+// refreshAMM requotes a market's house maker if any of the trades filled it.
+// It returns the trades the requotes themselves caused, already settled.
+func (s *Server) refreshAMM(marketID string, trades []*engine.Trade) []*engine.Trade {
+	s.ammMu.RLock()
+	amm := s.amms[marketID]
+	s.ammMu.RUnlock()
+	if amm == nil {
+		return nil
+	}
+
+	var fills []*engine.Trade
+	for i := 0; i < maxAMMRequotes && amm.Observe(trades); i++ {
+		trades = s.applyTrades(context.Background(), amm.Refresh())
+		fills = append(fills, trades...)
+	}
+	return fills
+}
+
+// applyTrades settles matched trades against positions and broadcasts them,
+// returning the ones applied. Open orders reserve the funds and shares they
+// need, so every fill can settle; one that can't means the reservation was
+// bypassed, and is logged rather than overdrawing either side.
+func (s *Server) applyTrades(ctx context.Context, trades []*engine.Trade) []*engine.Trade {
+	applied := make([]*engine.Trade, 0, len(trades))
+	for _, trade := range trades {
+		if err := s.positions.ExecuteTrade(trade); err != nil {
+			s.logger.ErrorContext(ctx, "trade rejected at settlement",
+				"trade_id", trade.ID,
+				"market_id", trade.MarketID,
+				"buyer", trade.BuyerID,
+				"seller", trade.SellerID,
+				"error", err,
+			)
+			continue
+		}
+		s.publishTrade(trade)
+		applied = append(applied, trade)
+	}
+	return applied
+}
+
+// OrdersExpired notifies WebSocket clients of a market whose good-til-date
+// orders have expired
+func (s *Server) OrdersExpired(marketID string, orders []*engine.Order) {
+	s.logger.Info("orders expired", "market_id", marketID, "count", len(orders))
+	s.broadcastOrderbookForMarket(marketID)
+}
+
+// logTrade records a matched trade, tagged with the request that caused it
+func (s *Server) logTrade(ctx context.Context, trade *engine.Trade) {
+	s.logger.InfoContext(ctx, "trade",
+		"trade_id", trade.ID,
+		"market_id", trade.MarketID,
+		"outcome", string(trade.OutcomeID),
+		"price", trade.Price,
+		"quantity", engine.Quantity(trade.Quantity).String(),
+		"buyer", trade.BuyerID,
+		"seller", trade.SellerID,
+	)
+}
+
+// publishTrade sends a trade to the WebSocket clients subscribed to its market
+func (s *Server) publishTrade(trade *engine.Trade) {
+	s.wsHub.Publish(marketTopic(trade.MarketID), Message{
+		Type: "trade",
+		Data: trade,
+	})
+}
+
 // broadcastOrderbookForMarket sends both YES and NO orderbooks for a market
+// to the clients subscribed to it
 func (s *Server) broadcastOrderbookForMarket(marketID string) {
 	obs := s.marketOrderbooks.Get(marketID)
 	if obs == nil {
 		return
 	}
 
-	yesSnapshot := obs.YES.GetSnapshot()
-	noSnapshot := obs.NO.GetSnapshot()
-
-	s.wsHub.Broadcast(Message{
+	// Only the latest snapshot matters to a client that is behind
+	s.wsHub.PublishLatest(marketTopic(marketID), "orderbook:"+marketID, Message{
 		Type: "orderbook",
-		Data: map[string]interface{}{
-			"market_id": marketID,
-			"YES": map[string]interface{}{
-				"bids": yesSnapshot.Bids,
-				"asks": yesSnapshot.Asks,
-			},
-			"NO": map[string]interface{}{
-				"bids": noSnapshot.Bids,
-				"asks": noSnapshot.Asks,
-			},
-		},
+		Data: orderbookData(marketID, obs, s.cfg.MaxDepth),
 	})
 }
 
+// orderbookData is the payload of an orderbook message: both books' levels
+func orderbookData(marketID string, obs *engine.OutcomeOrderbooks, depth int) map[string]interface{} {
+	yesSnapshot := obs.YES.GetDepth(depth)
+	noSnapshot := obs.NO.GetDepth(depth)
+	return map[string]interface{}{
+		"market_id": marketID,
+		"YES": map[string]interface{}{
+			"bids": yesSnapshot.Bids,
+			"asks": yesSnapshot.Asks,
+		},
+		"NO": map[string]interface{}{
+			"bids": noSnapshot.Bids,
+			"asks": noSnapshot.Asks,
+		},
+	}
+}
+
+// openYellowSession creates a market's session before anyone has traded,
+// with the operator as its only participant
+func (s *Server) openYellowSession(ctx context.Context, marketID string) {
+	if s.sessions == nil || s.yellowClient == nil || !s.yellowClient.IsAuthenticated() {
+		return
+	}
+
+	session, err := s.sessions.OpenMarketSession(ctx, marketID, nil, []yellow.Allocation{}, s.cfg.AdjudicatorAddr)
+	if err != nil {
+		s.logger.ErrorContext(ctx, "failed to create yellow session", "market_id", marketID, "error", err)
+		return
+	}
+	s.logger.InfoContext(ctx, "created yellow session", "market_id", marketID, "channel_id", session.GetChannelID())
+}
+
 // updateYellowSession updates the Yellow Network state channel after trades
 func (s *Server) updateYellowSession(ctx context.Context, marketID string) {
 	// Skip if Yellow Network is not connected
@@ -216,68 +878,119 @@ func (s *Server) updateYellowSession(ctx context.Context, marketID string) {
 	}
 
 	if !s.yellowClient.IsAuthenticated() {
-		log.Printf("Yellow Network not authenticated, skipping state update")
+		s.logger.WarnContext(ctx, "yellow network not authenticated, skipping state update", "market_id", marketID)
 		return
 	}
 
-	// Get all positions for this market and build allocations
-	positions := s.positions.GetAllPositions(marketID)
-	if len(positions) == 0 {
+	allocations := s.sessionAllocations(ctx, marketID)
+	if len(allocations) == 0 {
 		return
 	}
 
-	// Build allocations from current positions
-	allocations := make([]yellow.Allocation, 0)
-	for _, pos := range positions {
-		// Convert position to allocation
-		// In real implementation, this would track actual token balances
-		totalValue := pos.YesShares + pos.NoShares
-		if totalValue > 0 {
-			allocations = append(allocations, yellow.Allocation{
-				Participant: pos.UserID,
-				Token:       s.cfg.DefaultToken,
-				Amount:      fmt.Sprintf("%d", totalValue),
-			})
-		}
+	traders := make([]string, 0, len(allocations))
+	for _, alloc := range allocations {
+		traders = append(traders, alloc.Participant)
 	}
 
-	// Get or create session for this market
-	session, exists := s.sessions.GetSession(marketID)
+	// The market keeps one session; new traders join it instead of the
+	// session being recreated with a different membership
+	session, exists := s.sessions.MarketSession(marketID)
 	if !exists {
-		// Create new session for this market
-		participants := make([]string, 0, len(allocations))
-		for _, alloc := range allocations {
-			participants = append(participants, alloc.Participant)
-		}
-
 		var err error
-		session, err = s.sessions.CreateSession(ctx, participants, allocations, s.cfg.AdjudicatorAddr)
+		session, err = s.sessions.OpenMarketSession(ctx, marketID, traders, allocations, s.cfg.AdjudicatorAddr)
 		if err != nil {
-			log.Printf("Failed to create Yellow session for market %s: %v", marketID, err)
+			s.logger.ErrorContext(ctx, "failed to create yellow session", "market_id", marketID, "error", err)
 			return
 		}
-		log.Printf("Created Yellow session for market %s: %s", marketID, session.GetChannelID())
+		s.logger.InfoContext(ctx, "created yellow session", "market_id", marketID, "channel_id", session.GetChannelID())
+	} else if joined, err := session.Join(traders); err != nil {
+		s.logger.ErrorContext(ctx, "failed to add traders to yellow session", "market_id", marketID, "error", err)
+		return
+	} else if len(joined) > 0 {
+		s.logger.InfoContext(ctx, "traders joined yellow session", "market_id", marketID, "traders", joined)
 	}
 
 	// Build orderbook snapshot as appData
-	obs := s.marketOrderbooks.Get(marketID)
 	appData := ""
-	if obs != nil {
-		yesSnapshot := obs.YES.GetSnapshot()
-		noSnapshot := obs.NO.GetSnapshot()
-		appDataBytes, _ := json.Marshal(map[string]interface{}{
-			"market_id": marketID,
-			"YES":       yesSnapshot,
-			"NO":        noSnapshot,
-		})
-		appData = string(appDataBytes)
+	if obs := s.marketOrderbooks.Get(marketID); obs != nil {
+		appData = sessionAppData(marketID, obs)
 	}
 
 	// Update state channel
 	if err := session.UpdateState(ctx, allocations, appData); err != nil {
-		log.Printf("Failed to update Yellow session state for market %s: %v", marketID, err)
+		s.logger.ErrorContext(ctx, "failed to update yellow session state", "market_id", marketID, "error", err)
 		return
 	}
 
-	log.Printf("Updated Yellow session state for market %s (version %d)", marketID, session.GetChannelID())
+	s.logger.InfoContext(ctx, "updated yellow session state", "market_id", marketID, "channel_id", session.GetChannelID(), "version", session.Version())
+}
+
+// sessionAllocations values each trader's position in a market at the
+// session's YES price and returns it as an allocation of the default token,
+// ordered by participant so the same positions always produce the same
+// signed state. Every share is backed by a minted YES/NO pair, so the values
+// add up to the market's collateral.
+func (s *Server) sessionAllocations(ctx context.Context, marketID string) []yellow.Allocation {
+	yesPrice := uint64(5000)
+	if obs := s.marketOrderbooks.Get(marketID); obs != nil {
+		yesPrice = sessionYesPrice(obs)
+	}
+
+	var allocations []yellow.Allocation
+	for _, pos := range s.positions.GetAllPositions(marketID) {
+		value := engine.Notional(yesPrice, pos.YesShares) + engine.Notional(10000-yesPrice, pos.NoShares)
+		if value == 0 {
+			continue
+		}
+		participant, err := yellow.NormalizeAddress(pos.UserID)
+		if err != nil {
+			// Off-chain accounts (e.g. the house) can't hold channel allocations
+			s.logger.DebugContext(ctx, "skipping yellow allocation", "market_id", marketID, "error", err)
+			continue
+		}
+		allocations = append(allocations, yellow.Allocation{
+			Participant: participant,
+			Token:       s.cfg.DefaultToken,
+			Amount:      state.FormatAmount(value),
+		})
+	}
+
+	sort.Slice(allocations, func(i, j int) bool {
+		return allocations[i].Participant < allocations[j].Participant
+	})
+	return allocations
+}
+
+// sessionYesPrice returns the YES price of a market's last trade on either
+// book (a NO trade at q implies YES at 10000-q), or an even 5000 before the
+// first trade
+func sessionYesPrice(obs *engine.OutcomeOrderbooks) uint64 {
+	var last *engine.Trade
+	yesPrice := uint64(5000)
+	if recent := obs.YES.RecentTrades(1); len(recent) > 0 {
+		last, yesPrice = recent[0], recent[0].Price
+	}
+	if recent := obs.NO.RecentTrades(1); len(recent) > 0 && (last == nil || recent[0].Timestamp.After(last.Timestamp)) {
+		yesPrice = 10000 - recent[0].Price
+	}
+	return yesPrice
+}
+
+// appDataSnapshot is the orderbook state carried as a session's app data.
+// Its fields marshal in declaration order and each side's levels are sorted
+// by price, so the same books always serialize to the same bytes.
+type appDataSnapshot struct {
+	MarketID string                   `json:"market_id"`
+	YES      engine.OrderbookSnapshot `json:"YES"`
+	NO       engine.OrderbookSnapshot `json:"NO"`
+}
+
+// sessionAppData serializes a market's books as session app data
+func sessionAppData(marketID string, obs *engine.OutcomeOrderbooks) string {
+	data, _ := json.Marshal(appDataSnapshot{
+		MarketID: marketID,
+		YES:      obs.YES.GetSnapshot(),
+		NO:       obs.NO.GetSnapshot(),
+	})
+	return string(data)
 }
@@ -0,0 +1,707 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+	"orderbook-backend/internal/yellow"
+)
+
+// Run with -race: status reads while placing orders must not race with
+// lifecycle transitions
+func TestPlaceOrderWhileMarketsLock(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 1000)
+	markets := make([]*market.Market, 20)
+	for i := range markets {
+		markets[i] = ts.createMarket(t, alice)
+	}
+
+	var wg sync.WaitGroup
+	for _, mkt := range markets {
+		wg.Add(3)
+		go func(id string) {
+			defer wg.Done()
+			if err := ts.marketManager.Lock(id); err != nil {
+				t.Error(err)
+			}
+		}(mkt.ID)
+		go func(id string) {
+			defer wg.Done()
+			rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+				"user_id":    alice,
+				"market_id":  id,
+				"outcome_id": "YES",
+				"side":       "buy",
+				"price":      100,
+				"quantity":   1,
+			})
+			if rec.Code != http.StatusOK && rec.Code != http.StatusBadRequest {
+				t.Errorf("place order: status = %d, body %s", rec.Code, rec.Body)
+			}
+		}(mkt.ID)
+		go func(id string) {
+			defer wg.Done()
+			ts.do(t, "GET", "/api/v1/market/"+id, "", nil)
+			ts.do(t, "GET", "/api/v1/market/"+id+"/simulate?outcome=YES", "", nil)
+			ts.do(t, "POST", "/api/v1/buy", testAdminToken, nil)
+		}(mkt.ID)
+	}
+	wg.Wait()
+
+	for _, mkt := range markets {
+		if status, _ := ts.marketManager.Status(mkt.ID); status != market.StatusLocked {
+			t.Fatalf("market %s: status = %v, want locked", mkt.ID, status)
+		}
+	}
+}
+
+func TestSessionAllocationsValuePositionsInUSDC(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+
+	// Before any trade nobody holds shares
+	if allocs := ts.sessionAllocations(context.Background(), mkt.ID); len(allocs) != 0 {
+		t.Fatalf("allocations = %+v, want none", allocs)
+	}
+
+	// A YES bid at 6000 and a NO bid at 4000 mint 10 pairs (10 USDC)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+
+	got := ts.sessionAllocations(context.Background(), mkt.ID)
+	want := []yellow.Allocation{
+		{Participant: alice, Token: ts.cfg.DefaultToken, Amount: "6"},
+		{Participant: bob, Token: ts.cfg.DefaultToken, Amount: "4"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("allocations = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecentTradesInterleavesMarkets(t *testing.T) {
+	ts := newTestServer(t)
+	ts.marketOrderbooks.SetGlobalTradeCallback(ts.onTrade)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	a, b := ts.createMarket(t, alice), ts.createMarket(t, alice)
+
+	// One trade each in a, b, then a again
+	for i, marketID := range []string{a.ID, b.ID, a.ID} {
+		price := uint64(5000 + 100*i)
+		ts.placeOrder(t, alice, marketID, "YES", "buy", price, 1)
+		ts.placeOrder(t, bob, marketID, "NO", "buy", 10000-price, 1)
+	}
+
+	rec := ts.do(t, "GET", "/api/v1/trades/recent?limit=10", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var trades []*engine.Trade
+	if err := json.Unmarshal(rec.Body.Bytes(), &trades); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{a.ID, b.ID, a.ID}
+	if len(trades) != len(want) {
+		t.Fatalf("%d trades, want %d", len(trades), len(want))
+	}
+	for i, trade := range trades {
+		if trade.MarketID != want[len(want)-1-i] {
+			t.Errorf("trade %d in market %s, want %s", i, trade.MarketID, want[len(want)-1-i])
+		}
+		if i > 0 && trade.Timestamp.After(trades[i-1].Timestamp) {
+			t.Errorf("trade %d is newer than trade %d", i, i-1)
+		}
+	}
+
+	rec = ts.do(t, "GET", "/api/v1/trades/recent?limit=1", "", nil)
+	if err := json.Unmarshal(rec.Body.Bytes(), &trades); err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].MarketID != a.ID {
+		t.Fatalf("limit 1: %+v, want the latest trade in %s", trades, a.ID)
+	}
+}
+
+func TestGetTradesPagesNewestFirst(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 3); err != nil {
+		t.Fatal(err)
+	}
+	for _, price := range []uint64{5000, 5100, 5200} {
+		ts.placeOrder(t, bob, mkt.ID, "YES", "sell", price, 1)
+		ts.placeOrder(t, alice, mkt.ID, "YES", "buy", price, 1)
+	}
+
+	base := "/api/v1/trades?market_id=" + mkt.ID + "&outcome=YES"
+	for _, tc := range []struct {
+		query string
+		want  []uint64
+	}{
+		{"", []uint64{5200, 5100, 5000}},
+		{"&limit=2", []uint64{5200, 5100}},
+		{"&limit=2&offset=2", []uint64{5000}},
+		{"&until=2000-01-01T00:00:00Z", []uint64{}},
+	} {
+		rec := ts.do(t, "GET", base+tc.query, "", nil)
+		var trades []engine.Trade
+		if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &trades) != nil {
+			t.Fatalf("%q: status = %d, body %s", tc.query, rec.Code, rec.Body)
+		}
+		got := []uint64{}
+		for _, trade := range trades {
+			got = append(got, trade.Price)
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%q: prices %v, want %v", tc.query, got, tc.want)
+		}
+	}
+
+	for _, bad := range []string{"&since=yesterday", "&until=1", "&limit=0", "&offset=-1"} {
+		if rec := ts.do(t, "GET", base+bad, "", nil); rec.Code != http.StatusBadRequest {
+			t.Errorf("%q: status = %d, want 400", bad, rec.Code)
+		}
+	}
+}
+
+func TestMarketBuyAgainstComplementBids(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 5)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+
+	// No YES asks: the only liquidity is Bob's NO bid, which a YES buy fills
+	// by minting at 10000-4000
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+	marketBuy := map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "buy",
+		"type":       "market",
+		"quantity":   10,
+	}
+
+	// 10 YES at 6000 costs 6 USDC, more than Alice has
+	if rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, marketBuy); rec.Code != http.StatusBadRequest {
+		t.Fatalf("underfunded market buy: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := ts.positions.GetBalance(alice); got != 50000 {
+		t.Fatalf("alice balance = %d after a rejected order, want 50000", got)
+	}
+
+	ts.fund(t, alice, 1)
+	rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, marketBuy)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("market buy: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Trades []*engine.Trade `json:"trades"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Trades) != 1 || !resp.Trades[0].Mint || resp.Trades[0].Price != 6000 {
+		t.Fatalf("trades = %+v, want one mint at 6000", resp.Trades)
+	}
+	if a, b := ts.positions.GetBalance(alice), ts.positions.GetBalance(bob); a != 0 || b != 960000 {
+		t.Fatalf("balances alice %d bob %d, want 0 and 960000", a, b)
+	}
+	if pos := ts.positions.GetPosition(alice, mkt.ID); pos.YesShares != 10 {
+		t.Fatalf("alice holds %d YES, want 10", pos.YesShares)
+	}
+}
+
+func TestMarketOrderReportsCancelledRemainder(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 6000, 5)
+
+	// The price is ignored: the order takes the 5 on offer and drops the rest
+	rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "buy",
+		"type":       "market",
+		"price":      1,
+		"quantity":   8,
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Order        *engine.Order   `json:"order"`
+		Trades       []*engine.Trade `json:"trades"`
+		CancelledQty uint64          `json:"cancelled_qty"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Trades) != 1 || resp.Trades[0].Quantity != 5 || resp.Trades[0].Price != 6000 {
+		t.Fatalf("trades = %+v, want 5 at 6000", resp.Trades)
+	}
+	if resp.CancelledQty != 3 || resp.Order.Status != engine.StatusCancelled {
+		t.Fatalf("cancelled %d, status %s, want 3 cancelled", resp.CancelledQty, resp.Order.Status)
+	}
+	if bids := ts.marketOrderbooks.GetOrderbook(mkt.ID, engine.OutcomeYES).GetSnapshot().Bids; len(bids) != 0 {
+		t.Fatalf("remainder rests as bids %+v", bids)
+	}
+}
+
+func TestFillOrKillThroughAPI(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 6000, 5)
+	order := func(tif string, qty uint64) *httptest.ResponseRecorder {
+		return ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+			"user_id":       alice,
+			"market_id":     mkt.ID,
+			"outcome_id":    "YES",
+			"side":          "buy",
+			"price":         6000,
+			"quantity":      qty,
+			"time_in_force": tif,
+		})
+	}
+	var resp struct {
+		Order  *engine.Order   `json:"order"`
+		Trades []*engine.Trade `json:"trades"`
+	}
+
+	// One short of the 5 on offer: killed, the ask is untouched
+	rec := order("FOK", 6)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Trades) != 0 || resp.Order.Status != engine.StatusCancelled {
+		t.Fatalf("killed order: status %s, trades %+v", resp.Order.Status, resp.Trades)
+	}
+	if asks := ts.marketOrderbooks.GetOrderbook(mkt.ID, engine.OutcomeYES).GetSnapshot().Asks; len(asks) != 1 || asks[0].Quantity != 5 {
+		t.Fatalf("asks = %+v, want the 5 untouched", asks)
+	}
+
+	rec = order("FOK", 5)
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Trades) != 1 || resp.Order.Status != engine.StatusFilled {
+		t.Fatalf("exact order: status %s, trades %+v", resp.Order.Status, resp.Trades)
+	}
+
+	if rec := order("GTX", 1); rec.Code != http.StatusBadRequest {
+		t.Fatalf("unknown time in force: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestPostOnlyCrossRejected(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 6000, 5)
+	postOnly := func(price uint64) *httptest.ResponseRecorder {
+		return ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+			"user_id":    alice,
+			"market_id":  mkt.ID,
+			"outcome_id": "YES",
+			"side":       "buy",
+			"price":      price,
+			"quantity":   5,
+			"post_only":  true,
+		})
+	}
+
+	rec := postOnly(6000)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("crossing post-only: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var errResp ErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &errResp); err != nil || !strings.Contains(errResp.Error, "post-only") {
+		t.Fatalf("error body %s, want it to name the post-only rejection", rec.Body)
+	}
+	if rec := postOnly(5900); rec.Code != http.StatusOK {
+		t.Fatalf("resting post-only: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if bids := ts.marketOrderbooks.GetOrderbook(mkt.ID, engine.OutcomeYES).GetSnapshot().Bids; len(bids) != 1 || bids[0].Price != 5900 {
+		t.Fatalf("bids = %+v, want only the post-only bid at 5900", bids)
+	}
+}
+
+func TestPlaceOrderRejectsOffTickAndDust(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.TickSize, ts.cfg.MinOrderQty = 100, "5"
+	ts.marketOrderbooks.SetTickSize(100)
+	ts.marketOrderbooks.SetMinQuantity(5)
+	ts.fund(t, alice, 100)
+	mkt := ts.createMarket(t, alice)
+	bid := func(price, qty uint64) *httptest.ResponseRecorder {
+		return ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+			"user_id":    alice,
+			"market_id":  mkt.ID,
+			"outcome_id": "YES",
+			"side":       "buy",
+			"price":      price,
+			"quantity":   qty,
+		})
+	}
+
+	for _, tc := range []struct {
+		name        string
+		price, qty  uint64
+		wantMessage string
+	}{
+		{"off the tick", 5050, 5, "tick size (100 bps)"},
+		{"below the minimum", 5000, 4, "at least 5"},
+	} {
+		rec := bid(tc.price, tc.qty)
+		var errResp ErrorResponse
+		if rec.Code != http.StatusBadRequest || json.Unmarshal(rec.Body.Bytes(), &errResp) != nil || !strings.Contains(errResp.Error, tc.wantMessage) {
+			t.Errorf("%s: status = %d, body %s, want 400 mentioning %q", tc.name, rec.Code, rec.Body, tc.wantMessage)
+		}
+	}
+	if rec := bid(5000, 5); rec.Code != http.StatusOK {
+		t.Fatalf("bid on the tick at the minimum: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if bids := ts.marketOrderbooks.GetOrderbook(mkt.ID, engine.OutcomeYES).GetSnapshot().Bids; len(bids) != 1 {
+		t.Fatalf("bids = %+v, want only the valid bid", bids)
+	}
+}
+
+func TestTickerEmptyAndOneSided(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	type ticker struct {
+		BestBid *engine.OrderLevel `json:"best_bid"`
+		BestAsk *engine.OrderLevel `json:"best_ask"`
+		Spread  *int64             `json:"spread"`
+		Mid     *uint64            `json:"mid"`
+	}
+	get := func() ticker {
+		t.Helper()
+		rec := ts.do(t, "GET", "/api/v1/ticker?market_id="+mkt.ID+"&outcome=YES", "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+		var got ticker
+		if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	if got := get(); got.BestBid != nil || got.BestAsk != nil || got.Spread != nil || got.Mid != nil {
+		t.Fatalf("empty book: %+v, want all null", got)
+	}
+
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 4000, 3)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "buy", 4500, 2)
+	got := get()
+	if got.BestBid == nil || got.BestBid.Price != 4500 || got.BestBid.Quantity != 2 {
+		t.Fatalf("best bid = %+v, want 2 at 4500", got.BestBid)
+	}
+	if got.BestAsk != nil || got.Spread != nil || got.Mid != nil {
+		t.Fatalf("bids only: %+v, want no ask, spread or mid", got)
+	}
+
+	if err := ts.positions.MintShares(bob, mkt.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 5500, 5)
+	got = get()
+	if got.BestAsk == nil || got.BestAsk.Price != 5500 || *got.Spread != 1000 || *got.Mid != 5000 {
+		t.Fatalf("two-sided: %+v, want ask 5500, spread 1000, mid 5000", got)
+	}
+
+	if rec := ts.do(t, "GET", "/api/v1/ticker?market_id=missing", "", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("unknown market: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestMarketTicker(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 1); err != nil {
+		t.Fatal(err)
+	}
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 6000, 1)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 1)
+
+	rec := ts.do(t, "GET", "/api/v1/market/"+mkt.ID+"/ticker", "", nil)
+	var ticker struct {
+		YES, NO engine.PriceChange
+	}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &ticker) != nil {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	if ticker.YES.LastPrice == nil || *ticker.YES.LastPrice != 6000 || ticker.YES.Price24hAgo != nil {
+		t.Errorf("YES = %+v, want a last price of 6000 and no reference", ticker.YES)
+	}
+	if ticker.NO.LastPrice != nil {
+		t.Errorf("NO last price = %d, want nil before a NO trade", *ticker.NO.LastPrice)
+	}
+	if rec := ts.do(t, "GET", "/api/v1/market/missing/ticker", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown market: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestGetOrderStatus(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+	rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+		"user_id": alice, "market_id": mkt.ID, "outcome_id": "YES", "side": "buy", "price": 5000, "quantity": 10,
+	})
+	var placed PlaceOrderResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &placed) != nil {
+		t.Fatalf("place: status = %d, body %s", rec.Code, rec.Body)
+	}
+	orderID := placed.Order.ID
+	status := func(id string) (int, string, engine.Quantity, engine.Quantity, int) {
+		t.Helper()
+		rec := ts.do(t, "GET", "/api/v1/order/"+id+"?market_id="+mkt.ID+"&outcome=YES", "", nil)
+		var resp struct {
+			Status    string          `json:"status"`
+			Filled    engine.Quantity `json:"filled_qty"`
+			Remaining engine.Quantity `json:"remaining_qty"`
+			Trades    []engine.Trade  `json:"trades"`
+		}
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return rec.Code, resp.Status, resp.Filled, resp.Remaining, len(resp.Trades)
+	}
+	check := func(name, wantStatus string, wantFilled, wantRemaining engine.Quantity, wantTrades int) {
+		t.Helper()
+		code, st, filled, remaining, trades := status(orderID)
+		if code != http.StatusOK || st != wantStatus || filled != wantFilled || remaining != wantRemaining || trades != wantTrades {
+			t.Fatalf("%s: %d %s filled %d remaining %d with %d trades, want %s filled %d remaining %d with %d trades",
+				name, code, st, filled, remaining, trades, wantStatus, wantFilled, wantRemaining, wantTrades)
+		}
+	}
+
+	check("open", "open", 0, 10, 0)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 5000, 4)
+	check("partial", "partial", 4, 6, 1)
+
+	// Amending returns the order as amended, not as it was before
+	rec = ts.do(t, "PATCH", "/api/v1/order/"+orderID, testAdminToken, map[string]interface{}{
+		"market_id": mkt.ID, "outcome_id": "YES", "quantity": 8,
+	})
+	var amended PlaceOrderResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &amended) != nil || amended.Order.Quantity != 8 || amended.Order.FilledQty != 4 {
+		t.Fatalf("amend: status = %d, body %s, want the order at 8 with 4 filled", rec.Code, rec.Body)
+	}
+	check("amended", "partial", 4, 4, 1)
+
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 5000, 4)
+	check("filled and off the book", "filled", 8, 0, 2)
+
+	if code, _, _, _, _ := status("missing"); code != http.StatusNotFound {
+		t.Fatalf("unknown order: status = %d, want 404", code)
+	}
+}
+
+func TestCancelUserOrdersByOutcome(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 4000, 1)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 4100, 1)
+	ts.placeOrder(t, alice, mkt.ID, "NO", "buy", 3000, 1)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "buy", 4000, 1)
+	cancel := func(query string) int {
+		t.Helper()
+		rec := ts.do(t, "DELETE", "/api/v1/orders?user_id="+alice+"&market_id="+mkt.ID+query, testAdminToken, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+		}
+		var resp struct {
+			Cancelled int `json:"cancelled"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Cancelled
+	}
+
+	if n := cancel("&outcome=YES"); n != 2 {
+		t.Fatalf("YES cancel-all cancelled %d, want 2", n)
+	}
+	obs := ts.marketOrderbooks.Get(mkt.ID)
+	if bids := obs.YES.GetSnapshot().Bids; len(bids) != 1 || bids[0].Count != 1 {
+		t.Fatalf("YES bids = %+v, want only bob's", bids)
+	}
+	if bids := obs.NO.GetSnapshot().Bids; len(bids) != 1 {
+		t.Fatalf("NO bids = %+v, want alice's untouched", bids)
+	}
+	if n := cancel(""); n != 1 {
+		t.Fatalf("cancel-all cancelled %d, want the NO order", n)
+	}
+	if rec := ts.do(t, "DELETE", "/api/v1/orders?user_id="+alice+"&market_id="+mkt.ID+"&outcome=MAYBE", testAdminToken, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("bad outcome: status = %d, want 400", rec.Code)
+	}
+}
+
+func TestAmendBuyChecksOnlyTheExtraSize(t *testing.T) {
+	ts := newTestServer(t)
+	ts.positions.SetReservedFunds(ts.marketOrderbooks.OpenBuyNotional)
+	ts.fund(t, alice, 10)
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 5000, 10)
+	bids := ts.marketOrderbooks.GetOrderbook(mkt.ID, engine.OutcomeYES).GetL3Snapshot().Bids
+	if len(bids) != 1 {
+		t.Fatalf("%d resting bids, want 1", len(bids))
+	}
+
+	amend := func(qty uint64) *httptest.ResponseRecorder {
+		return ts.do(t, "PATCH", "/api/v1/order/"+bids[0].OrderID, testAdminToken, map[string]interface{}{
+			"market_id":  mkt.ID,
+			"outcome_id": "YES",
+			"quantity":   qty,
+		})
+	}
+	// The resting 5 USDC is already reserved, so doubling costs the other 5
+	if rec := amend(21); rec.Code != http.StatusBadRequest {
+		t.Fatalf("amend past the balance: status = %d, want 400", rec.Code)
+	}
+	if rec := amend(20); rec.Code != http.StatusOK {
+		t.Fatalf("amend to the whole balance: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := ts.positions.AvailableBalance(alice); got != 0 {
+		t.Fatalf("available = %d, want 0", got)
+	}
+}
+
+func TestRestingSellsCannotOversellShares(t *testing.T) {
+	ts := newTestServer(t)
+	ts.positions.SetReservedFunds(ts.marketOrderbooks.OpenBuyNotional)
+	ts.positions.SetReservedShares(ts.marketOrderbooks.OpenSellQuantity)
+	ts.fund(t, alice, 10)
+	ts.fund(t, bob, 20)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(alice, mkt.ID, 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice's 10 YES back her first ask, leaving none for a second
+	ts.placeOrder(t, alice, mkt.ID, "YES", "sell", 6000, 10)
+	rec := ts.do(t, "POST", "/api/v1/order", testAdminToken, map[string]interface{}{
+		"user_id":    alice,
+		"market_id":  mkt.ID,
+		"outcome_id": "YES",
+		"side":       "sell",
+		"price":      6000,
+		"quantity":   10,
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("second ask on the same shares: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	// Every fill Bob gets settles: the book, history and positions agree
+	ts.placeOrder(t, bob, mkt.ID, "YES", "buy", 6000, 20)
+	book := ts.marketOrderbooks.GetOrderbook(mkt.ID, engine.OutcomeYES)
+	if trades := book.RecentTrades(10); len(trades) != 1 || trades[0].Quantity != 10 {
+		t.Fatalf("book history = %+v, want one fill of 10", trades)
+	}
+	if bids := book.GetL3Snapshot().Bids; len(bids) != 1 || bids[0].RemainingQty != 10 {
+		t.Fatalf("resting bids = %+v, want Bob's remaining 10", bids)
+	}
+	if a, b := ts.positions.GetPosition(alice, mkt.ID), ts.positions.GetPosition(bob, mkt.ID); a.YesShares != 0 || b.YesShares != 10 {
+		t.Fatalf("alice holds %d YES, bob %d, want 0 and 10", a.YesShares, b.YesShares)
+	}
+}
+
+func TestSessionStateIsDeterministic(t *testing.T) {
+	orders := []*engine.Order{
+		engine.NewOrder("a", "m1", engine.OutcomeYES, engine.SideBuy, 4000, 5),
+		engine.NewOrder("b", "m1", engine.OutcomeYES, engine.SideBuy, 4500, 3),
+		engine.NewOrder("c", "m1", engine.OutcomeYES, engine.SideSell, 7000, 2),
+		engine.NewOrder("d", "m1", engine.OutcomeYES, engine.SideSell, 6500, 4),
+		engine.NewOrder("e", "m1", engine.OutcomeNO, engine.SideBuy, 2000, 6),
+		engine.NewOrder("f", "m1", engine.OutcomeNO, engine.SideSell, 8000, 1),
+	}
+	// The same orders placed in opposite orders serialize to the same bytes
+	build := func(order []int) string {
+		books := engine.NewMarketOrderbooks()
+		for _, i := range order {
+			o := *orders[i]
+			if _, err := books.GetOrderbook(o.MarketID, o.OutcomeID).PlaceOrder(&o); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return sessionAppData("m1", books.Get("m1"))
+	}
+	forward := build([]int{0, 1, 2, 3, 4, 5})
+	if again := build([]int{0, 1, 2, 3, 4, 5}); again != forward {
+		t.Fatalf("repeated serialization differs:\n%s\n%s", forward, again)
+	}
+	if backward := build([]int{5, 4, 3, 2, 1, 0}); backward != forward {
+		t.Fatalf("serialization depends on placement order:\n%s\n%s", forward, backward)
+	}
+
+	// Allocations are ordered by participant whatever the map order
+	ts := newTestServer(t)
+	traders := []string{"0x3333333333333333333333333333333333333333", bob, alice}
+	for _, trader := range traders {
+		ts.fund(t, trader, 100)
+	}
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, traders[0], mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, traders[1], mkt.ID, "NO", "buy", 4000, 5)
+	ts.placeOrder(t, traders[2], mkt.ID, "NO", "buy", 4000, 5)
+	first := ts.sessionAllocations(context.Background(), mkt.ID)
+	if len(first) != 3 {
+		t.Fatalf("allocations = %+v, want one per trader", first)
+	}
+	for i := 1; i < len(first); i++ {
+		if first[i-1].Participant >= first[i].Participant {
+			t.Fatalf("allocations not ordered by participant: %+v", first)
+		}
+	}
+	for i := 0; i < 10; i++ {
+		if got := ts.sessionAllocations(context.Background(), mkt.ID); !reflect.DeepEqual(got, first) {
+			t.Fatalf("allocations changed between calls: %+v, then %+v", first, got)
+		}
+	}
+}
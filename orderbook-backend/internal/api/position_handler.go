@@ -3,6 +3,9 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"sort"
+	"strconv"
+	"time"
 
 	"orderbook-backend/internal/engine"
 )
@@ -21,16 +24,20 @@ func (s *Server) handleDeposit(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.UserID == "" {
-		writeError(w, http.StatusBadRequest, "user_id is required")
+	userID, ok := actingUser(w, r, req.UserID)
+	if !ok {
 		return
 	}
+	req.UserID = userID
 	if req.Amount == 0 {
 		writeError(w, http.StatusBadRequest, "amount must be greater than 0")
 		return
 	}
 
-	s.positions.Deposit(req.UserID, req.Amount)
+	if err := s.positions.Deposit(req.UserID, req.Amount); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"user_id": req.UserID,
@@ -38,11 +45,42 @@ func (s *Server) handleDeposit(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// handleWithdraw handles POST /api/withdraw
+func (s *Server) handleWithdraw(w http.ResponseWriter, r *http.Request) {
+	var req DepositRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
+	if req.Amount == 0 {
+		writeError(w, http.StatusBadRequest, "amount must be greater than 0")
+		return
+	}
+
+	defer s.userLocks.lock(req.UserID)()
+	if err := s.positions.Withdraw(req.UserID, req.Amount); err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":   req.UserID,
+		"balance":   s.positions.GetBalance(req.UserID),
+		"available": s.positions.AvailableBalance(req.UserID),
+	})
+}
+
 // MintSharesRequest is the request to mint YES+NO shares
 type MintSharesRequest struct {
-	UserID   string `json:"user_id"`
-	MarketID string `json:"market_id"`
-	Amount   uint64 `json:"amount"` // Number of share pairs to mint (costs amount * 1 USDC)
+	UserID   string          `json:"user_id"`
+	MarketID string          `json:"market_id"`
+	Amount   engine.Quantity `json:"amount"` // Number of share pairs to mint (costs amount * 1 USDC)
 }
 
 // handleMintShares handles POST /api/mint
@@ -52,6 +90,11 @@ func (s *Server) handleMintShares(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
+	userID, ok := actingUser(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	req.UserID = userID
 
 	// Validate market exists
 	if _, ok := s.marketManager.Get(req.MarketID); !ok {
@@ -59,7 +102,8 @@ func (s *Server) handleMintShares(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := s.positions.MintShares(req.UserID, req.MarketID, req.Amount); err != nil {
+	defer s.userLocks.lock(req.UserID)()
+	if err := s.positions.MintShares(req.UserID, req.MarketID, uint64(req.Amount)); err != nil {
 		writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
@@ -68,12 +112,85 @@ func (s *Server) handleMintShares(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"user_id":    req.UserID,
 		"market_id":  req.MarketID,
-		"yes_shares": pos.YesShares,
-		"no_shares":  pos.NoShares,
+		"yes_shares": engine.Quantity(pos.YesShares),
+		"no_shares":  engine.Quantity(pos.NoShares),
 		"balance":    s.positions.GetBalance(req.UserID),
 	})
 }
 
+// handleGetLedger handles GET /api/ledger/{userId}?limit=50
+func (s *Server) handleGetLedger(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "userId required")
+		return
+	}
+
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer")
+			return
+		}
+		limit = n
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": userID,
+		"entries": s.positions.Ledger(userID, limit),
+	})
+}
+
+// handleGetPnL handles GET /api/pnl/{userId}?market_id=xxx
+func (s *Server) handleGetPnL(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	marketID := r.URL.Query().Get("market_id")
+	if userID == "" || marketID == "" {
+		writeError(w, http.StatusBadRequest, "userId and market_id required")
+		return
+	}
+	if _, ok := s.marketManager.Get(marketID); !ok {
+		writeError(w, http.StatusNotFound, "market not found")
+		return
+	}
+
+	yesMark, noMark := s.markPrices(marketID)
+	writeJSON(w, http.StatusOK, s.positions.GetPnL(userID, marketID, yesMark, noMark))
+}
+
+// markPrices returns the prices open positions in a market are marked at:
+// each book's mid, else its last trade, else the complement of the other
+// outcome's mark, else even odds
+func (s *Server) markPrices(marketID string) (yes, no uint64) {
+	obs := s.marketOrderbooks.GetOrCreate(marketID)
+	yes, hasYes := markPrice(obs.YES)
+	no, hasNo := markPrice(obs.NO)
+	switch {
+	case hasYes && !hasNo:
+		no = 10000 - yes
+	case hasNo && !hasYes:
+		yes = 10000 - no
+	case !hasYes && !hasNo:
+		yes, no = 5000, 5000
+	}
+	return yes, no
+}
+
+// markPrice returns a book's mid price, or its last trade price if one side
+// is empty
+func markPrice(ob *engine.Orderbook) (uint64, bool) {
+	bid, hasBid := ob.BestBid()
+	ask, hasAsk := ob.BestAsk()
+	if hasBid && hasAsk {
+		return (bid.Price + ask.Price) / 2, true
+	}
+	if last := ob.RecentTrades(1); len(last) > 0 {
+		return last[0].Price, true
+	}
+	return 0, false
+}
+
 // handleGetPosition handles GET /api/position/{userId}
 func (s *Server) handleGetPosition(w http.ResponseWriter, r *http.Request) {
 	userID := r.PathValue("userId")
@@ -105,3 +222,104 @@ func (s *Server) handleGetPosition(w http.ResponseWriter, r *http.Request) {
 
 	writeJSON(w, http.StatusOK, response)
 }
+
+// handleGetUserFills handles GET /api/fills/{userId}?market_id=xxx&since=RFC3339&until=RFC3339
+func (s *Server) handleGetUserFills(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("userId")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "userId required")
+		return
+	}
+
+	query := r.URL.Query()
+	marketID := query.Get("market_id")
+
+	since, err := parseTimeParam(query.Get("since"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid since format, use RFC3339")
+		return
+	}
+	until, err := parseTimeParam(query.Get("until"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid until format, use RFC3339")
+		return
+	}
+
+	trades, err := s.tradesBetween(marketID, since, until)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	fills := make([]engine.Fill, 0)
+	var totalFees, makerFees, takerFees, notional uint64
+	for _, trade := range trades {
+		fill, ok := trade.FillFor(userID)
+		if !ok {
+			continue
+		}
+		fills = append(fills, fill)
+		totalFees += fill.Fee
+		notional += fill.Notional
+		if fill.Role == "maker" {
+			makerFees += fill.Fee
+		} else {
+			takerFees += fill.Fee
+		}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id": userID,
+		"fills":   fills,
+		"summary": map[string]interface{}{
+			"fills":      len(fills),
+			"notional":   notional,
+			"total_fees": totalFees,
+			"maker_fees": makerFees,
+			"taker_fees": takerFees,
+		},
+	})
+}
+
+// parseTimeParam parses an optional RFC3339 query parameter (zero if absent)
+func parseTimeParam(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339, v)
+}
+
+// tradesBetween returns trades in [since, until] oldest first, for one
+// market or all of them. It reads the trade store when configured, otherwise
+// the in-memory history (the last 1000 trades per orderbook).
+func (s *Server) tradesBetween(marketID string, since, until time.Time) ([]*engine.Trade, error) {
+	if s.tradeStore != nil {
+		return s.tradeStore.Query(marketID, since, until)
+	}
+
+	marketIDs := []string{marketID}
+	if marketID == "" {
+		marketIDs = marketIDs[:0]
+		for _, m := range s.marketManager.List() {
+			marketIDs = append(marketIDs, m.ID)
+		}
+	}
+
+	var trades []*engine.Trade
+	for _, id := range marketIDs {
+		obs := s.marketOrderbooks.Get(id)
+		if obs == nil {
+			continue
+		}
+		for _, ob := range []*engine.Orderbook{obs.YES, obs.NO} {
+			for _, trade := range ob.RecentTrades(1000) {
+				if (!since.IsZero() && trade.Timestamp.Before(since)) || (!until.IsZero() && trade.Timestamp.After(until)) {
+					continue
+				}
+				trades = append(trades, trade)
+			}
+		}
+	}
+	sort.Slice(trades, func(i, j int) bool { return trades[i].Timestamp.Before(trades[j].Timestamp) })
+	return trades, nil
+}
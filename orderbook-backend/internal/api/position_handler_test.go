@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/state"
+	"orderbook-backend/internal/yellow"
+)
+
+type fillStatement struct {
+	Fills   []engine.Fill `json:"fills"`
+	Summary struct {
+		Fills     int    `json:"fills"`
+		TotalFees uint64 `json:"total_fees"`
+		MakerFees uint64 `json:"maker_fees"`
+		TakerFees uint64 `json:"taker_fees"`
+	} `json:"summary"`
+}
+
+func (ts *testServer) fills(t *testing.T, query string) fillStatement {
+	t.Helper()
+	rec := ts.do(t, "GET", "/api/v1/fills/"+query, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("fills: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var st fillStatement
+	if err := json.Unmarshal(rec.Body.Bytes(), &st); err != nil {
+		t.Fatal(err)
+	}
+	return st
+}
+
+func TestFillStatementFees(t *testing.T) {
+	ts := newTestServer(t)
+	ts.positions.SetFeeSchedule(engine.FeeSchedule{MakerBps: 10, TakerBps: 50})
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+
+	// Alice's resting YES bid is minted against by Bob's NO bid: the trade
+	// costs Bob 4 USDC, so he pays 200 bps taking and she pays 40 making
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+	// Bob lifts Alice's YES offer for 7 USDC: 350 taking, 70 making
+	ts.placeOrder(t, alice, mkt.ID, "YES", "sell", 7000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "buy", 7000, 10)
+
+	for _, tc := range []struct {
+		user                string
+		role                string
+		fees                []uint64
+		total, maker, taker uint64
+	}{
+		{alice, "maker", []uint64{40, 70}, 110, 110, 0},
+		{bob, "taker", []uint64{200, 350}, 550, 0, 550},
+	} {
+		st := ts.fills(t, tc.user+"?market_id="+mkt.ID)
+		if len(st.Fills) != len(tc.fees) || st.Summary.Fills != len(tc.fees) {
+			t.Fatalf("%s: %d fills (summary %d), want %d", tc.user, len(st.Fills), st.Summary.Fills, len(tc.fees))
+		}
+		for i, fill := range st.Fills {
+			if fill.Role != tc.role || fill.Fee != tc.fees[i] {
+				t.Errorf("%s fill %d: %s fee %d, want %s fee %d", tc.user, i, fill.Role, fill.Fee, tc.role, tc.fees[i])
+			}
+		}
+		if st.Summary.TotalFees != tc.total || st.Summary.MakerFees != tc.maker || st.Summary.TakerFees != tc.taker {
+			t.Errorf("%s summary = %+v, want total %d maker %d taker %d", tc.user, st.Summary, tc.total, tc.maker, tc.taker)
+		}
+	}
+
+	// A period after the trades has no fills
+	since := time.Now().Add(time.Hour).UTC().Format(time.RFC3339)
+	if st := ts.fills(t, alice+"?since="+since); len(st.Fills) != 0 || st.Summary.TotalFees != 0 {
+		t.Errorf("later period: %d fills, fees %d, want none", len(st.Fills), st.Summary.TotalFees)
+	}
+}
+
+func TestChannelBackedWithdrawal(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.ChannelBackedBalances = true
+	allocations, err := state.FromCheckpoints([]yellow.Checkpoint{{ChannelID: "0xfund", Allocations: []yellow.Allocation{
+		{Participant: alice, Token: ts.cfg.DefaultToken, Amount: "10"},
+		{Participant: bob, Token: ts.cfg.DefaultToken, Amount: "10"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.SetAllocations(allocations)
+
+	ts.fund(t, alice, 10)
+	ts.fund(t, bob, 10)
+	if err := ts.positions.Deposit(alice, 1); !errors.Is(err, engine.ErrUnbackedDeposit) {
+		t.Fatalf("deposit past the channel: err = %v, want %v", err, engine.ErrUnbackedDeposit)
+	}
+
+	// Alice wins Bob's 5 USDC, leaving her 15 against 10 in the channel
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 5000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 5000, 10)
+	if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", testAdminToken, map[string]string{"outcome": "YES"}); rec.Code != http.StatusOK {
+		t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+	}
+	if got := ts.positions.GetBalance(alice); got != 150000 {
+		t.Fatalf("alice balance = %d, want 150000", got)
+	}
+
+	withdraw := func(amount uint64) *httptest.ResponseRecorder {
+		return ts.do(t, "POST", "/api/v1/withdraw", ts.token(t, alice, time.Hour), map[string]interface{}{"amount": amount})
+	}
+	if rec := withdraw(120000); rec.Code != http.StatusBadRequest {
+		t.Fatalf("withdraw past the channel: status = %d, want 400", rec.Code)
+	}
+	if got := ts.positions.GetBalance(alice); got != 150000 {
+		t.Fatalf("rejected withdrawal moved funds: balance = %d", got)
+	}
+	if rec := withdraw(100000); rec.Code != http.StatusOK {
+		t.Fatalf("withdraw the channel amount: status = %d, body %s", rec.Code, rec.Body)
+	}
+}
+
+func TestWithdrawEndpoint(t *testing.T) {
+	ts := newTestServer(t)
+	ts.positions.SetReservedFunds(ts.marketOrderbooks.OpenBuyNotional)
+	ts.fund(t, alice, 10)
+	withdraw := func(amount uint64) *httptest.ResponseRecorder {
+		return ts.do(t, "POST", "/api/v1/withdraw", ts.token(t, alice, time.Hour), map[string]interface{}{"amount": amount})
+	}
+
+	if rec := withdraw(100001); rec.Code != http.StatusBadRequest {
+		t.Fatalf("over-withdrawal: status = %d, want 400", rec.Code)
+	}
+	if got := ts.positions.GetBalance(alice); got != 100000 {
+		t.Fatalf("rejected withdrawal moved funds: balance = %d", got)
+	}
+
+	// A resting 5 USDC bid holds back its funds until it is gone
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 5000, 10)
+	if rec := withdraw(60000); rec.Code != http.StatusBadRequest {
+		t.Fatalf("withdraw into reserved funds: status = %d, want 400", rec.Code)
+	}
+	if rec := ts.do(t, "DELETE", "/api/v1/orders?market_id="+mkt.ID, ts.token(t, alice, time.Hour), nil); rec.Code != http.StatusOK {
+		t.Fatalf("cancel all: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	rec := withdraw(100000)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("exact-balance withdrawal: status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Balance   uint64 `json:"balance"`
+		Available uint64 `json:"available"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Balance != 0 || resp.Available != 0 {
+		t.Fatalf("after withdrawing everything: %+v, want zero", resp)
+	}
+}
+
+func TestGetPnLMarksAtMid(t *testing.T) {
+	ts := newTestServer(t)
+	carol := "0x3333333333333333333333333333333333333333"
+	for _, u := range []string{alice, bob, carol} {
+		ts.fund(t, u, 100)
+	}
+	mkt := ts.createMarket(t, alice)
+
+	// Alice gets 10 YES at 6000 minted against Bob's NO bid, then the YES
+	// book quotes 6000 / 7000. NO has no quotes and is marked at the mint.
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+	ts.placeOrder(t, carol, mkt.ID, "YES", "buy", 6000, 1)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "sell", 7000, 1)
+
+	rec := ts.do(t, "GET", "/api/v1/pnl/"+alice+"?market_id="+mkt.ID, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var pnl engine.PnL
+	if err := json.Unmarshal(rec.Body.Bytes(), &pnl); err != nil {
+		t.Fatal(err)
+	}
+	if pnl.YesMark != 6500 || pnl.NoMark != 4000 {
+		t.Errorf("marks = %d/%d, want 6500/4000", pnl.YesMark, pnl.NoMark)
+	}
+	if pnl.Realized != 0 || pnl.Unrealized != 5000 {
+		t.Errorf("realized %d unrealized %d, want 0 and 5000", pnl.Realized, pnl.Unrealized)
+	}
+
+	if rec := ts.do(t, "GET", "/api/v1/pnl/"+alice+"?market_id=missing", "", nil); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown market: status = %d, want 404", rec.Code)
+	}
+}
+
+func TestGetLedger(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 10)
+	ts.fund(t, alice, 5)
+
+	rec := ts.do(t, "GET", "/api/v1/ledger/"+alice+"?limit=1", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var resp struct {
+		Entries []engine.LedgerEntry `json:"entries"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Entries) != 1 || resp.Entries[0].Amount != 50000 || resp.Entries[0].Balance != 150000 {
+		t.Fatalf("entries = %+v, want the 5 USDC deposit", resp.Entries)
+	}
+
+	if rec := ts.do(t, "GET", "/api/v1/ledger/"+alice+"?limit=0", "", nil); rec.Code != http.StatusBadRequest {
+		t.Errorf("limit=0: status = %d, want 400", rec.Code)
+	}
+}
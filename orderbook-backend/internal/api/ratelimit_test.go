@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRefills(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter := newRateLimiter(2, 3)
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := limiter.allow("ip:a"); !ok {
+			t.Fatalf("request %d of a burst of 3 throttled", i+1)
+		}
+	}
+	if ok, wait := limiter.allow("ip:a"); ok || wait != 500*time.Millisecond {
+		t.Fatalf("fourth request: allowed %v, wait %v, want throttled for 500ms", ok, wait)
+	}
+	if ok, _ := limiter.allow("ip:b"); !ok {
+		t.Fatal("another key shares the bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ := limiter.allow("ip:a"); !ok {
+		t.Fatal("throttled after refilling a token")
+	}
+	if ok, _ := limiter.allow("ip:a"); ok {
+		t.Fatal("allowed more than the refill")
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	ts := newTestServer(t)
+	handler := ts.rateLimitMiddleware(newRateLimiter(1, 2), ts.mux)
+	get := func(ip, bearer string) *httptest.ResponseRecorder {
+		req := ts.newRequest(t, "GET", "/api/v1/markets", bearer, nil)
+		req.RemoteAddr = ip + ":1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := get("10.0.0.1", ""); rec.Code != http.StatusOK {
+			t.Fatalf("request %d within the burst: status = %d", i+1, rec.Code)
+		}
+	}
+	rec := get("10.0.0.1", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over the burst: status = %d, Retry-After %q, want 429 after 1s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	// An address is throttled across IPs
+	token := ts.token(t, alice, time.Hour)
+	for i, ip := range []string{"10.0.0.2", "10.0.0.3"} {
+		if rec := get(ip, token); rec.Code != http.StatusOK {
+			t.Fatalf("authenticated request %d: status = %d", i+1, rec.Code)
+		}
+	}
+	if rec := get("10.0.0.4", token); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("third request for the address from a fresh IP: status = %d, want 429", rec.Code)
+	}
+	if rec := get("10.0.0.4", ""); rec.Code != http.StatusOK {
+		t.Fatalf("anonymous request from that IP: status = %d, want 200", rec.Code)
+	}
+}
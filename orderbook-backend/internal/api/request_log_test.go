@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"orderbook-backend/internal/logging"
+)
+
+func TestRequestLogTagsRequestID(t *testing.T) {
+	ts := newTestServer(t)
+	var logs bytes.Buffer
+	ts.SetLogger(logging.NewWithWriter(&logs, "json", slog.LevelInfo))
+
+	var seen string
+	handler := ts.requestLogMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logging.RequestID(r.Context())
+		w.WriteHeader(http.StatusTeapot)
+	}))
+	serve := func(id string) string {
+		t.Helper()
+		logs.Reset()
+		req := httptest.NewRequest("GET", "/api/v1/markets", nil)
+		if id != "" {
+			req.Header.Set(requestIDHeader, id)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		got := rec.Header().Get(requestIDHeader)
+		if got == "" || got != seen {
+			t.Fatalf("header %q, handler saw %q, want the same non-empty ID", got, seen)
+		}
+		var line struct {
+			RequestID string `json:"request_id"`
+			Method    string `json:"method"`
+			Path      string `json:"path"`
+			Status    int    `json:"status"`
+		}
+		if err := json.Unmarshal(logs.Bytes(), &line); err != nil {
+			t.Fatalf("log %q: %v", logs.String(), err)
+		}
+		if line.RequestID != got || line.Method != "GET" || line.Path != "/api/v1/markets" || line.Status != http.StatusTeapot {
+			t.Fatalf("log line %s, want request %s GET /api/v1/markets 418", logs.String(), got)
+		}
+		return got
+	}
+
+	first, second := serve(""), serve("")
+	if first == second {
+		t.Errorf("two requests share the ID %q", first)
+	}
+	if got := serve("trace-123"); got != "trace-123" {
+		t.Errorf("client ID replaced by %q", got)
+	}
+	if got := serve(strings.Repeat("x", maxRequestIDLen+1)); len(got) > maxRequestIDLen {
+		t.Errorf("overlong client ID kept")
+	}
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+
+	"orderbook-backend/internal/yellow"
 )
 
 // ErrorResponse is the standard error response format
@@ -10,6 +13,14 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// UpstreamErrorResponse reports an error returned by the Yellow ClearNode,
+// keeping its code and detail payload
+type UpstreamErrorResponse struct {
+	Error string          `json:"error"`
+	Code  int             `json:"code"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
 // writeJSON writes a JSON response
 func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -21,3 +32,18 @@ func writeJSON(w http.ResponseWriter, status int, data interface{}) {
 func writeError(w http.ResponseWriter, status int, message string) {
 	writeJSON(w, status, ErrorResponse{Error: message})
 }
+
+// writeUpstreamError writes an error from a Yellow call. ClearNode RPC errors
+// are reported as 502 with their code and data; anything else uses status.
+func writeUpstreamError(w http.ResponseWriter, status int, err error) {
+	var rpcErr *yellow.RPCError
+	if !errors.As(err, &rpcErr) {
+		writeError(w, status, err.Error())
+		return
+	}
+	resp := UpstreamErrorResponse{Error: err.Error(), Code: rpcErr.Code}
+	if rpcErr.HasData() {
+		resp.Data = rpcErr.Data
+	}
+	writeJSON(w, http.StatusBadGateway, resp)
+}
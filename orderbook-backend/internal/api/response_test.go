@@ -0,0 +1,34 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"orderbook-backend/internal/yellow"
+)
+
+func TestWriteUpstreamErrorKeepsRPCData(t *testing.T) {
+	rpcErr := &yellow.RPCError{Code: -32602, Message: "invalid allocations", Data: json.RawMessage(`{"reason":"exceeds deposit"}`)}
+	rec := httptest.NewRecorder()
+	writeUpstreamError(rec, http.StatusBadRequest, fmt.Errorf("update state error: %w", rpcErr))
+	if rec.Code != http.StatusBadGateway {
+		t.Fatalf("status = %d, want 502", rec.Code)
+	}
+	var resp UpstreamErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Code != -32602 || string(resp.Data) != `{"reason":"exceeds deposit"}` {
+		t.Fatalf("response = %+v", resp)
+	}
+
+	rec = httptest.NewRecorder()
+	writeUpstreamError(rec, http.StatusBadRequest, errors.New("client not authenticated"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("plain error: status = %d, want 400", rec.Code)
+	}
+}
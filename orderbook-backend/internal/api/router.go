@@ -0,0 +1,46 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+)
+
+// defaultAPIVersion is the version prefix used when API_VERSION is unset
+const defaultAPIVersion = "v1"
+
+// router registers one API version's routes under its prefix
+type router struct {
+	mux     *http.ServeMux
+	version string
+	legacy  bool // Also serve the routes unversioned under /api
+}
+
+// mountVersion registers an API version's routes under /api/<version>. With
+// legacy set the same routes are also served under /api, marked deprecated;
+// only one version may be mounted as legacy. A future version is added with
+// its own routes function, e.g. mountVersion(mux, "v2", false, s.routesV2).
+func mountVersion(mux *http.ServeMux, version string, legacy bool, routes func(*router)) {
+	routes(&router{mux: mux, version: version, legacy: legacy})
+}
+
+// handle registers a handler for a pattern like "GET /market/{id}", with the
+// path relative to the version prefix
+func (rt *router) handle(pattern string, h http.HandlerFunc) {
+	method, path, _ := strings.Cut(pattern, " ")
+	prefix := "/api/" + rt.version
+	rt.mux.HandleFunc(method+" "+prefix+path, h)
+	if rt.legacy {
+		rt.mux.HandleFunc(method+" /api"+path, deprecated(prefix, h))
+	}
+}
+
+// deprecated marks responses from an unversioned path as deprecated and
+// points clients at the versioned one
+func deprecated(prefix string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		successor := prefix + strings.TrimPrefix(r.URL.Path, "/api")
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+">; rel=\"successor-version\"")
+		h(w, r)
+	}
+}
@@ -0,0 +1,50 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestVersionedAndLegacyRoutes(t *testing.T) {
+	ts := newTestServer(t)
+	mkt := ts.createMarket(t, alice)
+
+	for _, path := range []string{"/api/v1/market/" + mkt.ID, "/api/market/" + mkt.ID} {
+		rec := ts.do(t, "GET", path, "", nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: status = %d, body %s", path, rec.Code, rec.Body)
+		}
+	}
+
+	rec := ts.do(t, "GET", "/api/v1/market/"+mkt.ID, "", nil)
+	if got := rec.Header().Get("Deprecation"); got != "" {
+		t.Errorf("versioned path: Deprecation = %q, want none", got)
+	}
+
+	rec = ts.do(t, "GET", "/api/market/"+mkt.ID, "", nil)
+	if got := rec.Header().Get("Deprecation"); got != "true" {
+		t.Errorf("legacy path: Deprecation = %q, want true", got)
+	}
+	if got, want := rec.Header().Get("Link"), `</api/v1/market/`+mkt.ID+`>; rel="successor-version"`; got != want {
+		t.Errorf("legacy path: Link = %q, want %q", got, want)
+	}
+}
+
+func TestMountAdditionalVersion(t *testing.T) {
+	mux := http.NewServeMux()
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusNoContent) }
+	mountVersion(mux, "v1", true, func(rt *router) { rt.handle("GET /ping", ok) })
+	mountVersion(mux, "v2", false, func(rt *router) { rt.handle("GET /ping", ok) })
+
+	for path, want := range map[string]int{
+		"/api/v1/ping": http.StatusNoContent,
+		"/api/v2/ping": http.StatusNoContent,
+		"/api/ping":    http.StatusNoContent,
+		"/api/v3/ping": http.StatusNotFound,
+	} {
+		ts := &testServer{mux: mux}
+		if rec := ts.do(t, "GET", path, "", nil); rec.Code != want {
+			t.Errorf("GET %s: status = %d, want %d", path, rec.Code, want)
+		}
+	}
+}
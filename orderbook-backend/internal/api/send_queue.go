@@ -0,0 +1,156 @@
+package api
+
+import (
+	"strconv"
+	"sync"
+)
+
+// pushResult says what happened to a message handed to a sendQueue
+type pushResult int
+
+const (
+	pushQueued    pushResult = iota // Appended to the queue
+	pushCoalesced                   // Replaced a queued message with the same key
+	pushEvicted                     // Queued after dropping the oldest keyed message to make room
+	pushRefused                     // Not queued: the queue is closed, or full of unkeyed messages
+)
+
+// resyncMessage tells a client that it fell behind and missed snapshots, so
+// it should request the state it tracks again
+var resyncMessage = []byte(`{"type":"resync"}`)
+
+// sendQueue is a client's outgoing message queue. Messages with a key
+// replace the queued message with the same key instead of growing the
+// queue; all others are kept in order up to a limit. When the queue is
+// full, keyed messages (snapshots) are dropped oldest first and the client
+// is sent a resync hint; unkeyed messages (trades) are never dropped.
+type sendQueue struct {
+	mu     sync.Mutex
+	items  []queuedMessage
+	keys   map[string]int // key -> index in items
+	limit  int
+	closed bool
+	resync bool   // A keyed message was dropped since the last take
+	seq    uint64 // Last sequence number handed out
+
+	// ready is signalled whenever there is something to take
+	ready chan struct{}
+}
+
+type queuedMessage struct {
+	data []byte
+	key  string
+}
+
+func newSendQueue(limit int) *sendQueue {
+	return &sendQueue{
+		keys:  make(map[string]int),
+		limit: limit,
+		ready: make(chan struct{}, 1),
+	}
+}
+
+// push queues a message
+func (q *sendQueue) push(data []byte, key string) pushResult {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.closed {
+		return pushRefused
+	}
+	if key != "" {
+		if i, exists := q.keys[key]; exists {
+			q.items[i].data = data
+			return pushCoalesced
+		}
+	}
+
+	result := pushQueued
+	if len(q.items) >= q.limit {
+		if !q.evictOldestKeyed() {
+			if key != "" {
+				// Nothing older to drop in favor of this snapshot
+				q.resync = true
+				q.signal()
+				return pushEvicted
+			}
+			return pushRefused
+		}
+		result = pushEvicted
+	}
+
+	q.items = append(q.items, queuedMessage{data: data, key: key})
+	if key != "" {
+		q.keys[key] = len(q.items) - 1
+	}
+	q.signal()
+	return result
+}
+
+// evictOldestKeyed drops the oldest keyed message and flags a resync. It
+// reports false if there is none (must hold lock).
+func (q *sendQueue) evictOldestKeyed() bool {
+	for i, item := range q.items {
+		if item.key == "" {
+			continue
+		}
+		q.items = append(q.items[:i], q.items[i+1:]...)
+		delete(q.keys, item.key)
+		for key, j := range q.keys {
+			if j > i {
+				q.keys[key] = j - 1
+			}
+		}
+		q.resync = true
+		return true
+	}
+	return false
+}
+
+// take removes and returns everything queued, each message stamped with
+// the client's next sequence number, followed by a resync hint if
+// snapshots were dropped. It also reports whether the queue is closed.
+func (q *sendQueue) take() ([][]byte, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	batch := make([][]byte, 0, len(q.items)+1)
+	for _, item := range q.items {
+		batch = append(batch, q.stamp(item.data))
+	}
+	if q.resync {
+		batch = append(batch, q.stamp(resyncMessage))
+		q.resync = false
+	}
+	q.items = nil
+	clear(q.keys)
+	return batch, q.closed
+}
+
+// stamp adds the next sequence number to a JSON object message (must hold lock)
+func (q *sendQueue) stamp(data []byte) []byte {
+	q.seq++
+	stamped := make([]byte, 0, len(data)+24)
+	stamped = append(stamped, `{"seq":`...)
+	stamped = strconv.AppendUint(stamped, q.seq, 10)
+	stamped = append(stamped, ',')
+	return append(stamped, data[1:]...)
+}
+
+// close stops the queue; messages already queued are still delivered
+func (q *sendQueue) close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		q.signal()
+	}
+}
+
+// signal wakes the writer without blocking (must hold lock)
+func (q *sendQueue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
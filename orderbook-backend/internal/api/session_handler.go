@@ -3,6 +3,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
 
 	"orderbook-backend/internal/yellow"
 )
@@ -19,8 +20,11 @@ type CreateSessionResponse struct {
 	Status    string `json:"status"`
 }
 
-// handleCreateSession handles POST /api/session
+// handleCreateSession handles POST /api/session (admin only)
 func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
 	if s.sessions == nil {
 		writeError(w, http.StatusServiceUnavailable, "session manager not initialized")
 		return
@@ -37,25 +41,54 @@ func (s *Server) handleCreateSession(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	participants, err := yellow.NormalizeAddresses(req.Participants)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	allocations, err := yellow.NormalizeAllocations(req.Allocations)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	amounts := make([]uint64, len(allocations))
+	for i, alloc := range allocations {
+		if amounts[i], err = strconv.ParseUint(alloc.Amount, 10, 64); err != nil {
+			writeError(w, http.StatusBadRequest, "allocation amount must be a whole number of USDC")
+			return
+		}
+	}
+
 	session, err := s.sessions.CreateSession(
 		r.Context(),
-		req.Participants,
-		req.Allocations,
+		participants,
+		allocations,
 		s.cfg.AdjudicatorAddr,
 	)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeUpstreamError(w, http.StatusInternalServerError, err)
 		return
 	}
 
+	// Funds committed to the channel back the participants' trading
+	// balances; allocations are tracked in basis points like positions
+	if s.allocations != nil {
+		for i, alloc := range allocations {
+			s.allocations.Credit(alloc.Token, alloc.Participant, amounts[i]*10000)
+		}
+	}
+
 	writeJSON(w, http.StatusOK, CreateSessionResponse{
 		ChannelID: session.GetChannelID(),
 		Status:    "created",
 	})
 }
 
-// handleCloseSession handles DELETE /api/session/{id}
+// handleCloseSession handles DELETE /api/session/{id} (admin only)
 func (s *Server) handleCloseSession(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
 	if s.sessions == nil {
 		writeError(w, http.StatusServiceUnavailable, "session manager not initialized")
 		return
@@ -68,7 +101,7 @@ func (s *Server) handleCloseSession(w http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := s.sessions.CloseSession(r.Context(), channelID); err != nil {
-		writeError(w, http.StatusInternalServerError, err.Error())
+		writeUpstreamError(w, http.StatusInternalServerError, err)
 		return
 	}
 
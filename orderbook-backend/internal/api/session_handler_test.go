@@ -0,0 +1,34 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSessionAndSettleRoutesRequireAdmin(t *testing.T) {
+	ts := newTestServer(t)
+	userToken := ts.token(t, alice, time.Hour)
+
+	routes := []struct {
+		method, path string
+		body         interface{}
+	}{
+		{"POST", "/api/v1/session", map[string]interface{}{"participants": []string{alice, bob}}},
+		{"DELETE", "/api/v1/session/0xabc", nil},
+		{"POST", "/api/v1/settle", map[string]string{"channel_id": "0xabc", "type": "cooperative"}},
+		{"POST", "/api/v1/settle", map[string]string{"channel_id": "0xabc", "type": "dispute"}},
+	}
+	for _, rt := range routes {
+		for _, bearer := range []string{"", userToken} {
+			if rec := ts.do(t, rt.method, rt.path, bearer, rt.body); rec.Code != http.StatusUnauthorized {
+				t.Errorf("%s %s with bearer %q: status = %d, want 401", rt.method, rt.path, bearer, rec.Code)
+			}
+		}
+	}
+
+	// The admin gets past the check to the missing session manager
+	if rec := ts.do(t, "DELETE", "/api/v1/session/0xabc", testAdminToken, nil); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("admin close: status = %d, want 503", rec.Code)
+	}
+}
@@ -2,7 +2,19 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"slices"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/state"
+	"orderbook-backend/internal/yellow"
+)
+
+var (
+	errMarketNotSettled = errors.New("market has not been settled; final balances are only known once it resolves")
+	errPositionsOpen    = errors.New("a participant still holds shares; final balances are only known once their markets resolve")
 )
 
 // SettleRequest is the request body for settlement
@@ -16,10 +28,18 @@ type SettleResponse struct {
 	Status    string `json:"status"`
 	ChannelID string `json:"channel_id"`
 	TxHash    string `json:"tx_hash,omitempty"`
+
+	// Final allocations the session was closed with
+	Allocations []yellow.Allocation `json:"allocations,omitempty"`
 }
 
-// handleSettle handles POST /api/settle
+// handleSettle handles POST /api/settle (admin only: it closes channels and
+// a dispute spends the operator's gas)
 func (s *Server) handleSettle(w http.ResponseWriter, r *http.Request) {
+	if !s.requireAdmin(w, r) {
+		return
+	}
+
 	var req SettleRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid request body")
@@ -33,34 +53,169 @@ func (s *Server) handleSettle(w http.ResponseWriter, r *http.Request) {
 
 	switch req.Type {
 	case "cooperative":
-		// In cooperative close, we close the session normally
-		// The Yellow Network handles the on-chain settlement
+		// Close the session on the settled balances; the Yellow Network
+		// handles the on-chain settlement
+		var allocations []yellow.Allocation
 		if s.sessions != nil {
-			if err := s.sessions.CloseSession(r.Context(), req.ChannelID); err != nil {
-				writeError(w, http.StatusInternalServerError, err.Error())
+			var err error
+			allocations, err = s.closingAllocations(req.ChannelID)
+			if err == errMarketNotSettled || err == errPositionsOpen || errors.Is(err, state.ErrInsufficientBalance) {
+				writeError(w, http.StatusConflict, err.Error())
+				return
+			}
+			if err != nil {
+				writeError(w, http.StatusNotFound, err.Error())
+				return
+			}
+			if err := s.sessions.CloseSessionWithAllocations(r.Context(), req.ChannelID, allocations); err != nil {
+				writeUpstreamError(w, http.StatusInternalServerError, err)
 				return
 			}
 		}
 
 		writeJSON(w, http.StatusOK, SettleResponse{
-			Status:    "settled",
-			ChannelID: req.ChannelID,
+			Status:      "settled",
+			ChannelID:   req.ChannelID,
+			Allocations: allocations,
 		})
 
 	case "dispute":
-		// In dispute mode, we would need to:
-		// 1. Collect the latest signed state
-		// 2. Submit it to the on-chain adjudicator contract
-		// This requires an Ethereum client connection
+		// Put the latest signed state on chain via the adjudicator
+		if s.disputer == nil {
+			writeError(w, http.StatusServiceUnavailable, "on-chain disputes are not configured (CHAIN_RPC_URL)")
+			return
+		}
+		cp, err := s.latestSignedState(req.ChannelID)
+		if err != nil {
+			writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		txHash, err := s.disputer.Challenge(r.Context(), cp)
+		if err == yellow.ErrNoSignedState {
+			writeError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err.Error())
+			return
+		}
+		s.logger.InfoContext(r.Context(), "dispute submitted", "channel_id", req.ChannelID, "version", cp.Version, "tx_hash", txHash.Hex())
 
-		// For now, return a placeholder response
 		writeJSON(w, http.StatusOK, SettleResponse{
 			Status:    "dispute_initiated",
 			ChannelID: req.ChannelID,
-			TxHash:    "", // Would be the actual tx hash
+			TxHash:    txHash.Hex(),
 		})
 
 	default:
 		writeError(w, http.StatusBadRequest, "type must be 'cooperative' or 'dispute'")
 	}
 }
+
+// closingAllocations maps a market session's settled positions to its final
+// allocations (see settledAllocations). Other sessions are netted (see
+// nettedAllocations).
+func (s *Server) closingAllocations(channelID string) ([]yellow.Allocation, error) {
+	session, ok := s.sessions.GetSession(channelID)
+	if !ok {
+		return nil, fmt.Errorf("session not found: %s", channelID)
+	}
+	marketID, ok := s.sessions.SessionMarket(channelID)
+	if !ok {
+		return s.nettedAllocations(session.Participants())
+	}
+	settlement, ok := s.positions.GetSettlement(marketID)
+	if !ok {
+		return nil, errMarketNotSettled
+	}
+	return s.settledAllocations(session.Participants(), settlement), nil
+}
+
+// settledAllocations gives each participant of a settled market's session
+// their balance in the default token. Settling already credited every
+// payout to its holder's balance, so the balance is what they had plus what
+// the resolution paid them; adding the payout again would pay it twice.
+// Participants who held no shares keep their balance as it is.
+func (s *Server) settledAllocations(participants []string, settlement *engine.Settlement) []yellow.Allocation {
+	holders := make(map[string]string)
+	for _, d := range settlement.Payouts {
+		addr, err := yellow.NormalizeAddress(d.UserID)
+		if err != nil {
+			continue // Off-chain accounts (e.g. the house) hold no channel funds
+		}
+		holders[addr] = d.UserID
+	}
+
+	allocations := make([]yellow.Allocation, 0, len(participants))
+	for _, participant := range participants {
+		userID, ok := holders[participant]
+		if !ok {
+			userID = participant
+		}
+		allocations = append(allocations, yellow.Allocation{
+			Participant: participant,
+			Token:       s.cfg.DefaultToken,
+			Amount:      state.FormatAmount(s.positions.GetBalance(userID)),
+		})
+	}
+	return allocations
+}
+
+// nettedAllocations nets the trading of a funding session's participants
+// across all markets into their channel allocations. A participant's final
+// amount of the default token is their channel balance plus the sum of their
+// signed per-market results (realized PnL, see engine.NetRealized), so gains
+// in one market offset losses in another. Without channel-backed balances
+// there is nothing to net against and the session closes on its last state
+// (nil).
+func (s *Server) nettedAllocations(participants []string) ([]yellow.Allocation, error) {
+	if s.allocations == nil {
+		return nil, nil
+	}
+	token, err := yellow.NormalizeAddress(s.cfg.DefaultToken)
+	if err != nil {
+		return nil, err
+	}
+
+	deltas := make(map[string]int64)
+	for _, net := range s.positions.NetRealized() {
+		addr, err := yellow.NormalizeAddress(net.UserID)
+		if err != nil || !slices.Contains(participants, addr) {
+			continue
+		}
+		if net.Open {
+			return nil, errPositionsOpen
+		}
+		deltas[addr] += net.Realized
+	}
+
+	netted, err := s.allocations.Net(map[string]map[string]int64{token: deltas})
+	if err != nil {
+		return nil, err
+	}
+
+	allocations := make([]yellow.Allocation, 0, len(participants))
+	for _, participant := range participants {
+		allocations = append(allocations, yellow.Allocation{
+			Participant: participant,
+			Token:       token,
+			Amount:      state.FormatAmount(netted.GetBalance(token, participant)),
+		})
+	}
+	return allocations, nil
+}
+
+// latestSignedState returns a channel's latest signed state: the live
+// session's if it is still tracked, otherwise the one checkpointed on shutdown
+func (s *Server) latestSignedState(channelID string) (yellow.Checkpoint, error) {
+	if s.sessions != nil {
+		if session, ok := s.sessions.GetSession(channelID); ok {
+			return session.Checkpoint(), nil
+		}
+	}
+	cp, err := yellow.ReadCheckpoint(s.cfg.CheckpointDir, channelID)
+	if err != nil {
+		return cp, fmt.Errorf("no signed state known for channel %s", channelID)
+	}
+	return cp, nil
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"testing"
+
+	"orderbook-backend/internal/state"
+	"orderbook-backend/internal/yellow"
+)
+
+func TestSettledAllocationsConserveFunds(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+
+	// alice pays 6 USDC and bob 4 for a minted pair; YES pays alice 10
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 4000, 10)
+	if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", testAdminToken, map[string]string{"outcome": "YES"}); rec.Code != http.StatusOK {
+		t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+	}
+	settlement, ok := ts.positions.GetSettlement(mkt.ID)
+	if !ok {
+		t.Fatal("market not settled")
+	}
+
+	operator := "0x3333333333333333333333333333333333333333"
+	allocations := ts.settledAllocations([]string{operator, alice, bob}, settlement)
+	want := map[string]uint64{operator: 0, alice: 1040000, bob: 960000}
+	if len(allocations) != len(want) {
+		t.Fatalf("allocations = %+v, want one per participant", allocations)
+	}
+	var total uint64
+	for _, a := range allocations {
+		amount, err := state.ParseAmount(a.Amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if amount != want[a.Participant] {
+			t.Errorf("%s: %s, want %s", a.Participant, a.Amount, state.FormatAmount(want[a.Participant]))
+		}
+		total += amount
+	}
+	if deposited := uint64(2000000) - ts.positions.CollectedFees(); total != deposited {
+		t.Fatalf("allocations sum to %d, want the %d deposited less fees", total, deposited)
+	}
+}
+
+func TestNettedAllocationsMatchPositionBalances(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.ChannelBackedBalances = true
+	allocations, err := state.FromCheckpoints([]yellow.Checkpoint{{ChannelID: "0xfund", Allocations: []yellow.Allocation{
+		{Participant: alice, Token: ts.cfg.DefaultToken, Amount: "10"},
+		{Participant: bob, Token: ts.cfg.DefaultToken, Amount: "10"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.SetAllocations(allocations)
+	ts.fund(t, alice, 10)
+	ts.fund(t, bob, 10)
+
+	// Off-round prices: alice pays 1.8003 USDC and bob 1.1997 for 3 pairs
+	mkt := ts.createMarket(t, alice)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6001, 3)
+	ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 3999, 3)
+	if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", testAdminToken, map[string]string{"outcome": "YES"}); rec.Code != http.StatusOK {
+		t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+	}
+
+	netted, err := ts.nettedAllocations([]string{alice, bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The channel moves exactly what the position manager moved
+	for _, a := range netted {
+		amount, err := state.ParseAmount(a.Amount)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := ts.positions.GetBalance(a.Participant); amount != want {
+			t.Errorf("%s: channel allocation %s, position balance %s", a.Participant, a.Amount, state.FormatAmount(want))
+		}
+	}
+	if got := ts.positions.GetBalance(alice); got != 111997 {
+		t.Fatalf("alice balance = %d, want 111997", got)
+	}
+}
+
+func TestNettedAllocationsOffsetAcrossMarkets(t *testing.T) {
+	ts := newTestServer(t)
+	ts.cfg.ChannelBackedBalances = true
+	allocations, err := state.FromCheckpoints([]yellow.Checkpoint{{ChannelID: "0xfund", Allocations: []yellow.Allocation{
+		{Participant: alice, Token: ts.cfg.DefaultToken, Amount: "10"},
+		{Participant: bob, Token: ts.cfg.DefaultToken, Amount: "10"},
+	}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts.SetAllocations(allocations)
+	ts.fund(t, alice, 10)
+	ts.fund(t, bob, 10)
+
+	// alice makes 4 USDC in one market and loses 5 in the other
+	trade := func(yesPrice uint64, outcome string) {
+		mkt := ts.createMarket(t, alice)
+		ts.placeOrder(t, alice, mkt.ID, "YES", "buy", yesPrice, 10)
+		ts.placeOrder(t, bob, mkt.ID, "NO", "buy", 10000-yesPrice, 10)
+		if outcome != "" {
+			if rec := ts.do(t, "POST", "/api/v1/market/"+mkt.ID+"/resolve", testAdminToken, map[string]string{"outcome": outcome}); rec.Code != http.StatusOK {
+				t.Fatalf("resolve: status = %d, body %s", rec.Code, rec.Body)
+			}
+		}
+	}
+	trade(6000, "YES")
+	trade(5000, "NO")
+
+	netted, err := ts.nettedAllocations([]string{alice, bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{alice: "9", bob: "11"}
+	if len(netted) != len(want) {
+		t.Fatalf("allocations = %+v, want one per participant", netted)
+	}
+	for _, a := range netted {
+		if a.Amount != want[a.Participant] {
+			t.Errorf("%s: %s, want the net %s", a.Participant, a.Amount, want[a.Participant])
+		}
+	}
+
+	// Shares still held in an open market leave the result unknown
+	trade(5000, "")
+	if _, err := ts.nettedAllocations([]string{alice, bob}); err != errPositionsOpen {
+		t.Fatalf("with an open market: err = %v, want %v", err, errPositionsOpen)
+	}
+}
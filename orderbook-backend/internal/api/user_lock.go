@@ -0,0 +1,44 @@
+package api
+
+import "sync"
+
+// userLocks serializes requests that spend one user's funds or shares, so a
+// multi-step operation such as /buy sees no withdrawal or order of the same
+// user between its steps
+type userLocks struct {
+	mu    sync.Mutex
+	locks map[string]*userLock
+}
+
+// userLock is one user's mutex and the number of requests holding or
+// waiting for it
+type userLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// lock blocks until no other request holds userID's lock and returns the
+// function that releases it
+func (l *userLocks) lock(userID string) (unlock func()) {
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*userLock)
+	}
+	ul, ok := l.locks[userID]
+	if !ok {
+		ul = &userLock{}
+		l.locks[userID] = ul
+	}
+	ul.refs++
+	l.mu.Unlock()
+
+	ul.mu.Lock()
+	return func() {
+		ul.mu.Unlock()
+		l.mu.Lock()
+		if ul.refs--; ul.refs == 0 {
+			delete(l.locks, userID)
+		}
+		l.mu.Unlock()
+	}
+}
@@ -4,8 +4,12 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"orderbook-backend/internal/engine"
 	"orderbook-backend/internal/yellow"
 
 	"github.com/gorilla/websocket"
@@ -19,17 +23,37 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
+// Client connection keepalive
+const (
+	wsWriteWait  = 10 * time.Second    // Time allowed to write one message
+	wsPongWait   = 60 * time.Second    // Time allowed between pongs before the client counts as dead
+	wsPingPeriod = wsPongWait * 9 / 10 // How often clients are pinged; must be less than wsPongWait
+	wsMaxMessage = 64 * 1024           // Largest message accepted from a client
+)
+
 // Message is a WebSocket message
 type Message struct {
 	Type string      `json:"type"`
 	Data interface{} `json:"data"`
 }
 
+// ClientMessage is a request sent by a WebSocket client
+type ClientMessage struct {
+	Type     string `json:"type"`
+	MarketID string `json:"market_id,omitempty"`
+	Outcome  string `json:"outcome,omitempty"`
+}
+
 // Client represents a WebSocket client
 type Client struct {
-	hub  *Hub
-	conn *websocket.Conn
-	send chan []byte
+	hub    *Hub
+	server *Server
+	conn   *websocket.Conn
+	send   *sendQueue
+
+	// Topics this client receives via Hub.Publish
+	subsMu        sync.RWMutex
+	subscriptions map[string]bool
 
 	// Yellow Network session info
 	yellowToken      string
@@ -37,29 +61,80 @@ type Client struct {
 	yellowAddress    string
 }
 
-// Hub manages all WebSocket clients
+// envelope is a message waiting to be fanned out to clients
+type envelope struct {
+	topic string // Empty = every client
+	key   string // Non-empty = only the latest message per key matters
+	data  []byte
+}
+
+// Hub manages all WebSocket clients. Producers hand messages to an
+// unbounded inbox and never block; Run fans them out to per-client queues.
+// Only keyed messages (orderbook snapshots) may be coalesced, or dropped
+// when a client's queue is full, in which case the client is told to
+// resync. A client whose backlog of other messages passes maxClientBacklog
+// is disconnected rather than silently missing messages.
 type Hub struct {
 	clients    map[*Client]bool
-	broadcast  chan []byte
 	register   chan *Client
 	unregister chan *Client
 	mu         sync.RWMutex
+
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	inboxMu sync.Mutex
+	inbox   []envelope
+	wake    chan struct{}
+
+	// Client keepalive (wsPongWait and wsPingPeriod outside tests)
+	pongWait   time.Duration
+	pingPeriod time.Duration
+
+	coalesced       atomic.Uint64
+	dropped         atomic.Uint64
+	resyncs         atomic.Uint64
+	slowDisconnects atomic.Uint64
+}
+
+// maxClientBacklog bounds the undelivered messages queued for one client
+const maxClientBacklog = 4096
+
+// HubStats reports delivery counters for the WebSocket feed
+type HubStats struct {
+	Clients         int    `json:"clients"`
+	Coalesced       uint64 `json:"coalesced"`        // Snapshots replaced by a newer one before sending
+	Dropped         uint64 `json:"dropped"`          // Messages refused by a full client queue (the client is then disconnected)
+	Resyncs         uint64 `json:"resyncs"`          // Snapshots dropped from a full queue (the client is sent a resync hint)
+	SlowDisconnects uint64 `json:"slow_disconnects"` // Clients cut off for falling too far behind
 }
 
 // NewHub creates a new WebSocket hub
 func NewHub() *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		wake:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		pongWait:   wsPongWait,
+		pingPeriod: wsPingPeriod,
 	}
 }
 
-// Run starts the hub
+// Run starts the hub. It returns after Stop.
 func (h *Hub) Run() {
 	for {
 		select {
+		case <-h.stop:
+			h.mu.Lock()
+			for client := range h.clients {
+				client.send.close()
+				delete(h.clients, client)
+			}
+			h.mu.Unlock()
+			return
+
 		case client := <-h.register:
 			h.mu.Lock()
 			h.clients[client] = true
@@ -69,38 +144,93 @@ func (h *Hub) Run() {
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
 				delete(h.clients, client)
-				close(client.send)
+				client.send.close()
 			}
 			h.mu.Unlock()
 
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
-				}
+		case <-h.wake:
+			h.inboxMu.Lock()
+			batch := h.inbox
+			h.inbox = nil
+			h.inboxMu.Unlock()
+
+			h.mu.Lock()
+			for _, env := range batch {
+				h.deliver(env)
 			}
-			h.mu.RUnlock()
+			h.mu.Unlock()
 		}
 	}
 }
 
+// Stop closes every client connection, after sending what is already queued
+// for it, and ends Run
+func (h *Hub) Stop() {
+	h.stopOnce.Do(func() { close(h.stop) })
+}
+
+// deliver queues a message for every matching client (must hold lock)
+func (h *Hub) deliver(env envelope) {
+	for client := range h.clients {
+		if env.topic != "" && !client.isSubscribed(env.topic) {
+			continue
+		}
+		switch client.send.push(env.data, env.key) {
+		case pushRefused:
+			h.dropped.Add(1)
+			h.slowDisconnects.Add(1)
+			client.send.close()
+			delete(h.clients, client)
+		case pushEvicted:
+			h.resyncs.Add(1)
+		case pushCoalesced:
+			h.coalesced.Add(1)
+		}
+	}
+}
+
+// enqueue adds a message to the inbox and wakes Run
+func (h *Hub) enqueue(env envelope) {
+	h.inboxMu.Lock()
+	h.inbox = append(h.inbox, env)
+	h.inboxMu.Unlock()
+
+	select {
+	case h.wake <- struct{}{}:
+	default: // Run is already due to drain the inbox
+	}
+}
+
 // Broadcast sends a message to all clients
 func (h *Hub) Broadcast(msg Message) {
+	h.send("", "", msg)
+}
+
+// BroadcastLatest sends a message to all clients, replacing any message with
+// the same key that a client hasn't received yet. Use it for snapshots that
+// supersede each other.
+func (h *Hub) BroadcastLatest(key string, msg Message) {
+	h.send("", key, msg)
+}
+
+// Publish sends a message to clients subscribed to a topic
+func (h *Hub) Publish(topic string, msg Message) {
+	h.send(topic, "", msg)
+}
+
+// PublishLatest sends a message to clients subscribed to a topic, replacing
+// any message with the same key that a client hasn't received yet
+func (h *Hub) PublishLatest(topic, key string, msg Message) {
+	h.send(topic, key, msg)
+}
+
+func (h *Hub) send(topic, key string, msg Message) {
 	data, err := json.Marshal(msg)
 	if err != nil {
 		log.Printf("Failed to marshal message: %v", err)
 		return
 	}
-
-	select {
-	case h.broadcast <- data:
-	default:
-		log.Printf("Broadcast channel full, dropping message")
-	}
+	h.enqueue(envelope{topic: topic, key: key, data: data})
 }
 
 // ClientCount returns the number of connected clients
@@ -110,6 +240,17 @@ func (h *Hub) ClientCount() int {
 	return len(h.clients)
 }
 
+// Stats returns the hub's delivery counters
+func (h *Hub) Stats() HubStats {
+	return HubStats{
+		Clients:         h.ClientCount(),
+		Coalesced:       h.coalesced.Load(),
+		Dropped:         h.dropped.Load(),
+		Resyncs:         h.resyncs.Load(),
+		SlowDisconnects: h.slowDisconnects.Load(),
+	}
+}
+
 // handleWebSocket handles WebSocket connections
 func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
@@ -119,12 +260,19 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	}
 
 	client := &Client{
-		hub:  s.wsHub,
-		conn: conn,
-		send: make(chan []byte, 256),
+		hub:           s.wsHub,
+		server:        s,
+		conn:          conn,
+		send:          newSendQueue(maxClientBacklog),
+		subscriptions: make(map[string]bool),
 	}
 
-	s.wsHub.register <- client
+	select {
+	case s.wsHub.register <- client:
+	case <-s.wsHub.stop:
+		conn.Close()
+		return
+	}
 
 	// Start write pump
 	go client.writePump()
@@ -137,30 +285,60 @@ func (s *Server) handleWebSocket(w http.ResponseWriter, r *http.Request) {
 		Type: "connected",
 		Data: map[string]string{"status": "connected"},
 	}
-	data, _ := json.Marshal(msg)
-	client.send <- data
+	client.sendMessage(msg)
 }
 
-// writePump sends messages to the WebSocket connection
+// writePump sends messages to the WebSocket connection and pings the
+// client every wsPingPeriod. A write that can't finish within wsWriteWait
+// closes the connection, which ends readPump and unregisters the client.
 func (c *Client) writePump() {
+	ticker := time.NewTicker(c.hub.pingPeriod)
 	defer func() {
+		ticker.Stop()
 		c.conn.Close()
 	}()
 
-	for message := range c.send {
-		if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
-			return
+	for {
+		select {
+		case <-c.send.ready:
+			batch, closed := c.send.take()
+			for _, message := range batch {
+				c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+					return
+				}
+			}
+			if closed {
+				c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+				c.conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+				return
+			}
+		case <-ticker.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
 		}
 	}
 }
 
-// readPump reads messages from the WebSocket connection
+// readPump reads messages from the WebSocket connection. A client that
+// sends nothing, not even a pong, for wsPongWait is disconnected.
 func (c *Client) readPump() {
 	defer func() {
-		c.hub.unregister <- c
+		select {
+		case c.hub.unregister <- c:
+		case <-c.hub.stop:
+		}
 		c.conn.Close()
 	}()
 
+	c.conn.SetReadLimit(wsMaxMessage)
+	c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(c.hub.pongWait))
+	})
+
 	for {
 		_, message, err := c.conn.ReadMessage()
 		if err != nil {
@@ -176,17 +354,159 @@ func (c *Client) readPump() {
 			continue
 		}
 
+		var clientMsg ClientMessage
+		if err := json.Unmarshal(message, &clientMsg); err == nil {
+			switch clientMsg.Type {
+			case "subscribe":
+				c.handleSubscribe(clientMsg)
+				continue
+			case "unsubscribe":
+				c.unsubscribe(marketTopic(clientMsg.MarketID))
+				continue
+			case "subscribe_l3":
+				c.handleSubscribeL3(clientMsg)
+				continue
+			case "unsubscribe_l3":
+				c.unsubscribe(l3Topic(clientMsg.MarketID, outcomeFromString(clientMsg.Outcome)))
+				continue
+			}
+		}
+
 		// Handle other message types here if needed
 		log.Printf("Received unhandled message: %s", string(message))
 	}
 }
 
+// subscribe adds a topic to the client's subscriptions
+func (c *Client) subscribe(topic string) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	c.subscriptions[topic] = true
+}
+
+// unsubscribe removes a topic from the client's subscriptions
+func (c *Client) unsubscribe(topic string) {
+	c.subsMu.Lock()
+	defer c.subsMu.Unlock()
+	delete(c.subscriptions, topic)
+}
+
+// isSubscribed reports whether the client receives a topic
+func (c *Client) isSubscribed(topic string) bool {
+	c.subsMu.RLock()
+	defer c.subsMu.RUnlock()
+	return c.subscriptions[topic]
+}
+
+// sendMessage queues a message for this client only
+func (c *Client) sendMessage(msg Message) {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		log.Printf("Failed to marshal message: %v", err)
+		return
+	}
+	c.send.push(data, "")
+}
+
+// subscribeTradeCount is how many recent trades per outcome the snapshot
+// sent on subscribe carries
+const subscribeTradeCount = 50
+
+// handleSubscribe subscribes the client to a market's orderbook and trade
+// updates and sends it the current books and recent trades. The client is
+// subscribed before the snapshot is taken, so no update is missed.
+func (c *Client) handleSubscribe(msg ClientMessage) {
+	if _, ok := c.server.marketManager.Get(msg.MarketID); !ok {
+		c.sendMessage(Message{
+			Type: "error",
+			Data: map[string]string{"error": "market not found"},
+		})
+		return
+	}
+	c.subscribe(marketTopic(msg.MarketID))
+
+	obs := c.server.marketOrderbooks.GetOrCreate(msg.MarketID)
+	data := orderbookData(msg.MarketID, obs, c.server.cfg.MaxDepth)
+	data["trades"] = map[string]interface{}{
+		"YES": obs.YES.QueryTrades(time.Time{}, time.Time{}, subscribeTradeCount, 0),
+		"NO":  obs.NO.QueryTrades(time.Time{}, time.Time{}, subscribeTradeCount, 0),
+	}
+	c.sendMessage(Message{Type: "snapshot", Data: data})
+}
+
+// handleSubscribeL3 subscribes the client to a book's level-3 feed and sends
+// the full book. The client is subscribed before the snapshot is taken, so
+// it must drop buffered events whose seq is <= the snapshot's seq.
+func (c *Client) handleSubscribeL3(msg ClientMessage) {
+	if _, ok := c.server.marketManager.Get(msg.MarketID); !ok {
+		c.sendMessage(Message{
+			Type: "error",
+			Data: map[string]string{"error": "market not found"},
+		})
+		return
+	}
+
+	outcome := outcomeFromString(msg.Outcome)
+	c.subscribe(l3Topic(msg.MarketID, outcome))
+
+	orderbook := c.server.marketOrderbooks.GetOrderbook(msg.MarketID, outcome)
+	c.sendMessage(Message{
+		Type: "l3_snapshot",
+		Data: map[string]interface{}{
+			"market_id": msg.MarketID,
+			"outcome":   string(outcome),
+			"snapshot":  orderbook.GetL3Snapshot(),
+		},
+	})
+}
+
+// publishOrderEvent streams a level-3 order event to its book's subscribers
+func (s *Server) publishOrderEvent(event engine.OrderEvent) {
+	s.wsHub.Publish(l3Topic(event.MarketID, event.OutcomeID), Message{
+		Type: "l3",
+		Data: event,
+	})
+}
+
+// publishOrderUpdate sends an order's status change to its owner's
+// authenticated WebSocket clients
+func (s *Server) publishOrderUpdate(update engine.OrderUpdate) {
+	s.wsHub.Publish(userTopic(update.UserID), Message{
+		Type: "order_update",
+		Data: update,
+	})
+}
+
+// marketTopic is the subscription topic for a market's orderbook and trades
+func marketTopic(marketID string) string {
+	return "market:" + marketID
+}
+
+// l3Topic is the subscription topic for a book's level-3 feed
+func l3Topic(marketID string, outcome engine.OutcomeID) string {
+	return "l3:" + marketID + ":" + string(outcome)
+}
+
+// userTopic is the topic a user's own order updates are published to.
+// Addresses are compared case-insensitively, as in actingUser.
+func userTopic(userID string) string {
+	return "user:" + strings.ToLower(userID)
+}
+
+// outcomeFromString parses an outcome, defaulting to YES like the HTTP handlers
+func outcomeFromString(s string) engine.OutcomeID {
+	if s == "NO" {
+		return engine.OutcomeNO
+	}
+	return engine.OutcomeYES
+}
+
 // handleYellowAuth handles Yellow Network authentication
 func (c *Client) handleYellowAuth(msg *yellow.YellowAuthMessage) {
 	log.Printf("Received Yellow auth: session_key=%s", msg.SessionKey)
 
 	// Validate the JWT token
-	session, err := yellow.ValidateToken(msg.JWTToken)
+	session, err := c.server.tokens.Validate(msg.JWTToken)
 	if err != nil {
 		log.Printf("Yellow auth failed: %v", err)
 		errorMsg := Message{
@@ -195,15 +515,18 @@ func (c *Client) handleYellowAuth(msg *yellow.YellowAuthMessage) {
 				"error": "Invalid Yellow authentication",
 			},
 		}
-		data, _ := json.Marshal(errorMsg)
-		c.send <- data
+		c.sendMessage(errorMsg)
 		return
 	}
 
-	// Store Yellow session info
+	// Store Yellow session info and follow the address's own orders
+	if c.yellowAddress != "" {
+		c.unsubscribe(userTopic(c.yellowAddress))
+	}
 	c.yellowToken = msg.JWTToken
 	c.yellowSessionKey = msg.SessionKey
 	c.yellowAddress = session.Address
+	c.subscribe(userTopic(session.Address))
 
 	log.Printf("✓ Yellow auth successful for address: %s", c.yellowAddress)
 
@@ -216,6 +539,5 @@ func (c *Client) handleYellowAuth(msg *yellow.YellowAuthMessage) {
 			"expires_at":  session.ExpiresAt.Unix(),
 		},
 	}
-	data, _ := json.Marshal(successMsg)
-	c.send <- data
+	c.sendMessage(successMsg)
 }
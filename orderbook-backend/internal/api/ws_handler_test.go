@@ -0,0 +1,377 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/engine"
+
+	"github.com/gorilla/websocket"
+)
+
+// drain takes every message queued for a client until want trades have
+// arrived, returning the messages' types and trade sequence in order
+func drain(t *testing.T, q *sendQueue, want int) (types []string, trades []int) {
+	t.Helper()
+	deadline := time.After(5 * time.Second)
+	for len(trades) < want {
+		select {
+		case <-q.ready:
+		case <-deadline:
+			t.Fatalf("got %d of %d trades", len(trades), want)
+		}
+		batch, _ := q.take()
+		for _, data := range batch {
+			var msg struct {
+				Type string `json:"type"`
+				Data int    `json:"data"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatal(err)
+			}
+			types = append(types, msg.Type)
+			if msg.Type == "trade" {
+				trades = append(trades, msg.Data)
+			}
+		}
+	}
+	return types, trades
+}
+
+func TestHubBurstKeepsEveryTrade(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	client := &Client{hub: hub, send: newSendQueue(maxClientBacklog), subscriptions: make(map[string]bool)}
+	hub.register <- client
+
+	// The client reads nothing while trades and snapshots pour in
+	const n = 2000
+	for i := 0; i < n; i++ {
+		hub.Broadcast(Message{Type: "trade", Data: i})
+		hub.BroadcastLatest("orderbook:m1", Message{Type: "orderbook", Data: i})
+	}
+
+	types, trades := drain(t, client.send, n)
+	for i, got := range trades {
+		if got != i {
+			t.Fatalf("trade %d arrived as %d: trades lost or reordered", i, got)
+		}
+	}
+	snapshots := 0
+	for _, typ := range types {
+		if typ == "orderbook" {
+			snapshots++
+		}
+	}
+	if snapshots == 0 || snapshots >= n {
+		t.Errorf("%d snapshots delivered, want fewer than %d but at least one", snapshots, n)
+	}
+
+	stats := hub.Stats()
+	if stats.Dropped != 0 || stats.SlowDisconnects != 0 {
+		t.Errorf("stats = %+v, want nothing dropped", stats)
+	}
+	if stats.Coalesced != uint64(n-snapshots) {
+		t.Errorf("coalesced = %d, want %d", stats.Coalesced, n-snapshots)
+	}
+}
+
+func TestHubSlowClient(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	client := &Client{hub: hub, send: newSendQueue(3), subscriptions: make(map[string]bool)}
+	hub.register <- client
+	waitFor := func(what string, cond func(HubStats) bool) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for !cond(hub.Stats()) {
+			if time.Now().After(deadline) {
+				t.Fatalf("timed out waiting for %s: %+v", what, hub.Stats())
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	// The client reads nothing: trades push the oldest snapshots out
+	for _, market := range []string{"m1", "m2", "m3"} {
+		hub.BroadcastLatest("orderbook:"+market, Message{Type: "orderbook"})
+	}
+	hub.Broadcast(Message{Type: "trade", Data: 1})
+	hub.Broadcast(Message{Type: "trade", Data: 2})
+	waitFor("two snapshots dropped", func(s HubStats) bool { return s.Resyncs == 2 })
+
+	types, trades := drain(t, client.send, 2)
+	if !reflect.DeepEqual(types, []string{"orderbook", "trade", "trade", "resync"}) || !reflect.DeepEqual(trades, []int{1, 2}) {
+		t.Fatalf("got %v with trades %v, want the last snapshot, both trades and a resync hint", types, trades)
+	}
+
+	// A backlog of trades alone can't be thinned, so the client is dropped
+	for i := 3; i <= 6; i++ {
+		hub.Broadcast(Message{Type: "trade", Data: i})
+	}
+	waitFor("the client to be disconnected", func(s HubStats) bool { return s.SlowDisconnects == 1 })
+	if hub.ClientCount() != 0 {
+		t.Fatalf("%d clients still registered", hub.ClientCount())
+	}
+	if _, closed := client.send.take(); !closed {
+		t.Fatal("slow client's queue is still open")
+	}
+}
+
+func TestPublishReachesOnlySubscribers(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Stop()
+
+	newClient := func(topics ...string) *Client {
+		c := &Client{hub: hub, send: newSendQueue(maxClientBacklog), subscriptions: make(map[string]bool)}
+		for _, topic := range topics {
+			c.subscribe(topic)
+		}
+		hub.register <- c
+		return c
+	}
+	m1, m2 := newClient(marketTopic("m1")), newClient(marketTopic("m2"))
+
+	// A broadcast trade at the end marks how far each client has read
+	hub.Publish(marketTopic("m1"), Message{Type: "trade", Data: 1})
+	hub.PublishLatest(marketTopic("m1"), "orderbook:m1", Message{Type: "orderbook", Data: 1})
+	hub.Broadcast(Message{Type: "trade", Data: 2})
+	if _, trades := drain(t, m1.send, 2); !reflect.DeepEqual(trades, []int{1, 2}) {
+		t.Fatalf("m1 subscriber got trades %v, want [1 2]", trades)
+	}
+	if types, trades := drain(t, m2.send, 1); !reflect.DeepEqual(trades, []int{2}) || len(types) != 1 {
+		t.Fatalf("m2 subscriber got %v with trades %v, want only the broadcast", types, trades)
+	}
+
+	m1.unsubscribe(marketTopic("m1"))
+	hub.Publish(marketTopic("m1"), Message{Type: "trade", Data: 3})
+	hub.Broadcast(Message{Type: "trade", Data: 4})
+	if types, trades := drain(t, m1.send, 1); !reflect.DeepEqual(trades, []int{4}) || len(types) != 1 {
+		t.Fatalf("unsubscribed client got %v with trades %v, want only the broadcast", types, trades)
+	}
+}
+
+func TestOrderUpdatesReachOnlyTheOwner(t *testing.T) {
+	ts := newTestServer(t)
+	ts.marketOrderbooks.SetGlobalOrderUpdateCallback(ts.publishOrderUpdate)
+	go ts.wsHub.Run()
+	defer ts.wsHub.Stop()
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 4); err != nil {
+		t.Fatal(err)
+	}
+
+	owner := &Client{hub: ts.wsHub, send: newSendQueue(maxClientBacklog), subscriptions: make(map[string]bool)}
+	owner.subscribe(userTopic(strings.ToUpper(alice))) // Topics ignore address case
+	ts.wsHub.register <- owner
+
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 5000, 10)
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 5000, 4)
+	ts.wsHub.Broadcast(Message{Type: "done"}) // Marks the end of the updates
+
+	var updates []engine.OrderUpdate
+	deadline := time.After(5 * time.Second)
+	for done := false; !done; {
+		select {
+		case <-owner.send.ready:
+		case <-deadline:
+			t.Fatalf("got %d updates before timing out", len(updates))
+		}
+		batch, _ := owner.send.take()
+		for _, data := range batch {
+			var msg struct {
+				Type string             `json:"type"`
+				Data engine.OrderUpdate `json:"data"`
+			}
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Fatal(err)
+			}
+			switch msg.Type {
+			case "order_update":
+				updates = append(updates, msg.Data)
+			case "done":
+				done = true
+			}
+		}
+	}
+	if len(updates) != 2 {
+		t.Fatalf("updates = %+v, want alice's open and partial, none of bob's", updates)
+	}
+	if last := updates[1]; last.UserID != alice || last.Status != engine.StatusPartial || last.FilledQty != 4 || last.RemainingQty != 6 {
+		t.Fatalf("update after the fill = %+v, want partial with 4 filled and 6 left", last)
+	}
+}
+
+func TestSendQueueFull(t *testing.T) {
+	q := newSendQueue(2)
+	if r := q.push([]byte(`{"type":"orderbook"}`), "book"); r != pushQueued {
+		t.Fatalf("snapshot: %v, want queued", r)
+	}
+	if r := q.push([]byte(`{"type":"trade"}`), ""); r != pushQueued {
+		t.Fatalf("trade: %v, want queued", r)
+	}
+	// A trade into a full queue evicts the snapshot rather than being lost
+	if r := q.push([]byte(`{"type":"trade"}`), ""); r != pushEvicted {
+		t.Fatalf("trade into a full queue: %v, want evicted", r)
+	}
+	// With only trades left there is nothing to give up
+	if r := q.push([]byte(`{"type":"trade"}`), ""); r != pushRefused {
+		t.Fatalf("trade into a queue of trades: %v, want refused", r)
+	}
+
+	batch, _ := q.take()
+	var types []string
+	for _, data := range batch {
+		var msg struct {
+			Seq  uint64 `json:"seq"`
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Seq != uint64(len(types)+1) {
+			t.Errorf("message %d has seq %d", len(types), msg.Seq)
+		}
+		types = append(types, msg.Type)
+	}
+	if len(types) != 3 || types[0] != "trade" || types[1] != "trade" || types[2] != "resync" {
+		t.Fatalf("batch = %v, want two trades and a resync hint", types)
+	}
+}
+
+// serveWS starts the test server's hub and serves it over HTTP, returning
+// the WebSocket URL
+func serveWS(t *testing.T, ts *testServer) string {
+	t.Helper()
+	go ts.wsHub.Run()
+	t.Cleanup(ts.wsHub.Stop)
+	srv := httptest.NewServer(ts.mux)
+	t.Cleanup(srv.Close)
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+}
+
+// dialWS connects a WebSocket client and reads past the welcome message
+func dialWS(t *testing.T, url string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if msg := readWS(t, conn); msg.Type != "connected" {
+		t.Fatalf("first message %q, want connected", msg.Type)
+	}
+	return conn
+}
+
+// wsMessage is a message read by a test WebSocket client
+type wsMessage struct {
+	Type string          `json:"type"`
+	Seq  uint64          `json:"seq"`
+	Data json.RawMessage `json:"data"`
+}
+
+// readWS reads the next message, failing the test if none arrives in time
+func readWS(t *testing.T, conn *websocket.Conn) wsMessage {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	var msg wsMessage
+	if err := conn.ReadJSON(&msg); err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestSubscribeSendsSnapshot(t *testing.T) {
+	ts := newTestServer(t)
+	ts.fund(t, alice, 100)
+	ts.fund(t, bob, 100)
+	mkt := ts.createMarket(t, alice)
+	if err := ts.positions.MintShares(bob, mkt.ID, 2); err != nil {
+		t.Fatal(err)
+	}
+	ts.placeOrder(t, bob, mkt.ID, "YES", "sell", 6000, 2)
+	ts.placeOrder(t, alice, mkt.ID, "YES", "buy", 6000, 1)
+	ts.placeOrder(t, alice, mkt.ID, "NO", "buy", 3000, 4)
+
+	conn := dialWS(t, serveWS(t, ts))
+	if err := conn.WriteJSON(ClientMessage{Type: "subscribe", MarketID: mkt.ID}); err != nil {
+		t.Fatal(err)
+	}
+	msg := readWS(t, conn)
+	if msg.Type != "snapshot" {
+		t.Fatalf("got %q before any new order, want snapshot", msg.Type)
+	}
+	var snap struct {
+		MarketID string `json:"market_id"`
+		YES, NO  struct {
+			Bids, Asks []engine.OrderLevel
+		}
+		Trades struct {
+			YES, NO []engine.Trade
+		}
+	}
+	if err := json.Unmarshal(msg.Data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	if snap.MarketID != mkt.ID || len(snap.YES.Asks) != 1 || len(snap.NO.Bids) != 1 || len(snap.Trades.YES) != 1 || len(snap.Trades.NO) != 0 {
+		t.Fatalf("snapshot = %s, want the YES ask, the NO bid and one YES trade", msg.Data)
+	}
+
+	if err := conn.WriteJSON(ClientMessage{Type: "subscribe", MarketID: "missing"}); err != nil {
+		t.Fatal(err)
+	}
+	if msg := readWS(t, conn); msg.Type != "error" {
+		t.Fatalf("subscribing to an unknown market: got %q, want error", msg.Type)
+	}
+}
+
+func TestSilentClientIsDisconnected(t *testing.T) {
+	ts := newTestServer(t)
+	ts.wsHub.pongWait, ts.wsHub.pingPeriod = 300*time.Millisecond, 100*time.Millisecond
+	url := serveWS(t, ts)
+
+	// Reading answers pings with pongs; the silent client never reads again
+	live, silent := dialWS(t, url), dialWS(t, url)
+	go func() {
+		for {
+			if _, _, err := live.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+	if n := ts.wsHub.ClientCount(); n != 2 {
+		t.Fatalf("%d clients registered, want 2", n)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for ts.wsHub.ClientCount() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients still registered, want the silent one dropped", ts.wsHub.ClientCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// The live client outlasts several pong windows
+	time.Sleep(3 * ts.wsHub.pongWait)
+	if n := ts.wsHub.ClientCount(); n != 1 {
+		t.Fatalf("%d clients registered, want the live one to stay", n)
+	}
+	silent.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		if _, _, err := silent.ReadMessage(); err != nil {
+			break // Pings until the server closed the connection
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package api
+
+import (
+	"net/http"
+
+	"orderbook-backend/internal/yellow"
+)
+
+// handleGetEIP712Domain handles GET /api/yellow/eip712-domain
+// It returns the exact domain and types the server signs the auth challenge with
+func (s *Server) handleGetEIP712Domain(w http.ResponseWriter, r *http.Request) {
+	domain := yellow.AuthDomain(yellow.AuthApplication)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"domain":       domain.Map(),
+		"primary_type": yellow.AuthPrimaryType,
+		"types":        yellow.AuthTypes,
+	})
+}
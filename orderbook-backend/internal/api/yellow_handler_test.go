@@ -0,0 +1,75 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/signer/core/apitypes"
+
+	"orderbook-backend/internal/yellow"
+)
+
+func TestEIP712DomainMatchesAuthSigning(t *testing.T) {
+	ts := newTestServer(t)
+	rec := ts.do(t, "GET", "/api/v1/yellow/eip712-domain", "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, body %s", rec.Code, rec.Body)
+	}
+	var served struct {
+		Domain      apitypes.TypedDataDomain `json:"domain"`
+		PrimaryType string                   `json:"primary_type"`
+		Types       apitypes.Types           `json:"types"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&served); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(served.Types, yellow.AuthTypes) {
+		t.Fatalf("types = %+v, want the signer's %+v", served.Types, yellow.AuthTypes)
+	}
+
+	// Hash a challenge the way a client would from the served definitions
+	params := yellow.AuthRequestParams{
+		Address:     alice,
+		SessionKey:  bob,
+		Allowances:  []yellow.AuthAllowance{{Asset: "usdc", Amount: "100"}},
+		ExpiresAt:   1700000000,
+		Scope:       yellow.AuthScope,
+		Application: yellow.AuthApplication,
+	}
+	typedData := apitypes.TypedData{
+		Types:       served.Types,
+		PrimaryType: served.PrimaryType,
+		Domain:      served.Domain,
+		Message: apitypes.TypedDataMessage{
+			"address":           params.Address,
+			"session_key":       params.SessionKey,
+			"challenge_message": "challenge",
+			"allowances":        []map[string]interface{}{{"asset": "usdc", "amount": "100"}},
+			"expires_at":        fmt.Sprintf("%d", params.ExpiresAt),
+			"scope":             params.Scope,
+			"application":       params.Application,
+		},
+	}
+	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
+	if err != nil {
+		t.Fatal(err)
+	}
+	messageHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientDigest := crypto.Keccak256([]byte("\x19\x01"), domainSeparator, messageHash)
+
+	signed, err := yellow.AuthTypedDataHash("challenge", params, yellow.AuthApplication)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(clientDigest, signed) {
+		t.Fatalf("digest from the served domain %x, signer hashes %x", clientDigest, signed)
+	}
+}
@@ -3,30 +3,212 @@ package config
 import (
 	"os"
 	"strconv"
+	"time"
 )
 
 // Config holds all configuration for the orderbook backend
 type Config struct {
 	// Server settings
 	ServerPort string
+	AdminToken string // Bearer token for admin-only endpoints (empty = admin endpoints disabled)
+	APIVersion string // Routes are mounted under /api/<version>
+
+	// Logging
+	LogFormat string // "json" or "text"
+	LogLevel  string // "debug", "info", "warn" or "error"
 
 	// Yellow Network settings
-	YellowNodeURL   string
-	PrivateKey      string
-	AdjudicatorAddr string
+	YellowNodeURL    string
+	PrivateKey       string
+	AdjudicatorAddr  string
+	AuthSignMode     string // "eip712" or "personal_sign", depending on the ClearNode version
+	YellowTrace      bool   // Log every JSON-RPC frame (credentials redacted)
+	YellowReconnect  bool   // Re-dial and re-authenticate after a dropped connection
+	SessionTrigger   string // Open a market's app session on "first_trade" or "market_creation"
+	ChainRPCURL      string // Ethereum JSON-RPC endpoint for on-chain disputes (empty = disabled)
+	ChainID          int    // Chain the adjudicator is deployed on; part of every state signature
+	JWTPublicKeyFile string // PEM public key the ClearNode signs user JWTs with (empty = JWTs rejected)
+
+	// ClearNode keepalive: ping interval (0 = off) and failures before reconnecting
+	YellowPingIntervalSec int
+	YellowPingFailures    int
+
+	// Cooperative close policy
+	SessionCloseTimeoutSec int
+	SessionCloseRetries    int
+
+	// Shutdown handling of open sessions
+	ShutdownTimeoutSec    int    // Deadline for final state pushes and closes
+	ShutdownCloseSessions bool   // Cooperatively close sessions (otherwise checkpoint them)
+	CheckpointDir         string // Where unclosed sessions' latest signed state is saved
 
 	// Trading settings
 	DefaultToken string
+	FeeFreeHours int // Fee-free window for new markets (0 = disabled)
+	MaxOutcomes  int // Upper bound on outcomes per market (each one gets an orderbook)
+	MarketIDSeed int // Seed for reproducible market IDs in dev (0 = random UUIDs)
+	DrainMinutes int // Drain markets this long before ResolvesAt (0 = never)
+
+	// Trading fees in basis points of each trade's notional
+	MakerFeeBps  int    // Charged to the resting order's owner
+	TakerFeeBps  int    // Charged to the incoming order's owner
+	FeeAccountID string // Account credited with collected fees
+
+	// Markets still unresolved with no open interest after ResolvesAt + grace
+	EmptyMarketPolicy       string // "keep", "void" or "flag"
+	EmptyMarketGraceMinutes int
+
+	// How long a proposed resolution can be disputed before it pays out (0 = final at once)
+	DisputeWindow time.Duration
+
+	// Oracle that resolves locked markets automatically ("{id}" = market ID, empty = off)
+	OracleURL        string
+	OracleTimeoutSec int
+
+	OpeningAuctionSec int // Opening auction length for new markets (0 = match immediately)
+	MinMakerSpread    int // Minimum gap between a user's own bid and ask in bps (0 = off)
+
+	// Order granularity
+	TickSize    int    // Limit prices must be a multiple of this many bps (1 = any)
+	MinOrderQty string // Smallest order quantity in shares, e.g. "0.5" ("0" = any)
+
+	MaxDepth int // Price levels per side in published orderbooks (0 = all)
+
+	// "allow", "cancel_resting", "cancel_incoming" or "skip"
+	SelfTradePrevention string
+
+	// How often expired good-til-date orders are swept off the books
+	OrderExpirySweepSec int
+
+	// Same-price fill allocation for new markets
+	AllocationMode string // "fifo" or "pro_rata"
+	ProRataMinQty  int    // Smallest pro-rata share; smaller shares go to the remainder
+
+	// Deposits/withdrawals must reconcile with state channel allocations
+	ChannelBackedBalances bool
+
+	// Testnet faucet (never enable in production)
+	FaucetEnabled bool
+	FaucetAmount  int // USDC credited per grant
+
+	// Per-IP and per-user request rate limit (0 = off)
+	RateLimitRPS   float64 // Sustained requests per second
+	RateLimitBurst int     // Requests allowed at once
+
+	// Units per share; 100 allows quantities like 0.01 shares
+	QuantityScale int
+
+	// Per-user limits, overridable per market (0 = unlimited)
+	PositionLimit      int // Max net shares (|YES - NO|) per user per market
+	DailyNotionalLimit int // Max USDC traded per user per market per UTC day
+
+	// House liquidity settings
+	HouseUserID   string // Account that owns house quotes; fund it via /api/deposit
+	AMMLiquidity  int    // Default LMSR liquidity for new markets in shares (0 = no AMM)
+	AMMQuoteSize  int    // Shares per house quote
+	AMMHalfSpread int    // House quote distance from the curve price in bps
+
+	// Trade persistence settings
+	TradeStoreDir     string // Directory for the durable trade tape (empty = disabled)
+	TradeStoreMaxSize int    // Rotate trade files after this many MB
+
+	// State snapshot settings
+	StateFile string // Markets, orders and positions are restored from and saved to this file (empty = disabled)
+
+	// Write-ahead journal settings
+	JournalFile  string // Every order change and position mutation is appended here (empty = disabled)
+	JournalFsync bool   // Flush each journal entry to disk before continuing
 }
 
 // Load reads configuration from environment variables
 func Load() *Config {
 	return &Config{
-		ServerPort:      getEnv("SERVER_PORT", "8080"),
-		YellowNodeURL:   getEnv("YELLOW_NODE_URL", "wss://clearnet.yellow.com/ws"),
-		PrivateKey:      getEnv("PRIVATE_KEY", ""),
-		AdjudicatorAddr: getEnv("ADJUDICATOR_ADDR", "0x33eA68432d7657CA49Db36f378A95c6c71d3BDF1"),
-		DefaultToken:    getEnv("DEFAULT_TOKEN", "0x0000000000000000000000000000000000000000"),
+		ServerPort: getEnv("SERVER_PORT", "8080"),
+		AdminToken: getEnv("ADMIN_TOKEN", ""),
+		APIVersion: getEnv("API_VERSION", "v1"),
+
+		LogFormat: getEnv("LOG_FORMAT", "json"),
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+
+		YellowNodeURL:    getEnv("YELLOW_NODE_URL", "wss://clearnet.yellow.com/ws"),
+		PrivateKey:       getEnv("PRIVATE_KEY", ""),
+		AdjudicatorAddr:  getEnv("ADJUDICATOR_ADDR", "0x33eA68432d7657CA49Db36f378A95c6c71d3BDF1"),
+		AuthSignMode:     getEnv("YELLOW_AUTH_SIGN_MODE", "eip712"),
+		YellowTrace:      getEnvBool("YELLOW_TRACE", false),
+		YellowReconnect:  getEnvBool("YELLOW_RECONNECT", true),
+		SessionTrigger:   getEnv("YELLOW_SESSION_TRIGGER", "first_trade"),
+		ChainRPCURL:      getEnv("CHAIN_RPC_URL", ""),
+		ChainID:          getEnvInt("CHAIN_ID", 11155111),
+		JWTPublicKeyFile: getEnv("JWT_PUBLIC_KEY_FILE", ""),
+
+		YellowPingIntervalSec: getEnvInt("YELLOW_PING_INTERVAL_SEC", 30),
+		YellowPingFailures:    getEnvInt("YELLOW_PING_FAILURES", 3),
+
+		SessionCloseTimeoutSec: getEnvInt("SESSION_CLOSE_TIMEOUT_SEC", 10),
+		SessionCloseRetries:    getEnvInt("SESSION_CLOSE_RETRIES", 2),
+
+		ShutdownTimeoutSec:    getEnvInt("SHUTDOWN_TIMEOUT_SEC", 15),
+		ShutdownCloseSessions: getEnvBool("SHUTDOWN_CLOSE_SESSIONS", false),
+		CheckpointDir:         getEnv("CHECKPOINT_DIR", "checkpoints"),
+
+		DefaultToken: getEnv("DEFAULT_TOKEN", "0x0000000000000000000000000000000000000000"),
+		FeeFreeHours: getEnvInt("FEE_FREE_HOURS", 0),
+		MaxOutcomes:  getEnvInt("MAX_OUTCOMES", 16),
+		MarketIDSeed: getEnvInt("MARKET_ID_SEED", 0),
+		DrainMinutes: getEnvInt("DRAIN_MINUTES", 0),
+
+		MakerFeeBps:  getEnvInt("MAKER_FEE_BPS", 0),
+		TakerFeeBps:  getEnvInt("TAKER_FEE_BPS", 0),
+		FeeAccountID: getEnv("FEE_ACCOUNT_ID", "fees"),
+
+		EmptyMarketPolicy:       getEnv("EMPTY_MARKET_POLICY", "keep"),
+		EmptyMarketGraceMinutes: getEnvInt("EMPTY_MARKET_GRACE_MINUTES", 60),
+
+		DisputeWindow: getEnvDuration("DISPUTE_WINDOW", time.Duration(getEnvInt("DISPUTE_WINDOW_MINUTES", 0))*time.Minute),
+
+		OracleURL:        getEnv("ORACLE_URL", ""),
+		OracleTimeoutSec: getEnvInt("ORACLE_TIMEOUT_SEC", 10),
+
+		OpeningAuctionSec: getEnvInt("OPENING_AUCTION_SEC", 0),
+		MinMakerSpread:    getEnvInt("MIN_MAKER_SPREAD", 0),
+
+		TickSize:    getEnvInt("TICK_SIZE", 1),
+		MinOrderQty: getEnv("MIN_ORDER_QTY", "0"),
+
+		MaxDepth: getEnvInt("MAX_DEPTH", 0),
+
+		SelfTradePrevention: getEnv("SELF_TRADE_PREVENTION", "allow"),
+
+		OrderExpirySweepSec: getEnvInt("ORDER_EXPIRY_SWEEP_SEC", 1),
+
+		AllocationMode: getEnv("ALLOCATION_MODE", "fifo"),
+		ProRataMinQty:  getEnvInt("PRO_RATA_MIN_QTY", 1),
+
+		ChannelBackedBalances: getEnvBool("CHANNEL_BACKED_BALANCES", false),
+
+		FaucetEnabled: getEnvBool("FAUCET_ENABLED", false),
+		FaucetAmount:  getEnvInt("FAUCET_AMOUNT", 100),
+
+		RateLimitRPS:   getEnvFloat("RATE_LIMIT_RPS", 0),
+		RateLimitBurst: getEnvInt("RATE_LIMIT_BURST", 20),
+
+		QuantityScale: getEnvInt("QUANTITY_SCALE", 1),
+
+		PositionLimit:      getEnvInt("POSITION_LIMIT", 0),
+		DailyNotionalLimit: getEnvInt("DAILY_NOTIONAL_LIMIT", 0),
+
+		HouseUserID:   getEnv("HOUSE_USER_ID", "house"),
+		AMMLiquidity:  getEnvInt("AMM_LIQUIDITY", 0),
+		AMMQuoteSize:  getEnvInt("AMM_QUOTE_SIZE", 100),
+		AMMHalfSpread: getEnvInt("AMM_HALF_SPREAD", 100),
+
+		TradeStoreDir:     getEnv("TRADE_STORE_DIR", ""),
+		TradeStoreMaxSize: getEnvInt("TRADE_STORE_MAX_MB", 64),
+
+		StateFile: getEnv("STATE_FILE", ""),
+
+		JournalFile:  getEnv("JOURNAL_FILE", ""),
+		JournalFsync: getEnvBool("JOURNAL_FSYNC", false),
 	}
 }
 
@@ -45,3 +227,30 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,83 @@
+package config
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	if err := Load().Validate(); err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		mutate func(c *Config)
+		want   string
+	}{
+		{"non-numeric port", func(c *Config) { c.ServerPort = "http" }, "SERVER_PORT"},
+		{"port out of range", func(c *Config) { c.ServerPort = "70000" }, "SERVER_PORT"},
+		{"http node URL", func(c *Config) { c.YellowNodeURL = "https://clearnet.yellow.com/ws" }, "YELLOW_NODE_URL"},
+		{"node URL without host", func(c *Config) { c.YellowNodeURL = "wss://" }, "YELLOW_NODE_URL"},
+		{"short adjudicator", func(c *Config) { c.AdjudicatorAddr = "0x1234" }, "ADJUDICATOR_ADDR"},
+		{"unprefixed adjudicator", func(c *Config) {
+			c.AdjudicatorAddr = "33eA68432d7657CA49Db36f378A95c6c71d3BDF1"
+		}, "ADJUDICATOR_ADDR"},
+		{"non-hex token", func(c *Config) { c.DefaultToken = "0xZZ00000000000000000000000000000000000000" }, "DEFAULT_TOKEN"},
+		{"zero chain ID", func(c *Config) { c.ChainID = 0 }, "CHAIN_ID"},
+		{"negative maker fee", func(c *Config) { c.MakerFeeBps = -1 }, "MAKER_FEE_BPS"},
+		{"taker fee over 100%", func(c *Config) { c.TakerFeeBps = 10001 }, "TAKER_FEE_BPS"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			c := Load()
+			tc.mutate(c)
+			err := c.Validate()
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("err = %v, want one naming %s", err, tc.want)
+			}
+		})
+	}
+
+	c := Load()
+	c.ServerPort, c.ChainID = "", 0
+	if err := c.Validate(); err == nil || !strings.Contains(err.Error(), "SERVER_PORT") || !strings.Contains(err.Error(), "CHAIN_ID") {
+		t.Fatalf("err = %v, want both problems reported", err)
+	}
+}
+
+func TestLoadTypedSettings(t *testing.T) {
+	for _, key := range []string{"MAKER_FEE_BPS", "TAKER_FEE_BPS", "TICK_SIZE", "DISPUTE_WINDOW", "DISPUTE_WINDOW_MINUTES", "MAX_DEPTH"} {
+		t.Setenv(key, "")
+	}
+	c := Load()
+	if c.MakerFeeBps != 0 || c.TakerFeeBps != 0 || c.TickSize != 1 || c.DisputeWindow != 0 || c.MaxDepth != 0 {
+		t.Fatalf("defaults: maker %d taker %d tick %d dispute %v depth %d", c.MakerFeeBps, c.TakerFeeBps, c.TickSize, c.DisputeWindow, c.MaxDepth)
+	}
+
+	t.Setenv("MAKER_FEE_BPS", "5")
+	t.Setenv("TAKER_FEE_BPS", "20")
+	t.Setenv("TICK_SIZE", "100")
+	t.Setenv("DISPUTE_WINDOW", "90m")
+	t.Setenv("MAX_DEPTH", "25")
+	c = Load()
+	if c.MakerFeeBps != 5 || c.TakerFeeBps != 20 || c.TickSize != 100 || c.DisputeWindow != 90*time.Minute || c.MaxDepth != 25 {
+		t.Fatalf("overrides: maker %d taker %d tick %d dispute %v depth %d", c.MakerFeeBps, c.TakerFeeBps, c.TickSize, c.DisputeWindow, c.MaxDepth)
+	}
+
+	// Unparseable values fall back to the defaults
+	t.Setenv("TAKER_FEE_BPS", "twenty")
+	t.Setenv("TICK_SIZE", "1.5")
+	t.Setenv("DISPUTE_WINDOW", "90")
+	c = Load()
+	if c.TakerFeeBps != 0 || c.TickSize != 1 || c.DisputeWindow != 0 {
+		t.Fatalf("invalid values: taker %d tick %d dispute %v, want the defaults", c.TakerFeeBps, c.TickSize, c.DisputeWindow)
+	}
+
+	// The older minutes setting still applies when DISPUTE_WINDOW is unset
+	t.Setenv("DISPUTE_WINDOW", "")
+	t.Setenv("DISPUTE_WINDOW_MINUTES", "30")
+	if c := Load(); c.DisputeWindow != 30*time.Minute {
+		t.Fatalf("DISPUTE_WINDOW_MINUTES=30: dispute window %v", c.DisputeWindow)
+	}
+}
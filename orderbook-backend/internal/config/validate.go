@@ -0,0 +1,48 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// Validate checks settings that would otherwise only fail once they are
+// first used. It reports every problem found, not just the first.
+func (c *Config) Validate() error {
+	var errs []error
+
+	if port, err := strconv.Atoi(c.ServerPort); err != nil || port < 1 || port > 65535 {
+		errs = append(errs, fmt.Errorf("SERVER_PORT %q is not a port number", c.ServerPort))
+	}
+
+	if u, err := url.Parse(c.YellowNodeURL); err != nil || (u.Scheme != "ws" && u.Scheme != "wss") || u.Host == "" {
+		errs = append(errs, fmt.Errorf("YELLOW_NODE_URL %q must be a ws:// or wss:// URL", c.YellowNodeURL))
+	}
+	if !isHexAddress(c.AdjudicatorAddr) {
+		errs = append(errs, fmt.Errorf("ADJUDICATOR_ADDR %q is not a hex Ethereum address", c.AdjudicatorAddr))
+	}
+	if c.ChainID < 1 {
+		errs = append(errs, fmt.Errorf("CHAIN_ID %d must be positive", c.ChainID))
+	}
+	if !isHexAddress(c.DefaultToken) {
+		errs = append(errs, fmt.Errorf("DEFAULT_TOKEN %q is not a hex Ethereum address", c.DefaultToken))
+	}
+
+	if c.MakerFeeBps < 0 || c.MakerFeeBps > 10000 {
+		errs = append(errs, fmt.Errorf("MAKER_FEE_BPS %d must be between 0 and 10000", c.MakerFeeBps))
+	}
+	if c.TakerFeeBps < 0 || c.TakerFeeBps > 10000 {
+		errs = append(errs, fmt.Errorf("TAKER_FEE_BPS %d must be between 0 and 10000", c.TakerFeeBps))
+	}
+
+	return errors.Join(errs...)
+}
+
+// isHexAddress reports whether s is a 0x-prefixed 20-byte hex address
+func isHexAddress(s string) bool {
+	return strings.HasPrefix(strings.ToLower(s), "0x") && common.IsHexAddress(s)
+}
@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+var ErrAmendBelowFilled = errors.New("amended quantity must be greater than the filled quantity")
+
+// AmendOrder changes a resting order's price and total quantity. A pure
+// quantity decrease at the same price is applied in place and keeps the
+// order's time priority. Any other change takes the order off the book and
+// re-inserts it with a fresh sequence number, matching first if the new
+// price crosses, like a new order would. It returns a copy of the order as
+// amended, including any fills.
+func (ob *Orderbook) AmendOrder(orderID string, newPrice, newQty uint64) (*Order, []*Trade, error) {
+	if newPrice > 10000 {
+		return nil, nil, ErrInvalidPrice
+	}
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	order, exists := ob.orders[orderID]
+	if !exists {
+		return nil, nil, ErrOrderNotFound
+	}
+	if newQty <= order.FilledQty {
+		return nil, nil, ErrAmendBelowFilled
+	}
+
+	// Same price, no more size: priority is kept
+	if newPrice == order.Price && newQty <= order.Quantity {
+		if newQty < order.Quantity {
+			order.Quantity = newQty
+			ob.emitOrderEvent(OrderModified, order)
+		}
+		copied := *order
+		return &copied, nil, nil
+	}
+
+	amended := *order
+	amended.Price = newPrice
+	amended.Quantity = newQty
+	if err := ob.checkOrderSize(&amended); err != nil {
+		return nil, nil, err
+	}
+	if err := ob.checkOwnSpread(&amended); err != nil {
+		return nil, nil, err
+	}
+	if order.PostOnly && ob.matchableQty(&amended) > 0 {
+		return nil, nil, ErrWouldCross
+	}
+
+	if order.IsBuy() {
+		ob.bids.remove(order)
+	} else {
+		ob.asks.remove(order)
+	}
+	delete(ob.orders, order.ID)
+	ob.emitOrderEvent(OrderRemoved, order)
+
+	order.Price = newPrice
+	order.Quantity = newQty
+	order.Timestamp = time.Now()
+	order.SequenceNum = atomic.AddUint64(&orderSequence, 1)
+
+	var trades []*Trade
+	if !ob.inAuction {
+		if order.IsBuy() {
+			trades = ob.matchBuy(order)
+		} else {
+			trades = ob.matchSell(order)
+		}
+	}
+	if order.RemainingQty() > 0 && order.Status != StatusCancelled {
+		ob.rest(order)
+	}
+	ob.emitOrderUpdate(order)
+
+	ob.notifyTrades(trades)
+
+	copied := *order
+	return &copied, trades, nil
+}
@@ -0,0 +1,82 @@
+package engine
+
+import "testing"
+
+func TestAmendOrderPriority(t *testing.T) {
+	tests := []struct {
+		name         string
+		price, qty   uint64
+		keepPriority bool
+	}{
+		{"smaller size at the same price", 6000, 6, true},
+		{"same size and price", 6000, 10, true},
+		{"larger size", 6000, 12, false},
+		{"new price", 6100, 10, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			first, _ := place(t, ob, "alice", SideSell, 6000, 10)
+			place(t, ob, "bob", SideSell, tt.price, 10) // Already waiting at the amended price
+			seq := first.SequenceNum
+
+			if _, trades, err := ob.AmendOrder(first.ID, tt.price, tt.qty); err != nil || len(trades) != 0 {
+				t.Fatalf("amend: trades %+v, err %v", trades, err)
+			}
+			if first.Price != tt.price || first.Quantity != tt.qty {
+				t.Fatalf("order is %d@%d, want %d@%d", first.Quantity, first.Price, tt.qty, tt.price)
+			}
+			if (first.SequenceNum == seq) != tt.keepPriority {
+				t.Fatalf("sequence %d -> %d, keep priority = %v", seq, first.SequenceNum, tt.keepPriority)
+			}
+
+			// The first fill at the amended price shows who is ahead
+			_, trades := place(t, ob, "carol", SideBuy, tt.price, 1)
+			wantSeller := "bob"
+			if tt.keepPriority {
+				wantSeller = "alice"
+			}
+			if len(trades) != 1 || trades[0].SellerID != wantSeller {
+				t.Fatalf("trades = %+v, want a fill against %s", trades, wantSeller)
+			}
+		})
+	}
+}
+
+func TestAmendOrderRejections(t *testing.T) {
+	ob := NewOrderbook()
+	order, _ := place(t, ob, "alice", SideSell, 6000, 10)
+	place(t, ob, "bob", SideBuy, 6000, 4)
+
+	for _, qty := range []uint64{3, 4} {
+		if _, _, err := ob.AmendOrder(order.ID, 6000, qty); err != ErrAmendBelowFilled {
+			t.Errorf("amend to %d with 4 filled: err = %v, want %v", qty, err, ErrAmendBelowFilled)
+		}
+	}
+	if _, _, err := ob.AmendOrder(order.ID, 10001, 10); err != ErrInvalidPrice {
+		t.Errorf("price 10001: err = %v, want %v", err, ErrInvalidPrice)
+	}
+	if _, _, err := ob.AmendOrder("missing", 6000, 10); err != ErrOrderNotFound {
+		t.Errorf("unknown order: err = %v, want %v", err, ErrOrderNotFound)
+	}
+	if order.Price != 6000 || order.Quantity != 10 {
+		t.Fatalf("rejected amends changed the order to %d@%d", order.Quantity, order.Price)
+	}
+}
+
+func TestAmendOrderThatCrossesMatches(t *testing.T) {
+	ob := NewOrderbook()
+	ask, _ := place(t, ob, "alice", SideSell, 6500, 10)
+	place(t, ob, "bob", SideBuy, 6000, 4)
+
+	_, trades, err := ob.AmendOrder(ask.ID, 6000, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].Quantity != 4 || trades[0].Price != 6000 {
+		t.Fatalf("trades = %+v, want 4 at 6000", trades)
+	}
+	if ask.RemainingQty() != 6 {
+		t.Fatalf("remaining %d, want 6 resting", ask.RemainingQty())
+	}
+}
@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"math"
+	"sync"
+)
+
+// AMMConfig configures a house market maker
+type AMMConfig struct {
+	UserID     string  // House account that owns the quotes and inventory
+	Liquidity  float64 // LMSR liquidity parameter b (higher = prices move less per share)
+	QuoteSize  uint64  // Shares quoted on each side of each book
+	HalfSpread uint64  // Distance from the curve price to each quote in bps
+}
+
+// AMM is a house liquidity provider that quotes both outcome books of a
+// binary market around the price implied by a logarithmic market scoring
+// rule (LMSR). Fills against its quotes move its inventory, and therefore
+// its price, along the curve.
+type AMM struct {
+	mu        sync.Mutex
+	cfg       AMMConfig
+	marketID  string
+	books     *OutcomeOrderbooks
+	positions *PositionManager
+
+	// Net shares the AMM has sold per outcome (negative = net bought)
+	soldYes float64
+	soldNo  float64
+
+	resting map[OutcomeID][]*Order
+}
+
+// NewAMM creates a house market maker for a market. It places no orders
+// until Refresh is called.
+func NewAMM(marketID string, cfg AMMConfig, books *OutcomeOrderbooks, positions *PositionManager) *AMM {
+	return &AMM{
+		cfg:       cfg,
+		marketID:  marketID,
+		books:     books,
+		positions: positions,
+		resting:   make(map[OutcomeID][]*Order),
+	}
+}
+
+// Price returns the curve's current YES price in basis points. The NO price
+// is its complement.
+func (a *AMM) Price() uint64 {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.price()
+}
+
+// price computes the LMSR YES price (must hold lock)
+func (a *AMM) price() uint64 {
+	// Softmax of inventory; subtracting the max keeps exp from overflowing
+	yes, no := a.soldYes/a.cfg.Liquidity, a.soldNo/a.cfg.Liquidity
+	m := math.Max(yes, no)
+	ey, en := math.Exp(yes-m), math.Exp(no-m)
+	return uint64(math.Round(10000 * ey / (ey + en)))
+}
+
+// Observe updates the AMM's inventory from trades it took part in. It
+// returns true if any of the trades filled one of its quotes.
+func (a *AMM) Observe(trades []*Trade) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	filled := false
+	for _, trade := range trades {
+		if trade.MarketID != a.marketID {
+			continue
+		}
+		var delta float64
+		switch a.cfg.UserID {
+		case trade.SellerID:
+			delta = float64(trade.Quantity) / float64(QuantityScale())
+		case trade.BuyerID:
+			delta = -float64(trade.Quantity) / float64(QuantityScale())
+		default:
+			continue
+		}
+		if trade.OutcomeID == OutcomeYES {
+			a.soldYes += delta
+		} else {
+			a.soldNo += delta
+		}
+		filled = true
+	}
+	return filled
+}
+
+// Refresh cancels the AMM's resting quotes and requotes both books around
+// the current curve price. Any trades caused by the new quotes crossing
+// the book are returned for the caller to settle.
+func (a *AMM) Refresh() []*Trade {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	yes := a.price()
+	var trades []*Trade
+	trades = append(trades, a.requote(a.books.YES, OutcomeYES, yes)...)
+	trades = append(trades, a.requote(a.books.NO, OutcomeNO, 10000-yes)...)
+	return trades
+}
+
+// requote replaces the quotes on one outcome book (must hold lock)
+func (a *AMM) requote(ob *Orderbook, outcome OutcomeID, mid uint64) []*Trade {
+	for _, order := range a.resting[outcome] {
+		_ = ob.CancelOrder(order.ID) // Already filled orders are gone
+	}
+	a.resting[outcome] = nil
+
+	var trades []*Trade
+	tick := ob.TickSize()
+	if mid > a.cfg.HalfSpread {
+		if bid := roundToTick(mid-a.cfg.HalfSpread, tick, SideBuy); bid > 0 {
+			trades = append(trades, a.quote(ob, outcome, SideBuy, bid)...)
+		}
+	}
+	if mid+a.cfg.HalfSpread < 10000 {
+		if ask := roundToTick(mid+a.cfg.HalfSpread, tick, SideSell); ask < 10000 {
+			trades = append(trades, a.quote(ob, outcome, SideSell, ask)...)
+		}
+	}
+	return trades
+}
+
+// quote places one house order, minting shares to back an ask if needed.
+// Quotes the house can't fund are skipped (must hold lock).
+func (a *AMM) quote(ob *Orderbook, outcome OutcomeID, side Side, price uint64) []*Trade {
+	order := NewOrder(a.cfg.UserID, a.marketID, outcome, side, price, a.cfg.QuoteSize)
+
+	if side == SideSell {
+		pos := a.positions.GetPosition(a.cfg.UserID, a.marketID)
+		held := pos.YesShares
+		if outcome == OutcomeNO {
+			held = pos.NoShares
+		}
+		if held < a.cfg.QuoteSize {
+			if err := a.positions.MintShares(a.cfg.UserID, a.marketID, a.cfg.QuoteSize-held); err != nil {
+				return nil
+			}
+		}
+	}
+	if err := a.positions.ValidateOrder(order); err != nil {
+		return nil
+	}
+
+	trades, err := ob.PlaceOrder(order)
+	if err != nil {
+		return nil
+	}
+	if order.RemainingQty() > 0 {
+		a.resting[outcome] = append(a.resting[outcome], order)
+	}
+	return trades
+}
@@ -0,0 +1,56 @@
+package engine
+
+import "testing"
+
+func TestAMMFillMovesPrice(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	deposit(t, pm, "house", 1000)
+	deposit(t, pm, "trader", 100)
+	obs := NewMarketOrderbooks().GetOrCreate("m1")
+	amm := NewAMM("m1", AMMConfig{UserID: "house", Liquidity: 100, QuoteSize: 10, HalfSpread: 200}, obs, pm)
+
+	if trades := amm.Refresh(); len(trades) != 0 {
+		t.Fatalf("initial quotes traded: %+v", trades)
+	}
+	if amm.Price() != 5000 {
+		t.Fatalf("initial price = %d, want 5000", amm.Price())
+	}
+	ask, ok := obs.YES.BestAsk()
+	if !ok || ask.Price != 5200 || ask.Quantity != 10 {
+		t.Fatalf("YES ask = %+v, want 10 at 5200", ask)
+	}
+	if bid, ok := obs.NO.BestBid(); !ok || bid.Price != 4800 {
+		t.Fatalf("NO bid = %+v, want 4800", bid)
+	}
+
+	buy := NewOrder("trader", "m1", OutcomeYES, SideBuy, 5200, 5)
+	trades, err := obs.YES.PlaceOrder(buy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if buy.Status != StatusFilled || len(trades) != 1 || trades[0].SellerID != "house" {
+		t.Fatalf("buy %s, trades %+v: want filled by the house", buy.Status, trades)
+	}
+	for _, trade := range trades {
+		if err := pm.ExecuteTrade(trade); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if pos := pm.GetPosition("trader", "m1"); pos.YesShares != 5 {
+		t.Fatalf("trader holds %d YES, want 5", pos.YesShares)
+	}
+
+	if !amm.Observe(trades) {
+		t.Fatal("AMM did not notice its quote was filled")
+	}
+	amm.Refresh()
+	if amm.Price() <= 5000 {
+		t.Fatalf("price = %d after selling YES, want above 5000", amm.Price())
+	}
+	if ask, _ := obs.YES.BestAsk(); ask.Price <= 5200 {
+		t.Fatalf("requoted YES ask = %d, want above 5200", ask.Price)
+	}
+	if bid, _ := obs.NO.BestBid(); bid.Price >= 4800 {
+		t.Fatalf("requoted NO bid = %d, want below 4800", bid.Price)
+	}
+}
@@ -0,0 +1,132 @@
+package engine
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+// StartAuction puts the orderbook into an opening auction until the given
+// time. Orders placed during the auction rest without matching; at the end a
+// single uncross executes every crossable order at one clearing price.
+func (ob *Orderbook) StartAuction(until time.Time) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.auctionUntil = until
+	ob.inAuction = true
+}
+
+// InAuction returns whether the orderbook is collecting orders without matching
+func (ob *Orderbook) InAuction() bool {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.inAuction
+}
+
+// Uncross ends the auction if its window has passed and executes all
+// crossable orders at the clearing price. It is a no-op otherwise.
+func (ob *Orderbook) Uncross() []*Trade {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	trades := ob.uncrossIfDue()
+	ob.notifyTrades(trades)
+	return trades
+}
+
+// uncrossIfDue runs the uncross once the auction window has elapsed (must hold lock)
+func (ob *Orderbook) uncrossIfDue() []*Trade {
+	if !ob.inAuction || time.Now().Before(ob.auctionUntil) {
+		return nil
+	}
+	ob.inAuction = false
+
+	price, ok := ob.clearingPrice()
+	if !ok {
+		return nil
+	}
+
+	var trades []*Trade
+	for ob.bids.Len() > 0 && ob.asks.Len() > 0 {
+		bid, ask := ob.bids.Peek(), ob.asks.Peek()
+		if ob.dropIfDead(ob.bids, bid) || ob.dropIfDead(ob.asks, ask) {
+			continue
+		}
+		if bid.Price < price || ask.Price > price {
+			break
+		}
+
+		matchQty := min(bid.RemainingQty(), ask.RemainingQty())
+		if matchQty == 0 {
+			break // Never emit an empty trade
+		}
+		bid.Fill(matchQty)
+		ask.Fill(matchQty)
+		trades = append(trades, NewTrade(bid, ask, price, matchQty))
+
+		ob.settleAuctionFill(ob.bids, bid)
+		ob.settleAuctionFill(ob.asks, ask)
+	}
+
+	return trades
+}
+
+// settleAuctionFill removes a filled top-of-book order or reports its new size (must hold lock)
+func (ob *Orderbook) settleAuctionFill(h *orderHeap, order *Order) {
+	if order.RemainingQty() == 0 {
+		heap.Pop(h)
+		delete(ob.orders, order.ID)
+		ob.emitOrderEvent(OrderRemoved, order)
+		return
+	}
+	ob.emitOrderEvent(OrderModified, order)
+}
+
+// clearingPrice finds the price that maximizes executable volume. Ties are
+// broken by the smallest buy/sell imbalance, then by the lowest price
+// (must hold lock).
+func (ob *Orderbook) clearingPrice() (uint64, bool) {
+	candidates := make(map[uint64]bool)
+	for _, o := range ob.bids.orders {
+		candidates[o.Price] = true
+	}
+	for _, o := range ob.asks.orders {
+		candidates[o.Price] = true
+	}
+
+	prices := make([]uint64, 0, len(candidates))
+	for p := range candidates {
+		prices = append(prices, p)
+	}
+	sort.Slice(prices, func(i, j int) bool { return prices[i] < prices[j] })
+
+	var best uint64
+	var bestVolume, bestImbalance uint64
+	found := false
+	for _, p := range prices {
+		demand := liveQtyWhere(ob.bids, func(o *Order) bool { return o.Price >= p })
+		supply := liveQtyWhere(ob.asks, func(o *Order) bool { return o.Price <= p })
+		volume := min(demand, supply)
+		if volume == 0 {
+			continue
+		}
+		imbalance := max(demand, supply) - volume
+		if !found || volume > bestVolume || (volume == bestVolume && imbalance < bestImbalance) {
+			best, bestVolume, bestImbalance, found = p, volume, imbalance, true
+		}
+	}
+
+	return best, found
+}
+
+// liveQtyWhere sums the remaining quantity of live orders matching pred
+func liveQtyWhere(h *orderHeap, pred func(*Order) bool) uint64 {
+	var total uint64
+	for _, o := range h.orders {
+		if o.RemainingQty() == 0 || !pred(o) {
+			continue
+		}
+		total += o.RemainingQty()
+	}
+	return total
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAuctionUncrossesAtOneClearingPrice(t *testing.T) {
+	ob := NewOrderbook()
+	const window = 50 * time.Millisecond
+	ob.StartAuction(time.Now().Add(window))
+
+	for _, o := range []struct {
+		side       Side
+		price, qty uint64
+	}{
+		{SideBuy, 6000, 10},
+		{SideBuy, 5500, 10},
+		{SideBuy, 5000, 10},
+		{SideSell, 4800, 5},
+		{SideSell, 5200, 10},
+		{SideSell, 5800, 10},
+	} {
+		if _, trades := place(t, ob, "u", o.side, o.price, o.qty); len(trades) != 0 {
+			t.Fatalf("order matched during the auction: %+v", trades)
+		}
+	}
+	if !ob.InAuction() {
+		t.Fatal("book left the auction early")
+	}
+	if trades := ob.Uncross(); trades != nil {
+		t.Fatalf("uncrossed before the window ended: %+v", trades)
+	}
+
+	time.Sleep(window)
+	trades := ob.Uncross()
+	if ob.InAuction() {
+		t.Fatal("book still in auction after uncross")
+	}
+
+	// 5200 clears 15 shares, the same as 5500, with the same imbalance; the lower price wins
+	var qty uint64
+	for _, trade := range trades {
+		if trade.Price != 5200 {
+			t.Errorf("trade at %d, want clearing price 5200", trade.Price)
+		}
+		qty += trade.Quantity
+	}
+	if qty != 15 {
+		t.Fatalf("uncross executed %d shares, want 15", qty)
+	}
+
+	// What didn't cross stays on the book and trades normally from now on
+	snapshot := ob.GetSnapshot()
+	if bid, _ := ob.BestBid(); bid.Price != 5500 || bid.Quantity != 5 {
+		t.Fatalf("best bid = %+v, want 5 at 5500", bid)
+	}
+	if len(snapshot.Asks) != 1 || snapshot.Asks[0].Price != 5800 {
+		t.Fatalf("asks = %+v, want only 5800", snapshot.Asks)
+	}
+	if _, trades := place(t, ob, "u", SideSell, 5500, 5); len(trades) != 1 {
+		t.Fatalf("post-auction order: %d trades, want 1", len(trades))
+	}
+}
@@ -0,0 +1,58 @@
+package engine
+
+import "time"
+
+// Candle summarizes the trades in one time bucket
+type Candle struct {
+	Start  time.Time `json:"start"` // Bucket start, aligned to the interval
+	Open   uint64    `json:"open"`
+	High   uint64    `json:"high"`
+	Low    uint64    `json:"low"`
+	Close  uint64    `json:"close"`
+	Volume uint64    `json:"volume"` // Quantity traded
+	Trades int       `json:"trades"`
+}
+
+// Candles buckets the trades at or after since into intervals aligned to
+// the Unix epoch, oldest first. Buckets without trades are omitted rather
+// than filled with the previous close.
+func (h *TradeHistory) Candles(interval time.Duration, since time.Time) []Candle {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	candles := make([]Candle, 0)
+	if interval <= 0 {
+		return candles
+	}
+	for _, trade := range h.trades {
+		if !since.IsZero() && trade.Timestamp.Before(since) {
+			continue
+		}
+		start := trade.Timestamp.UTC().Truncate(interval)
+		if n := len(candles); n > 0 && candles[n-1].Start.Equal(start) {
+			c := &candles[n-1]
+			c.High = max(c.High, trade.Price)
+			c.Low = min(c.Low, trade.Price)
+			c.Close = trade.Price
+			c.Volume += trade.Quantity
+			c.Trades++
+			continue
+		}
+		candles = append(candles, Candle{
+			Start:  start,
+			Open:   trade.Price,
+			High:   trade.Price,
+			Low:    trade.Price,
+			Close:  trade.Price,
+			Volume: trade.Quantity,
+			Trades: 1,
+		})
+	}
+	return candles
+}
+
+// Candles returns OHLC candles from the book's trade history (see
+// TradeHistory.Candles)
+func (ob *Orderbook) Candles(interval time.Duration, since time.Time) []Candle {
+	return ob.history.Candles(interval, since)
+}
@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"container/heap"
+	"time"
+)
+
+// Complementary matching
+//
+// In a binary market a YES+NO pair always pays out exactly 1 USDC, so a
+// buy of YES at p and a buy of NO at q can both be filled whenever
+// p + q >= 10000: the two buyers fund a newly minted pair and each takes
+// their side. The resting order's price is honoured, so with a NO bid
+// resting at q an incoming YES buyer pays 10000-q (never more than p) and
+// the NO buyer pays q. For quantity n, using integer basis points:
+//
+//	YES buyer pays Notional(10000-q, n)
+//	NO buyer pays  Collateral(n) - Notional(10000-q, n)   (= Trade.MintCost)
+//
+// which adds up to the pair's collateral, Collateral(n), exactly. The trade
+// is recorded on the incoming order's book with Mint set, the incoming
+// buyer as BuyerID and the resting buyer as SellerID; PositionManager
+// mints the pair to the resting buyer and transfers the incoming buyer's
+// side to them like an ordinary trade.
+
+// linkComplements pairs the YES and NO books of a market. They share one
+// lock so that a match can update both books atomically.
+func linkComplements(yes, no *Orderbook) {
+	no.mu = yes.mu
+	yes.complement = no
+	no.complement = yes
+}
+
+// complementBid returns the best bid on the complement book if it can fill
+// an incoming buy at a better price than this book's best ask. Ties go to
+// the ask, which needs no minting (must hold lock).
+func (ob *Orderbook) complementBid(buy *Order) *Order {
+	c := ob.complement
+	if c == nil || c.inAuction {
+		return nil
+	}
+	resting := c.bids.Peek()
+	if resting == nil {
+		return nil
+	}
+
+	implied := 10000 - resting.Price
+	if !withinPrice(buy, implied) {
+		return nil
+	}
+	if bestAsk := ob.asks.Peek(); bestAsk != nil && bestAsk.Price <= implied {
+		return nil
+	}
+	return resting
+}
+
+// fillComplement matches an incoming buy with a resting buy of the other
+// outcome. It returns nil rather than an empty trade if either has nothing
+// left to fill (must hold lock).
+func (ob *Orderbook) fillComplement(buy, resting *Order) *Trade {
+	matchQty := min(buy.RemainingQty(), resting.RemainingQty())
+	if matchQty == 0 {
+		return nil
+	}
+	buy.Fill(matchQty)
+	resting.Fill(matchQty)
+
+	trade := NewTrade(buy, resting, 10000-resting.Price, matchQty)
+	trade.Mint = true
+
+	c := ob.complement
+	if resting.RemainingQty() == 0 {
+		heap.Pop(c.bids)
+		delete(c.orders, resting.ID)
+		c.emitOrderEvent(OrderRemoved, resting)
+	} else {
+		c.emitOrderEvent(OrderModified, resting)
+	}
+	return trade
+}
+
+// complementQty returns how much of an incoming buy the complement book could
+// fill right now (must hold lock)
+func (ob *Orderbook) complementQty(buy *Order) uint64 {
+	c := ob.complement
+	if c == nil || c.inAuction || !buy.IsBuy() {
+		return 0
+	}
+
+	now := time.Now()
+	var qty uint64
+	for _, resting := range c.bids.orders {
+		if ob.preventsSelfTrade(buy, resting) || resting.Expired(now) {
+			continue
+		}
+		if withinPrice(buy, 10000-resting.Price) {
+			qty += resting.RemainingQty()
+		}
+	}
+	return qty
+}
+
+// withinPrice reports whether a buy may pay the implied price of a mint. A
+// market buy has no limit of its own, but one priced at its sweep price (as
+// the API does to check the buyer's balance) pays no more than that.
+func withinPrice(buy *Order, implied uint64) bool {
+	if buy.IsMarket() && buy.Price == 0 {
+		return true
+	}
+	return implied <= buy.Price
+}
+
+// impliedAsks returns the complement book's bids as the asks they amount to
+// on this book, best first (must hold lock)
+func (ob *Orderbook) impliedAsks() []OrderLevel {
+	c := ob.complement
+	if c == nil || c.inAuction {
+		return nil
+	}
+	levels := c.aggregateLevels(c.bids, true)
+	for i := range levels {
+		levels[i].Price = 10000 - levels[i].Price
+	}
+	return levels
+}
@@ -0,0 +1,160 @@
+package engine
+
+import "testing"
+
+// placeOn places an order on a market's book and fails the test on error
+func placeOn(t *testing.T, books *MarketOrderbooks, order *Order) []*Trade {
+	t.Helper()
+	trades, err := books.GetOrderbook(order.MarketID, order.OutcomeID).PlaceOrder(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return trades
+}
+
+func TestComplementaryBidsMint(t *testing.T) {
+	tests := []struct {
+		name      string
+		resting   *Order
+		incoming  *Order
+		wantPrice uint64 // Recorded on the incoming order's book
+	}{
+		{"YES buyer takes a resting NO bid",
+			NewOrder("no", "m1", OutcomeNO, SideBuy, 4000, 10),
+			NewOrder("yes", "m1", OutcomeYES, SideBuy, 6000, 10),
+			6000},
+		{"NO buyer takes a resting YES bid",
+			NewOrder("yes", "m1", OutcomeYES, SideBuy, 6000, 10),
+			NewOrder("no", "m1", OutcomeNO, SideBuy, 4000, 10),
+			4000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			books := NewMarketOrderbooks()
+			pm, _ := newTestPositions(t)
+			deposit(t, pm, "yes", 10)
+			deposit(t, pm, "no", 10)
+
+			placeOn(t, books, tt.resting)
+			trades := placeOn(t, books, tt.incoming)
+			if len(trades) != 1 {
+				t.Fatalf("%d trades, want 1", len(trades))
+			}
+			trade := trades[0]
+			if !trade.Mint || trade.Price != tt.wantPrice || trade.Quantity != 10 ||
+				trade.BuyerID != tt.incoming.UserID || trade.SellerID != tt.resting.UserID {
+				t.Fatalf("trade = %+v, want a mint of 10 at %d", trade, tt.wantPrice)
+			}
+			if err := pm.ExecuteTrade(trade); err != nil {
+				t.Fatal(err)
+			}
+
+			// The YES buyer pays 6 USDC and the NO buyer 4: one pair each
+			yes, no := pm.GetPosition("yes", "m1"), pm.GetPosition("no", "m1")
+			if yes.YesShares != 10 || yes.NoShares != 0 || no.YesShares != 0 || no.NoShares != 10 {
+				t.Fatalf("YES buyer holds %d/%d, NO buyer %d/%d, want 10 YES and 10 NO", yes.YesShares, yes.NoShares, no.YesShares, no.NoShares)
+			}
+			if got := pm.GetBalance("yes"); got != 40000 {
+				t.Errorf("YES buyer balance = %d, want 40000", got)
+			}
+			if got := pm.GetBalance("no"); got != 60000 {
+				t.Errorf("NO buyer balance = %d, want 60000", got)
+			}
+			for _, ob := range []*Orderbook{books.GetOrderbook("m1", OutcomeYES), books.GetOrderbook("m1", OutcomeNO)} {
+				if snap := ob.GetSnapshot(); len(snap.Bids)+len(snap.Asks) != 0 {
+					t.Fatalf("book not empty after the mint: %+v", snap)
+				}
+			}
+		})
+	}
+}
+
+func TestComplementaryBidsThatDoNotCrossRest(t *testing.T) {
+	books := NewMarketOrderbooks()
+	placeOn(t, books, NewOrder("no", "m1", OutcomeNO, SideBuy, 3900, 10))
+	if trades := placeOn(t, books, NewOrder("yes", "m1", OutcomeYES, SideBuy, 6000, 10)); len(trades) != 0 {
+		t.Fatalf("6000 + 3900 < 10000 matched: %+v", trades)
+	}
+}
+
+func TestSweepPriceCountsComplementBids(t *testing.T) {
+	books := NewMarketOrderbooks()
+	placeOn(t, books, NewOrder("no", "m1", OutcomeNO, SideBuy, 4000, 5))
+	placeOn(t, books, NewOrder("no", "m1", OutcomeNO, SideBuy, 3000, 5))
+	yes := books.GetOrderbook("m1", OutcomeYES)
+
+	tests := []struct {
+		side Side
+		qty  uint64
+		want uint64
+	}{
+		{SideBuy, 5, 6000},
+		{SideBuy, 8, 7000},
+		{SideBuy, 50, 7000},
+		{SideSell, 5, 0}, // Sells only hit YES bids
+	}
+	for _, tt := range tests {
+		if got := yes.SweepPrice(tt.side, tt.qty); got != tt.want {
+			t.Errorf("%s %d: sweep price %d, want %d", tt.side, tt.qty, got, tt.want)
+		}
+	}
+
+	// Asks and implied asks are swept together, best first
+	placeOn(t, books, NewOrder("seller", "m1", OutcomeYES, SideSell, 6500, 5))
+	if got := yes.SweepPrice(SideBuy, 8); got != 6500 {
+		t.Errorf("with an ask at 6500: sweep price %d, want 6500", got)
+	}
+}
+
+func TestMarketBuyPaysNoMoreThanItsSweepPrice(t *testing.T) {
+	books := NewMarketOrderbooks()
+	placeOn(t, books, NewOrder("no", "m1", OutcomeNO, SideBuy, 3000, 10))
+
+	// Priced at a sweep of 6000, it must not mint at 7000
+	capped := NewMarketOrder("yes", "m1", OutcomeYES, SideBuy, 10)
+	capped.Price = 6000
+	if trades := placeOn(t, books, capped); len(trades) != 0 {
+		t.Fatalf("capped market buy filled: %+v", trades)
+	}
+
+	uncapped := NewMarketOrder("yes", "m1", OutcomeYES, SideBuy, 10)
+	if trades := placeOn(t, books, uncapped); len(trades) != 1 || trades[0].Price != 7000 {
+		t.Fatalf("unpriced market buy: trades %+v, want one mint at 7000", trades)
+	}
+}
+
+func TestExecuteTradeRejectsOverdraft(t *testing.T) {
+	tests := []struct {
+		name  string
+		trade *Trade
+		want  error
+	}{
+		{"buyer short of cost", sharesTrade("poor", "rich", 6000, 10, SideBuy), ErrInsufficientBalance},
+		{"minter short of their side", &Trade{ID: "t-mint", MarketID: "m1", OutcomeID: OutcomeYES, BuyerID: "rich", SellerID: "poor", Price: 6000, Quantity: 10, TakerSide: SideBuy, Mint: true}, ErrInsufficientBalance},
+		{"seller short of shares", sharesTrade("rich", "poor", 1000, 10, SideBuy), ErrInsufficientPosition},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, _ := newTestPositions(t)
+			pm.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+			deposit(t, pm, "poor", 1)
+			deposit(t, pm, "rich", 100)
+			if err := pm.MintShares("rich", "m1", 10); err != nil {
+				t.Fatal(err)
+			}
+
+			if err := pm.ExecuteTrade(tt.trade); err != tt.want {
+				t.Fatalf("err = %v, want %v", err, tt.want)
+			}
+			if pm.GetBalance("poor") != 10000 || pm.GetBalance("rich") != 900000 || pm.CollectedFees() != 0 {
+				t.Fatalf("balances changed: poor %d rich %d fees %d", pm.GetBalance("poor"), pm.GetBalance("rich"), pm.CollectedFees())
+			}
+			if pos := pm.GetPosition("poor", "m1"); pos.YesShares != 0 || pos.NoShares != 0 {
+				t.Fatalf("poor holds %d/%d shares after a rejected trade", pos.YesShares, pos.NoShares)
+			}
+			if pos := pm.GetPosition("rich", "m1"); pos.YesShares != 10 || pos.NoShares != 10 {
+				t.Fatalf("rich holds %d/%d shares after a rejected trade, want 10/10", pos.YesShares, pos.NoShares)
+			}
+		})
+	}
+}
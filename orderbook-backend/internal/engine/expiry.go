@@ -0,0 +1,135 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Expired reports whether a good-til-date order has passed its expiry
+func (o *Order) Expired(now time.Time) bool {
+	return !o.ExpiresAt.IsZero() && !now.Before(o.ExpiresAt)
+}
+
+// dropIfExpired cancels resting, the top of h, if it has expired. It reports
+// whether it did, in which case the caller must look at the book again
+// (must hold lock).
+func (ob *Orderbook) dropIfExpired(h *orderHeap, resting *Order, now time.Time) bool {
+	if !resting.Expired(now) {
+		return false
+	}
+	h.remove(resting)
+	resting.Cancel()
+	delete(ob.orders, resting.ID)
+	ob.emitOrderEvent(OrderRemoved, resting)
+	return true
+}
+
+// ExpireOrders cancels every resting order that has expired by now and
+// returns them
+func (ob *Orderbook) ExpireOrders(now time.Time) []*Order {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	var expired []*Order
+	for _, order := range ob.orders {
+		if !order.Expired(now) {
+			continue
+		}
+		if order.IsBuy() {
+			ob.dropIfExpired(ob.bids, order, now)
+		} else {
+			ob.dropIfExpired(ob.asks, order, now)
+		}
+		expired = append(expired, order)
+	}
+	return expired
+}
+
+// ExpireOrders cancels expired resting orders in every market. It returns
+// the expired orders keyed by market ID.
+func (m *MarketOrderbooks) ExpireOrders(now time.Time) map[string][]*Order {
+	m.mu.RLock()
+	books := make(map[string]*OutcomeOrderbooks, len(m.orderbooks))
+	for marketID, obs := range m.orderbooks {
+		books[marketID] = obs
+	}
+	m.mu.RUnlock()
+
+	expired := make(map[string][]*Order)
+	for marketID, obs := range books {
+		orders := append(obs.YES.ExpireOrders(now), obs.NO.ExpireOrders(now)...)
+		if len(orders) > 0 {
+			expired[marketID] = orders
+		}
+	}
+	return expired
+}
+
+// ExpirySweeper periodically cancels good-til-date orders that have expired
+type ExpirySweeper struct {
+	books    *MarketOrderbooks
+	interval time.Duration
+	onExpire func(marketID string, orders []*Order) // Called for each market with expired orders
+
+	stopCh chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewExpirySweeper creates a sweeper that checks the books every interval
+// (default one second)
+func NewExpirySweeper(books *MarketOrderbooks, interval time.Duration) *ExpirySweeper {
+	if interval <= 0 {
+		interval = time.Second
+	}
+	return &ExpirySweeper{
+		books:    books,
+		interval: interval,
+		stopCh:   make(chan struct{}),
+	}
+}
+
+// SetExpireCallback sets the callback for markets whose orders expired
+func (s *ExpirySweeper) SetExpireCallback(fn func(marketID string, orders []*Order)) {
+	s.onExpire = fn
+}
+
+// Start begins the sweep goroutine
+func (s *ExpirySweeper) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go s.run(ctx)
+}
+
+// Stop stops the sweeper
+func (s *ExpirySweeper) Stop() {
+	close(s.stopCh)
+	s.wg.Wait()
+}
+
+// run sweeps the books on every tick
+func (s *ExpirySweeper) run(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.stopCh:
+			return
+		case now := <-ticker.C:
+			s.Sweep(now)
+		}
+	}
+}
+
+// Sweep cancels every order expired by now
+func (s *ExpirySweeper) Sweep(now time.Time) {
+	for marketID, orders := range s.books.ExpireOrders(now) {
+		if s.onExpire != nil {
+			s.onExpire(marketID, orders)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestExpirySweeperRemovesExpiredOrders(t *testing.T) {
+	books := NewMarketOrderbooks()
+	gtd := NewOrder("alice", "m1", OutcomeYES, SideBuy, 5000, 10)
+	gtd.ExpiresAt = time.Now().Add(20 * time.Millisecond)
+	placeOn(t, books, gtd)
+	gtc := NewOrder("bob", "m1", OutcomeYES, SideBuy, 4000, 10)
+	placeOn(t, books, gtc)
+
+	expired := make(chan []*Order, 1)
+	sweeper := NewExpirySweeper(books, 5*time.Millisecond)
+	sweeper.SetExpireCallback(func(marketID string, orders []*Order) {
+		if marketID == "m1" {
+			expired <- orders
+		}
+	})
+	sweeper.Start(context.Background())
+	defer sweeper.Stop()
+
+	select {
+	case orders := <-expired:
+		if len(orders) != 1 || orders[0].ID != gtd.ID {
+			t.Fatalf("expired %+v, want only the good-til-date order", orders)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("sweeper never expired the order")
+	}
+
+	ob := books.GetOrderbook("m1", OutcomeYES)
+	snap := ob.GetSnapshot()
+	if len(snap.Bids) != 1 || snap.Bids[0].Price != 4000 {
+		t.Fatalf("bids = %+v, want only the GTC bid at 4000", snap.Bids)
+	}
+	if gtd.Status != StatusCancelled {
+		t.Fatalf("expired order status = %s, want cancelled", gtd.Status)
+	}
+	if _, err := ob.GetOrder(gtc.ID); err != nil {
+		t.Fatalf("GTC order: %v", err)
+	}
+}
+
+func TestMatchingSkipsExpiredOrders(t *testing.T) {
+	ob := NewOrderbook()
+	stale, _ := place(t, ob, "alice", SideBuy, 5000, 10)
+	place(t, ob, "bob", SideBuy, 4500, 10)
+	stale.ExpiresAt = time.Now().Add(-time.Second) // Expired before the sweeper ran
+
+	_, trades := place(t, ob, "carol", SideSell, 4500, 5)
+	if len(trades) != 1 || trades[0].BuyerID != "bob" || trades[0].Price != 4500 {
+		t.Fatalf("trades = %+v, want one fill against bob at 4500", trades)
+	}
+	if stale.Status != StatusCancelled || stale.FilledQty != 0 {
+		t.Fatalf("expired order status %s filled %d, want cancelled unfilled", stale.Status, stale.FilledQty)
+	}
+	if _, err := ob.GetOrder(stale.ID); err != ErrOrderNotFound {
+		t.Fatalf("expired order still on the book: %v", err)
+	}
+}
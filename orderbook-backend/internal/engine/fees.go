@@ -0,0 +1,27 @@
+package engine
+
+// FeeSchedule defines maker/taker trading fees in basis points of notional
+type FeeSchedule struct {
+	MakerBps uint64 `json:"maker_bps"`
+	TakerBps uint64 `json:"taker_bps"`
+}
+
+// NoFees is the zero fee schedule
+var NoFees = FeeSchedule{}
+
+// MakerFee returns the fee charged to the resting side for a given notional
+func (f FeeSchedule) MakerFee(notional uint64) uint64 {
+	return notional * f.MakerBps / 10000
+}
+
+// MaxFee returns the larger of the maker and taker fee for a given notional,
+// which is the most an order can be charged before it is known whether it
+// will rest or cross
+func (f FeeSchedule) MaxFee(notional uint64) uint64 {
+	return max(f.MakerFee(notional), f.TakerFee(notional))
+}
+
+// TakerFee returns the fee charged to the aggressing side for a given notional
+func (f FeeSchedule) TakerFee(notional uint64) uint64 {
+	return notional * f.TakerBps / 10000
+}
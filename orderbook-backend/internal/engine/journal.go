@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Journal
+//
+// Every change to a resting order and every position mutation is reported
+// to a journal callback that appends it to a log. The callback runs under
+// the lock that applies the change, after the change is made but before the
+// lock is released, so nothing that reads the books or positions sees a
+// change the log doesn't hold yet. A crash loses at most the operations in
+// flight, none of which has been reported to its caller. Matching and
+// settlement are separate operations, though: a crash after an order has
+// matched but before its trades are settled replays the fills on the book
+// without the trades.
+//
+// Order entries carry the order as it is after the change, so books are
+// rebuilt by applying them directly rather than by matching again. Position
+// entries carry the inputs of the operation and its time, and are
+// re-applied without the checks that already passed; a replayed trade is
+// also added back to its book's trade history. Replaying a log into empty
+// books and positions, or on top of the snapshot taken when the log was
+// started, reproduces the state it recorded. Market entries are written and
+// replayed by the journal package, since the engine doesn't know markets.
+
+// JournalEntryType names what a journal entry records
+type JournalEntryType string
+
+const (
+	JournalOrder        JournalEntryType = "order"    // Resting order added, modified or removed
+	JournalTrade        JournalEntryType = "trade"    // Trade applied to positions
+	JournalDeposit      JournalEntryType = "deposit"  // UserID, Amount
+	JournalWithdraw     JournalEntryType = "withdraw" // UserID, Amount
+	JournalMint         JournalEntryType = "mint"     // UserID, MarketID, Shares
+	JournalRedeem       JournalEntryType = "redeem"   // UserID, MarketID, Shares
+	JournalPayout       JournalEntryType = "payout"   // UserID, MarketID, Outcome
+	JournalSettle       JournalEntryType = "settle"   // MarketID, Outcome
+	JournalFeeFree      JournalEntryType = "fee_free" // MarketID, Until
+	JournalMarketLimits JournalEntryType = "market_limits"
+	JournalMarket       JournalEntryType = "market" // MarketID, Market (see the journal package)
+)
+
+// JournalEntry is one line of the journal
+type JournalEntry struct {
+	Timestamp time.Time        `json:"timestamp"`
+	Type      JournalEntryType `json:"type"`
+	Event     OrderEventType   `json:"event,omitempty"`
+	Order     *Order           `json:"order,omitempty"`
+	Trade     *Trade           `json:"trade,omitempty"`
+	UserID    string           `json:"user_id,omitempty"`
+	MarketID  string           `json:"market_id,omitempty"`
+	Amount    uint64           `json:"amount,omitempty"` // Basis points
+	Shares    Quantity         `json:"shares,omitempty"`
+	Outcome   OutcomeID        `json:"outcome,omitempty"`
+	Until     *time.Time       `json:"until,omitempty"`
+	Limits    *Limits          `json:"limits,omitempty"`
+	Market    json.RawMessage  `json:"market,omitempty"`
+}
+
+// SetJournalCallback sets the callback that receives every change to the
+// book's resting orders, under the book's lock
+func (ob *Orderbook) SetJournalCallback(fn func(JournalEntry)) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.onJournal = fn
+}
+
+// SetGlobalJournalCallback sets the journal callback for all existing and
+// future orderbooks
+func (m *MarketOrderbooks) SetGlobalJournalCallback(fn func(JournalEntry)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onJournal = fn
+	m.forEach(func(ob *Orderbook) { ob.SetJournalCallback(fn) })
+}
+
+// SetJournalCallback sets the callback that receives every position
+// mutation, under the position manager's lock
+func (pm *PositionManager) SetJournalCallback(fn func(JournalEntry)) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.onJournal = fn
+}
+
+// journal reports an order change (must hold lock)
+func (ob *Orderbook) journal(eventType OrderEventType, order *Order, at time.Time) {
+	if ob.onJournal == nil {
+		return
+	}
+	copied := *order
+	ob.onJournal(JournalEntry{Timestamp: at, Type: JournalOrder, Event: eventType, Order: &copied})
+}
+
+// journal reports a position mutation that has just been applied (must hold lock)
+func (pm *PositionManager) journal(entry JournalEntry) {
+	if pm.onJournal != nil {
+		pm.onJournal(entry)
+	}
+}
+
+// Replay reads a journal written as JSON lines and applies it in order to
+// books and positions. Replayed changes are not journaled again. A torn
+// final line, left by a crash mid-write, is ignored.
+func Replay(r io.Reader, books *MarketOrderbooks, positions *PositionManager) (int, error) {
+	return ReplayEach(r, func(entry JournalEntry) error {
+		return ApplyJournalEntry(entry, books, positions)
+	})
+}
+
+// ReplayEach reads a journal written as JSON lines and passes each entry to
+// apply in order, stopping at the first error. A torn final line, left by a
+// crash mid-write, is ignored.
+func ReplayEach(r io.Reader, apply func(JournalEntry) error) (int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	applied := 0
+	var pending error // Decode error, fatal unless it was on the last line
+	for line := 1; scanner.Scan(); line++ {
+		if pending != nil {
+			return applied, pending
+		}
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			pending = fmt.Errorf("journal line %d: %w", line, err)
+			continue
+		}
+
+		if err := apply(entry); err != nil {
+			return applied, fmt.Errorf("journal line %d: %w", line, err)
+		}
+		applied++
+	}
+	return applied, scanner.Err()
+}
+
+// ApplyJournalEntry re-applies one journaled order change or position
+// mutation without journaling it again
+func ApplyJournalEntry(entry JournalEntry, books *MarketOrderbooks, positions *PositionManager) error {
+	switch entry.Type {
+	case JournalOrder:
+		return books.replay(entry)
+	case JournalTrade:
+		if err := positions.replay(entry); err != nil {
+			return err
+		}
+		books.replayTrade(entry.Trade)
+		return nil
+	default:
+		return positions.replay(entry)
+	}
+}
+
+// replayTrade adds a replayed trade back to the trade history of the book
+// it was matched on
+func (m *MarketOrderbooks) replayTrade(trade *Trade) {
+	m.GetOrderbook(trade.MarketID, trade.OutcomeID).history.Add(trade)
+}
+
+// replay applies a journaled order change to the order's book
+func (m *MarketOrderbooks) replay(entry JournalEntry) error {
+	order := entry.Order
+	if order == nil {
+		return fmt.Errorf("order entry without an order")
+	}
+	ob := m.GetOrderbook(order.MarketID, order.OutcomeID)
+
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	journal := ob.onJournal
+	ob.onJournal = nil
+	defer func() { ob.onJournal = journal }()
+
+	existing, exists := ob.orders[order.ID]
+	switch entry.Event {
+	case OrderAdded:
+		if exists {
+			return fmt.Errorf("%w: %s", ErrDuplicateOrder, order.ID)
+		}
+		copied := *order
+		copied.heapIndex = -1
+		ob.rest(&copied)
+		bumpOrderSequence(copied.SequenceNum)
+	case OrderModified:
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrOrderNotFound, order.ID)
+		}
+		existing.Quantity = order.Quantity
+		existing.FilledQty = order.FilledQty
+		existing.Status = order.Status
+		ob.emitOrderEvent(OrderModified, existing)
+	case OrderRemoved:
+		if !exists {
+			return fmt.Errorf("%w: %s", ErrOrderNotFound, order.ID)
+		}
+		if existing.IsBuy() {
+			ob.bids.remove(existing)
+		} else {
+			ob.asks.remove(existing)
+		}
+		delete(ob.orders, existing.ID)
+		existing.FilledQty = order.FilledQty
+		existing.Status = order.Status
+		ob.emitOrderEvent(OrderRemoved, existing)
+	default:
+		return fmt.Errorf("unknown order event %q", entry.Event)
+	}
+	return nil
+}
+
+// replay re-applies a journaled position mutation at its original time
+func (pm *PositionManager) replay(entry JournalEntry) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	// Fee windows and daily limits read the clock
+	clock := pm.now
+	pm.now = func() time.Time { return entry.Timestamp }
+	defer func() { pm.now = clock }()
+
+	at := entry.Timestamp
+	switch entry.Type {
+	case JournalTrade:
+		if entry.Trade == nil {
+			return fmt.Errorf("trade entry without a trade")
+		}
+		if err := pm.executeTrade(at, entry.Trade); err != nil {
+			return fmt.Errorf("trade %s: %w", entry.Trade.ID, err)
+		}
+	case JournalDeposit:
+		pm.deposit(at, entry.UserID, entry.Amount)
+	case JournalWithdraw:
+		pm.withdraw(at, entry.UserID, entry.Amount)
+	case JournalMint:
+		shares := uint64(entry.Shares)
+		pm.mint(at, entry.UserID, entry.MarketID, shares, OutcomeYES, Collateral(shares)/2)
+	case JournalRedeem:
+		pm.redeem(at, pm.getOrCreatePosition(entry.UserID, entry.MarketID), uint64(entry.Shares))
+	case JournalPayout:
+		pm.payout(at, entry.UserID, entry.MarketID, entry.Outcome)
+	case JournalSettle:
+		pm.settle(at, entry.MarketID, entry.Outcome)
+	case JournalFeeFree:
+		if entry.Until == nil {
+			return fmt.Errorf("fee_free entry without an end time")
+		}
+		pm.setFeeFreeWindow(entry.MarketID, *entry.Until)
+	case JournalMarketLimits:
+		if entry.Limits == nil {
+			return fmt.Errorf("market_limits entry without limits")
+		}
+		pm.marketLimits[entry.MarketID] = *entry.Limits
+	default:
+		return fmt.Errorf("unknown journal entry type %q", entry.Type)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// journalTo records journal entries as JSON lines into buf
+func journalTo(t *testing.T, buf *bytes.Buffer) func(JournalEntry) {
+	return func(entry JournalEntry) {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		buf.Write(append(line, '\n'))
+	}
+}
+
+// exportJSON encodes the books and positions for comparison
+func exportJSON(t *testing.T, books *MarketOrderbooks, pm *PositionManager) string {
+	t.Helper()
+	data, err := json.Marshal(struct {
+		Books     OrderbooksSnapshot
+		Positions PositionsSnapshot
+	}{books.Export(), pm.Export()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestReplayAfterCrashReproducesState(t *testing.T) {
+	var log bytes.Buffer
+	books := NewMarketOrderbooks()
+	books.SetGlobalJournalCallback(journalTo(t, &log))
+	pm, _ := newTestPositions(t)
+	pm.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+	pm.SetJournalCallback(journalTo(t, &log))
+
+	execute := func(order *Order) []*Trade {
+		t.Helper()
+		if err := pm.ValidateOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		trades := placeOn(t, books, order)
+		for _, trade := range trades {
+			if err := pm.ExecuteTrade(trade); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return trades
+	}
+	deposit(t, pm, "alice", 100)
+	deposit(t, pm, "bob", 100)
+	deposit(t, pm, "carol", 100)
+	if err := pm.MintShares("alice", "m1", 10); err != nil {
+		t.Fatal(err)
+	}
+	execute(NewOrder("alice", "m1", OutcomeYES, SideSell, 6500, 10))
+	execute(NewOrder("bob", "m1", OutcomeYES, SideBuy, 6500, 4)) // Partial fill
+	execute(NewOrder("bob", "m1", OutcomeNO, SideBuy, 3000, 5))  // Rests
+	if trades := execute(NewOrder("carol", "m1", OutcomeYES, SideBuy, 7000, 8)); len(trades) != 2 || !trades[1].Mint {
+		t.Fatalf("trades = %+v, want the rest of the ask and then a mint", trades)
+	}
+	resting := NewOrder("bob", "m1", OutcomeYES, SideBuy, 5000, 3)
+	execute(resting)
+	if err := books.GetOrderbook("m1", OutcomeYES).CancelOrder(resting.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.Withdraw("bob", 10000); err != nil {
+		t.Fatal(err)
+	}
+
+	// The process dies halfway through writing the next entry
+	log.WriteString(`{"timestamp":"2026-01-01T12:00:00Z","type":"depo`)
+
+	replayedBooks := NewMarketOrderbooks()
+	replayedPositions, _ := newTestPositions(t)
+	replayedPositions.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+	n, err := Replay(strings.NewReader(log.String()), replayedBooks, replayedPositions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n == 0 {
+		t.Fatal("no entries replayed")
+	}
+	if got, want := exportJSON(t, replayedBooks, replayedPositions), exportJSON(t, books, pm); got != want {
+		t.Fatalf("replayed state differs:\n got %s\nwant %s", got, want)
+	}
+	for _, outcome := range []OutcomeID{OutcomeYES, OutcomeNO} {
+		got := replayedBooks.GetOrderbook("m1", outcome).RecentTrades(10)
+		want := books.GetOrderbook("m1", outcome).RecentTrades(10)
+		if len(got) != len(want) {
+			t.Fatalf("%s: replayed %d trades into the history, want %d", outcome, len(got), len(want))
+		}
+	}
+}
+
+func TestReplayRejectsCorruptMiddleLine(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	log := `{"type":"deposit","user_id":"alice","amount":10000}
+{"type":"depo
+{"type":"deposit","user_id":"alice","amount":10000}
+`
+	n, err := Replay(strings.NewReader(log), NewMarketOrderbooks(), pm)
+	if err == nil || n != 1 {
+		t.Fatalf("applied %d, err %v; want 1 and an error", n, err)
+	}
+}
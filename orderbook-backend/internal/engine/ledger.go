@@ -0,0 +1,62 @@
+package engine
+
+import "time"
+
+// maxLedgerEntries bounds the ledger kept for each user
+const maxLedgerEntries = 10000
+
+// LedgerEntryType names what changed a user's balance
+type LedgerEntryType string
+
+const (
+	LedgerDeposit     LedgerEntryType = "deposit"
+	LedgerWithdraw    LedgerEntryType = "withdraw"
+	LedgerTradeDebit  LedgerEntryType = "trade_debit"  // Paid for shares bought, fee included
+	LedgerTradeCredit LedgerEntryType = "trade_credit" // Received for shares sold, net of fee
+	LedgerMint        LedgerEntryType = "mint"         // Collateral for newly minted YES+NO pairs
+	LedgerRedeem      LedgerEntryType = "redeem"       // Collateral back for burned pairs
+	LedgerPayout      LedgerEntryType = "payout"       // Winning shares paid out at resolution
+	LedgerFee         LedgerEntryType = "fee"          // Trading fees credited to the fee account
+)
+
+// LedgerEntry is one change to a user's USDC balance, in basis points
+type LedgerEntry struct {
+	Timestamp time.Time       `json:"timestamp"`
+	Type      LedgerEntryType `json:"type"`
+	Amount    int64           `json:"amount"`  // Negative for debits
+	Balance   uint64          `json:"balance"` // Balance after the change
+	MarketID  string          `json:"market_id,omitempty"`
+	TradeID   string          `json:"trade_id,omitempty"`
+}
+
+// record appends an entry for a balance change that has just been applied
+// (must hold lock)
+func (pm *PositionManager) record(now time.Time, userID string, entryType LedgerEntryType, amount int64, marketID, tradeID string) {
+	entries := append(pm.ledger[userID], LedgerEntry{
+		Timestamp: now,
+		Type:      entryType,
+		Amount:    amount,
+		Balance:   pm.balances[userID],
+		MarketID:  marketID,
+		TradeID:   tradeID,
+	})
+	if len(entries) > maxLedgerEntries {
+		entries = entries[len(entries)-maxLedgerEntries:]
+	}
+	pm.ledger[userID] = entries
+}
+
+// Ledger returns up to limit of a user's most recent balance changes,
+// newest first
+func (pm *PositionManager) Ledger(userID string, limit int) []LedgerEntry {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	entries := pm.ledger[userID]
+	n := min(limit, len(entries))
+	result := make([]LedgerEntry, n)
+	for i := range result {
+		result[i] = entries[len(entries)-1-i]
+	}
+	return result
+}
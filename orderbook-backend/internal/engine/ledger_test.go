@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLedgerDepositThenTrade(t *testing.T) {
+	pm, clock := newTestPositions(t)
+	pm.SetFeeSchedule(FeeSchedule{TakerBps: 50})
+	deposit(t, pm, "buyer", 100)
+	deposit(t, pm, "seller", 100)
+	if err := pm.MintShares("seller", "m1", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.now = clock.now.Add(time.Minute)
+	trade := sharesTrade("buyer", "seller", 6000, 10, SideBuy)
+	if err := pm.ExecuteTrade(trade); err != nil {
+		t.Fatal(err)
+	}
+
+	// 60000 for the shares plus a 300 taker fee, newest first
+	entries := pm.Ledger("buyer", 50)
+	want := []LedgerEntry{
+		{Timestamp: clock.now, Type: LedgerTradeDebit, Amount: -60300, Balance: 939700, MarketID: "m1", TradeID: trade.ID},
+		{Timestamp: clock.now.Add(-time.Minute), Type: LedgerDeposit, Amount: 1000000, Balance: 1000000},
+	}
+	if len(entries) != len(want) {
+		t.Fatalf("%d entries %+v, want %d", len(entries), entries, len(want))
+	}
+	for i := range want {
+		if entries[i] != want[i] {
+			t.Errorf("entry %d = %+v, want %+v", i, entries[i], want[i])
+		}
+	}
+
+	if got := pm.Ledger("buyer", 1); len(got) != 1 || got[0].Type != LedgerTradeDebit {
+		t.Errorf("limit 1: %+v, want the trade debit", got)
+	}
+	seller := pm.Ledger("seller", 50)
+	if len(seller) != 3 || seller[0].Type != LedgerTradeCredit || seller[0].Amount != 60000 || seller[1].Type != LedgerMint {
+		t.Errorf("seller ledger = %+v, want trade credit, mint and deposit", seller)
+	}
+}
@@ -0,0 +1,119 @@
+package engine
+
+import "errors"
+
+var (
+	ErrPositionLimit      = errors.New("order would exceed your net position limit")
+	ErrDailyNotionalLimit = errors.New("order would exceed your daily traded notional limit")
+)
+
+// Limits caps a single user's exposure in one market. Zero disables a limit.
+type Limits struct {
+	MaxNetPosition   uint64 `json:"max_net_position"`   // Shares, |YES - NO|
+	MaxDailyNotional uint64 `json:"max_daily_notional"` // Basis points (price * quantity), per UTC day
+}
+
+// dailyNotional tracks traded notional for one user in one market on one day
+type dailyNotional struct {
+	day      string // UTC date, YYYY-MM-DD
+	notional uint64
+}
+
+// SetLimits sets the limits applied to markets without an override
+func (pm *PositionManager) SetLimits(limits Limits) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.limits = limits
+}
+
+// SetMarketLimits overrides the global limits for one market
+func (pm *PositionManager) SetMarketLimits(marketID string, limits Limits) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.marketLimits[marketID] = limits
+	pm.journal(JournalEntry{Timestamp: pm.now(), Type: JournalMarketLimits, MarketID: marketID, Limits: &limits})
+}
+
+// LimitsFor returns the limits in effect for a market
+func (pm *PositionManager) LimitsFor(marketID string) Limits {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.limitsFor(marketID)
+}
+
+// limitsFor returns the limits in effect for a market (must hold lock)
+func (pm *PositionManager) limitsFor(marketID string) Limits {
+	if limits, ok := pm.marketLimits[marketID]; ok {
+		return limits
+	}
+	return pm.limits
+}
+
+// CheckLimits rejects an order that could take the user past their limits.
+// The net position check assumes every resting order of the user in books
+// fills as well, so later maker fills can never breach the limit either.
+func (pm *PositionManager) CheckLimits(order *Order, books *OutcomeOrderbooks) error {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	limits := pm.limitsFor(order.MarketID)
+
+	if limits.MaxDailyNotional > 0 {
+		traded := pm.dailyNotionalFor(order.UserID, order.MarketID)
+		if traded+Notional(order.Price, order.Quantity) > limits.MaxDailyNotional {
+			return ErrDailyNotionalLimit
+		}
+	}
+
+	if limits.MaxNetPosition > 0 {
+		var yes, no uint64
+		if pos, ok := pm.positions[order.UserID][order.MarketID]; ok {
+			yes, no = pos.YesShares, pos.NoShares
+		}
+
+		// Buying YES or selling NO moves the user long; the reverse moves them short
+		long := yes + books.YES.OpenQuantity(order.UserID, SideBuy) + books.NO.OpenQuantity(order.UserID, SideSell)
+		short := no + books.NO.OpenQuantity(order.UserID, SideBuy) + books.YES.OpenQuantity(order.UserID, SideSell)
+		if (order.OutcomeID == OutcomeYES) == order.IsBuy() {
+			long += order.Quantity
+			if long > no && long-no > limits.MaxNetPosition {
+				return ErrPositionLimit
+			}
+		} else {
+			short += order.Quantity
+			if short > yes && short-yes > limits.MaxNetPosition {
+				return ErrPositionLimit
+			}
+		}
+	}
+
+	return nil
+}
+
+// dailyNotionalFor returns today's traded notional for a user (must hold lock)
+func (pm *PositionManager) dailyNotionalFor(userID, marketID string) uint64 {
+	d, ok := pm.dailyNotional[userID][marketID]
+	if !ok || d.day != pm.today() {
+		return 0
+	}
+	return d.notional
+}
+
+// recordNotional adds traded notional to a user's daily total (must hold lock)
+func (pm *PositionManager) recordNotional(userID, marketID string, notional uint64) {
+	if _, ok := pm.dailyNotional[userID]; !ok {
+		pm.dailyNotional[userID] = make(map[string]*dailyNotional)
+	}
+	today := pm.today()
+	d, ok := pm.dailyNotional[userID][marketID]
+	if !ok || d.day != today {
+		d = &dailyNotional{day: today}
+		pm.dailyNotional[userID][marketID] = d
+	}
+	d.notional += notional
+}
+
+// today returns the current UTC date (must hold lock)
+func (pm *PositionManager) today() string {
+	return pm.now().UTC().Format("2006-01-02")
+}
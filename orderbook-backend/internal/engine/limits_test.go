@@ -0,0 +1,76 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPositionLimit(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	pm.SetLimits(Limits{MaxNetPosition: 10})
+	books := NewMarketOrderbooks().GetOrCreate("m1")
+
+	tests := []struct {
+		name    string
+		outcome OutcomeID
+		side    Side
+		qty     uint64
+		err     error
+	}{
+		{"long at the limit", OutcomeYES, SideBuy, 10, nil},
+		{"long past the limit", OutcomeYES, SideBuy, 11, ErrPositionLimit},
+		{"short past the limit", OutcomeNO, SideBuy, 11, ErrPositionLimit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := pm.CheckLimits(NewOrder("alice", "m1", tt.outcome, tt.side, 5000, tt.qty), books)
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// Resting orders count as if they fill
+	place(t, books.YES, "alice", SideBuy, 4000, 6)
+	if err := pm.CheckLimits(NewOrder("alice", "m1", OutcomeYES, SideBuy, 5000, 5), books); err != ErrPositionLimit {
+		t.Fatalf("with 6 resting: err = %v, want %v", err, ErrPositionLimit)
+	}
+	if err := pm.CheckLimits(NewOrder("alice", "m1", OutcomeYES, SideBuy, 5000, 4), books); err != nil {
+		t.Fatalf("with 6 resting, 4 more: %v", err)
+	}
+
+	// A per-market override replaces the global limits
+	pm.SetMarketLimits("m1", Limits{})
+	if err := pm.CheckLimits(NewOrder("alice", "m1", OutcomeYES, SideBuy, 5000, 100), books); err != nil {
+		t.Fatalf("after unlimited override: %v", err)
+	}
+}
+
+func TestDailyNotionalLimitResetsEachDay(t *testing.T) {
+	pm, clock := newTestPositions(t)
+	pm.SetLimits(Limits{MaxDailyNotional: 50000}) // 5 USDC
+	books := NewMarketOrderbooks().GetOrCreate("m1")
+	deposit(t, pm, "alice", 100)
+	deposit(t, pm, "bob", 100)
+	if err := pm.MintShares("bob", "m1", 20); err != nil {
+		t.Fatal(err)
+	}
+
+	// 8 shares at 0.50 = 4 USDC traded today
+	if err := pm.ExecuteTrade(sharesTrade("alice", "bob", 5000, 8, SideBuy)); err != nil {
+		t.Fatal(err)
+	}
+
+	next := NewOrder("alice", "m1", OutcomeYES, SideBuy, 5000, 3)
+	if err := pm.CheckLimits(next, books); err != ErrDailyNotionalLimit {
+		t.Fatalf("err = %v, want %v", err, ErrDailyNotionalLimit)
+	}
+	if err := pm.CheckLimits(NewOrder("alice", "m1", OutcomeYES, SideBuy, 5000, 2), books); err != nil {
+		t.Fatalf("order reaching the limit exactly: %v", err)
+	}
+
+	clock.now = clock.now.Add(24 * time.Hour)
+	if err := pm.CheckLimits(next, books); err != nil {
+		t.Fatalf("next day: %v", err)
+	}
+}
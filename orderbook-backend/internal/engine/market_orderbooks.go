@@ -1,6 +1,9 @@
 package engine
 
-import "sync"
+import (
+	"sync"
+	"time"
+)
 
 // OutcomeID represents a binary prediction outcome
 type OutcomeID string
@@ -10,10 +13,28 @@ const (
 	OutcomeNO  OutcomeID = "NO"
 )
 
+// Complement returns the other outcome of a binary market
+func (o OutcomeID) Complement() OutcomeID {
+	if o == OutcomeYES {
+		return OutcomeNO
+	}
+	return OutcomeYES
+}
+
 // MarketOrderbooks manages separate orderbooks for YES and NO outcomes
 type MarketOrderbooks struct {
 	mu         sync.RWMutex
 	orderbooks map[string]*OutcomeOrderbooks // marketID -> outcome orderbooks
+
+	// Settings applied to every existing and future orderbook
+	onTrade   func(*Trade)
+	onEvent   func(OrderEvent)
+	onUpdate  func(OrderUpdate)
+	onJournal func(JournalEntry)
+	minSpread uint64
+	tickSize  uint64
+	minQty    uint64
+	selfTrade SelfTradeMode
 }
 
 // OutcomeOrderbooks holds both YES and NO orderbooks for a single market
@@ -29,7 +50,8 @@ func NewMarketOrderbooks() *MarketOrderbooks {
 	}
 }
 
-// GetOrCreate returns the orderbooks for a market, creating them if needed
+// GetOrCreate returns the orderbooks for a market, creating them if needed.
+// Newly created orderbooks inherit the global callbacks and settings.
 func (m *MarketOrderbooks) GetOrCreate(marketID string) *OutcomeOrderbooks {
 	m.mu.Lock()
 	defer m.mu.Unlock()
@@ -42,10 +64,41 @@ func (m *MarketOrderbooks) GetOrCreate(marketID string) *OutcomeOrderbooks {
 		YES: NewOrderbook(),
 		NO:  NewOrderbook(),
 	}
+	m.configure(obs.YES)
+	m.configure(obs.NO)
+	linkComplements(obs.YES, obs.NO)
 	m.orderbooks[marketID] = obs
 	return obs
 }
 
+// configure applies the global callbacks and settings to an orderbook (must hold lock)
+func (m *MarketOrderbooks) configure(ob *Orderbook) {
+	if m.onTrade != nil {
+		ob.SetTradeCallback(m.onTrade)
+	}
+	if m.onEvent != nil {
+		ob.SetOrderEventCallback(m.onEvent)
+	}
+	if m.onUpdate != nil {
+		ob.SetOrderUpdateCallback(m.onUpdate)
+	}
+	if m.onJournal != nil {
+		ob.SetJournalCallback(m.onJournal)
+	}
+	ob.SetMinSpread(m.minSpread)
+	ob.SetTickSize(m.tickSize)
+	ob.SetMinQuantity(m.minQty)
+	ob.SetSelfTradePrevention(m.selfTrade)
+}
+
+// forEach calls fn on every existing orderbook (must hold lock)
+func (m *MarketOrderbooks) forEach(fn func(*Orderbook)) {
+	for _, obs := range m.orderbooks {
+		fn(obs.YES)
+		fn(obs.NO)
+	}
+}
+
 // Get returns the orderbooks for a market, or nil if not found
 func (m *MarketOrderbooks) Get(marketID string) *OutcomeOrderbooks {
 	m.mu.RLock()
@@ -73,8 +126,106 @@ func (m *MarketOrderbooks) SetTradeCallback(marketID string, fn func(*Trade)) {
 func (m *MarketOrderbooks) SetGlobalTradeCallback(fn func(*Trade)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	for _, obs := range m.orderbooks {
-		obs.YES.SetTradeCallback(fn)
-		obs.NO.SetTradeCallback(fn)
+	m.onTrade = fn
+	m.forEach(func(ob *Orderbook) { ob.SetTradeCallback(fn) })
+}
+
+// SetGlobalOrderEventCallback sets the level-3 order event callback for all
+// existing and future orderbooks
+func (m *MarketOrderbooks) SetGlobalOrderEventCallback(fn func(OrderEvent)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onEvent = fn
+	m.forEach(func(ob *Orderbook) { ob.SetOrderEventCallback(fn) })
+}
+
+// SetGlobalOrderUpdateCallback sets the order status update callback for all
+// existing and future orderbooks
+func (m *MarketOrderbooks) SetGlobalOrderUpdateCallback(fn func(OrderUpdate)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onUpdate = fn
+	m.forEach(func(ob *Orderbook) { ob.SetOrderUpdateCallback(fn) })
+}
+
+// SetMinSpread sets the minimum own-quote spread for all existing and future orderbooks
+func (m *MarketOrderbooks) SetMinSpread(bps uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.minSpread = bps
+	m.forEach(func(ob *Orderbook) { ob.SetMinSpread(bps) })
+}
+
+// SetTickSize sets the price increment for all existing and future orderbooks
+func (m *MarketOrderbooks) SetTickSize(bps uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.tickSize = bps
+	m.forEach(func(ob *Orderbook) { ob.SetTickSize(bps) })
+}
+
+// SetMinQuantity sets the minimum order quantity for all existing and future orderbooks
+func (m *MarketOrderbooks) SetMinQuantity(qty uint64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.minQty = qty
+	m.forEach(func(ob *Orderbook) { ob.SetMinQuantity(qty) })
+}
+
+// SetSelfTradePrevention sets the self-trade prevention mode for all existing
+// and future orderbooks
+func (m *MarketOrderbooks) SetSelfTradePrevention(mode SelfTradeMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.selfTrade = mode
+	m.forEach(func(ob *Orderbook) { ob.SetSelfTradePrevention(mode) })
+}
+
+// OpenBuyNotional returns the USDC (basis points) backing a user's resting
+// buy orders across every market
+func (m *MarketOrderbooks) OpenBuyNotional(userID string) uint64 {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var total uint64
+	m.forEach(func(ob *Orderbook) { total += ob.OpenBuyNotional(userID) })
+	return total
+}
+
+// OpenSellQuantity returns the shares of one outcome backing a user's
+// resting sell orders in a market
+func (m *MarketOrderbooks) OpenSellQuantity(userID, marketID string, outcome OutcomeID) uint64 {
+	obs := m.Get(marketID)
+	if obs == nil {
+		return 0
+	}
+	if outcome == OutcomeYES {
+		return obs.YES.OpenQuantity(userID, SideSell)
+	}
+	return obs.NO.OpenQuantity(userID, SideSell)
+}
+
+// HasTraded reports whether either outcome orderbook of a market has
+// recorded a trade
+func (m *MarketOrderbooks) HasTraded(marketID string) bool {
+	obs := m.Get(marketID)
+	if obs == nil {
+		return false
 	}
+	return len(obs.YES.RecentTrades(1)) > 0 || len(obs.NO.RecentTrades(1)) > 0
+}
+
+// StartAuction puts both outcome orderbooks of a market into an opening auction
+func (m *MarketOrderbooks) StartAuction(marketID string, until time.Time) {
+	obs := m.GetOrCreate(marketID)
+	obs.YES.StartAuction(until)
+	obs.NO.StartAuction(until)
+}
+
+// SetAllocationMode sets how fills are shared at a price level for both
+// outcome orderbooks of a market
+func (m *MarketOrderbooks) SetAllocationMode(marketID string, mode AllocationMode, minQty uint64) {
+	obs := m.GetOrCreate(marketID)
+	obs.YES.SetAllocationMode(mode, minQty)
+	obs.NO.SetAllocationMode(mode, minQty)
 }
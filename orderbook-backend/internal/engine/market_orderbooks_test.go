@@ -0,0 +1,21 @@
+package engine
+
+import "testing"
+
+func TestGetOrCreateWiresGlobalTradeCallback(t *testing.T) {
+	m := NewMarketOrderbooks()
+	var got []*Trade
+	m.SetGlobalTradeCallback(func(trade *Trade) { got = append(got, trade) })
+
+	obs := m.GetOrCreate("m1")
+	if m.Get("m1") != obs {
+		t.Fatal("orderbooks not registered on creation")
+	}
+	for _, ob := range []*Orderbook{obs.YES, obs.NO} {
+		place(t, ob, "seller", SideSell, 5000, 1)
+		place(t, ob, "buyer", SideBuy, 5000, 1)
+	}
+	if len(got) != 2 {
+		t.Fatalf("callback saw %d trades, want one per outcome book", len(got))
+	}
+}
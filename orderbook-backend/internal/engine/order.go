@@ -15,6 +15,23 @@ const (
 	SideSell Side = "sell"
 )
 
+// OrderType distinguishes limit orders from market orders
+type OrderType string
+
+const (
+	OrderTypeLimit  OrderType = "limit"  // Rests at its price until filled or cancelled
+	OrderTypeMarket OrderType = "market" // Takes liquidity at any price; never rests
+)
+
+// TimeInForce says how long an order may stay on the book
+type TimeInForce string
+
+const (
+	TimeInForceGTC TimeInForce = "GTC" // Good till cancelled (default)
+	TimeInForceFOK TimeInForce = "FOK" // Fill or kill: fill completely at once or not at all
+	TimeInForceIOC TimeInForce = "IOC" // Immediate or cancel: fill what is possible, cancel the rest
+)
+
 // OrderStatus represents the current status of an order
 type OrderStatus string
 
@@ -25,19 +42,29 @@ const (
 	StatusCancelled OrderStatus = "cancelled"
 )
 
-// Order represents a limit order in the orderbook
+// Order represents an order in the orderbook
 type Order struct {
 	ID          string      `json:"id"`
 	UserID      string      `json:"user_id"`
 	MarketID    string      `json:"market_id"`  // Prediction market ID
 	OutcomeID   OutcomeID   `json:"outcome_id"` // YES or NO
 	Side        Side        `json:"side"`
+	Type        OrderType   `json:"type"`
+	TimeInForce TimeInForce `json:"time_in_force"`
 	Price       uint64      `json:"price"`      // Price in basis points (0-10000 for 0.00-1.00 probability)
 	Quantity    uint64      `json:"quantity"`   // Total quantity (shares)
 	FilledQty   uint64      `json:"filled_qty"` // Already filled quantity
+	PostOnly    bool        `json:"post_only"`  // Reject instead of matching on arrival
 	Status      OrderStatus `json:"status"`
 	Timestamp   time.Time   `json:"timestamp"`
 	SequenceNum uint64      `json:"sequence_num"` // For FIFO ordering at same price
+	ExpiresAt   time.Time   `json:"expires_at"`   // Good-til-date expiry (zero = no expiry)
+
+	heapIndex int // Position in its book's heap while resting, -1 otherwise
+
+	// State last reported through the order update callback
+	reportedStatus OrderStatus
+	reportedFill   uint64
 }
 
 var orderSequence uint64
@@ -50,15 +77,31 @@ func NewOrder(userID, marketID string, outcomeID OutcomeID, side Side, price, qu
 		MarketID:    marketID,
 		OutcomeID:   outcomeID,
 		Side:        side,
+		Type:        OrderTypeLimit,
+		TimeInForce: TimeInForceGTC,
 		Price:       price,
 		Quantity:    quantity,
 		FilledQty:   0,
 		Status:      StatusOpen,
 		Timestamp:   time.Now(),
 		SequenceNum: atomic.AddUint64(&orderSequence, 1),
+		heapIndex:   -1,
 	}
 }
 
+// NewMarketOrder creates a market order, which fills against whatever the
+// opposite side offers and cancels any remainder
+func NewMarketOrder(userID, marketID string, outcomeID OutcomeID, side Side, quantity uint64) *Order {
+	order := NewOrder(userID, marketID, outcomeID, side, 0, quantity)
+	order.Type = OrderTypeMarket
+	return order
+}
+
+// IsMarket returns true if this is a market order
+func (o *Order) IsMarket() bool {
+	return o.Type == OrderTypeMarket
+}
+
 // RemainingQty returns the unfilled quantity
 func (o *Order) RemainingQty() uint64 {
 	return o.Quantity - o.FilledQty
@@ -0,0 +1,44 @@
+package engine
+
+import "time"
+
+// OrderEventType is the kind of change to a resting order
+type OrderEventType string
+
+const (
+	OrderAdded    OrderEventType = "add"    // Order now rests on the book
+	OrderModified OrderEventType = "modify" // Resting order's remaining quantity changed
+	OrderRemoved  OrderEventType = "remove" // Order left the book (filled or cancelled)
+)
+
+// OrderEvent is a level-3 book update. It deliberately carries no user ID so
+// it can be streamed to any client to rebuild the full book.
+type OrderEvent struct {
+	Seq          uint64         `json:"seq"` // Per-orderbook, strictly increasing
+	Type         OrderEventType `json:"type"`
+	MarketID     string         `json:"market_id"`
+	OutcomeID    OutcomeID      `json:"outcome_id"`
+	OrderID      string         `json:"order_id"`
+	Side         Side           `json:"side"`
+	Price        uint64         `json:"price"`
+	RemainingQty uint64         `json:"remaining_qty"`
+	Status       OrderStatus    `json:"status"`
+	Timestamp    time.Time      `json:"timestamp"`
+}
+
+// L3Order is an anonymized resting order in a full-book snapshot
+type L3Order struct {
+	OrderID      string `json:"order_id"`
+	Side         Side   `json:"side"`
+	Price        uint64 `json:"price"`
+	RemainingQty uint64 `json:"remaining_qty"`
+	SequenceNum  uint64 `json:"sequence_num"`
+}
+
+// L3Snapshot is every resting order at a given event sequence. Events with
+// Seq <= this Seq are already reflected in it.
+type L3Snapshot struct {
+	Seq  uint64    `json:"seq"`
+	Bids []L3Order `json:"bids"`
+	Asks []L3Order `json:"asks"`
+}
@@ -0,0 +1,51 @@
+package engine
+
+import "time"
+
+// OrderUpdate reports a change in an order's status or filled quantity to
+// its owner. Unlike OrderEvent it names the user, and it covers incoming
+// orders that never rest as well.
+type OrderUpdate struct {
+	OrderID      string      `json:"order_id"`
+	UserID       string      `json:"user_id"`
+	MarketID     string      `json:"market_id"`
+	OutcomeID    OutcomeID   `json:"outcome_id"`
+	Side         Side        `json:"side"`
+	Price        uint64      `json:"price"`
+	Status       OrderStatus `json:"status"`
+	FilledQty    uint64      `json:"filled_qty"`
+	RemainingQty uint64      `json:"remaining_qty"`
+	Timestamp    time.Time   `json:"timestamp"`
+}
+
+// SetOrderUpdateCallback sets the callback for order status updates
+func (ob *Orderbook) SetOrderUpdateCallback(fn func(OrderUpdate)) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.onOrderUpdate = fn
+}
+
+// emitOrderUpdate notifies the owner of an order if its status or filled
+// quantity changed since the last update (must hold lock)
+func (ob *Orderbook) emitOrderUpdate(order *Order) {
+	if order.reportedStatus == order.Status && order.reportedFill == order.FilledQty {
+		return
+	}
+	order.reportedStatus = order.Status
+	order.reportedFill = order.FilledQty
+	if ob.onOrderUpdate == nil {
+		return
+	}
+	ob.onOrderUpdate(OrderUpdate{
+		OrderID:      order.ID,
+		UserID:       order.UserID,
+		MarketID:     order.MarketID,
+		OutcomeID:    order.OutcomeID,
+		Side:         order.Side,
+		Price:        order.Price,
+		Status:       order.Status,
+		FilledQty:    order.FilledQty,
+		RemainingQty: order.RemainingQty(),
+		Timestamp:    time.Now(),
+	})
+}
@@ -0,0 +1,34 @@
+package engine
+
+import "testing"
+
+func TestOrderUpdatesOnStatusChange(t *testing.T) {
+	ob := NewOrderbook()
+	var updates []OrderUpdate
+	ob.SetOrderUpdateCallback(func(u OrderUpdate) { updates = append(updates, u) })
+
+	bid, _ := place(t, ob, "alice", SideBuy, 5000, 10)
+	if len(updates) != 1 || updates[0].Status != StatusOpen {
+		t.Fatalf("updates on placing = %+v, want one open", updates)
+	}
+	updates = nil
+
+	place(t, ob, "bob", SideSell, 5000, 4)
+	var mine []OrderUpdate
+	for _, u := range updates {
+		if u.OrderID == bid.ID {
+			mine = append(mine, u)
+		}
+	}
+	if len(mine) != 1 || mine[0].Status != StatusPartial || mine[0].FilledQty != 4 || mine[0].RemainingQty != 6 || mine[0].UserID != "alice" {
+		t.Fatalf("updates for the bid = %+v, want one partial with 4 filled and 6 left", mine)
+	}
+
+	updates = nil
+	if err := ob.CancelOrder(bid.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(updates) != 1 || updates[0].Status != StatusCancelled || updates[0].FilledQty != 4 {
+		t.Fatalf("updates on cancel = %+v, want one cancelled with 4 filled", updates)
+	}
+}
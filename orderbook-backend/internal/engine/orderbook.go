@@ -3,18 +3,22 @@ package engine
 import (
 	"container/heap"
 	"errors"
+	"sort"
 	"sync"
+	"time"
 )
 
 var (
 	ErrInvalidPrice    = errors.New("invalid price: must be between 0 and 10000 basis points")
 	ErrInvalidQuantity = errors.New("invalid quantity: must be greater than 0")
 	ErrOrderNotFound   = errors.New("order not found")
+	ErrSpreadTooNarrow = errors.New("order would narrow your own spread below the minimum")
+	ErrWouldCross      = errors.New("post-only order would match immediately")
 )
 
 // Orderbook is the core matching engine with price-time priority
 type Orderbook struct {
-	mu      sync.RWMutex
+	mu      *sync.RWMutex
 	bids    *orderHeap // Max heap for buy orders (highest price first)
 	asks    *orderHeap // Min heap for sell orders (lowest price first)
 	orders  map[string]*Order
@@ -22,11 +26,46 @@ type Orderbook struct {
 
 	// Callback for trade notifications
 	onTrade func(*Trade)
+
+	// Callback for level-3 order lifecycle notifications
+	onOrderEvent func(OrderEvent)
+	eventSeq     uint64
+
+	// Callback for per-order status updates to their owners
+	onOrderUpdate func(OrderUpdate)
+
+	// Recent level-3 events, kept for replaying the book to a past seq
+	events eventLog
+
+	// Callback for the write-ahead journal (see journal.go)
+	onJournal func(JournalEntry)
+
+	// Minimum gap (basis points) between a user's own bids and asks, 0 = off
+	minSpread uint64
+
+	// Price increment in basis points and smallest order quantity, 0 = any
+	tickSize uint64
+	minQty   uint64
+
+	// How fills are shared between resting orders at one price
+	allocMode  AllocationMode
+	proRataMin uint64
+
+	// What happens when a user's orders would match each other
+	selfTrade SelfTradeMode
+
+	// Opening auction: orders rest unmatched until auctionUntil
+	inAuction    bool
+	auctionUntil time.Time
+
+	// The other outcome's book; buys on the two books can match by minting
+	complement *Orderbook
 }
 
 // NewOrderbook creates a new orderbook matching engine
 func NewOrderbook() *Orderbook {
 	ob := &Orderbook{
+		mu:      new(sync.RWMutex),
 		bids:    newOrderHeap(true),  // Max heap
 		asks:    newOrderHeap(false), // Min heap
 		orders:  make(map[string]*Order),
@@ -44,6 +83,65 @@ func (ob *Orderbook) SetTradeCallback(fn func(*Trade)) {
 	ob.onTrade = fn
 }
 
+// SetMinSpread sets the minimum distance a user's bid must keep below their
+// own asks (and vice versa). Zero disables the check.
+func (ob *Orderbook) SetMinSpread(bps uint64) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.minSpread = bps
+}
+
+// checkOwnSpread rejects an order that quotes within minSpread of the same
+// user's resting orders on the other side (must hold lock)
+func (ob *Orderbook) checkOwnSpread(order *Order) error {
+	if ob.minSpread == 0 || order.IsMarket() {
+		return nil
+	}
+	for _, resting := range ob.orders {
+		if resting.UserID != order.UserID || resting.Side == order.Side {
+			continue
+		}
+		bid, ask := order.Price, resting.Price
+		if !order.IsBuy() {
+			bid, ask = resting.Price, order.Price
+		}
+		if ask < bid+ob.minSpread {
+			return ErrSpreadTooNarrow
+		}
+	}
+	return nil
+}
+
+// SetOrderEventCallback sets the callback for level-3 order events
+func (ob *Orderbook) SetOrderEventCallback(fn func(OrderEvent)) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.onOrderEvent = fn
+}
+
+// emitOrderEvent records a change to a resting order (must hold lock)
+func (ob *Orderbook) emitOrderEvent(eventType OrderEventType, order *Order) {
+	ob.eventSeq++
+	event := OrderEvent{
+		Seq:          ob.eventSeq,
+		Type:         eventType,
+		MarketID:     order.MarketID,
+		OutcomeID:    order.OutcomeID,
+		OrderID:      order.ID,
+		Side:         order.Side,
+		Price:        order.Price,
+		RemainingQty: order.RemainingQty(),
+		Status:       order.Status,
+		Timestamp:    time.Now(),
+	}
+	ob.events.append(event)
+	ob.journal(eventType, order, event.Timestamp)
+	if ob.onOrderEvent != nil {
+		ob.onOrderEvent(event)
+	}
+	ob.emitOrderUpdate(order)
+}
+
 // PlaceOrder adds a new order and attempts to match it
 func (ob *Orderbook) PlaceOrder(order *Order) ([]*Trade, error) {
 	if order.Price > 10000 {
@@ -56,50 +154,151 @@ func (ob *Orderbook) PlaceOrder(order *Order) ([]*Trade, error) {
 	ob.mu.Lock()
 	defer ob.mu.Unlock()
 
-	var trades []*Trade
+	if err := ob.checkOrderSize(order); err != nil {
+		return nil, err
+	}
+	if err := ob.checkOwnSpread(order); err != nil {
+		return nil, err
+	}
 
-	if order.IsBuy() {
-		trades = ob.matchBuy(order)
-	} else {
-		trades = ob.matchSell(order)
+	// Post-only: reject rather than take liquidity
+	if order.PostOnly && ob.matchableQty(order) > 0 {
+		return nil, ErrWouldCross
 	}
 
-	// If order is not fully filled, add to book
-	if order.RemainingQty() > 0 && order.Status != StatusCancelled {
-		ob.orders[order.ID] = order
+	// An expired opening auction uncrosses before the new order is handled
+	trades := ob.uncrossIfDue()
+
+	// Fill-or-kill: check the whole quantity is available before touching the book
+	if order.TimeInForce == TimeInForceFOK && (ob.inAuction || ob.matchableQty(order) < order.Quantity) {
+		order.Cancel()
+	}
+
+	if !ob.inAuction && order.Status != StatusCancelled {
 		if order.IsBuy() {
-			heap.Push(ob.bids, order)
+			trades = append(trades, ob.matchBuy(order)...)
 		} else {
-			heap.Push(ob.asks, order)
+			trades = append(trades, ob.matchSell(order)...)
+		}
+	}
+
+	// A market or IOC order's remainder is cancelled rather than rested
+	if (order.IsMarket() || order.TimeInForce == TimeInForceIOC) && order.RemainingQty() > 0 {
+		order.Cancel()
+	}
+
+	// If order is not fully filled, add to book
+	if order.RemainingQty() > 0 && order.Status != StatusCancelled {
+		ob.rest(order)
+	}
+	ob.emitOrderUpdate(order)
+
+	ob.notifyTrades(trades)
+
+	return trades, nil
+}
+
+// rest adds an order to its side of the book (must hold lock)
+func (ob *Orderbook) rest(order *Order) {
+	ob.orders[order.ID] = order
+	if order.IsBuy() {
+		heap.Push(ob.bids, order)
+	} else {
+		heap.Push(ob.asks, order)
+	}
+	ob.emitOrderEvent(OrderAdded, order)
+}
+
+// matchableQty returns how much of an order could fill against the book (and,
+// for buys, the complement book) right now, without changing anything
+// (must hold lock)
+func (ob *Orderbook) matchableQty(order *Order) uint64 {
+	opposite := ob.asks
+	if !order.IsBuy() {
+		opposite = ob.bids
+	}
+
+	now := time.Now()
+	qty := ob.complementQty(order)
+	for _, resting := range opposite.orders {
+		if ob.preventsSelfTrade(order, resting) || resting.Expired(now) {
+			continue
+		}
+		crosses := order.IsMarket() ||
+			(order.IsBuy() && order.Price >= resting.Price) ||
+			(!order.IsBuy() && order.Price <= resting.Price)
+		if crosses {
+			qty += resting.RemainingQty()
 		}
 	}
+	return qty
+}
 
-	// Notify trades
+// notifyTrades records trades and fires the trade callback. Empty trades
+// are never recorded (must hold lock).
+func (ob *Orderbook) notifyTrades(trades []*Trade) {
 	for _, trade := range trades {
+		if trade.Quantity == 0 {
+			continue
+		}
 		ob.history.Add(trade)
 		if ob.onTrade != nil {
 			ob.onTrade(trade)
 		}
 	}
-
-	return trades, nil
 }
 
 // matchBuy matches a buy order against the ask book
 func (ob *Orderbook) matchBuy(buy *Order) []*Trade {
 	var trades []*Trade
-
-	for ob.asks.Len() > 0 && buy.RemainingQty() > 0 {
+	var skipped []skippedOrder
+	defer func() { restoreSkipped(skipped) }()
+	now := time.Now()
+
+	for buy.RemainingQty() > 0 && buy.Status != StatusCancelled {
+		if resting := ob.complementBid(buy); resting != nil {
+			if ob.complement.dropIfDead(ob.complement.bids, resting) ||
+				ob.complement.dropIfExpired(ob.complement.bids, resting, now) {
+				continue
+			}
+			if ob.avoidSelfTrade(ob.complement, ob.complement.bids, buy, resting, &skipped) {
+				continue
+			}
+			trade := ob.fillComplement(buy, resting)
+			if trade == nil {
+				break
+			}
+			trades = append(trades, trade)
+			continue
+		}
+		if ob.asks.Len() == 0 {
+			break
+		}
 		bestAsk := ob.asks.Peek()
+		if ob.dropIfDead(ob.asks, bestAsk) || ob.dropIfExpired(ob.asks, bestAsk, now) {
+			continue
+		}
 
-		// Price check: buy price must be >= ask price
-		if buy.Price < bestAsk.Price {
+		// Price check: buy price must be >= ask price (market orders take any price)
+		if !buy.IsMarket() && buy.Price < bestAsk.Price {
 			break
 		}
 
+		if ob.avoidSelfTrade(ob, ob.asks, buy, bestAsk, &skipped) {
+			continue
+		}
+
+		if ob.allocMode == AllocationProRata {
+			trades = append(trades, ob.fillLevelProRata(buy, ob.asks, bestAsk.Price)...)
+			continue
+		}
+
 		// Match at the ask price (price improvement for buyer)
 		matchQty := min(buy.RemainingQty(), bestAsk.RemainingQty())
 		matchPrice := bestAsk.Price
+		if matchQty == 0 {
+			break // Never emit an empty trade
+		}
 
 		buy.Fill(matchQty)
 		bestAsk.Fill(matchQty)
@@ -111,6 +310,9 @@ func (ob *Orderbook) matchBuy(buy *Order) []*Trade {
 		if bestAsk.RemainingQty() == 0 {
 			heap.Pop(ob.asks)
 			delete(ob.orders, bestAsk.ID)
+			ob.emitOrderEvent(OrderRemoved, bestAsk)
+		} else {
+			ob.emitOrderEvent(OrderModified, bestAsk)
 		}
 	}
 
@@ -120,18 +322,36 @@ func (ob *Orderbook) matchBuy(buy *Order) []*Trade {
 // matchSell matches a sell order against the bid book
 func (ob *Orderbook) matchSell(sell *Order) []*Trade {
 	var trades []*Trade
+	var skipped []skippedOrder
+	defer func() { restoreSkipped(skipped) }()
+	now := time.Now()
 
-	for ob.bids.Len() > 0 && sell.RemainingQty() > 0 {
+	for ob.bids.Len() > 0 && sell.RemainingQty() > 0 && sell.Status != StatusCancelled {
 		bestBid := ob.bids.Peek()
+		if ob.dropIfDead(ob.bids, bestBid) || ob.dropIfExpired(ob.bids, bestBid, now) {
+			continue
+		}
 
-		// Price check: sell price must be <= bid price
-		if sell.Price > bestBid.Price {
+		// Price check: sell price must be <= bid price (market orders take any price)
+		if !sell.IsMarket() && sell.Price > bestBid.Price {
 			break
 		}
 
+		if ob.avoidSelfTrade(ob, ob.bids, sell, bestBid, &skipped) {
+			continue
+		}
+
+		if ob.allocMode == AllocationProRata {
+			trades = append(trades, ob.fillLevelProRata(sell, ob.bids, bestBid.Price)...)
+			continue
+		}
+
 		// Match at the bid price (price improvement for seller)
 		matchQty := min(sell.RemainingQty(), bestBid.RemainingQty())
 		matchPrice := bestBid.Price
+		if matchQty == 0 {
+			break // Never emit an empty trade
+		}
 
 		sell.Fill(matchQty)
 		bestBid.Fill(matchQty)
@@ -143,12 +363,32 @@ func (ob *Orderbook) matchSell(sell *Order) []*Trade {
 		if bestBid.RemainingQty() == 0 {
 			heap.Pop(ob.bids)
 			delete(ob.orders, bestBid.ID)
+			ob.emitOrderEvent(OrderRemoved, bestBid)
+		} else {
+			ob.emitOrderEvent(OrderModified, bestBid)
 		}
 	}
 
 	return trades
 }
 
+// dropIfDead discards resting, the top of h, if it can no longer trade:
+// cancelled, or with nothing left to fill. Such an order should already be
+// off the book, but matching against one would produce an empty trade. It
+// reports whether it did, in which case the caller must look at the book
+// again (must hold lock).
+func (ob *Orderbook) dropIfDead(h *orderHeap, resting *Order) bool {
+	if resting.Status != StatusCancelled && resting.RemainingQty() > 0 {
+		return false
+	}
+	h.remove(resting)
+	if ob.orders[resting.ID] == resting {
+		delete(ob.orders, resting.ID)
+		ob.emitOrderEvent(OrderRemoved, resting)
+	}
+	return true
+}
+
 // CancelOrder cancels an order by ID
 func (ob *Orderbook) CancelOrder(orderID string) error {
 	ob.mu.Lock()
@@ -159,16 +399,44 @@ func (ob *Orderbook) CancelOrder(orderID string) error {
 		return ErrOrderNotFound
 	}
 
+	if order.IsBuy() {
+		ob.bids.remove(order)
+	} else {
+		ob.asks.remove(order)
+	}
 	order.Cancel()
 	delete(ob.orders, orderID)
-
-	// Note: Order stays in heap but will be skipped during matching
-	// A cleaner approach would be to rebuild heaps, but this is O(n)
+	ob.emitOrderEvent(OrderRemoved, order)
 
 	return nil
 }
 
-// GetOrder returns an order by ID
+// CancelUserOrders cancels every resting order of a user and returns how
+// many were cancelled
+func (ob *Orderbook) CancelUserOrders(userID string) int {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+
+	cancelled := 0
+	for id, order := range ob.orders {
+		if order.UserID != userID {
+			continue
+		}
+		if order.IsBuy() {
+			ob.bids.remove(order)
+		} else {
+			ob.asks.remove(order)
+		}
+		order.Cancel()
+		delete(ob.orders, id)
+		ob.emitOrderEvent(OrderRemoved, order)
+		cancelled++
+	}
+	return cancelled
+}
+
+// GetOrder returns a copy of a resting order by ID, taken under the book's
+// lock so it can be read while matching goes on
 func (ob *Orderbook) GetOrder(orderID string) (*Order, error) {
 	ob.mu.RLock()
 	defer ob.mu.RUnlock()
@@ -177,7 +445,8 @@ func (ob *Orderbook) GetOrder(orderID string) (*Order, error) {
 	if !exists {
 		return nil, ErrOrderNotFound
 	}
-	return order, nil
+	copied := *order
+	return &copied, nil
 }
 
 // Snapshot returns the current state of the orderbook
@@ -203,11 +472,80 @@ func (ob *Orderbook) GetSnapshot() OrderbookSnapshot {
 	return OrderbookSnapshot{Bids: bids, Asks: asks}
 }
 
+// GetDepth returns up to levels of the best price levels on each side (0 = all)
+func (ob *Orderbook) GetDepth(levels int) OrderbookSnapshot {
+	snapshot := ob.GetSnapshot()
+	if levels > 0 {
+		snapshot.Bids = snapshot.Bids[:min(levels, len(snapshot.Bids))]
+		snapshot.Asks = snapshot.Asks[:min(levels, len(snapshot.Asks))]
+	}
+	return snapshot
+}
+
+// BestBid returns the highest bid price and the quantity resting there.
+// ok is false if there are no bids.
+func (ob *Orderbook) BestBid() (level OrderLevel, ok bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return topLevel(ob.bids)
+}
+
+// BestAsk returns the lowest ask price and the quantity resting there.
+// ok is false if there are no asks.
+func (ob *Orderbook) BestAsk() (level OrderLevel, ok bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return topLevel(ob.asks)
+}
+
+// topLevel aggregates the orders at the top price of a heap (must hold lock)
+func topLevel(h *orderHeap) (OrderLevel, bool) {
+	top := h.Peek()
+	if top == nil {
+		return OrderLevel{}, false
+	}
+	level := OrderLevel{Price: top.Price}
+	for _, order := range h.orders {
+		if order.Price == top.Price && order.RemainingQty() > 0 {
+			level.Quantity += order.RemainingQty()
+			level.Count++
+		}
+	}
+	return level, level.Count > 0
+}
+
+// SweepPrice returns the worst price a market order of the given side and
+// quantity would trade at right now, or 0 if there is nothing to trade
+// against. A buy also counts the complement book's bids, which it can fill by
+// minting (see complement.go).
+func (ob *Orderbook) SweepPrice(side Side, qty uint64) uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var levels []OrderLevel
+	if side == SideSell {
+		levels = ob.aggregateLevels(ob.bids, true)
+	} else {
+		levels = append(ob.aggregateLevels(ob.asks, false), ob.impliedAsks()...)
+		sortOrderLevelsAsc(levels)
+	}
+
+	var price uint64
+	for _, level := range levels {
+		price = level.Price
+		if level.Quantity >= qty {
+			break
+		}
+		qty -= level.Quantity
+	}
+	return price
+}
+
 func (ob *Orderbook) aggregateLevels(h *orderHeap, reverse bool) []OrderLevel {
 	levels := make(map[uint64]*OrderLevel)
 
 	for _, order := range h.orders {
-		if order.Status == StatusCancelled || order.RemainingQty() == 0 {
+		if order.RemainingQty() == 0 {
 			continue
 		}
 
@@ -238,11 +576,122 @@ func (ob *Orderbook) aggregateLevels(h *orderHeap, reverse bool) []OrderLevel {
 	return result
 }
 
+// CostToPrice returns the quantity and notional needed to sweep the opposing
+// side of the book up to (buy) or down to (sell) targetPrice. If liquidity runs
+// out first, the totals cover the whole opposing side. The book is not mutated.
+func (ob *Orderbook) CostToPrice(side Side, targetPrice uint64) (quantity, notional uint64) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var levels []OrderLevel
+	if side == SideBuy {
+		levels = ob.aggregateLevels(ob.asks, false)
+	} else {
+		levels = ob.aggregateLevels(ob.bids, true)
+	}
+
+	for _, level := range levels {
+		if side == SideBuy && level.Price > targetPrice {
+			break
+		}
+		if side == SideSell && level.Price < targetPrice {
+			break
+		}
+		quantity += level.Quantity
+		notional += Notional(level.Price, level.Quantity)
+	}
+
+	return quantity, notional
+}
+
+// GetL3Snapshot returns every resting order in priority order, anonymized
+func (ob *Orderbook) GetL3Snapshot() L3Snapshot {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	return L3Snapshot{
+		Seq:  ob.eventSeq,
+		Bids: l3Orders(ob.bids),
+		Asks: l3Orders(ob.asks),
+	}
+}
+
+func l3Orders(h *orderHeap) []L3Order {
+	live := make([]*Order, 0, len(h.orders))
+	for _, order := range h.orders {
+		if order.RemainingQty() == 0 {
+			continue
+		}
+		live = append(live, order)
+	}
+	sort.Slice(live, func(i, j int) bool {
+		if live[i].Price == live[j].Price {
+			return live[i].SequenceNum < live[j].SequenceNum
+		}
+		if h.isMax {
+			return live[i].Price > live[j].Price
+		}
+		return live[i].Price < live[j].Price
+	})
+
+	result := make([]L3Order, len(live))
+	for i, order := range live {
+		result[i] = L3Order{
+			OrderID:      order.ID,
+			Side:         order.Side,
+			Price:        order.Price,
+			RemainingQty: order.RemainingQty(),
+			SequenceNum:  order.SequenceNum,
+		}
+	}
+	return result
+}
+
+// OpenQuantity returns the unfilled quantity of a user's resting orders on one side
+func (ob *Orderbook) OpenQuantity(userID string, side Side) uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var total uint64
+	for _, order := range ob.orders {
+		if order.UserID == userID && order.Side == side {
+			total += order.RemainingQty()
+		}
+	}
+	return total
+}
+
+// OpenBuyNotional returns the USDC (basis points) needed to fill a user's
+// resting buy orders at their prices
+func (ob *Orderbook) OpenBuyNotional(userID string) uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var total uint64
+	for _, order := range ob.orders {
+		if order.UserID == userID && order.IsBuy() {
+			total += Notional(order.Price, order.RemainingQty())
+		}
+	}
+	return total
+}
+
 // RecentTrades returns recent trades
 func (ob *Orderbook) RecentTrades(n int) []*Trade {
 	return ob.history.Recent(n)
 }
 
+// QueryTrades returns a page of the book's trade history, newest first (see
+// TradeHistory.Query)
+func (ob *Orderbook) QueryTrades(since, until time.Time, limit, offset int) []*Trade {
+	return ob.history.Query(since, until, limit, offset)
+}
+
+// OrderTrades returns the book's retained trades for an order, oldest first
+func (ob *Orderbook) OrderTrades(orderID string) []*Trade {
+	return ob.history.ForOrder(orderID)
+}
+
 // --- Order Heap Implementation ---
 
 type orderHeap struct {
@@ -262,14 +711,6 @@ func (h *orderHeap) Len() int { return len(h.orders) }
 func (h *orderHeap) Less(i, j int) bool {
 	oi, oj := h.orders[i], h.orders[j]
 
-	// Skip cancelled orders
-	if oi.Status == StatusCancelled {
-		return false
-	}
-	if oj.Status == StatusCancelled {
-		return true
-	}
-
 	if oi.Price == oj.Price {
 		// Same price: earlier order has priority (FIFO)
 		return oi.SequenceNum < oj.SequenceNum
@@ -283,20 +724,35 @@ func (h *orderHeap) Less(i, j int) bool {
 
 func (h *orderHeap) Swap(i, j int) {
 	h.orders[i], h.orders[j] = h.orders[j], h.orders[i]
+	h.orders[i].heapIndex = i
+	h.orders[j].heapIndex = j
 }
 
 func (h *orderHeap) Push(x any) {
-	h.orders = append(h.orders, x.(*Order))
+	order := x.(*Order)
+	order.heapIndex = len(h.orders)
+	h.orders = append(h.orders, order)
 }
 
 func (h *orderHeap) Pop() any {
 	old := h.orders
 	n := len(old)
 	order := old[n-1]
+	old[n-1] = nil // Let the order be collected
+	order.heapIndex = -1
 	h.orders = old[0 : n-1]
 	return order
 }
 
+// remove takes an order out of the heap in O(log n) using its tracked index
+func (h *orderHeap) remove(order *Order) {
+	i := order.heapIndex
+	if i < 0 || i >= len(h.orders) || h.orders[i] != order {
+		return // Not in this heap
+	}
+	heap.Remove(h, i)
+}
+
 func (h *orderHeap) Peek() *Order {
 	if len(h.orders) == 0 {
 		return nil
@@ -307,21 +763,9 @@ func (h *orderHeap) Peek() *Order {
 // --- Sorting helpers ---
 
 func sortOrderLevelsDesc(levels []OrderLevel) {
-	for i := 0; i < len(levels)-1; i++ {
-		for j := i + 1; j < len(levels); j++ {
-			if levels[i].Price < levels[j].Price {
-				levels[i], levels[j] = levels[j], levels[i]
-			}
-		}
-	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Price > levels[j].Price })
 }
 
 func sortOrderLevelsAsc(levels []OrderLevel) {
-	for i := 0; i < len(levels)-1; i++ {
-		for j := i + 1; j < len(levels); j++ {
-			if levels[i].Price > levels[j].Price {
-				levels[i], levels[j] = levels[j], levels[i]
-			}
-		}
-	}
+	sort.Slice(levels, func(i, j int) bool { return levels[i].Price < levels[j].Price })
 }
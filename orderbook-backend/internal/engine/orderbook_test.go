@@ -0,0 +1,691 @@
+package engine
+
+import (
+	"math/rand"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// place adds a limit order to a book and fails the test on error
+func place(t *testing.T, ob *Orderbook, userID string, side Side, price, qty uint64) (*Order, []*Trade) {
+	t.Helper()
+	order := NewOrder(userID, "m1", OutcomeYES, side, price, qty)
+	trades, err := ob.PlaceOrder(order)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return order, trades
+}
+
+func TestCostToPrice(t *testing.T) {
+	ob := NewOrderbook()
+	place(t, ob, "mm", SideSell, 6000, 10)
+	place(t, ob, "mm", SideSell, 6500, 20)
+	place(t, ob, "mm", SideSell, 7000, 30)
+	place(t, ob, "mm", SideSell, 7500, 40)
+	place(t, ob, "mm", SideBuy, 5000, 5)
+	place(t, ob, "mm", SideBuy, 4000, 15)
+
+	tests := []struct {
+		name     string
+		side     Side
+		target   uint64
+		quantity uint64
+		notional uint64
+	}{
+		{"buy to 70%", SideBuy, 7000, 60, 6000*10 + 6500*20 + 7000*30},
+		{"buy between levels", SideBuy, 6900, 30, 6000*10 + 6500*20},
+		{"buy below the best ask", SideBuy, 5500, 0, 0},
+		{"buy past all liquidity", SideBuy, 9900, 100, 6000*10 + 6500*20 + 7000*30 + 7500*40},
+		{"sell to 40%", SideSell, 4000, 20, 5000*5 + 4000*15},
+		{"sell above the best bid", SideSell, 5500, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			quantity, notional := ob.CostToPrice(tt.side, tt.target)
+			if quantity != tt.quantity || notional != tt.notional {
+				t.Fatalf("CostToPrice = (%d, %d), want (%d, %d)", quantity, notional, tt.quantity, tt.notional)
+			}
+		})
+	}
+
+	// The book is left as it was
+	snapshot := ob.GetSnapshot()
+	if len(snapshot.Asks) != 4 || len(snapshot.Bids) != 2 {
+		t.Fatalf("book changed: %d asks, %d bids", len(snapshot.Asks), len(snapshot.Bids))
+	}
+}
+
+func TestL3EventSequence(t *testing.T) {
+	ob := NewOrderbook()
+	var events []OrderEvent
+	ob.SetOrderEventCallback(func(e OrderEvent) { events = append(events, e) })
+
+	ask, _ := place(t, ob, "maker", SideSell, 5000, 10)
+	place(t, ob, "taker", SideBuy, 5000, 4)
+	if err := ob.CancelOrder(ask.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		typ       OrderEventType
+		remaining uint64
+		status    OrderStatus
+	}{
+		{OrderAdded, 10, StatusOpen},
+		{OrderModified, 6, StatusPartial},
+		{OrderRemoved, 6, StatusCancelled},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("%d events, want %d: %+v", len(events), len(want), events)
+	}
+	for i, e := range events {
+		if e.Seq != uint64(i+1) || e.OrderID != ask.ID || e.Type != want[i].typ ||
+			e.RemainingQty != want[i].remaining || e.Status != want[i].status || e.Price != 5000 {
+			t.Errorf("event %d = %+v, want seq %d %s remaining %d %s", i, e, i+1, want[i].typ, want[i].remaining, want[i].status)
+		}
+	}
+
+	if snap := ob.GetL3Snapshot(); snap.Seq != 3 || len(snap.Asks) != 0 || len(snap.Bids) != 0 {
+		t.Fatalf("snapshot = %+v, want an empty book at seq 3", snap)
+	}
+}
+
+func TestL3SnapshotOrdersByPriority(t *testing.T) {
+	ob := NewOrderbook()
+	first, _ := place(t, ob, "a", SideBuy, 5000, 1)
+	second, _ := place(t, ob, "b", SideBuy, 5000, 2)
+	better, _ := place(t, ob, "c", SideBuy, 5100, 3)
+
+	snap := ob.GetL3Snapshot()
+	if snap.Seq != 3 || len(snap.Bids) != 3 {
+		t.Fatalf("snapshot = %+v", snap)
+	}
+	for i, id := range []string{better.ID, first.ID, second.ID} {
+		if snap.Bids[i].OrderID != id {
+			t.Errorf("bid %d = %s, want %s", i, snap.Bids[i].OrderID, id)
+		}
+	}
+}
+
+func TestMinSpreadRejectsNarrowingOwnQuote(t *testing.T) {
+	tests := []struct {
+		name  string
+		side  Side
+		price uint64
+		err   error
+	}{
+		{"bid too close to own ask", SideBuy, 5450, ErrSpreadTooNarrow},
+		{"bid at the minimum", SideBuy, 5400, nil},
+		{"ask too close to own bid", SideSell, 4550, ErrSpreadTooNarrow},
+		{"ask at the minimum", SideSell, 4600, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			ob.SetMinSpread(100)
+			place(t, ob, "mm", SideSell, 5500, 10)
+			place(t, ob, "mm", SideBuy, 4500, 10)
+
+			_, err := ob.PlaceOrder(NewOrder("mm", "m1", OutcomeYES, tt.side, tt.price, 1))
+			if err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	// Other users may quote inside the maker's spread
+	ob := NewOrderbook()
+	ob.SetMinSpread(100)
+	place(t, ob, "mm", SideSell, 5500, 10)
+	if _, err := ob.PlaceOrder(NewOrder("other", "m1", OutcomeYES, SideBuy, 5450, 1)); err != nil {
+		t.Fatalf("other user's bid: %v", err)
+	}
+}
+
+func TestFillOrKill(t *testing.T) {
+	tests := []struct {
+		name   string
+		side   Side
+		price  uint64
+		qty    uint64
+		filled uint64 // 0 = killed untouched
+	}{
+		{"buy fills exactly", SideBuy, 6500, 30, 30},
+		{"buy fills within", SideBuy, 6500, 25, 25},
+		{"buy short by one is killed", SideBuy, 6500, 31, 0},
+		{"buy priced below the second level is killed", SideBuy, 6400, 30, 0},
+		{"buy at the best level only", SideBuy, 6000, 10, 10},
+		{"buy with far more than enough", SideBuy, 7000, 5, 5},
+		{"sell fills across levels", SideSell, 4000, 20, 20},
+		{"sell beyond the bids is killed", SideSell, 4000, 21, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			place(t, ob, "mm", SideSell, 6000, 10)
+			place(t, ob, "mm", SideSell, 6500, 20)
+			place(t, ob, "mm", SideBuy, 5000, 5)
+			place(t, ob, "mm", SideBuy, 4000, 15)
+			before := ob.GetSnapshot()
+
+			order := NewOrder("taker", "m1", OutcomeYES, tt.side, tt.price, tt.qty)
+			order.TimeInForce = TimeInForceFOK
+			trades, err := ob.PlaceOrder(order)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var traded uint64
+			for _, trade := range trades {
+				traded += trade.Quantity
+			}
+			if traded != tt.filled || order.FilledQty != tt.filled {
+				t.Fatalf("traded %d (order filled %d), want %d", traded, order.FilledQty, tt.filled)
+			}
+			if tt.filled == 0 {
+				if order.Status != StatusCancelled {
+					t.Errorf("status = %s, want cancelled", order.Status)
+				}
+				if after := ob.GetSnapshot(); !reflect.DeepEqual(after.Bids, before.Bids) || !reflect.DeepEqual(after.Asks, before.Asks) {
+					t.Errorf("killed order changed the book: %+v -> %+v", before, after)
+				}
+			} else if order.Status != StatusFilled {
+				t.Errorf("status = %s, want filled", order.Status)
+			}
+			if _, err := ob.GetOrder(order.ID); err == nil {
+				t.Error("FOK order rests on the book")
+			}
+		})
+	}
+}
+
+func TestFillOrKillDuringAuctionIsKilled(t *testing.T) {
+	ob := NewOrderbook()
+	place(t, ob, "mm", SideSell, 6000, 10)
+	ob.StartAuction(time.Now().Add(time.Hour))
+
+	order := NewOrder("taker", "m1", OutcomeYES, SideBuy, 6000, 5)
+	order.TimeInForce = TimeInForceFOK
+	if trades, err := ob.PlaceOrder(order); err != nil || len(trades) != 0 {
+		t.Fatalf("trades %v, err %v: want none", trades, err)
+	}
+	if order.Status != StatusCancelled {
+		t.Fatalf("status = %s, want cancelled", order.Status)
+	}
+}
+
+func BenchmarkPlaceRestingOrder(b *testing.B) {
+	ob := NewOrderbook()
+	for i := 0; i < b.N; i++ {
+		order := NewOrder("maker", "m1", OutcomeYES, SideBuy, uint64(1+i%9000), 10)
+		if _, err := ob.PlaceOrder(order); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMatchRestingAsk(b *testing.B) {
+	ob := NewOrderbook()
+	for i := 0; i < b.N; i++ {
+		// Refill five ask levels whenever takers have emptied the book
+		if ob.asks.Len() == 0 {
+			b.StopTimer()
+			for p := uint64(6000); p < 6500; p += 100 {
+				if _, err := ob.PlaceOrder(NewOrder("maker", "m1", OutcomeYES, SideSell, p, 10)); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.StartTimer()
+		}
+		if _, err := ob.PlaceOrder(NewOrder("taker", "m1", OutcomeYES, SideBuy, 6500, 10)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestMarketOrderCancelsRemainder(t *testing.T) {
+	tests := []struct {
+		name   string
+		book   bool // Rest asks 6000x10 and 6500x20 and bids 5000x5 and 4000x15
+		side   Side
+		qty    uint64
+		filled uint64
+	}{
+		{"buy into an empty book", false, SideBuy, 10, 0},
+		{"sell into an empty book", false, SideSell, 10, 0},
+		{"buy exhausts the asks", true, SideBuy, 50, 30},
+		{"sell exhausts the bids", true, SideSell, 25, 20},
+		{"buy fills completely", true, SideBuy, 15, 15},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			if tt.book {
+				place(t, ob, "mm", SideSell, 6000, 10)
+				place(t, ob, "mm", SideSell, 6500, 20)
+				place(t, ob, "mm", SideBuy, 5000, 5)
+				place(t, ob, "mm", SideBuy, 4000, 15)
+			}
+
+			order := NewMarketOrder("taker", "m1", OutcomeYES, tt.side, tt.qty)
+			trades, err := ob.PlaceOrder(order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var traded uint64
+			for _, trade := range trades {
+				traded += trade.Quantity
+			}
+			if traded != tt.filled || order.FilledQty != tt.filled {
+				t.Fatalf("traded %d (order filled %d), want %d", traded, order.FilledQty, tt.filled)
+			}
+
+			wantStatus := StatusCancelled
+			if tt.filled == tt.qty {
+				wantStatus = StatusFilled
+			}
+			if order.Status != wantStatus {
+				t.Fatalf("status = %s, want %s", order.Status, wantStatus)
+			}
+			if _, err := ob.GetOrder(order.ID); err != ErrOrderNotFound {
+				t.Fatalf("market order rests on the book: %v", err)
+			}
+		})
+	}
+}
+
+func TestImmediateOrCancelNeverRests(t *testing.T) {
+	tests := []struct {
+		name   string
+		price  uint64
+		qty    uint64
+		filled uint64
+	}{
+		{"no cross", 5500, 10, 0},
+		{"partial", 6000, 15, 10},
+		{"across levels", 6500, 40, 30},
+		{"complete", 6500, 12, 12},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			place(t, ob, "mm", SideSell, 6000, 10)
+			place(t, ob, "mm", SideSell, 6500, 20)
+			place(t, ob, "mm", SideBuy, 5000, 5)
+
+			order := NewOrder("taker", "m1", OutcomeYES, SideBuy, tt.price, tt.qty)
+			order.TimeInForce = TimeInForceIOC
+			trades, err := ob.PlaceOrder(order)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var traded uint64
+			for _, trade := range trades {
+				traded += trade.Quantity
+			}
+			if traded != tt.filled {
+				t.Fatalf("traded %d, want %d", traded, tt.filled)
+			}
+			if tt.filled < tt.qty && order.Status != StatusCancelled {
+				t.Fatalf("status = %s, want cancelled", order.Status)
+			}
+
+			// Only the original bid may be left on the buy side
+			bids := ob.GetSnapshot().Bids
+			if len(bids) != 1 || bids[0].Price != 5000 || bids[0].Quantity != 5 {
+				t.Fatalf("bids = %+v, want only 5000x5", bids)
+			}
+		})
+	}
+}
+
+func TestPostOnly(t *testing.T) {
+	tests := []struct {
+		name  string
+		side  Side
+		price uint64
+		err   error
+	}{
+		{"buy at the best ask", SideBuy, 6000, ErrWouldCross},
+		{"buy through the ask", SideBuy, 7000, ErrWouldCross},
+		{"buy inside the spread", SideBuy, 5500, nil},
+		{"sell at the best bid", SideSell, 5000, ErrWouldCross},
+		{"sell inside the spread", SideSell, 5999, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			place(t, ob, "mm", SideSell, 6000, 10)
+			place(t, ob, "mm", SideBuy, 5000, 10)
+			before := ob.GetSnapshot()
+
+			order := NewOrder("maker", "m1", OutcomeYES, tt.side, tt.price, 5)
+			order.PostOnly = true
+			trades, err := ob.PlaceOrder(order)
+			if err != tt.err || len(trades) != 0 {
+				t.Fatalf("err = %v, trades %+v, want %v and no trades", err, trades, tt.err)
+			}
+
+			_, restErr := ob.GetOrder(order.ID)
+			if tt.err != nil {
+				if restErr == nil || !reflect.DeepEqual(ob.GetSnapshot(), before) {
+					t.Fatal("rejected post-only order changed the book")
+				}
+			} else if restErr != nil {
+				t.Fatalf("accepted post-only order is not resting: %v", restErr)
+			}
+		})
+	}
+}
+
+func TestCancelShrinksHeap(t *testing.T) {
+	ob := NewOrderbook()
+	orders := make([]*Order, 1000)
+	for i := range orders {
+		orders[i], _ = place(t, ob, "mm", SideBuy, uint64(1+i%500), 1)
+	}
+
+	// Cancel every other order; each must leave the heap, not linger
+	for i := 0; i < len(orders); i += 2 {
+		if err := ob.CancelOrder(orders[i].ID); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if ob.bids.Len() != 500 || len(ob.orders) != 500 {
+		t.Fatalf("heap %d, index %d after cancelling half, want 500", ob.bids.Len(), len(ob.orders))
+	}
+	for i, order := range ob.bids.orders {
+		if order.heapIndex != i || order.Status == StatusCancelled {
+			t.Fatalf("heap slot %d holds %+v", i, order)
+		}
+	}
+
+	// The best bid is a live order and priority is intact
+	best := ob.bids.Peek()
+	if best.Price != 500 || best.ID != orders[499].ID {
+		t.Fatalf("best bid %s at %d, want order 499 at 500", best.ID, best.Price)
+	}
+	if err := ob.CancelOrder(orders[0].ID); err == nil {
+		t.Fatal("cancelled an order twice")
+	}
+}
+
+// BenchmarkCancelOrder cancels from books of growing size; the cost per
+// cancel should grow with log n
+func BenchmarkCancelOrder(b *testing.B) {
+	for _, n := range []int{1000, 10000, 100000} {
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			ob := NewOrderbook()
+			ids := make([]string, n)
+			rest := func(i int) {
+				order := NewOrder("mm", "m1", OutcomeYES, SideBuy, uint64(1+i%9999), 1)
+				if _, err := ob.PlaceOrder(order); err != nil {
+					b.Fatal(err)
+				}
+				ids[i] = order.ID
+			}
+			for i := range ids {
+				rest(i)
+			}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := ob.CancelOrder(ids[i%n]); err != nil {
+					b.Fatal(err)
+				}
+				// Replace the order so the book keeps its size
+				b.StopTimer()
+				rest(i % n)
+				b.StartTimer()
+			}
+		})
+	}
+}
+
+// swapSortLevelsDesc is the O(n^2) sort that sortOrderLevelsDesc replaced,
+// kept to benchmark against
+func swapSortLevelsDesc(levels []OrderLevel) {
+	for i := 0; i < len(levels)-1; i++ {
+		for j := i + 1; j < len(levels); j++ {
+			if levels[i].Price < levels[j].Price {
+				levels[i], levels[j] = levels[j], levels[i]
+			}
+		}
+	}
+}
+
+// shuffledLevels returns n price levels in a fixed random order
+func shuffledLevels(n int) []OrderLevel {
+	rng := rand.New(rand.NewSource(1))
+	levels := make([]OrderLevel, n)
+	for i, p := range rng.Perm(n) {
+		levels[i] = OrderLevel{Price: uint64(p + 1), Quantity: 1, Count: 1}
+	}
+	return levels
+}
+
+func TestSortOrderLevels(t *testing.T) {
+	levels := shuffledLevels(1000)
+	want := append([]OrderLevel(nil), levels...)
+	swapSortLevelsDesc(want)
+	sortOrderLevelsDesc(levels)
+	if !reflect.DeepEqual(levels, want) {
+		t.Fatal("descending sort disagrees with the reference sort")
+	}
+	sortOrderLevelsAsc(levels)
+	for i := 1; i < len(levels); i++ {
+		if levels[i-1].Price >= levels[i].Price {
+			t.Fatalf("ascending sort out of order at %d", i)
+		}
+	}
+}
+
+func BenchmarkSortLevels(b *testing.B) {
+	levels := shuffledLevels(1000)
+	work := make([]OrderLevel, len(levels))
+	for _, bm := range []struct {
+		name string
+		sort func([]OrderLevel)
+	}{
+		{"swap", swapSortLevelsDesc},
+		{"sort.Slice", sortOrderLevelsDesc},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				copy(work, levels)
+				bm.sort(work)
+			}
+		})
+	}
+}
+
+func BenchmarkGetSnapshot(b *testing.B) {
+	ob := NewOrderbook()
+	for p := uint64(1); p <= 1000; p++ {
+		if _, err := ob.PlaceOrder(NewOrder("mm", "m1", OutcomeYES, SideBuy, p, 1)); err != nil {
+			b.Fatal(err)
+		}
+		if _, err := ob.PlaceOrder(NewOrder("mm", "m1", OutcomeYES, SideSell, 9000+p, 1)); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ob.GetSnapshot()
+	}
+}
+
+func TestBestBidAndAsk(t *testing.T) {
+	ob := NewOrderbook()
+	if _, ok := ob.BestBid(); ok {
+		t.Fatal("empty book has a best bid")
+	}
+	if _, ok := ob.BestAsk(); ok {
+		t.Fatal("empty book has a best ask")
+	}
+
+	top, _ := place(t, ob, "a", SideBuy, 5000, 3)
+	place(t, ob, "b", SideBuy, 5000, 2)
+	place(t, ob, "c", SideBuy, 4000, 7)
+	if level, ok := ob.BestBid(); !ok || level != (OrderLevel{Price: 5000, Quantity: 5, Count: 2}) {
+		t.Fatalf("best bid = %+v, %v, want 5 at 5000 from 2 orders", level, ok)
+	}
+	if _, ok := ob.BestAsk(); ok {
+		t.Fatal("bids-only book has a best ask")
+	}
+
+	// A cancelled order no longer counts towards the top level
+	if err := ob.CancelOrder(top.ID); err != nil {
+		t.Fatal(err)
+	}
+	if level, _ := ob.BestBid(); level != (OrderLevel{Price: 5000, Quantity: 2, Count: 1}) {
+		t.Fatalf("best bid after cancel = %+v, want 2 at 5000", level)
+	}
+}
+
+func TestCancelUserOrdersLeavesOthers(t *testing.T) {
+	ob := NewOrderbook()
+	var mine []*Order
+	for _, o := range []struct {
+		user  string
+		side  Side
+		price uint64
+	}{
+		{"alice", SideBuy, 4000}, {"bob", SideBuy, 4000}, {"alice", SideBuy, 4500},
+		{"bob", SideSell, 6000}, {"alice", SideSell, 6000}, {"alice", SideSell, 7000},
+	} {
+		order, _ := place(t, ob, o.user, o.side, o.price, 5)
+		if o.user == "alice" {
+			mine = append(mine, order)
+		}
+	}
+
+	if n := ob.CancelUserOrders("alice"); n != 4 {
+		t.Fatalf("cancelled %d, want 4", n)
+	}
+	for _, order := range mine {
+		if order.Status != StatusCancelled {
+			t.Errorf("alice's order %s is %s", order.ID, order.Status)
+		}
+	}
+	want := OrderbookSnapshot{
+		Bids: []OrderLevel{{Price: 4000, Quantity: 5, Count: 1}},
+		Asks: []OrderLevel{{Price: 6000, Quantity: 5, Count: 1}},
+	}
+	if got := ob.GetSnapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("snapshot = %+v, want only bob's orders %+v", got, want)
+	}
+	if ob.bids.Len() != 1 || ob.asks.Len() != 1 {
+		t.Fatalf("heaps hold %d bids and %d asks, want 1 and 1", ob.bids.Len(), ob.asks.Len())
+	}
+	if n := ob.CancelUserOrders("alice"); n != 0 {
+		t.Fatalf("second cancel-all cancelled %d, want 0", n)
+	}
+}
+
+func TestGetOrderReturnsACopy(t *testing.T) {
+	ob := NewOrderbook()
+	bid, _ := place(t, ob, "alice", SideBuy, 5000, 10)
+
+	got, err := ob.GetOrder(bid.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got == bid || got.ID != bid.ID || got.Quantity != 10 {
+		t.Fatalf("GetOrder = %p %+v, want a copy of %p", got, got, bid)
+	}
+	got.Quantity = 1
+
+	// Fills reach the book's order but not a copy already handed out
+	place(t, ob, "bob", SideSell, 5000, 4)
+	if got.FilledQty != 0 || bid.FilledQty != 4 || bid.Quantity != 10 {
+		t.Fatalf("copy filled %d, book's order %d of %d, want 0 and 4 of 10", got.FilledQty, bid.FilledQty, bid.Quantity)
+	}
+}
+
+func TestMatchingDiscardsDeadOrdersAtTheTop(t *testing.T) {
+	ob := NewOrderbook()
+
+	// Interleave resting asks with cancels, so cancelled orders pass through
+	// the top of the heap as it is rebuilt
+	cancelled := make(map[string]bool)
+	var live uint64
+	for i := 0; i < 24; i++ {
+		seller := "s" + strconv.Itoa(i)
+		ask, _ := place(t, ob, seller, SideSell, 6000+uint64(i%4)*100, 5)
+		if i%3 == 0 {
+			if err := ob.CancelOrder(ask.ID); err != nil {
+				t.Fatal(err)
+			}
+			cancelled[seller] = true
+			continue
+		}
+		live += 5
+	}
+	// Orders that can no longer trade but were left on the book, best first
+	tombstone, _ := place(t, ob, "tombstone", SideSell, 5800, 5)
+	tombstone.Status = StatusCancelled
+	empty, _ := place(t, ob, "empty", SideSell, 5900, 5)
+	empty.FilledQty = empty.Quantity
+
+	_, trades := place(t, ob, "buyer", SideBuy, 6300, 1000)
+	var filled uint64
+	for _, trade := range trades {
+		if trade.Quantity == 0 || cancelled[trade.SellerID] || trade.SellerID == "tombstone" || trade.SellerID == "empty" {
+			t.Fatalf("trade against a dead order: %+v", trade)
+		}
+		filled += trade.Quantity
+	}
+	if filled != live {
+		t.Fatalf("filled %d, want every live ask's %d", filled, live)
+	}
+	for _, dead := range []*Order{tombstone, empty} {
+		if _, err := ob.GetOrder(dead.ID); err != ErrOrderNotFound {
+			t.Errorf("%s still on the book", dead.UserID)
+		}
+	}
+
+	// The same holds for bids hit by a sell
+	ob = NewOrderbook()
+	dead, _ := place(t, ob, "tombstone", SideBuy, 6500, 5)
+	dead.Status = StatusCancelled
+	place(t, ob, "bidder", SideBuy, 6200, 5)
+	if _, trades := place(t, ob, "seller", SideSell, 6000, 5); len(trades) != 1 || trades[0].BuyerID != "bidder" || trades[0].Quantity != 5 {
+		t.Fatalf("trades = %+v, want one fill of the live bid", trades)
+	}
+}
+
+func TestNoZeroQuantityTrades(t *testing.T) {
+	ob := NewOrderbook()
+	var recorded []*Trade
+	ob.SetTradeCallback(func(trade *Trade) { recorded = append(recorded, trade) })
+
+	// An ask emptied while resting crosses but has nothing to give
+	ask, _ := place(t, ob, "seller", SideSell, 6000, 5)
+	ask.FilledQty = ask.Quantity
+	if _, trades := place(t, ob, "buyer", SideBuy, 6000, 5); len(trades) != 0 {
+		t.Fatalf("trades = %+v, want none against an empty ask", trades)
+	}
+
+	// Likewise an emptied NO bid that would mint with a YES buy
+	books := NewMarketOrderbooks()
+	no := NewOrder("no", "m1", OutcomeNO, SideBuy, 4000, 5)
+	placeOn(t, books, no)
+	no.FilledQty = no.Quantity
+	if trades := placeOn(t, books, NewOrder("yes", "m1", OutcomeYES, SideBuy, 6000, 5)); len(trades) != 0 {
+		t.Fatalf("trades = %+v, want no mint with an empty bid", trades)
+	}
+
+	// An empty trade that reaches recording is dropped
+	ob.mu.Lock()
+	ob.notifyTrades([]*Trade{{ID: "empty", Price: 6000}, {ID: "real", Price: 6000, Quantity: 1}})
+	ob.mu.Unlock()
+	if len(recorded) != 1 || recorded[0].ID != "real" {
+		t.Fatalf("callback saw %+v, want only the real trade", recorded)
+	}
+	if recent := ob.RecentTrades(10); len(recent) != 1 || recent[0].ID != "real" {
+		t.Fatalf("history = %+v, want only the real trade", recent)
+	}
+}
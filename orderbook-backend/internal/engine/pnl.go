@@ -0,0 +1,120 @@
+package engine
+
+import "sort"
+
+// Cost basis and PnL
+//
+// Each position tracks what it paid for the shares it still holds (fees
+// included), per outcome. Selling, redeeming or settling shares closes them
+// at their weighted average cost, and the difference from what they brought
+// in is realized. Minted pairs are split evenly between YES and NO, except
+// in a mint match where the traded side is booked at the trade price, so
+// its sale realizes nothing and the kept side costs what its buyer bid.
+
+// PnL is a user's profit and loss in one market, in basis points
+type PnL struct {
+	UserID      string   `json:"user_id"`
+	MarketID    string   `json:"market_id"`
+	YesShares   Quantity `json:"yes_shares"`
+	NoShares    Quantity `json:"no_shares"`
+	YesAvgPrice uint64   `json:"yes_avg_price"` // Average entry price of held YES shares
+	NoAvgPrice  uint64   `json:"no_avg_price"`
+	YesMark     uint64   `json:"yes_mark"` // Prices the holdings were marked at
+	NoMark      uint64   `json:"no_mark"`
+	Realized    int64    `json:"realized"`   // From shares already closed
+	Unrealized  int64    `json:"unrealized"` // Held shares marked to market
+	Total       int64    `json:"total"`
+}
+
+// addCost adds to the basis of held shares of an outcome
+func (pos *Position) addCost(outcome OutcomeID, cost uint64) {
+	if outcome == OutcomeYES {
+		pos.yesCost += cost
+	} else {
+		pos.noCost += cost
+	}
+}
+
+// closeShares realizes qty shares of an outcome closed for proceeds. It must
+// be called before the shares are taken off the position.
+func (pos *Position) closeShares(outcome OutcomeID, qty, proceeds uint64) {
+	shares, cost := pos.YesShares, &pos.yesCost
+	if outcome == OutcomeNO {
+		shares, cost = pos.NoShares, &pos.noCost
+	}
+	if shares == 0 {
+		return
+	}
+	basis := *cost * qty / shares
+	*cost -= basis
+	pos.realized += int64(proceeds) - int64(basis)
+}
+
+// settleCost realizes every held share at resolution
+func (pos *Position) settleCost(winningOutcome OutcomeID) {
+	pos.realized += int64(payoutFor(pos, winningOutcome)) - int64(pos.yesCost+pos.noCost)
+	pos.yesCost = 0
+	pos.noCost = 0
+}
+
+// avgPrice returns the average price per share paid for shares
+func avgPrice(cost, shares uint64) uint64 {
+	if shares == 0 {
+		return 0
+	}
+	return cost * quantityScale / shares
+}
+
+// GetPnL returns a user's realized PnL in a market and their unrealized PnL
+// with held shares marked at yesMark and noMark (basis points)
+func (pm *PositionManager) GetPnL(userID, marketID string, yesMark, noMark uint64) PnL {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	pnl := PnL{UserID: userID, MarketID: marketID, YesMark: yesMark, NoMark: noMark}
+	pos, ok := pm.positions[userID][marketID]
+	if !ok {
+		return pnl
+	}
+
+	pnl.YesShares = Quantity(pos.YesShares)
+	pnl.NoShares = Quantity(pos.NoShares)
+	pnl.YesAvgPrice = avgPrice(pos.yesCost, pos.YesShares)
+	pnl.NoAvgPrice = avgPrice(pos.noCost, pos.NoShares)
+	pnl.Realized = pos.realized
+	pnl.Unrealized = int64(Notional(yesMark, pos.YesShares)) - int64(pos.yesCost) +
+		int64(Notional(noMark, pos.NoShares)) - int64(pos.noCost)
+	pnl.Total = pnl.Realized + pnl.Unrealized
+	return pnl
+}
+
+// NetPnL is a user's realized PnL summed over every market they traded
+type NetPnL struct {
+	UserID   string `json:"user_id"`
+	Realized int64  `json:"realized"` // Basis points, negative for a net loss
+	Open     bool   `json:"open"`     // Some market still holds their shares
+}
+
+// NetRealized returns every user's realized PnL summed across markets,
+// sorted by user. Once a user holds no shares this is exactly their net
+// cash flow from trading: sale proceeds and payouts, less purchases, mints
+// and fees. Gains in one market offset losses in another.
+func (pm *PositionManager) NetRealized() []NetPnL {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	result := make([]NetPnL, 0, len(pm.positions))
+	for userID, userPositions := range pm.positions {
+		net := NetPnL{UserID: userID}
+		for _, pos := range userPositions {
+			net.Realized += pos.realized
+			if pos.YesShares > 0 || pos.NoShares > 0 {
+				net.Open = true
+			}
+		}
+		result = append(result, net)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].UserID < result[j].UserID })
+	return result
+}
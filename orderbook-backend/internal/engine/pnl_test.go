@@ -0,0 +1,56 @@
+package engine
+
+import "testing"
+
+func TestPnLBuyThenPartialSell(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	deposit(t, pm, "alice", 100)
+	deposit(t, pm, "bob", 100)
+	deposit(t, pm, "carol", 100)
+	if err := pm.MintShares("bob", "m1", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Alice buys 10 YES at 6000, then sells 4 at 7000
+	if err := pm.ExecuteTrade(sharesTrade("alice", "bob", 6000, 10, SideBuy)); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.ExecuteTrade(sharesTrade("carol", "alice", 7000, 4, SideSell)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The 4 sold realize 4 * (7000 - 6000); the 6 held gain 6 * 500 at 6500
+	pnl := pm.GetPnL("alice", "m1", 6500, 3500)
+	if pnl.YesShares != 6 || pnl.YesAvgPrice != 6000 {
+		t.Errorf("holds %d YES at %d, want 6 at 6000", pnl.YesShares, pnl.YesAvgPrice)
+	}
+	if pnl.Realized != 4000 || pnl.Unrealized != 3000 || pnl.Total != 7000 {
+		t.Errorf("realized %d unrealized %d total %d, want 4000, 3000 and 7000", pnl.Realized, pnl.Unrealized, pnl.Total)
+	}
+
+	// Settling YES realizes the rest: 6 * (10000 - 6000) more
+	pm.SettleMarket("m1", OutcomeYES)
+	pnl = pm.GetPnL("alice", "m1", 6500, 3500)
+	if pnl.Realized != 28000 || pnl.Unrealized != 0 {
+		t.Errorf("after settlement: realized %d unrealized %d, want 28000 and 0", pnl.Realized, pnl.Unrealized)
+	}
+}
+
+func TestPnLCountsFees(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	pm.SetFeeSchedule(FeeSchedule{TakerBps: 100})
+	deposit(t, pm, "alice", 100)
+	deposit(t, pm, "bob", 100)
+	if err := pm.MintShares("bob", "m1", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// Taking 10 YES at 5000 costs 50000 plus a 500 fee
+	if err := pm.ExecuteTrade(sharesTrade("alice", "bob", 5000, 10, SideBuy)); err != nil {
+		t.Fatal(err)
+	}
+	pnl := pm.GetPnL("alice", "m1", 5000, 5000)
+	if pnl.YesAvgPrice != 5050 || pnl.Unrealized != -500 {
+		t.Errorf("avg price %d unrealized %d, want 5050 and -500", pnl.YesAvgPrice, pnl.Unrealized)
+	}
+}
@@ -2,12 +2,16 @@ package engine
 
 import (
 	"errors"
+	"sort"
 	"sync"
+	"time"
 )
 
 var (
-	ErrInsufficientBalance  = errors.New("insufficient USDC balance")
-	ErrInsufficientPosition = errors.New("insufficient shares to sell")
+	ErrInsufficientBalance   = errors.New("insufficient USDC balance")
+	ErrInsufficientPosition  = errors.New("insufficient shares to sell")
+	ErrUnbackedDeposit       = errors.New("deposit exceeds funds committed to the state channel")
+	ErrExceedsChannelBalance = errors.New("withdrawal exceeds channel-backed balance")
 )
 
 // Position tracks a user's share holdings in a specific market
@@ -17,6 +21,19 @@ type Position struct {
 	YesShares uint64 `json:"yes_shares"`
 	NoShares  uint64 `json:"no_shares"`
 	Balance   uint64 `json:"balance"` // USDC balance in basis points (10000 = 1 USDC)
+
+	// Cost basis of held shares and realized PnL, in basis points (see pnl.go)
+	yesCost  uint64
+	noCost   uint64
+	realized int64
+}
+
+// shares returns how many shares of an outcome the position holds
+func (pos *Position) shares(outcome OutcomeID) uint64 {
+	if outcome == OutcomeYES {
+		return pos.YesShares
+	}
+	return pos.NoShares
 }
 
 // PositionManager tracks all user positions
@@ -24,21 +41,210 @@ type PositionManager struct {
 	mu        sync.RWMutex
 	positions map[string]map[string]*Position // userID -> marketID -> Position
 	balances  map[string]uint64               // userID -> USDC balance
+
+	fees          FeeSchedule
+	feeFreeUntil  map[string]time.Time // marketID -> end of fee-free window
+	collectedFees uint64
+	feeAccount    string // Credited with every fee collected ("" = fees are only tallied)
+	now           func() time.Time
+
+	settlements map[string]*Settlement // marketID -> payout record
+
+	// userID -> balance changes, oldest first
+	ledger map[string][]LedgerEntry
+
+	// Callback for the write-ahead journal (see journal.go)
+	onJournal func(JournalEntry)
+
+	// Optional state channel backing for deposits and withdrawals
+	channelBalance func(userID string) uint64 // Channel-backed funds in basis points
+	funded         map[string]uint64          // userID -> net deposits
+
+	// Optional source of the funds backing a user's open buy orders
+	reserved func(userID string) uint64
+	// Optional source of the shares backing a user's open sell orders
+	reservedShares func(userID, marketID string, outcome OutcomeID) uint64
+
+	limits        Limits
+	marketLimits  map[string]Limits                    // marketID -> override
+	dailyNotional map[string]map[string]*dailyNotional // userID -> marketID -> today's volume
 }
 
 // NewPositionManager creates a new position manager
 func NewPositionManager() *PositionManager {
 	return &PositionManager{
-		positions: make(map[string]map[string]*Position),
-		balances:  make(map[string]uint64),
+		positions:     make(map[string]map[string]*Position),
+		balances:      make(map[string]uint64),
+		fees:          NoFees,
+		feeFreeUntil:  make(map[string]time.Time),
+		now:           time.Now,
+		settlements:   make(map[string]*Settlement),
+		ledger:        make(map[string][]LedgerEntry),
+		funded:        make(map[string]uint64),
+		marketLimits:  make(map[string]Limits),
+		dailyNotional: make(map[string]map[string]*dailyNotional),
+	}
+}
+
+// SetClock overrides the time source (used for fee windows and daily limits)
+func (pm *PositionManager) SetClock(fn func() time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.now = fn
+}
+
+// SetFeeSchedule sets the maker/taker fees applied to every trade
+func (pm *PositionManager) SetFeeSchedule(fees FeeSchedule) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.fees = fees
+}
+
+// SetFeeAccount sets the account credited with collected trading fees
+func (pm *PositionManager) SetFeeAccount(userID string) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.feeAccount = userID
+}
+
+// SetFeeFreeWindow waives trading fees in a market until the given time
+func (pm *PositionManager) SetFeeFreeWindow(marketID string, until time.Time) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.setFeeFreeWindow(marketID, until)
+	pm.journal(JournalEntry{Timestamp: pm.now(), Type: JournalFeeFree, MarketID: marketID, Until: &until})
+}
+
+// setFeeFreeWindow applies SetFeeFreeWindow (must hold lock)
+func (pm *PositionManager) setFeeFreeWindow(marketID string, until time.Time) {
+	if until.IsZero() {
+		delete(pm.feeFreeUntil, marketID)
+		return
+	}
+	pm.feeFreeUntil[marketID] = until
+}
+
+// CollectedFees returns the total fees collected so far
+func (pm *PositionManager) CollectedFees() uint64 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.collectedFees
+}
+
+// feeScheduleFor returns the fees in effect for a market right now (must hold lock)
+func (pm *PositionManager) feeScheduleFor(marketID string) FeeSchedule {
+	if until, ok := pm.feeFreeUntil[marketID]; ok && pm.now().Before(until) {
+		return NoFees
+	}
+	return pm.fees
+}
+
+// SetChannelBacking requires deposits and withdrawals to reconcile with
+// the funds each user has committed to the state channel. fn returns that
+// amount in basis points; nil turns the check off.
+func (pm *PositionManager) SetChannelBacking(fn func(userID string) uint64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.channelBalance = fn
+}
+
+// SetReservedFunds makes withdrawals and new buy orders leave behind the
+// funds backing each user's open buy orders. fn returns that amount in basis points.
+func (pm *PositionManager) SetReservedFunds(fn func(userID string) uint64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.reserved = fn
+}
+
+// SetReservedShares makes new sell orders and redemptions leave behind the
+// shares backing each user's open sell orders, so every resting sell can
+// settle when it fills. fn returns the open sell quantity of one outcome.
+func (pm *PositionManager) SetReservedShares(fn func(userID, marketID string, outcome OutcomeID) uint64) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+	pm.reservedShares = fn
+}
+
+// availableShares returns the shares of an outcome a user holds that are not
+// backing open sell orders (must hold lock)
+func (pm *PositionManager) availableShares(userID, marketID string, outcome OutcomeID) uint64 {
+	pos := pm.positions[userID][marketID]
+	if pos == nil {
+		return 0
 	}
+	held := pos.shares(outcome)
+	if pm.reservedShares == nil {
+		return held
+	}
+	return held - min(pm.reservedShares(userID, marketID, outcome), held)
+}
+
+// available returns the part of a user's balance not backing open orders
+// (must hold lock)
+func (pm *PositionManager) available(userID string) uint64 {
+	balance := pm.balances[userID]
+	if pm.reserved == nil {
+		return balance
+	}
+	return balance - min(pm.reserved(userID), balance)
+}
+
+// AvailableBalance returns a user's USDC balance less the funds backing
+// their open buy orders
+func (pm *PositionManager) AvailableBalance(userID string) uint64 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.available(userID)
 }
 
-// Deposit adds USDC to a user's balance
-func (pm *PositionManager) Deposit(userID string, amount uint64) {
+// Deposit adds USDC to a user's balance. With channel backing on, net
+// deposits may not exceed the user's channel-backed funds.
+func (pm *PositionManager) Deposit(userID string, amount uint64) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
+
+	if pm.channelBalance != nil && pm.funded[userID]+amount > pm.channelBalance(userID) {
+		return ErrUnbackedDeposit
+	}
+
+	now := pm.now()
+	pm.deposit(now, userID, amount)
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalDeposit, UserID: userID, Amount: amount})
+	return nil
+}
+
+// deposit credits a deposit (must hold lock)
+func (pm *PositionManager) deposit(now time.Time, userID string, amount uint64) {
 	pm.balances[userID] += amount
+	pm.funded[userID] += amount
+	pm.record(now, userID, LedgerDeposit, int64(amount), "", "")
+}
+
+// Withdraw removes USDC from a user's balance. Funds backing open buy
+// orders can't be withdrawn. With channel backing on, a withdrawal may not
+// exceed the user's channel-backed funds.
+func (pm *PositionManager) Withdraw(userID string, amount uint64) error {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	if pm.available(userID) < amount {
+		return ErrInsufficientBalance
+	}
+	if pm.channelBalance != nil && amount > pm.channelBalance(userID) {
+		return ErrExceedsChannelBalance
+	}
+
+	now := pm.now()
+	pm.withdraw(now, userID, amount)
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalWithdraw, UserID: userID, Amount: amount})
+	return nil
+}
+
+// withdraw debits a withdrawal (must hold lock)
+func (pm *PositionManager) withdraw(now time.Time, userID string, amount uint64) {
+	pm.balances[userID] -= amount
+	pm.funded[userID] -= min(amount, pm.funded[userID])
+	pm.record(now, userID, LedgerWithdraw, -int64(amount), "", "")
 }
 
 // GetBalance returns a user's USDC balance
@@ -84,21 +290,15 @@ func (pm *PositionManager) ValidateOrder(order *Order) error {
 	defer pm.mu.RUnlock()
 
 	if order.Side == SideBuy {
-		// Buy: need USDC = price * quantity
-		cost := order.Price * order.Quantity / 10000 // Convert from basis points
-		if pm.balances[order.UserID] < cost*10000 {  // Compare in basis points
+		// Buy: need USDC = price * quantity, plus the fee it could be charged,
+		// out of funds not already backing open buys
+		notional := Notional(order.Price, order.Quantity)
+		if pm.available(order.UserID) < notional+pm.feeScheduleFor(order.MarketID).MaxFee(notional) {
 			return ErrInsufficientBalance
 		}
 	} else {
-		// Sell: need shares
-		pos := pm.GetPosition(order.UserID, order.MarketID)
-		var available uint64
-		if order.OutcomeID == OutcomeYES {
-			available = pos.YesShares
-		} else {
-			available = pos.NoShares
-		}
-		if available < order.Quantity {
+		// Sell: need shares not already backing open sells
+		if pm.availableShares(order.UserID, order.MarketID, order.OutcomeID) < order.Quantity {
 			return ErrInsufficientPosition
 		}
 	}
@@ -109,23 +309,80 @@ func (pm *PositionManager) ValidateOrder(order *Order) error {
 // ExecuteTrade updates positions after a trade is executed
 // buyer pays USDC, receives shares
 // seller pays shares, receives USDC
-func (pm *PositionManager) ExecuteTrade(trade *Trade) {
+// A trade either side can no longer cover is rejected and changes nothing.
+func (pm *PositionManager) ExecuteTrade(trade *Trade) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	now := pm.now()
+	if err := pm.executeTrade(now, trade); err != nil {
+		return err
+	}
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalTrade, Trade: trade})
+	return nil
+}
+
+// executeTrade applies a trade (must hold lock)
+func (pm *PositionManager) executeTrade(now time.Time, trade *Trade) error {
 	// Trade has buyerID, sellerID, price, quantity
 	// The order that matched determines which outcome was traded
 
+	// Cost = price * quantity (in basis points)
+	cost := Notional(trade.Price, trade.Quantity)
+
+	// Fees are charged on top of the cost for the buyer and out of the
+	// proceeds for the seller, depending on which side was the taker
+	fees := pm.feeScheduleFor(trade.MarketID)
+	makerFee, takerFee := fees.MakerFee(cost), fees.TakerFee(cost)
+	buyerFee, sellerFee := makerFee, takerFee
+	if trade.TakerSide == SideBuy {
+		buyerFee, sellerFee = takerFee, makerFee
+	}
+
+	// Check both sides before changing anything; balances are unsigned and
+	// an overdrawn one would wrap around
+	if pm.balances[trade.BuyerID] < cost+buyerFee {
+		return ErrInsufficientBalance
+	}
+	if trade.Mint {
+		if pm.balances[trade.SellerID] < trade.MintCost()+sellerFee {
+			return ErrInsufficientBalance
+		}
+	} else if pos := pm.positions[trade.SellerID][trade.MarketID]; pos == nil || pos.shares(trade.OutcomeID) < trade.Quantity {
+		return ErrInsufficientPosition
+	}
+
 	buyerPos := pm.getOrCreatePosition(trade.BuyerID, trade.MarketID)
 	sellerPos := pm.getOrCreatePosition(trade.SellerID, trade.MarketID)
 
-	// Cost = price * quantity (in basis points)
-	cost := trade.Price * trade.Quantity
+	// In a mint match the "seller" is the buyer of the other outcome: they
+	// fund a new YES+NO pair and sell the traded side on (see complement.go)
+	sellerNotional := cost
+	if trade.Mint {
+		pm.mint(now, trade.SellerID, trade.MarketID, trade.Quantity, trade.OutcomeID, cost)
+		sellerNotional = trade.MintCost()
+	}
+	trade.MakerFee, trade.TakerFee = makerFee, takerFee
 
 	// Buyer pays USDC
-	pm.balances[trade.BuyerID] -= cost
+	pm.balances[trade.BuyerID] -= cost + buyerFee
 	// Seller receives USDC
-	pm.balances[trade.SellerID] += cost
+	pm.balances[trade.SellerID] += cost - sellerFee
+	pm.record(now, trade.BuyerID, LedgerTradeDebit, -int64(cost+buyerFee), trade.MarketID, trade.ID)
+	pm.record(now, trade.SellerID, LedgerTradeCredit, int64(cost-sellerFee), trade.MarketID, trade.ID)
+
+	// Fees go to the fee account
+	pm.collectedFees += buyerFee + sellerFee
+	if pm.feeAccount != "" && buyerFee+sellerFee > 0 {
+		pm.balances[pm.feeAccount] += buyerFee + sellerFee
+		pm.record(now, pm.feeAccount, LedgerFee, int64(buyerFee+sellerFee), trade.MarketID, trade.ID)
+	}
+
+	pm.recordNotional(trade.BuyerID, trade.MarketID, cost)
+	pm.recordNotional(trade.SellerID, trade.MarketID, sellerNotional)
+
+	buyerPos.addCost(trade.OutcomeID, cost+buyerFee)
+	sellerPos.closeShares(trade.OutcomeID, trade.Quantity, cost-sellerFee)
 
 	// Transfer shares based on outcome
 	if trade.OutcomeID == OutcomeYES {
@@ -135,6 +392,7 @@ func (pm *PositionManager) ExecuteTrade(trade *Trade) {
 		buyerPos.NoShares += trade.Quantity
 		sellerPos.NoShares -= trade.Quantity
 	}
+	return nil
 }
 
 // MintShares mints new shares for a market (used when user deposits for first time)
@@ -144,42 +402,64 @@ func (pm *PositionManager) MintShares(userID, marketID string, amount uint64) er
 	defer pm.mu.Unlock()
 
 	// Cost to mint = amount USDC (10000 basis points = 1 USDC)
-	cost := amount * 10000
+	cost := Collateral(amount)
 	if pm.balances[userID] < cost {
 		return ErrInsufficientBalance
 	}
 
+	now := pm.now()
+	pm.mint(now, userID, marketID, amount, OutcomeYES, cost/2)
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalMint, UserID: userID, MarketID: marketID, Shares: Quantity(amount)})
+	return nil
+}
+
+// mint charges a user the collateral for amount YES+NO pairs and credits
+// the shares. cost of the collateral is booked to outcome's basis and the
+// rest to the other outcome (must hold lock).
+func (pm *PositionManager) mint(now time.Time, userID, marketID string, amount uint64, outcome OutcomeID, cost uint64) {
 	pos := pm.getOrCreatePosition(userID, marketID)
 
 	// Deduct USDC
-	pm.balances[userID] -= cost
+	collateral := Collateral(amount)
+	pm.balances[userID] -= collateral
+	pm.record(now, userID, LedgerMint, -int64(collateral), marketID, "")
+	pos.addCost(outcome, cost)
+	pos.addCost(outcome.Complement(), collateral-cost)
 
 	// Mint equal YES and NO shares
 	pos.YesShares += amount
 	pos.NoShares += amount
-
-	return nil
 }
 
-// RedeemShares redeems YES+NO pairs back to USDC
+// RedeemShares redeems YES+NO pairs back to USDC. Shares backing open sell
+// orders can't be redeemed.
 func (pm *PositionManager) RedeemShares(userID, marketID string, amount uint64) error {
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
-	pos := pm.getOrCreatePosition(userID, marketID)
-
-	if pos.YesShares < amount || pos.NoShares < amount {
+	if pm.availableShares(userID, marketID, OutcomeYES) < amount || pm.availableShares(userID, marketID, OutcomeNO) < amount {
 		return ErrInsufficientPosition
 	}
+	pos := pm.getOrCreatePosition(userID, marketID)
+
+	now := pm.now()
+	pm.redeem(now, pos, amount)
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalRedeem, UserID: userID, MarketID: marketID, Shares: Quantity(amount)})
+	return nil
+}
 
+// redeem burns amount YES+NO pairs of a position for collateral (must hold lock)
+func (pm *PositionManager) redeem(now time.Time, pos *Position, amount uint64) {
 	// Burn shares
+	half := Collateral(amount) / 2
+	pos.closeShares(OutcomeYES, amount, half)
+	pos.closeShares(OutcomeNO, amount, Collateral(amount)-half)
 	pos.YesShares -= amount
 	pos.NoShares -= amount
 
 	// Credit USDC (1 pair = 1 USDC = 10000 basis points)
-	pm.balances[userID] += amount * 10000
-
-	return nil
+	pm.balances[pos.UserID] += Collateral(amount)
+	pm.record(now, pos.UserID, LedgerRedeem, int64(Collateral(amount)), pos.MarketID, "")
 }
 
 // PayoutWinningShares pays out winning shares after market resolution
@@ -187,21 +467,143 @@ func (pm *PositionManager) PayoutWinningShares(userID, marketID string, winningO
 	pm.mu.Lock()
 	defer pm.mu.Unlock()
 
+	now := pm.now()
+	payout := pm.payout(now, userID, marketID, winningOutcome)
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalPayout, UserID: userID, MarketID: marketID, Outcome: winningOutcome})
+	return payout
+}
+
+// payout pays out one position at resolution (must hold lock)
+func (pm *PositionManager) payout(now time.Time, userID, marketID string, winningOutcome OutcomeID) uint64 {
 	pos := pm.getOrCreatePosition(userID, marketID)
 
-	var payout uint64
-	if winningOutcome == OutcomeYES {
-		payout = pos.YesShares * 10000 // Each share = 1 USDC = 10000 basis points
+	payout := payoutFor(pos, winningOutcome)
+	pos.settleCost(winningOutcome)
+	pos.YesShares = 0
+	pos.NoShares = 0 // Losing shares become worthless
+
+	pm.balances[userID] += payout
+	if payout > 0 {
+		pm.record(now, userID, LedgerPayout, int64(payout), marketID, "")
+	}
+	return payout
+}
+
+// Settlement records the payouts made when a market resolved
+type Settlement struct {
+	MarketID    string         `json:"market_id"`
+	Outcome     OutcomeID      `json:"outcome"`
+	TotalPayout uint64         `json:"total_payout"`
+	Payouts     []BalanceDelta `json:"payouts"`
+	SettledAt   time.Time      `json:"settled_at"`
+}
+
+// SettleMarket pays out every position holder in a market and keeps a record
+// of what each one held and received
+func (pm *PositionManager) SettleMarket(marketID string, winningOutcome OutcomeID) *Settlement {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	now := pm.now()
+	settlement := pm.settle(now, marketID, winningOutcome)
+	pm.journal(JournalEntry{Timestamp: now, Type: JournalSettle, MarketID: marketID, Outcome: winningOutcome})
+	return settlement
+}
+
+// settle pays out every position in a market at resolution (must hold lock)
+func (pm *PositionManager) settle(now time.Time, marketID string, winningOutcome OutcomeID) *Settlement {
+	settlement := &Settlement{
+		MarketID:  marketID,
+		Outcome:   winningOutcome,
+		Payouts:   pm.deltas(marketID, winningOutcome),
+		SettledAt: now,
+	}
+	for _, d := range settlement.Payouts {
+		pos := pm.positions[d.UserID][marketID]
+		pos.settleCost(winningOutcome)
 		pos.YesShares = 0
 		pos.NoShares = 0 // Losing shares become worthless
-	} else {
-		payout = pos.NoShares * 10000
-		pos.NoShares = 0
-		pos.YesShares = 0
+		pm.balances[d.UserID] += d.Payout
+		if d.Payout > 0 {
+			pm.record(now, d.UserID, LedgerPayout, int64(d.Payout), marketID, "")
+		}
+		settlement.TotalPayout += d.Payout
 	}
 
-	pm.balances[userID] += payout
-	return payout
+	pm.settlements[marketID] = settlement
+	return settlement
+}
+
+// GetSettlement returns the payout record for a resolved market
+func (pm *PositionManager) GetSettlement(marketID string) (*Settlement, bool) {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	settlement, ok := pm.settlements[marketID]
+	return settlement, ok
+}
+
+// payoutFor returns what a position is owed if the given outcome wins
+func payoutFor(pos *Position, winningOutcome OutcomeID) uint64 {
+	if winningOutcome == OutcomeYES {
+		return Collateral(pos.YesShares) // Each share = 1 USDC = 10000 basis points
+	}
+	return Collateral(pos.NoShares)
+}
+
+// BalanceDelta describes how a resolution would change a user's balance
+type BalanceDelta struct {
+	UserID        string `json:"user_id"`
+	YesShares     uint64 `json:"yes_shares"`
+	NoShares      uint64 `json:"no_shares"`
+	Payout        uint64 `json:"payout"`
+	BalanceBefore uint64 `json:"balance_before"`
+	BalanceAfter  uint64 `json:"balance_after"`
+}
+
+// SimulatePayouts is a dry run of PayoutWinningShares for every position
+// holder in a market. It reports balance changes without mutating state.
+func (pm *PositionManager) SimulatePayouts(marketID string, winningOutcome OutcomeID) []BalanceDelta {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+	return pm.deltas(marketID, winningOutcome)
+}
+
+// deltas computes each position holder's payout, sorted by user (must hold lock)
+func (pm *PositionManager) deltas(marketID string, winningOutcome OutcomeID) []BalanceDelta {
+	var deltas []BalanceDelta
+	for userID, userPositions := range pm.positions {
+		pos, ok := userPositions[marketID]
+		if !ok || (pos.YesShares == 0 && pos.NoShares == 0) {
+			continue
+		}
+		payout := payoutFor(pos, winningOutcome)
+		deltas = append(deltas, BalanceDelta{
+			UserID:        userID,
+			YesShares:     pos.YesShares,
+			NoShares:      pos.NoShares,
+			Payout:        payout,
+			BalanceBefore: pm.balances[userID],
+			BalanceAfter:  pm.balances[userID] + payout,
+		})
+	}
+
+	sort.Slice(deltas, func(i, j int) bool { return deltas[i].UserID < deltas[j].UserID })
+	return deltas
+}
+
+// OpenInterest returns the number of YES+NO pairs outstanding in a market.
+// Shares are only created in pairs, so this equals the total YES shares held.
+func (pm *PositionManager) OpenInterest(marketID string) uint64 {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	var total uint64
+	for _, userPositions := range pm.positions {
+		if pos, ok := userPositions[marketID]; ok {
+			total += pos.YesShares
+		}
+	}
+	return total
 }
 
 // GetAllPositions returns all positions for a market
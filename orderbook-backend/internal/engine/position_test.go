@@ -0,0 +1,298 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+// testClock is a settable time source for PositionManager
+type testClock struct{ now time.Time }
+
+func (c *testClock) Now() time.Time { return c.now }
+
+// newTestPositions creates a position manager on a fixed clock
+func newTestPositions(t *testing.T) (*PositionManager, *testClock) {
+	t.Helper()
+	clock := &testClock{now: time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)}
+	pm := NewPositionManager()
+	pm.SetClock(clock.Now)
+	return pm, clock
+}
+
+// deposit credits whole USDC to a user
+func deposit(t *testing.T, pm *PositionManager, userID string, usdc uint64) {
+	t.Helper()
+	if err := pm.Deposit(userID, usdc*10000); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sharesTrade builds a trade where seller sells qty YES to buyer at price
+func sharesTrade(buyer, seller string, price, qty uint64, takerSide Side) *Trade {
+	return &Trade{
+		ID:        "t-" + buyer + "-" + seller,
+		MarketID:  "m1",
+		OutcomeID: OutcomeYES,
+		BuyerID:   buyer,
+		SellerID:  seller,
+		Price:     price,
+		Quantity:  qty,
+		TakerSide: takerSide,
+	}
+}
+
+func TestFeeFreeWindow(t *testing.T) {
+	pm, clock := newTestPositions(t)
+	pm.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+	pm.SetFeeFreeWindow("m1", clock.now.Add(time.Hour))
+	deposit(t, pm, "buyer", 100)
+	deposit(t, pm, "seller", 100)
+	if err := pm.MintShares("seller", "m1", 20); err != nil {
+		t.Fatal(err)
+	}
+
+	inWindow := sharesTrade("buyer", "seller", 5000, 10, SideBuy)
+	if err := pm.ExecuteTrade(inWindow); err != nil {
+		t.Fatal(err)
+	}
+	if inWindow.MakerFee != 0 || inWindow.TakerFee != 0 || pm.CollectedFees() != 0 {
+		t.Fatalf("fees inside the window: maker %d taker %d collected %d, want 0", inWindow.MakerFee, inWindow.TakerFee, pm.CollectedFees())
+	}
+
+	clock.now = clock.now.Add(time.Hour)
+	after := sharesTrade("buyer", "seller", 5000, 10, SideBuy)
+	if err := pm.ExecuteTrade(after); err != nil {
+		t.Fatal(err)
+	}
+	// 10 shares at 0.50 = 50000 bps notional
+	if after.MakerFee != 50 || after.TakerFee != 250 {
+		t.Fatalf("fees after the window: maker %d taker %d, want 50 and 250", after.MakerFee, after.TakerFee)
+	}
+	if got := pm.CollectedFees(); got != 300 {
+		t.Fatalf("collected = %d, want 300", got)
+	}
+}
+
+func TestTradingFees(t *testing.T) {
+	// 10 YES at 5000 is 5 USDC of notional: 5 bps maker and 40 bps taker
+	for _, tc := range []struct {
+		name                  string
+		takerSide             Side
+		wantBuyer, wantSeller uint64
+	}{
+		{"buyer takes", SideBuy, 1000000 - 50000 - 200, 1000000 + 50000 - 25},
+		{"seller takes", SideSell, 1000000 - 50000 - 25, 1000000 + 50000 - 200},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pm, _ := newTestPositions(t)
+			pm.SetFeeSchedule(FeeSchedule{MakerBps: 5, TakerBps: 40})
+			pm.SetFeeAccount("house")
+			deposit(t, pm, "buyer", 100)
+			deposit(t, pm, "seller", 100)
+			if err := pm.MintShares("seller", "m1", 10); err != nil {
+				t.Fatal(err)
+			}
+			// Minting 10 pairs costs the seller 10 USDC up front
+			wantSeller := tc.wantSeller - 100000
+
+			trade := sharesTrade("buyer", "seller", 5000, 10, tc.takerSide)
+			if err := pm.ExecuteTrade(trade); err != nil {
+				t.Fatal(err)
+			}
+			if trade.MakerFee != 25 || trade.TakerFee != 200 {
+				t.Errorf("trade records maker %d taker %d, want 25 and 200", trade.MakerFee, trade.TakerFee)
+			}
+			if got := pm.GetBalance("buyer"); got != tc.wantBuyer {
+				t.Errorf("buyer balance = %d, want %d", got, tc.wantBuyer)
+			}
+			if got := pm.GetBalance("seller"); got != wantSeller {
+				t.Errorf("seller balance = %d, want %d", got, wantSeller)
+			}
+			if pm.CollectedFees() != 225 || pm.GetBalance("house") != 225 {
+				t.Errorf("collected %d, house balance %d, want 225", pm.CollectedFees(), pm.GetBalance("house"))
+			}
+		})
+	}
+}
+
+func TestFeeScheduleMaxFee(t *testing.T) {
+	for _, tc := range []struct {
+		fees FeeSchedule
+		want uint64
+	}{
+		{FeeSchedule{MakerBps: 10, TakerBps: 50}, 500},
+		{FeeSchedule{MakerBps: 50, TakerBps: 10}, 500},
+		{NoFees, 0},
+	} {
+		if got := tc.fees.MaxFee(100000); got != tc.want {
+			t.Errorf("%+v: max fee on 100000 = %d, want %d", tc.fees, got, tc.want)
+		}
+	}
+
+	// A buy must cover its notional plus the larger fee
+	pm, _ := newTestPositions(t)
+	pm.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+	deposit(t, pm, "alice", 10)
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeYES, SideBuy, 10000, 10)); err != ErrInsufficientBalance {
+		t.Errorf("buy of the whole balance before fees: err = %v, want %v", err, ErrInsufficientBalance)
+	}
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeYES, SideBuy, 9950, 10)); err != nil {
+		t.Errorf("buy leaving room for the taker fee: %v", err)
+	}
+}
+
+func TestSimulatePayoutsMatchesSettlement(t *testing.T) {
+	for _, outcome := range []OutcomeID{OutcomeYES, OutcomeNO} {
+		t.Run(string(outcome), func(t *testing.T) {
+			pm, _ := newTestPositions(t)
+			deposit(t, pm, "alice", 20)
+			deposit(t, pm, "bob", 20)
+			if err := pm.MintShares("alice", "m1", 10); err != nil {
+				t.Fatal(err)
+			}
+			if err := pm.ExecuteTrade(sharesTrade("bob", "alice", 6000, 6, SideBuy)); err != nil {
+				t.Fatal(err)
+			}
+
+			simulated := pm.SimulatePayouts("m1", outcome)
+			if len(simulated) != 2 {
+				t.Fatalf("%d deltas, want 2", len(simulated))
+			}
+			before := map[string]uint64{"alice": pm.GetBalance("alice"), "bob": pm.GetBalance("bob")}
+
+			settlement := pm.SettleMarket("m1", outcome)
+			for i, d := range simulated {
+				if d.BalanceBefore != before[d.UserID] {
+					t.Errorf("%s: simulated balance before %d, actual %d", d.UserID, d.BalanceBefore, before[d.UserID])
+				}
+				if got := pm.GetBalance(d.UserID); d.BalanceAfter != got {
+					t.Errorf("%s: simulated balance after %d, actual %d", d.UserID, d.BalanceAfter, got)
+				}
+				if settlement.Payouts[i] != d {
+					t.Errorf("%s: settled %+v, simulated %+v", d.UserID, settlement.Payouts[i], d)
+				}
+			}
+		})
+	}
+}
+
+func TestSimulatePayoutsDoesNotMutate(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	deposit(t, pm, "alice", 10)
+	if err := pm.MintShares("alice", "m1", 5); err != nil {
+		t.Fatal(err)
+	}
+
+	pm.SimulatePayouts("m1", OutcomeYES)
+	pos := pm.GetPosition("alice", "m1")
+	if pos.YesShares != 5 || pos.NoShares != 5 || pm.GetBalance("alice") != 50000 {
+		t.Fatalf("state changed: %d YES, %d NO, balance %d", pos.YesShares, pos.NoShares, pm.GetBalance("alice"))
+	}
+	if _, ok := pm.GetSettlement("m1"); ok {
+		t.Fatal("simulation recorded a settlement")
+	}
+}
+
+func BenchmarkExecuteTrade(b *testing.B) {
+	pm := NewPositionManager()
+	pm.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+	if err := pm.Deposit("buyer", uint64(b.N)*10000); err != nil {
+		b.Fatal(err)
+	}
+	if err := pm.Deposit("seller", uint64(b.N)*10000); err != nil {
+		b.Fatal(err)
+	}
+	if err := pm.MintShares("seller", "m1", uint64(b.N)); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := pm.ExecuteTrade(sharesTrade("buyer", "seller", 5000, 1, SideBuy)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestOpenBuysReserveFunds(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	books := NewMarketOrderbooks()
+	pm.SetReservedFunds(books.OpenBuyNotional)
+	deposit(t, pm, "alice", 10)
+
+	// A resting bid for 6 USDC leaves 4 to spend or withdraw
+	placeOn(t, books, NewOrder("alice", "m1", OutcomeYES, SideBuy, 6000, 10))
+	if got := pm.AvailableBalance("alice"); got != 40000 {
+		t.Fatalf("available = %d, want 40000", got)
+	}
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeNO, SideBuy, 5000, 10)); err != ErrInsufficientBalance {
+		t.Errorf("buy covered only by reserved funds: err = %v, want %v", err, ErrInsufficientBalance)
+	}
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeNO, SideBuy, 4000, 10)); err != nil {
+		t.Errorf("buy of the available 4 USDC: %v", err)
+	}
+	if err := pm.Withdraw("alice", 40001); err != ErrInsufficientBalance {
+		t.Errorf("withdraw into reserved funds: err = %v, want %v", err, ErrInsufficientBalance)
+	}
+	if err := pm.Withdraw("alice", 40000); err != nil {
+		t.Fatalf("withdraw the available balance: %v", err)
+	}
+	if got := pm.GetBalance("alice"); got != 60000 {
+		t.Fatalf("balance = %d, want the 60000 backing the bid", got)
+	}
+}
+
+func TestOpenSellsReserveShares(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	books := NewMarketOrderbooks()
+	pm.SetReservedShares(books.OpenSellQuantity)
+	deposit(t, pm, "alice", 10)
+	if err := pm.MintShares("alice", "m1", 10); err != nil {
+		t.Fatal(err)
+	}
+
+	// A resting ask for 6 YES leaves 4 to sell or redeem
+	placeOn(t, books, NewOrder("alice", "m1", OutcomeYES, SideSell, 6000, 6))
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeYES, SideSell, 6500, 5)); err != ErrInsufficientPosition {
+		t.Errorf("sell covered only by reserved shares: err = %v, want %v", err, ErrInsufficientPosition)
+	}
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeYES, SideSell, 6500, 4)); err != nil {
+		t.Errorf("sell of the 4 free shares: %v", err)
+	}
+	if err := pm.ValidateOrder(NewOrder("alice", "m1", OutcomeNO, SideSell, 4000, 10)); err != nil {
+		t.Errorf("sell of the other outcome: %v", err)
+	}
+	if err := pm.RedeemShares("alice", "m1", 5); err != ErrInsufficientPosition {
+		t.Errorf("redeem into reserved shares: err = %v, want %v", err, ErrInsufficientPosition)
+	}
+	if err := pm.RedeemShares("alice", "m1", 4); err != nil {
+		t.Fatalf("redeem the free pairs: %v", err)
+	}
+	if pos := pm.GetPosition("alice", "m1"); pos.YesShares != 6 || pos.NoShares != 6 {
+		t.Fatalf("position = %d YES / %d NO, want the 6 YES backing the ask", pos.YesShares, pos.NoShares)
+	}
+}
+
+func TestWithdraw(t *testing.T) {
+	tests := []struct {
+		name        string
+		amount      uint64
+		wantErr     error
+		wantBalance uint64
+	}{
+		{"part of the balance", 40000, nil, 60000},
+		{"exact balance", 100000, nil, 0},
+		{"over the balance", 100001, ErrInsufficientBalance, 100000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pm, _ := newTestPositions(t)
+			deposit(t, pm, "alice", 10)
+			if err := pm.Withdraw("alice", tt.amount); err != tt.wantErr {
+				t.Fatalf("err = %v, want %v", err, tt.wantErr)
+			}
+			if got := pm.GetBalance("alice"); got != tt.wantBalance {
+				t.Fatalf("balance = %d, want %d", got, tt.wantBalance)
+			}
+		})
+	}
+}
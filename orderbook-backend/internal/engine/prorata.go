@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrInvalidAllocationMode = errors.New("invalid allocation mode: must be 'fifo' or 'pro_rata'")
+
+// AllocationMode decides how an incoming order's quantity is shared between
+// resting orders at the same price
+type AllocationMode string
+
+const (
+	AllocationFIFO    AllocationMode = "fifo"     // Strict price-time priority (default)
+	AllocationProRata AllocationMode = "pro_rata" // Proportional to resting size
+)
+
+// ParseAllocationMode validates an allocation mode name
+func ParseAllocationMode(s string) (AllocationMode, error) {
+	switch m := AllocationMode(s); m {
+	case AllocationFIFO, AllocationProRata:
+		return m, nil
+	default:
+		return "", ErrInvalidAllocationMode
+	}
+}
+
+// SetAllocationMode sets how fills are shared at a price level. In pro-rata
+// mode each resting order gets floor(qty * size / levelSize); shares below
+// minQty are dropped, and the rounding remainder goes out in time priority.
+func (ob *Orderbook) SetAllocationMode(mode AllocationMode, minQty uint64) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.allocMode = mode
+	ob.proRataMin = minQty
+}
+
+// fillLevelProRata fills an incoming order against every live resting order
+// at one price in h, proportionally to their remaining size (must hold lock)
+func (ob *Orderbook) fillLevelProRata(incoming *Order, h *orderHeap, price uint64) []*Trade {
+	now := time.Now()
+	var level []*Order
+	var total uint64
+	for _, o := range h.orders {
+		// Under self-trade prevention the incoming user's own orders at
+		// this price never share in the fill; expired orders never do
+		if o.Price == price && o.RemainingQty() > 0 && !ob.preventsSelfTrade(incoming, o) && !o.Expired(now) {
+			level = append(level, o)
+			total += o.RemainingQty()
+		}
+	}
+	sort.Slice(level, func(i, j int) bool { return level[i].SequenceNum < level[j].SequenceNum })
+
+	qty := incoming.RemainingQty()
+	allocs := make([]uint64, len(level))
+	if qty >= total {
+		for i, o := range level {
+			allocs[i] = o.RemainingQty()
+		}
+	} else {
+		var allocated uint64
+		for i, o := range level {
+			share := qty * o.RemainingQty() / total
+			if share < ob.proRataMin {
+				share = 0
+			}
+			allocs[i] = share
+			allocated += share
+		}
+		// Rounding remainder goes to the earliest orders first
+		for i, o := range level {
+			if allocated == qty {
+				break
+			}
+			extra := min(qty-allocated, o.RemainingQty()-allocs[i])
+			allocs[i] += extra
+			allocated += extra
+		}
+	}
+
+	var trades []*Trade
+	for i, resting := range level {
+		if allocs[i] == 0 {
+			continue
+		}
+		incoming.Fill(allocs[i])
+		resting.Fill(allocs[i])
+
+		if incoming.IsBuy() {
+			trades = append(trades, NewTrade(incoming, resting, price, allocs[i]))
+		} else {
+			trades = append(trades, NewTrade(resting, incoming, price, allocs[i]))
+		}
+
+		if resting.RemainingQty() == 0 {
+			h.remove(resting)
+			delete(ob.orders, resting.ID)
+			ob.emitOrderEvent(OrderRemoved, resting)
+		} else {
+			ob.emitOrderEvent(OrderModified, resting)
+		}
+	}
+	return trades
+}
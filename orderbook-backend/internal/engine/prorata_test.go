@@ -0,0 +1,47 @@
+package engine
+
+import "testing"
+
+func TestProRataAllocation(t *testing.T) {
+	tests := []struct {
+		name   string
+		mode   AllocationMode
+		minQty uint64
+		buy    uint64
+		want   []uint64 // Filled quantity of each resting order, in time priority
+	}{
+		{"half fill splits evenly", AllocationProRata, 1, 15, []uint64{5, 5, 5}},
+		{"remainder goes to the earliest", AllocationProRata, 1, 16, []uint64{6, 5, 5}},
+		{"shares below the minimum are dropped", AllocationProRata, 6, 15, []uint64{10, 5, 0}},
+		{"full fill takes every order", AllocationProRata, 1, 30, []uint64{10, 10, 10}},
+		{"fifo fills in time priority", AllocationFIFO, 1, 15, []uint64{10, 5, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			ob.SetAllocationMode(tt.mode, tt.minQty)
+			var resting []*Order
+			for _, user := range []string{"u1", "u2", "u3"} {
+				order, _ := place(t, ob, user, SideSell, 6000, 10)
+				resting = append(resting, order)
+			}
+
+			buy, trades := place(t, ob, "taker", SideBuy, 6000, tt.buy)
+			if buy.RemainingQty() != 0 {
+				t.Fatalf("taker has %d unfilled", buy.RemainingQty())
+			}
+			var traded uint64
+			for _, trade := range trades {
+				traded += trade.Quantity
+			}
+			if traded != tt.buy {
+				t.Fatalf("traded %d, want %d", traded, tt.buy)
+			}
+			for i, order := range resting {
+				if order.FilledQty != tt.want[i] {
+					t.Errorf("order %d filled %d, want %d", i, order.FilledQty, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,275 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidQuantityScale = errors.New("quantity scale must be 1, 10, 100, 1000 or 10000")
+	ErrQuantityPrecision    = errors.New("quantity has more decimals than the quantity scale allows")
+)
+
+// Quantities (order sizes, fills and share balances) are fixed-point
+// integers: one share is quantityScale units, so with a scale of 100 the
+// engine stores 0.5 shares as 50. The scale divides 10000 so that one share
+// is always worth a whole number of basis points of collateral.
+var (
+	quantityScale    uint64 = 1
+	quantityDecimals        = 0
+)
+
+// SetQuantityScale sets how many units make one share. It must be called
+// before any orders or positions exist and is not safe to change later.
+func SetQuantityScale(scale uint64) error {
+	decimals := 0
+	for s := scale; s > 1; s /= 10 {
+		if s%10 != 0 {
+			return ErrInvalidQuantityScale
+		}
+		decimals++
+	}
+	if scale == 0 || decimals > 4 {
+		return ErrInvalidQuantityScale
+	}
+	quantityScale = scale
+	quantityDecimals = decimals
+	return nil
+}
+
+// QuantityScale returns how many units make one share
+func QuantityScale() uint64 {
+	return quantityScale
+}
+
+// Notional returns what qty units cost at a price, in basis points
+// (10000 = 1 USDC). Fractions of a basis point are rounded down; buyer and
+// seller use the same figure, so value is conserved.
+func Notional(price, qty uint64) uint64 {
+	return price * qty / quantityScale
+}
+
+// Collateral returns the USDC backing qty units of YES+NO pairs, which is
+// also what qty winning shares pay out, in basis points
+func Collateral(qty uint64) uint64 {
+	return qty * (10000 / quantityScale)
+}
+
+// Quantity is a scaled quantity that reads and writes JSON as a decimal
+// number of shares
+type Quantity uint64
+
+// String formats the quantity as a decimal number of shares
+func (q Quantity) String() string {
+	if quantityDecimals == 0 {
+		return strconv.FormatUint(uint64(q), 10)
+	}
+	whole, frac := uint64(q)/quantityScale, uint64(q)%quantityScale
+	if frac == 0 {
+		return strconv.FormatUint(whole, 10)
+	}
+	digits := strings.TrimRight(fmt.Sprintf("%0*d", quantityDecimals, frac), "0")
+	return strconv.FormatUint(whole, 10) + "." + digits
+}
+
+// MarshalJSON writes the quantity as a JSON number of shares
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return []byte(q.String()), nil
+}
+
+// UnmarshalJSON reads a number of shares given as a JSON number or string
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	parsed, err := ParseQuantity(strings.Trim(string(data), `"`))
+	if err != nil {
+		return err
+	}
+	*q = Quantity(parsed)
+	return nil
+}
+
+// ParseQuantity converts a decimal number of shares such as "0.5" to units
+func ParseQuantity(s string) (uint64, error) {
+	whole, frac, hasFrac := strings.Cut(s, ".")
+	if hasFrac {
+		frac = strings.TrimRight(frac, "0")
+	}
+	if len(frac) > quantityDecimals {
+		return 0, ErrQuantityPrecision
+	}
+
+	w, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	units := w * quantityScale
+	if frac != "" {
+		f, err := strconv.ParseUint(frac, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid quantity %q", s)
+		}
+		for i := len(frac); i < quantityDecimals; i++ {
+			f *= 10
+		}
+		units += f
+	}
+	return units, nil
+}
+
+// expiresAt returns t for JSON output, or nil for no expiry
+func expiresAt(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// The JSON forms of the engine types below write quantities as decimal
+// shares. With the default scale of 1 the output is unchanged.
+
+func (o Order) MarshalJSON() ([]byte, error) {
+	type plain Order
+	return json.Marshal(struct {
+		plain
+		Quantity  Quantity   `json:"quantity"`
+		FilledQty Quantity   `json:"filled_qty"`
+		ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	}{plain(o), Quantity(o.Quantity), Quantity(o.FilledQty), expiresAt(o.ExpiresAt)})
+}
+
+// UnmarshalJSON reads orders back from a snapshot
+func (o *Order) UnmarshalJSON(data []byte) error {
+	type plain Order
+	aux := struct {
+		*plain
+		Quantity  Quantity   `json:"quantity"`
+		FilledQty Quantity   `json:"filled_qty"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}{plain: (*plain)(o)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	o.Quantity = uint64(aux.Quantity)
+	o.FilledQty = uint64(aux.FilledQty)
+	o.ExpiresAt = time.Time{}
+	if aux.ExpiresAt != nil {
+		o.ExpiresAt = *aux.ExpiresAt
+	}
+	return nil
+}
+
+func (t Trade) MarshalJSON() ([]byte, error) {
+	type plain Trade
+	return json.Marshal(struct {
+		plain
+		Quantity Quantity `json:"quantity"`
+	}{plain(t), Quantity(t.Quantity)})
+}
+
+// UnmarshalJSON reads trades back from the trade store
+func (t *Trade) UnmarshalJSON(data []byte) error {
+	type plain Trade
+	aux := struct {
+		*plain
+		Quantity Quantity `json:"quantity"`
+	}{plain: (*plain)(t)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	t.Quantity = uint64(aux.Quantity)
+	return nil
+}
+
+func (f Fill) MarshalJSON() ([]byte, error) {
+	type plain Fill
+	return json.Marshal(struct {
+		plain
+		Quantity Quantity `json:"quantity"`
+	}{plain(f), Quantity(f.Quantity)})
+}
+
+func (p Position) MarshalJSON() ([]byte, error) {
+	type plain Position
+	return json.Marshal(struct {
+		plain
+		YesShares Quantity `json:"yes_shares"`
+		NoShares  Quantity `json:"no_shares"`
+	}{plain(p), Quantity(p.YesShares), Quantity(p.NoShares)})
+}
+
+func (l OrderLevel) MarshalJSON() ([]byte, error) {
+	type plain OrderLevel
+	return json.Marshal(struct {
+		plain
+		Quantity Quantity `json:"quantity"`
+	}{plain(l), Quantity(l.Quantity)})
+}
+
+func (c Candle) MarshalJSON() ([]byte, error) {
+	type plain Candle
+	return json.Marshal(struct {
+		plain
+		Volume Quantity `json:"volume"`
+	}{plain(c), Quantity(c.Volume)})
+}
+
+func (s MarketStats) MarshalJSON() ([]byte, error) {
+	type plain MarketStats
+	return json.Marshal(struct {
+		plain
+		OpenInterest Quantity `json:"open_interest"`
+	}{plain(s), Quantity(s.OpenInterest)})
+}
+
+func (e OrderEvent) MarshalJSON() ([]byte, error) {
+	type plain OrderEvent
+	return json.Marshal(struct {
+		plain
+		RemainingQty Quantity `json:"remaining_qty"`
+	}{plain(e), Quantity(e.RemainingQty)})
+}
+
+func (u OrderUpdate) MarshalJSON() ([]byte, error) {
+	type plain OrderUpdate
+	return json.Marshal(struct {
+		plain
+		FilledQty    Quantity `json:"filled_qty"`
+		RemainingQty Quantity `json:"remaining_qty"`
+	}{plain(u), Quantity(u.FilledQty), Quantity(u.RemainingQty)})
+}
+
+func (o L3Order) MarshalJSON() ([]byte, error) {
+	type plain L3Order
+	return json.Marshal(struct {
+		plain
+		RemainingQty Quantity `json:"remaining_qty"`
+	}{plain(o), Quantity(o.RemainingQty)})
+}
+
+func (d BalanceDelta) MarshalJSON() ([]byte, error) {
+	type plain BalanceDelta
+	return json.Marshal(struct {
+		plain
+		YesShares Quantity `json:"yes_shares"`
+		NoShares  Quantity `json:"no_shares"`
+	}{plain(d), Quantity(d.YesShares), Quantity(d.NoShares)})
+}
+
+// UnmarshalJSON reads settlement payouts back from a snapshot
+func (d *BalanceDelta) UnmarshalJSON(data []byte) error {
+	type plain BalanceDelta
+	aux := struct {
+		*plain
+		YesShares Quantity `json:"yes_shares"`
+		NoShares  Quantity `json:"no_shares"`
+	}{plain: (*plain)(d)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	d.YesShares = uint64(aux.YesShares)
+	d.NoShares = uint64(aux.NoShares)
+	return nil
+}
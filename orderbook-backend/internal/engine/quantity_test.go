@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// useQuantityScale sets the quantity scale for the rest of a test
+func useQuantityScale(t *testing.T, scale uint64) {
+	t.Helper()
+	if err := SetQuantityScale(scale); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetQuantityScale(1) })
+}
+
+func TestSetQuantityScale(t *testing.T) {
+	t.Cleanup(func() { SetQuantityScale(1) })
+	for _, scale := range []uint64{1, 10, 100, 1000, 10000} {
+		if err := SetQuantityScale(scale); err != nil {
+			t.Errorf("scale %d: %v", scale, err)
+		}
+	}
+	for _, scale := range []uint64{0, 3, 50, 100000} {
+		if err := SetQuantityScale(scale); err != ErrInvalidQuantityScale {
+			t.Errorf("scale %d: err = %v, want %v", scale, err, ErrInvalidQuantityScale)
+		}
+	}
+}
+
+func TestParseQuantityRoundTrips(t *testing.T) {
+	useQuantityScale(t, 100)
+	tests := []struct {
+		in    string
+		units uint64
+		out   string
+	}{
+		{"0.5", 50, "0.5"},
+		{"0.50", 50, "0.5"},
+		{"1.25", 125, "1.25"},
+		{"2", 200, "2"},
+		{"2.0", 200, "2"},
+		{"0.01", 1, "0.01"},
+	}
+	for _, tt := range tests {
+		units, err := ParseQuantity(tt.in)
+		if err != nil || units != tt.units {
+			t.Errorf("ParseQuantity(%q) = %d, %v, want %d", tt.in, units, err, tt.units)
+			continue
+		}
+		if got := Quantity(units).String(); got != tt.out {
+			t.Errorf("Quantity(%d) = %q, want %q", units, got, tt.out)
+		}
+	}
+
+	if _, err := ParseQuantity("0.125"); err != ErrQuantityPrecision {
+		t.Errorf("0.125: err = %v, want %v", err, ErrQuantityPrecision)
+	}
+	for _, bad := range []string{"", "-1", "abc", "1.x"} {
+		if _, err := ParseQuantity(bad); err == nil {
+			t.Errorf("ParseQuantity(%q) succeeded", bad)
+		}
+	}
+
+	var order Order
+	if err := json.Unmarshal([]byte(`{"quantity":"1.5","filled_qty":0.25}`), &order); err != nil {
+		t.Fatal(err)
+	}
+	if order.Quantity != 150 || order.FilledQty != 25 {
+		t.Fatalf("order quantity %d filled %d, want 150 and 25", order.Quantity, order.FilledQty)
+	}
+}
+
+func TestFractionalTradesConserveValue(t *testing.T) {
+	useQuantityScale(t, 100)
+	pm, _ := newTestPositions(t)
+	books := NewMarketOrderbooks()
+	users := []string{"maker", "taker", "minter"}
+	for _, u := range users {
+		deposit(t, pm, u, 10)
+	}
+
+	// maker mints 1 pair and sells 0.33 YES at 6001 to taker; 0.33 * 0.6001
+	// is 0.198033 USDC, which rounds down to 1980 bps for both sides
+	if err := pm.MintShares("maker", "m1", 100); err != nil {
+		t.Fatal(err)
+	}
+	execute := func(order *Order) []*Trade {
+		t.Helper()
+		if err := pm.ValidateOrder(order); err != nil {
+			t.Fatal(err)
+		}
+		trades, err := books.GetOrderbook("m1", order.OutcomeID).PlaceOrder(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, trade := range trades {
+			if err := pm.ExecuteTrade(trade); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return trades
+	}
+	execute(NewOrder("maker", "m1", OutcomeYES, SideSell, 6001, 33))
+	trades := execute(NewOrder("taker", "m1", OutcomeYES, SideBuy, 6001, 33))
+	if len(trades) != 1 || trades[0].Quantity != 33 {
+		t.Fatalf("trades = %+v, want one fill of 0.33", trades)
+	}
+
+	// A NO bid of 0.5 at 3999 and a YES bid of 0.25 at 6001 mint 0.25 pairs
+	execute(NewOrder("minter", "m1", OutcomeNO, SideBuy, 3999, 50))
+	trades = execute(NewOrder("taker", "m1", OutcomeYES, SideBuy, 6001, 25))
+	if len(trades) != 1 || !trades[0].Mint || trades[0].Quantity != 25 {
+		t.Fatalf("trades = %+v, want one mint of 0.25", trades)
+	}
+
+	wantShares := map[string][2]uint64{
+		"maker":  {67, 100},
+		"taker":  {58, 0},
+		"minter": {0, 25},
+	}
+	var yes, no uint64
+	for _, u := range users {
+		pos := pm.GetPosition(u, "m1")
+		if got := [2]uint64{pos.YesShares, pos.NoShares}; got != wantShares[u] {
+			t.Errorf("%s holds YES/NO %v, want %v", u, got, wantShares[u])
+		}
+		yes += pos.YesShares
+		no += pos.NoShares
+	}
+	if yes != no {
+		t.Fatalf("YES %d != NO %d: shares are not fully collateralized", yes, no)
+	}
+
+	settlement := pm.SettleMarket("m1", OutcomeYES)
+	if settlement.TotalPayout != Collateral(yes) {
+		t.Fatalf("total payout %d, want %d", settlement.TotalPayout, Collateral(yes))
+	}
+	var total uint64
+	for _, u := range users {
+		total += pm.GetBalance(u)
+	}
+	if want := uint64(len(users)) * 10 * 10000; total != want {
+		t.Fatalf("balances sum to %d after settlement, want the %d deposited", total, want)
+	}
+}
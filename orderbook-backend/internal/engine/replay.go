@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"container/heap"
+	"errors"
+	"sort"
+)
+
+var ErrSeqUnavailable = errors.New("sequence number is outside the retained event log")
+
+// maxEventLog bounds the level-3 events each orderbook keeps for replay
+const maxEventLog = 100000
+
+// eventLog holds an orderbook's recent level-3 events. Events that fall off
+// the front are folded into base, the resting orders as of baseSeq, so the
+// book can still be rebuilt from there.
+type eventLog struct {
+	base    map[string]L3Order
+	baseSeq uint64
+	events  []OrderEvent
+}
+
+// append records an event, folding the oldest into the base once full
+func (l *eventLog) append(event OrderEvent) {
+	l.events = append(l.events, event)
+	if len(l.events) <= maxEventLog {
+		return
+	}
+
+	if l.base == nil {
+		l.base = make(map[string]L3Order)
+	}
+	oldest := l.events[0]
+	applyToBase(l.base, oldest)
+	l.baseSeq = oldest.Seq
+	l.events[0] = OrderEvent{}
+	l.events = l.events[1:]
+}
+
+// applyToBase applies one event to a set of resting orders
+func applyToBase(base map[string]L3Order, e OrderEvent) {
+	switch e.Type {
+	case OrderAdded:
+		base[e.OrderID] = L3Order{
+			OrderID:      e.OrderID,
+			Side:         e.Side,
+			Price:        e.Price,
+			RemainingQty: e.RemainingQty,
+			SequenceNum:  e.Seq,
+		}
+	case OrderModified:
+		if o, ok := base[e.OrderID]; ok {
+			o.RemainingQty = e.RemainingQty
+			base[e.OrderID] = o
+		}
+	case OrderRemoved:
+		delete(base, e.OrderID)
+	}
+}
+
+// RemovalEvent returns the event that took an order off the book, if it is
+// still in the retained log. Its status says whether the order filled or
+// was cancelled.
+func (ob *Orderbook) RemovalEvent(orderID string) (OrderEvent, bool) {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	for i := len(ob.events.events) - 1; i >= 0; i-- {
+		e := ob.events.events[i]
+		if e.OrderID == orderID && e.Type == OrderRemoved {
+			return e, true
+		}
+	}
+	return OrderEvent{}, false
+}
+
+// ReplayTo rebuilds the book as it stood right after event seq, in a fresh
+// orderbook that is never connected to matching or callbacks. Returns
+// ErrSeqUnavailable if seq is in the future or older than the retained log.
+func (ob *Orderbook) ReplayTo(seq uint64) (*Orderbook, error) {
+	ob.mu.RLock()
+	if seq > ob.eventSeq || seq < ob.events.baseSeq {
+		ob.mu.RUnlock()
+		return nil, ErrSeqUnavailable
+	}
+	resting := make(map[string]L3Order, len(ob.events.base))
+	for id, o := range ob.events.base {
+		resting[id] = o
+	}
+	for _, e := range ob.events.events {
+		if e.Seq > seq {
+			break
+		}
+		applyToBase(resting, e)
+	}
+	ob.mu.RUnlock()
+
+	// Rest the orders in time priority, without matching
+	orders := make([]L3Order, 0, len(resting))
+	for _, o := range resting {
+		orders = append(orders, o)
+	}
+	sort.Slice(orders, func(i, j int) bool { return orders[i].SequenceNum < orders[j].SequenceNum })
+
+	replay := NewOrderbook()
+	for _, o := range orders {
+		order := &Order{
+			ID:          o.OrderID,
+			Side:        o.Side,
+			Type:        OrderTypeLimit,
+			TimeInForce: TimeInForceGTC,
+			Price:       o.Price,
+			Quantity:    o.RemainingQty,
+			Status:      StatusOpen,
+			SequenceNum: o.SequenceNum,
+			heapIndex:   -1,
+		}
+		replay.orders[order.ID] = order
+		if order.IsBuy() {
+			heap.Push(replay.bids, order)
+		} else {
+			heap.Push(replay.asks, order)
+		}
+	}
+	replay.eventSeq = seq
+	return replay, nil
+}
+
+// EventSeq returns the sequence number of the latest level-3 event
+func (ob *Orderbook) EventSeq() uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.eventSeq
+}
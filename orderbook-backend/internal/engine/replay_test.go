@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestReplayToPastSequence(t *testing.T) {
+	ob := NewOrderbook()
+	place(t, ob, "a", SideBuy, 5000, 10)
+	place(t, ob, "b", SideSell, 6000, 5)
+	place(t, ob, "c", SideBuy, 5000, 4)
+	mid := ob.EventSeq()
+	want := OrderbookSnapshot{
+		Bids: []OrderLevel{{Price: 5000, Quantity: 14, Count: 2}},
+		Asks: []OrderLevel{{Price: 6000, Quantity: 5, Count: 1}},
+	}
+	if got := ob.GetSnapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("live snapshot = %+v, want %+v", got, want)
+	}
+
+	// Later activity: the ask is taken and one bid partly sold into
+	place(t, ob, "d", SideBuy, 6000, 5)
+	place(t, ob, "e", SideSell, 5000, 12)
+	if got := ob.GetSnapshot(); len(got.Asks) != 0 || len(got.Bids) != 1 || got.Bids[0].Quantity != 2 {
+		t.Fatalf("live snapshot after trading = %+v", got)
+	}
+
+	replay, err := ob.ReplayTo(mid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := replay.GetSnapshot(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("replay to %d = %+v, want %+v", mid, got, want)
+	}
+	l3 := replay.GetL3Snapshot()
+	if len(l3.Bids) != 2 || l3.Bids[0].RemainingQty != 10 || l3.Bids[1].RemainingQty != 4 {
+		t.Fatalf("replayed bids = %+v, want a's 10 ahead of c's 4", l3.Bids)
+	}
+
+	empty, err := ob.ReplayTo(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := empty.GetSnapshot(); len(got.Bids)+len(got.Asks) != 0 {
+		t.Fatalf("replay to 0 = %+v, want an empty book", got)
+	}
+	if _, err := ob.ReplayTo(ob.EventSeq() + 1); err != ErrSeqUnavailable {
+		t.Fatalf("future seq: err = %v, want %v", err, ErrSeqUnavailable)
+	}
+}
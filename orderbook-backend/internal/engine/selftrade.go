@@ -0,0 +1,79 @@
+package engine
+
+import (
+	"container/heap"
+	"errors"
+)
+
+var ErrInvalidSelfTradeMode = errors.New("invalid self-trade prevention mode: must be 'allow', 'cancel_resting', 'cancel_incoming' or 'skip'")
+
+// SelfTradeMode decides what happens when an incoming order would match a
+// resting order of the same user
+type SelfTradeMode string
+
+const (
+	SelfTradeAllow          SelfTradeMode = "allow"           // Match as usual (default)
+	SelfTradeCancelResting  SelfTradeMode = "cancel_resting"  // Cancel the resting order and keep matching
+	SelfTradeCancelIncoming SelfTradeMode = "cancel_incoming" // Cancel the rest of the incoming order
+	SelfTradeSkip           SelfTradeMode = "skip"            // Leave the resting order and match past it
+)
+
+// ParseSelfTradeMode validates a self-trade prevention mode name
+func ParseSelfTradeMode(s string) (SelfTradeMode, error) {
+	switch m := SelfTradeMode(s); m {
+	case SelfTradeAllow, SelfTradeCancelResting, SelfTradeCancelIncoming, SelfTradeSkip:
+		return m, nil
+	default:
+		return "", ErrInvalidSelfTradeMode
+	}
+}
+
+// SetSelfTradePrevention sets how the book handles a user's orders meeting
+// each other, including across a linked complement book
+func (ob *Orderbook) SetSelfTradePrevention(mode SelfTradeMode) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.selfTrade = mode
+}
+
+// preventsSelfTrade reports whether an incoming order may not match resting
+func (ob *Orderbook) preventsSelfTrade(incoming, resting *Order) bool {
+	return ob.selfTrade != "" && ob.selfTrade != SelfTradeAllow && incoming.UserID == resting.UserID
+}
+
+// skippedOrder is a resting order taken off the top of its heap while an
+// incoming order matches past it
+type skippedOrder struct {
+	h     *orderHeap
+	order *Order
+}
+
+// avoidSelfTrade applies self-trade prevention when resting, the top of h in
+// book, belongs to the incoming order's user. It reports whether it acted,
+// in which case the caller must look at the book again (must hold lock).
+func (ob *Orderbook) avoidSelfTrade(book *Orderbook, h *orderHeap, incoming, resting *Order, skipped *[]skippedOrder) bool {
+	if !ob.preventsSelfTrade(incoming, resting) {
+		return false
+	}
+
+	switch ob.selfTrade {
+	case SelfTradeCancelResting:
+		h.remove(resting)
+		resting.Cancel()
+		delete(book.orders, resting.ID)
+		book.emitOrderEvent(OrderRemoved, resting)
+	case SelfTradeCancelIncoming:
+		incoming.Cancel()
+	case SelfTradeSkip:
+		heap.Pop(h)
+		*skipped = append(*skipped, skippedOrder{h: h, order: resting})
+	}
+	return true
+}
+
+// restoreSkipped puts skipped orders back on their heaps (must hold lock)
+func restoreSkipped(skipped []skippedOrder) {
+	for _, s := range skipped {
+		heap.Push(s.h, s.order)
+	}
+}
@@ -0,0 +1,83 @@
+package engine
+
+import "testing"
+
+func TestSelfTradePrevention(t *testing.T) {
+	tests := []struct {
+		mode              SelfTradeMode
+		wantFills         []uint64 // Quantity of each trade, in order
+		wantIncoming      OrderStatus
+		wantOwnAsk        bool // Alice's ask is still resting
+		wantIncomingRests bool // The incoming remainder rests on the book
+	}{
+		{SelfTradeAllow, []uint64{5, 3}, StatusFilled, false, false},
+		{SelfTradeCancelResting, []uint64{5}, StatusPartial, false, true},
+		{SelfTradeCancelIncoming, nil, StatusCancelled, true, false},
+		{SelfTradeSkip, []uint64{5}, StatusPartial, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			ob := NewOrderbook()
+			ob.SetSelfTradePrevention(tt.mode)
+			own, _ := place(t, ob, "alice", SideSell, 6000, 5)
+			place(t, ob, "bob", SideSell, 6100, 5)
+
+			incoming, trades := place(t, ob, "alice", SideBuy, 6100, 8)
+			if len(trades) != len(tt.wantFills) {
+				t.Fatalf("%d trades %+v, want %d", len(trades), trades, len(tt.wantFills))
+			}
+			for i, trade := range trades {
+				if trade.Quantity != tt.wantFills[i] {
+					t.Errorf("trade %d: quantity %d, want %d", i, trade.Quantity, tt.wantFills[i])
+				}
+				if tt.mode != SelfTradeAllow && trade.BuyerID == trade.SellerID {
+					t.Errorf("trade %d is a self-trade: %+v", i, trade)
+				}
+			}
+			if incoming.Status != tt.wantIncoming {
+				t.Errorf("incoming status = %s, want %s", incoming.Status, tt.wantIncoming)
+			}
+			if _, err := ob.GetOrder(own.ID); (err == nil) != tt.wantOwnAsk {
+				t.Errorf("own ask resting = %v, want %v", err == nil, tt.wantOwnAsk)
+			}
+			if tt.mode == SelfTradeCancelResting && own.Status != StatusCancelled {
+				t.Errorf("own ask status = %s, want cancelled", own.Status)
+			}
+			if _, err := ob.GetOrder(incoming.ID); (err == nil) != tt.wantIncomingRests {
+				t.Errorf("incoming resting = %v, want %v", err == nil, tt.wantIncomingRests)
+			}
+		})
+	}
+}
+
+func TestSelfTradePreventionCoversMinting(t *testing.T) {
+	for _, mode := range []SelfTradeMode{SelfTradeCancelResting, SelfTradeCancelIncoming, SelfTradeSkip} {
+		t.Run(string(mode), func(t *testing.T) {
+			books := NewMarketOrderbooks()
+			books.SetSelfTradePrevention(mode)
+			placeOn(t, books, NewOrder("alice", "m1", OutcomeNO, SideBuy, 4000, 5))
+			placeOn(t, books, NewOrder("bob", "m1", OutcomeNO, SideBuy, 3500, 5))
+
+			trades := placeOn(t, books, NewOrder("alice", "m1", OutcomeYES, SideBuy, 6500, 5))
+			for _, trade := range trades {
+				if trade.BuyerID == trade.SellerID {
+					t.Fatalf("self-trade through minting: %+v", trade)
+				}
+			}
+			if mode != SelfTradeCancelIncoming && (len(trades) != 1 || trades[0].SellerID != "bob") {
+				t.Fatalf("trades = %+v, want one mint with bob", trades)
+			}
+		})
+	}
+}
+
+func TestParseSelfTradeMode(t *testing.T) {
+	for _, s := range []string{"allow", "cancel_resting", "cancel_incoming", "skip"} {
+		if mode, err := ParseSelfTradeMode(s); err != nil || string(mode) != s {
+			t.Errorf("ParseSelfTradeMode(%q) = %q, %v", s, mode, err)
+		}
+	}
+	if _, err := ParseSelfTradeMode("cancel_both"); err != ErrInvalidSelfTradeMode {
+		t.Errorf("err = %v, want %v", err, ErrInvalidSelfTradeMode)
+	}
+}
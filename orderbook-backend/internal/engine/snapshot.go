@@ -0,0 +1,269 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// Snapshots
+//
+// Export and Import save and restore the engine's state across restarts.
+// Quantities are written as decimal shares like everywhere else in JSON, so
+// a snapshot survives a change of QUANTITY_SCALE as long as no quantity
+// becomes too precise for the new scale. Trade history, level-3 event logs
+// and daily notional counters are not kept: they restart empty.
+
+var ErrDuplicateOrder = errors.New("order already on the book")
+
+// BookSnapshot is one outcome orderbook's resting orders and settings
+type BookSnapshot struct {
+	Orders         []*Order       `json:"orders"` // In time priority
+	AllocationMode AllocationMode `json:"allocation_mode,omitempty"`
+	ProRataMin     Quantity       `json:"pro_rata_min,omitempty"`
+	AuctionUntil   *time.Time     `json:"auction_until,omitempty"` // Set while in an opening auction
+}
+
+// MarketBooksSnapshot holds both outcome orderbooks of a market
+type MarketBooksSnapshot struct {
+	YES BookSnapshot `json:"YES"`
+	NO  BookSnapshot `json:"NO"`
+}
+
+// OrderbooksSnapshot is the state of every market's orderbooks
+type OrderbooksSnapshot struct {
+	Markets map[string]MarketBooksSnapshot `json:"markets"`
+}
+
+// export captures the orderbook (must hold lock)
+func (ob *Orderbook) export() BookSnapshot {
+	snap := BookSnapshot{
+		Orders:         make([]*Order, 0, len(ob.orders)),
+		AllocationMode: ob.allocMode,
+		ProRataMin:     Quantity(ob.proRataMin),
+	}
+	for _, order := range ob.orders {
+		copied := *order
+		snap.Orders = append(snap.Orders, &copied)
+	}
+	sort.Slice(snap.Orders, func(i, j int) bool {
+		return snap.Orders[i].SequenceNum < snap.Orders[j].SequenceNum
+	})
+	if ob.inAuction {
+		snap.AuctionUntil = &ob.auctionUntil
+	}
+	return snap
+}
+
+// restore rests a snapshot's orders on the book, keeping their sequence
+// numbers so price-time priority is unchanged (must hold lock)
+func (ob *Orderbook) restore(snap BookSnapshot, outcome OutcomeID) error {
+	for _, order := range snap.Orders {
+		if order.OutcomeID != outcome {
+			return fmt.Errorf("order %s: outcome %s on the %s book", order.ID, order.OutcomeID, outcome)
+		}
+		if _, exists := ob.orders[order.ID]; exists {
+			return fmt.Errorf("%w: %s", ErrDuplicateOrder, order.ID)
+		}
+		if order.Price > 10000 || order.RemainingQty() == 0 {
+			return fmt.Errorf("order %s is not a resting order", order.ID)
+		}
+		copied := *order
+		copied.heapIndex = -1
+		ob.rest(&copied)
+		bumpOrderSequence(copied.SequenceNum)
+	}
+
+	if snap.AllocationMode != "" {
+		ob.allocMode = snap.AllocationMode
+		ob.proRataMin = uint64(snap.ProRataMin)
+	}
+	if snap.AuctionUntil != nil {
+		ob.inAuction = true
+		ob.auctionUntil = *snap.AuctionUntil
+	}
+	return nil
+}
+
+// bumpOrderSequence makes sure orders placed from now on sort after seq
+func bumpOrderSequence(seq uint64) {
+	for {
+		current := atomic.LoadUint64(&orderSequence)
+		if current >= seq || atomic.CompareAndSwapUint64(&orderSequence, current, seq) {
+			return
+		}
+	}
+}
+
+// Export captures the resting orders and settings of every market's orderbooks
+func (m *MarketOrderbooks) Export() OrderbooksSnapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snap := OrderbooksSnapshot{Markets: make(map[string]MarketBooksSnapshot, len(m.orderbooks))}
+	for marketID, obs := range m.orderbooks {
+		// Linked books share one lock
+		obs.YES.mu.RLock()
+		snap.Markets[marketID] = MarketBooksSnapshot{
+			YES: obs.YES.export(),
+			NO:  obs.NO.export(),
+		}
+		obs.YES.mu.RUnlock()
+	}
+	return snap
+}
+
+// Import rests the orders of a snapshot on the matching orderbooks, creating
+// them as needed. Orders are restored as they were, without matching.
+func (m *MarketOrderbooks) Import(snap OrderbooksSnapshot) error {
+	for marketID, books := range snap.Markets {
+		obs := m.GetOrCreate(marketID)
+
+		obs.YES.mu.Lock()
+		err := obs.YES.restore(books.YES, OutcomeYES)
+		if err == nil {
+			err = obs.NO.restore(books.NO, OutcomeNO)
+		}
+		obs.YES.mu.Unlock()
+		if err != nil {
+			return fmt.Errorf("market %s: %w", marketID, err)
+		}
+	}
+	return nil
+}
+
+// PositionSnapshot is a position with its cost basis
+type PositionSnapshot struct {
+	UserID    string   `json:"user_id"`
+	MarketID  string   `json:"market_id"`
+	YesShares Quantity `json:"yes_shares"`
+	NoShares  Quantity `json:"no_shares"`
+	Balance   uint64   `json:"balance"`
+	YesCost   uint64   `json:"yes_cost"`
+	NoCost    uint64   `json:"no_cost"`
+	Realized  int64    `json:"realized"`
+}
+
+// PositionsSnapshot is the state of the position manager
+type PositionsSnapshot struct {
+	Balances      map[string]uint64        `json:"balances"`
+	Funded        map[string]uint64        `json:"funded,omitempty"`
+	Positions     []PositionSnapshot       `json:"positions"`
+	CollectedFees uint64                   `json:"collected_fees"`
+	FeeFreeUntil  map[string]time.Time     `json:"fee_free_until,omitempty"`
+	MarketLimits  map[string]Limits        `json:"market_limits,omitempty"`
+	Settlements   map[string]*Settlement   `json:"settlements,omitempty"`
+	Ledger        map[string][]LedgerEntry `json:"ledger,omitempty"`
+}
+
+// Export captures balances, positions, settlements and ledgers
+func (pm *PositionManager) Export() PositionsSnapshot {
+	pm.mu.RLock()
+	defer pm.mu.RUnlock()
+
+	snap := PositionsSnapshot{
+		Balances:      make(map[string]uint64, len(pm.balances)),
+		Funded:        make(map[string]uint64, len(pm.funded)),
+		Positions:     make([]PositionSnapshot, 0),
+		CollectedFees: pm.collectedFees,
+		FeeFreeUntil:  make(map[string]time.Time, len(pm.feeFreeUntil)),
+		MarketLimits:  make(map[string]Limits, len(pm.marketLimits)),
+		Settlements:   make(map[string]*Settlement, len(pm.settlements)),
+		Ledger:        make(map[string][]LedgerEntry, len(pm.ledger)),
+	}
+	for userID, balance := range pm.balances {
+		snap.Balances[userID] = balance
+	}
+	for userID, amount := range pm.funded {
+		snap.Funded[userID] = amount
+	}
+	for _, markets := range pm.positions {
+		for _, pos := range markets {
+			snap.Positions = append(snap.Positions, PositionSnapshot{
+				UserID:    pos.UserID,
+				MarketID:  pos.MarketID,
+				YesShares: Quantity(pos.YesShares),
+				NoShares:  Quantity(pos.NoShares),
+				Balance:   pos.Balance,
+				YesCost:   pos.yesCost,
+				NoCost:    pos.noCost,
+				Realized:  pos.realized,
+			})
+		}
+	}
+	sort.Slice(snap.Positions, func(i, j int) bool {
+		a, b := snap.Positions[i], snap.Positions[j]
+		if a.UserID != b.UserID {
+			return a.UserID < b.UserID
+		}
+		return a.MarketID < b.MarketID
+	})
+	for marketID, until := range pm.feeFreeUntil {
+		snap.FeeFreeUntil[marketID] = until
+	}
+	for marketID, limits := range pm.marketLimits {
+		snap.MarketLimits[marketID] = limits
+	}
+	for marketID, settlement := range pm.settlements {
+		copied := *settlement
+		copied.Payouts = append([]BalanceDelta(nil), settlement.Payouts...)
+		snap.Settlements[marketID] = &copied
+	}
+	for userID, entries := range pm.ledger {
+		snap.Ledger[userID] = append([]LedgerEntry(nil), entries...)
+	}
+	return snap
+}
+
+// Import replaces the position manager's state with a snapshot. Fee
+// schedule, global limits and callbacks are configuration and are kept.
+func (pm *PositionManager) Import(snap PositionsSnapshot) {
+	pm.mu.Lock()
+	defer pm.mu.Unlock()
+
+	pm.balances = make(map[string]uint64, len(snap.Balances))
+	for userID, balance := range snap.Balances {
+		pm.balances[userID] = balance
+	}
+	pm.funded = make(map[string]uint64, len(snap.Funded))
+	for userID, amount := range snap.Funded {
+		pm.funded[userID] = amount
+	}
+	pm.positions = make(map[string]map[string]*Position)
+	for _, p := range snap.Positions {
+		if _, ok := pm.positions[p.UserID]; !ok {
+			pm.positions[p.UserID] = make(map[string]*Position)
+		}
+		pm.positions[p.UserID][p.MarketID] = &Position{
+			UserID:    p.UserID,
+			MarketID:  p.MarketID,
+			YesShares: uint64(p.YesShares),
+			NoShares:  uint64(p.NoShares),
+			Balance:   p.Balance,
+			yesCost:   p.YesCost,
+			noCost:    p.NoCost,
+			realized:  p.Realized,
+		}
+	}
+	pm.collectedFees = snap.CollectedFees
+	pm.feeFreeUntil = make(map[string]time.Time, len(snap.FeeFreeUntil))
+	for marketID, until := range snap.FeeFreeUntil {
+		pm.feeFreeUntil[marketID] = until
+	}
+	pm.marketLimits = make(map[string]Limits, len(snap.MarketLimits))
+	for marketID, limits := range snap.MarketLimits {
+		pm.marketLimits[marketID] = limits
+	}
+	pm.settlements = make(map[string]*Settlement, len(snap.Settlements))
+	for marketID, settlement := range snap.Settlements {
+		copied := *settlement
+		pm.settlements[marketID] = &copied
+	}
+	pm.ledger = make(map[string][]LedgerEntry, len(snap.Ledger))
+	for userID, entries := range snap.Ledger {
+		pm.ledger[userID] = append([]LedgerEntry(nil), entries...)
+	}
+	pm.dailyNotional = make(map[string]map[string]*dailyNotional)
+}
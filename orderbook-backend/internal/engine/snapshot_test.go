@@ -0,0 +1,85 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestImportKeepsTimePriority(t *testing.T) {
+	books := NewMarketOrderbooks()
+	placeOn(t, books, NewOrder("first", "m1", OutcomeYES, SideBuy, 6000, 5))
+	placeOn(t, books, NewOrder("second", "m1", OutcomeYES, SideBuy, 6000, 5))
+	placeOn(t, books, NewOrder("no", "m1", OutcomeNO, SideBuy, 3000, 5))
+
+	// Through JSON, as it is written to disk
+	data, err := json.Marshal(books.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap OrderbooksSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	restored := NewMarketOrderbooks()
+	if err := restored.Import(snap); err != nil {
+		t.Fatal(err)
+	}
+	got, err := json.Marshal(restored.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Fatalf("round trip changed the books:\n got %s\nwant %s", got, data)
+	}
+
+	// A bid placed after the restore queues behind both restored bids
+	placeOn(t, restored, NewOrder("third", "m1", OutcomeYES, SideBuy, 6000, 5))
+	trades := placeOn(t, restored, NewOrder("seller", "m1", OutcomeYES, SideSell, 6000, 15))
+	if len(trades) != 3 {
+		t.Fatalf("%d trades, want 3", len(trades))
+	}
+	for i, want := range []string{"first", "second", "third"} {
+		if trades[i].BuyerID != want {
+			t.Errorf("fill %d went to %s, want %s", i, trades[i].BuyerID, want)
+		}
+	}
+
+	if err := restored.Import(snap); err == nil {
+		t.Error("importing resting orders twice: want an error")
+	}
+}
+
+func TestPositionsRoundTrip(t *testing.T) {
+	pm, _ := newTestPositions(t)
+	pm.SetFeeSchedule(FeeSchedule{MakerBps: 10, TakerBps: 50})
+	deposit(t, pm, "buyer", 100)
+	deposit(t, pm, "seller", 100)
+	if err := pm.MintShares("seller", "m1", 10); err != nil {
+		t.Fatal(err)
+	}
+	if err := pm.ExecuteTrade(sharesTrade("buyer", "seller", 6000, 4, SideBuy)); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(pm.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap PositionsSnapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatal(err)
+	}
+	restored, _ := newTestPositions(t)
+	restored.Import(snap)
+
+	got, err := json.Marshal(restored.Export())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(data) {
+		t.Fatalf("round trip changed the positions:\n got %s\nwant %s", got, data)
+	}
+	if pnl := restored.GetPnL("seller", "m1", 6000, 4000); pnl != pm.GetPnL("seller", "m1", 6000, 4000) {
+		t.Fatalf("cost basis lost: PnL %+v, want %+v", pnl, pm.GetPnL("seller", "m1", 6000, 4000))
+	}
+}
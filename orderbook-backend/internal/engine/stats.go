@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"math"
+	"time"
+)
+
+// MarketStats summarizes trading activity and liquidity across both
+// outcome orderbooks of a market. Volumes cover the trades still held in
+// the books' in-memory trade histories.
+type MarketStats struct {
+	MarketID     string  `json:"market_id"`
+	Volume       uint64  `json:"volume"`     // Basis points
+	Volume24h    uint64  `json:"volume_24h"` // Basis points
+	TradeCount   int     `json:"trade_count"`
+	OpenInterest uint64  `json:"open_interest"` // Resting quantity on both books
+	YesMid       *uint64 `json:"yes_mid"`       // Nil unless the YES book has both a bid and an ask
+	NoMid        *uint64 `json:"no_mid"`
+}
+
+// Stats aggregates a market's trade histories and resting orders. Trades
+// from the last 24 hours before now count towards Volume24h.
+func (m *MarketOrderbooks) Stats(marketID string, now time.Time) MarketStats {
+	stats := MarketStats{MarketID: marketID}
+	obs := m.Get(marketID)
+	if obs == nil {
+		return stats
+	}
+
+	since := now.Add(-24 * time.Hour)
+	for _, ob := range []*Orderbook{obs.YES, obs.NO} {
+		for _, trade := range ob.history.All() {
+			notional := Notional(trade.Price, trade.Quantity)
+			stats.Volume += notional
+			if !trade.Timestamp.Before(since) {
+				stats.Volume24h += notional
+			}
+			stats.TradeCount++
+		}
+		stats.OpenInterest += ob.RestingQuantity()
+	}
+	stats.YesMid = obs.YES.Mid()
+	stats.NoMid = obs.NO.Mid()
+	return stats
+}
+
+// PriceChange compares a book's last trade price with its price a day earlier
+type PriceChange struct {
+	LastPrice   *uint64  `json:"last_price"`    // Nil before the first trade
+	Price24hAgo *uint64  `json:"price_24h_ago"` // Last trade at or before now-24h
+	ChangePct   *float64 `json:"change_pct"`    // Nil unless both prices are known and the old one is above zero
+}
+
+// PriceChange reports the last trade price and the change over the 24
+// hours before now, from the book's trade history
+func (ob *Orderbook) PriceChange(now time.Time) PriceChange {
+	var change PriceChange
+	if last, ok := ob.history.PriceAt(now); ok {
+		change.LastPrice = &last
+	}
+	if ago, ok := ob.history.PriceAt(now.Add(-24 * time.Hour)); ok {
+		change.Price24hAgo = &ago
+		if change.LastPrice != nil && ago > 0 {
+			pct := math.Round((float64(*change.LastPrice)-float64(ago))/float64(ago)*10000) / 100
+			change.ChangePct = &pct
+		}
+	}
+	return change
+}
+
+// RestingQuantity returns the unfilled quantity of every resting order
+func (ob *Orderbook) RestingQuantity() uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	var total uint64
+	for _, order := range ob.orders {
+		total += order.RemainingQty()
+	}
+	return total
+}
+
+// Mid returns the midpoint of the best bid and ask, or nil if either side
+// is empty
+func (ob *Orderbook) Mid() *uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+
+	bid, hasBid := topLevel(ob.bids)
+	ask, hasAsk := topLevel(ob.asks)
+	if !hasBid || !hasAsk {
+		return nil
+	}
+	mid := (bid.Price + ask.Price) / 2
+	return &mid
+}
@@ -0,0 +1,71 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarketStats(t *testing.T) {
+	books := NewMarketOrderbooks()
+	obs := books.GetOrCreate("m1")
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	// Two YES trades a day and a half apart and one NO trade in the last hour
+	obs.YES.history.Add(&Trade{ID: "t1", Price: 5000, Quantity: 10, Timestamp: now.Add(-36 * time.Hour)})
+	obs.YES.history.Add(&Trade{ID: "t2", Price: 6000, Quantity: 5, Timestamp: now.Add(-2 * time.Hour)})
+	obs.NO.history.Add(&Trade{ID: "t3", Price: 4000, Quantity: 4, Timestamp: now.Add(-time.Hour)})
+
+	place(t, obs.YES, "bidder", SideBuy, 5800, 3)
+	place(t, obs.YES, "asker", SideSell, 6200, 7)
+	place(t, obs.NO, "bidder", SideBuy, 3500, 2)
+
+	stats := books.Stats("m1", now)
+	if stats.Volume != 50000+30000+16000 || stats.Volume24h != 30000+16000 || stats.TradeCount != 3 {
+		t.Fatalf("volume %d, 24h %d over %d trades, want 96000, 46000 over 3", stats.Volume, stats.Volume24h, stats.TradeCount)
+	}
+	if stats.OpenInterest != 12 {
+		t.Errorf("open interest = %d, want 12", stats.OpenInterest)
+	}
+	if stats.YesMid == nil || *stats.YesMid != 6000 {
+		t.Errorf("YES mid = %v, want 6000", stats.YesMid)
+	}
+	if stats.NoMid != nil {
+		t.Errorf("NO mid = %d, want nil with no NO asks", *stats.NoMid)
+	}
+
+	if empty := books.Stats("missing", now); empty.TradeCount != 0 || empty.Volume != 0 || empty.YesMid != nil {
+		t.Errorf("unknown market stats = %+v, want zeroes", empty)
+	}
+}
+
+func TestPriceChange(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	ob := NewOrderbook()
+	if change := ob.PriceChange(now); change.LastPrice != nil || change.Price24hAgo != nil || change.ChangePct != nil {
+		t.Fatalf("no trades: %+v, want all nil", change)
+	}
+
+	// The trade exactly 24h ago is the reference; the older one is not
+	ob.history.Add(&Trade{Price: 4000, Quantity: 1, Timestamp: now.Add(-30 * time.Hour)})
+	ob.history.Add(&Trade{Price: 5000, Quantity: 1, Timestamp: now.Add(-24 * time.Hour)})
+	ob.history.Add(&Trade{Price: 5100, Quantity: 1, Timestamp: now.Add(-time.Hour)})
+	ob.history.Add(&Trade{Price: 5210, Quantity: 1, Timestamp: now.Add(-time.Minute)})
+
+	change := ob.PriceChange(now)
+	if change.LastPrice == nil || *change.LastPrice != 5210 {
+		t.Fatalf("last price = %v, want 5210", change.LastPrice)
+	}
+	if change.Price24hAgo == nil || *change.Price24hAgo != 5000 {
+		t.Fatalf("price 24h ago = %v, want 5000", change.Price24hAgo)
+	}
+	if change.ChangePct == nil || *change.ChangePct != 4.2 {
+		t.Fatalf("change = %v, want 4.2%%", change.ChangePct)
+	}
+
+	// Only trades from the last day: there is no reference price yet
+	recent := NewOrderbook()
+	recent.history.Add(&Trade{Price: 5000, Quantity: 1, Timestamp: now.Add(-time.Hour)})
+	if change := recent.PriceChange(now); change.LastPrice == nil || change.Price24hAgo != nil || change.ChangePct != nil {
+		t.Fatalf("trades only in the last day: %+v, want a last price and nothing else", change)
+	}
+}
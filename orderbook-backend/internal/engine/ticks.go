@@ -0,0 +1,65 @@
+package engine
+
+import "errors"
+
+var (
+	ErrOffTick          = errors.New("price is not a multiple of the tick size")
+	ErrBelowMinQuantity = errors.New("quantity is below the minimum order size")
+	ErrInvalidTickSize  = errors.New("tick size must divide 10000")
+)
+
+// SetTickSize sets the price increment, in basis points, that limit order
+// prices must be a multiple of. Zero or one allows any price.
+func (ob *Orderbook) SetTickSize(bps uint64) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.tickSize = bps
+}
+
+// SetMinQuantity sets the smallest quantity an order may be placed for.
+// Zero allows any positive quantity.
+func (ob *Orderbook) SetMinQuantity(qty uint64) {
+	ob.mu.Lock()
+	defer ob.mu.Unlock()
+	ob.minQty = qty
+}
+
+// TickSize returns the book's price increment in basis points (0 = any)
+func (ob *Orderbook) TickSize() uint64 {
+	ob.mu.RLock()
+	defer ob.mu.RUnlock()
+	return ob.tickSize
+}
+
+// checkOrderSize rejects a limit price off the tick and a quantity below
+// the minimum (must hold lock)
+func (ob *Orderbook) checkOrderSize(order *Order) error {
+	if ob.tickSize > 1 && !order.IsMarket() && order.Price%ob.tickSize != 0 {
+		return ErrOffTick
+	}
+	if order.Quantity < ob.minQty {
+		return ErrBelowMinQuantity
+	}
+	return nil
+}
+
+// CheckTickSize validates a tick size before it is applied
+func CheckTickSize(bps uint64) error {
+	if bps > 1 && 10000%bps != 0 {
+		return ErrInvalidTickSize
+	}
+	return nil
+}
+
+// roundToTick moves a quote price onto the tick grid, away from the mid so
+// the quote stays at least as wide: bids round down, asks round up
+func roundToTick(price, tick uint64, side Side) uint64 {
+	if tick <= 1 || price%tick == 0 {
+		return price
+	}
+	price -= price % tick
+	if side == SideSell {
+		price += tick
+	}
+	return price
+}
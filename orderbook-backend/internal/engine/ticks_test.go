@@ -0,0 +1,59 @@
+package engine
+
+import "testing"
+
+func TestTickSizeAndMinQuantity(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		order *Order
+		want  error
+	}{
+		{"on the tick", NewOrder("u", "m1", OutcomeYES, SideBuy, 5000, 10), nil},
+		{"lowest tick", NewOrder("u", "m1", OutcomeYES, SideBuy, 100, 10), nil},
+		{"highest tick", NewOrder("u", "m1", OutcomeYES, SideSell, 9900, 10), nil},
+		{"off the tick", NewOrder("u", "m1", OutcomeYES, SideBuy, 5050, 10), ErrOffTick},
+		{"below the minimum", NewOrder("u", "m1", OutcomeYES, SideBuy, 5000, 9), ErrBelowMinQuantity},
+		{"market order off the tick", NewMarketOrder("u", "m1", OutcomeYES, SideBuy, 10), nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ob := NewOrderbook()
+			ob.SetTickSize(100)
+			ob.SetMinQuantity(10)
+			if _, err := ob.PlaceOrder(tc.order); err != tc.want {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			if _, err := ob.GetOrder(tc.order.ID); tc.want != nil && err == nil {
+				t.Fatal("rejected order is resting")
+			}
+		})
+	}
+}
+
+func TestCheckTickSize(t *testing.T) {
+	for _, bps := range []uint64{0, 1, 5, 100, 2500, 10000} {
+		if err := CheckTickSize(bps); err != nil {
+			t.Errorf("tick %d: %v", bps, err)
+		}
+	}
+	for _, bps := range []uint64{3, 300, 7000} {
+		if err := CheckTickSize(bps); err != ErrInvalidTickSize {
+			t.Errorf("tick %d: err = %v, want %v", bps, err, ErrInvalidTickSize)
+		}
+	}
+}
+
+func TestRoundToTick(t *testing.T) {
+	for _, tc := range []struct {
+		price uint64
+		side  Side
+		want  uint64
+	}{
+		{5050, SideBuy, 5000},
+		{5050, SideSell, 5100},
+		{5000, SideSell, 5000},
+	} {
+		if got := roundToTick(tc.price, 100, tc.side); got != tc.want {
+			t.Errorf("%s at %d: %d, want %d", tc.side, tc.price, got, tc.want)
+		}
+	}
+}
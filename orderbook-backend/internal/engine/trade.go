@@ -18,11 +18,25 @@ type Trade struct {
 	SellerID    string    `json:"seller_id"`
 	Price       uint64    `json:"price"`
 	Quantity    uint64    `json:"quantity"`
+	TakerSide   Side      `json:"taker_side"` // Side of the incoming (aggressing) order
+	MakerFee    uint64    `json:"maker_fee"`  // Fee charged to the resting order's owner
+	TakerFee    uint64    `json:"taker_fee"`  // Fee charged to the incoming order's owner
 	Timestamp   time.Time `json:"timestamp"`
+
+	// Mint marks a match between buyers of opposite outcomes. The "seller"
+	// bought the other outcome at 10000-Price: together the two paid for a
+	// freshly minted YES+NO pair and each took one side.
+	Mint bool `json:"mint,omitempty"`
 }
 
 // NewTrade creates a new trade record
 func NewTrade(buyOrder, sellOrder *Order, price, quantity uint64) *Trade {
+	// The later order is the one that crossed the book
+	takerSide := SideSell
+	if buyOrder.SequenceNum > sellOrder.SequenceNum {
+		takerSide = SideBuy
+	}
+
 	return &Trade{
 		ID:          uuid.New().String(),
 		MarketID:    buyOrder.MarketID,
@@ -33,10 +47,72 @@ func NewTrade(buyOrder, sellOrder *Order, price, quantity uint64) *Trade {
 		SellerID:    sellOrder.UserID,
 		Price:       price,
 		Quantity:    quantity,
+		TakerSide:   takerSide,
 		Timestamp:   time.Now(),
 	}
 }
 
+// MintCost returns what the opposite-outcome buyer of a mint match pays: the
+// pair's collateral less what the other buyer paid, so the two always add up
+// to exactly the collateral
+func (t *Trade) MintCost() uint64 {
+	return Collateral(t.Quantity) - Notional(t.Price, t.Quantity)
+}
+
+// Fill is one side of a trade from a single user's point of view
+type Fill struct {
+	TradeID   string    `json:"trade_id"`
+	MarketID  string    `json:"market_id"`
+	OutcomeID OutcomeID `json:"outcome_id"`
+	Side      Side      `json:"side"`
+	Role      string    `json:"role"` // "maker" or "taker"
+	Price     uint64    `json:"price"`
+	Quantity  uint64    `json:"quantity"`
+	Notional  uint64    `json:"notional"` // price * quantity, in basis points
+	Fee       uint64    `json:"fee"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// FillFor returns the user's side of the trade, or false if they weren't in it
+func (t *Trade) FillFor(userID string) (Fill, bool) {
+	var side Side
+	switch userID {
+	case t.BuyerID:
+		side = SideBuy
+	case t.SellerID:
+		side = SideSell
+	default:
+		return Fill{}, false
+	}
+
+	role, fee := "maker", t.MakerFee
+	if side == t.TakerSide {
+		role, fee = "taker", t.TakerFee
+	}
+
+	fill := Fill{
+		TradeID:   t.ID,
+		MarketID:  t.MarketID,
+		OutcomeID: t.OutcomeID,
+		Side:      side,
+		Role:      role,
+		Price:     t.Price,
+		Quantity:  t.Quantity,
+		Notional:  Notional(t.Price, t.Quantity),
+		Fee:       fee,
+		Timestamp: t.Timestamp,
+	}
+
+	// The other buyer of a mint match bought the opposite outcome
+	if t.Mint && side == SideSell {
+		fill.OutcomeID = t.OutcomeID.Complement()
+		fill.Side = SideBuy
+		fill.Price = 10000 - t.Price
+		fill.Notional = t.MintCost()
+	}
+	return fill, true
+}
+
 // TradeHistory stores all completed trades
 type TradeHistory struct {
 	mu     sync.RWMutex
@@ -79,6 +155,62 @@ func (h *TradeHistory) Recent(n int) []*Trade {
 	return result
 }
 
+// Query returns trades with timestamps in [since, until], newest first,
+// skipping the first offset matches and returning at most limit of them. A
+// zero since or until leaves that end open; limit <= 0 means no limit.
+func (h *TradeHistory) Query(since, until time.Time, limit, offset int) []*Trade {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make([]*Trade, 0)
+	for i := len(h.trades) - 1; i >= 0; i-- {
+		trade := h.trades[i]
+		if !until.IsZero() && trade.Timestamp.After(until) {
+			continue
+		}
+		if !since.IsZero() && trade.Timestamp.Before(since) {
+			continue
+		}
+		if offset > 0 {
+			offset--
+			continue
+		}
+		if limit > 0 && len(result) == limit {
+			break
+		}
+		result = append(result, trade)
+	}
+	return result
+}
+
+// PriceAt returns the price of the last trade at or before t. ok is false
+// if there was none.
+func (h *TradeHistory) PriceAt(t time.Time) (price uint64, ok bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for i := len(h.trades) - 1; i >= 0; i-- {
+		if !h.trades[i].Timestamp.After(t) {
+			return h.trades[i].Price, true
+		}
+	}
+	return 0, false
+}
+
+// ForOrder returns the retained trades an order took part in, oldest first
+func (h *TradeHistory) ForOrder(orderID string) []*Trade {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	result := make([]*Trade, 0)
+	for _, trade := range h.trades {
+		if trade.BuyOrderID == orderID || trade.SellOrderID == orderID {
+			result = append(result, trade)
+		}
+	}
+	return result
+}
+
 // All returns all trades
 func (h *TradeHistory) All() []*Trade {
 	h.mu.RLock()
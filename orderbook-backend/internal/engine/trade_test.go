@@ -0,0 +1,89 @@
+package engine
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// historyAt builds a trade history with one trade per timestamp, IDs t0, t1, ...
+func historyAt(times ...time.Time) *TradeHistory {
+	h := NewTradeHistory(len(times))
+	for i, at := range times {
+		h.Add(&Trade{ID: fmt.Sprintf("t%d", i), Price: 5000, Quantity: 1, Timestamp: at})
+	}
+	return h
+}
+
+func TestTradeHistoryQuery(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := func(n int) time.Time { return base.Add(time.Duration(n) * time.Minute) }
+	h := historyAt(minute(0), minute(1), minute(2), minute(3), minute(4))
+
+	ids := func(trades []*Trade) string {
+		out := ""
+		for _, trade := range trades {
+			out += trade.ID + " "
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name          string
+		since, until  time.Time
+		limit, offset int
+		want          string
+	}{
+		{"everything, newest first", time.Time{}, time.Time{}, 0, 0, "t4 t3 t2 t1 t0 "},
+		{"inclusive range", minute(1), minute(3), 0, 0, "t3 t2 t1 "},
+		{"open start", time.Time{}, minute(1), 0, 0, "t1 t0 "},
+		{"first page", time.Time{}, time.Time{}, 2, 0, "t4 t3 "},
+		{"second page", time.Time{}, time.Time{}, 2, 2, "t2 t1 "},
+		{"last page", time.Time{}, time.Time{}, 2, 4, "t0 "},
+		{"paged range", minute(1), minute(4), 2, 1, "t3 t2 "},
+		{"empty range", minute(5), time.Time{}, 0, 0, ""},
+	} {
+		if got := ids(h.Query(tc.since, tc.until, tc.limit, tc.offset)); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestCandlesAcrossBucketBoundaries(t *testing.T) {
+	base := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+	h := NewTradeHistory(10)
+	for _, trade := range []struct {
+		after      time.Duration
+		price, qty uint64
+	}{
+		{10 * time.Second, 5000, 2},
+		{30 * time.Second, 5400, 1},
+		{50 * time.Second, 4800, 3},
+		{59 * time.Second, 5100, 1},
+		{60 * time.Second, 5200, 4}, // First trade of the next minute
+		{3*time.Minute + time.Second, 5300, 2},
+	} {
+		h.Add(&Trade{Price: trade.price, Quantity: trade.qty, Timestamp: base.Add(trade.after)})
+	}
+
+	want := []Candle{
+		{Start: base, Open: 5000, High: 5400, Low: 4800, Close: 5100, Volume: 7, Trades: 4},
+		{Start: base.Add(time.Minute), Open: 5200, High: 5200, Low: 5200, Close: 5200, Volume: 4, Trades: 1},
+		// The empty minute at 10:02 is omitted
+		{Start: base.Add(3 * time.Minute), Open: 5300, High: 5300, Low: 5300, Close: 5300, Volume: 2, Trades: 1},
+	}
+	if got := h.Candles(time.Minute, time.Time{}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("1m candles:\n got %+v\nwant %+v", got, want)
+	}
+
+	hourly := h.Candles(time.Hour, time.Time{})
+	if len(hourly) != 1 || hourly[0].Open != 5000 || hourly[0].Close != 5300 || hourly[0].Volume != 13 {
+		t.Fatalf("1h candles = %+v, want one from 5000 to 5300 of 13", hourly)
+	}
+	if since := h.Candles(time.Minute, base.Add(time.Minute)); len(since) != 2 || since[0].Open != 5200 {
+		t.Fatalf("candles since 10:01 = %+v, want the last two", since)
+	}
+	if got := h.Candles(0, time.Time{}); len(got) != 0 {
+		t.Fatalf("zero interval: %+v, want none", got)
+	}
+}
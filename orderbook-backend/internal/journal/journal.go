@@ -0,0 +1,132 @@
+// Package journal writes the engine's write-ahead journal to a JSON-lines
+// file and replays it on startup.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+)
+
+// Writer appends journal entries to a file. Entries are written under the
+// lock of the change they record, once it is made but before anyone else
+// can see it (see engine/journal.go), so unlike the trade store it writes
+// synchronously.
+type Writer struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	sync bool
+}
+
+// Open opens (or creates) the journal at path for appending
+func Open(path string) (*Writer, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open journal: %w", err)
+	}
+	return &Writer{path: path, file: f}, nil
+}
+
+// SetSync makes every entry be flushed to disk before Record returns, so the
+// journal also survives a power loss rather than only a process crash
+func (w *Writer) SetSync(enabled bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.sync = enabled
+}
+
+// Record appends one entry. It is the callback handed to the orderbooks
+// and the position manager.
+func (w *Writer) Record(entry engine.JournalEntry) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		log.Printf("Failed to encode journal entry: %v", err)
+		return
+	}
+	line = append(line, '\n')
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.file.Write(line); err != nil {
+		log.Printf("Failed to write journal entry: %v", err)
+		return
+	}
+	if w.sync {
+		if err := w.file.Sync(); err != nil {
+			log.Printf("Failed to sync journal: %v", err)
+		}
+	}
+}
+
+// marketChange is the body of a market entry
+type marketChange struct {
+	Market     *market.Market           `json:"market"`
+	Settlement *market.SettlementRecord `json:"settlement,omitempty"`
+}
+
+// RecordMarket appends a market as it is after being created or changed,
+// with its settlement record once it has one. It is the callback handed to
+// the market manager.
+func (w *Writer) RecordMarket(mkt *market.Market, record *market.SettlementRecord) {
+	body, err := json.Marshal(marketChange{Market: mkt, Settlement: record})
+	if err != nil {
+		log.Printf("Failed to encode journal entry: %v", err)
+		return
+	}
+	w.Record(engine.JournalEntry{
+		Timestamp: time.Now().UTC(),
+		Type:      engine.JournalMarket,
+		MarketID:  mkt.ID,
+		Market:    body,
+	})
+}
+
+// Reset empties the journal. Call it right after a snapshot has been saved,
+// since the snapshot already holds everything the journal recorded.
+func (w *Writer) Reset() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Truncate(0)
+}
+
+// Close closes the journal file
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.file.Close()
+}
+
+// Replay applies the journal at path to markets, books and positions and
+// returns how many entries it applied. A missing journal applies nothing.
+func Replay(path string, markets *market.Manager, books *engine.MarketOrderbooks, positions *engine.PositionManager) (int, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	return engine.ReplayEach(f, func(entry engine.JournalEntry) error {
+		if entry.Type != engine.JournalMarket {
+			return engine.ApplyJournalEntry(entry, books, positions)
+		}
+		var change marketChange
+		if err := json.Unmarshal(entry.Market, &change); err != nil {
+			return fmt.Errorf("market entry: %w", err)
+		}
+		if change.Market == nil {
+			return fmt.Errorf("market entry without a market")
+		}
+		markets.Replay(change.Market, change.Settlement)
+		return nil
+	})
+}
@@ -0,0 +1,145 @@
+package journal
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+)
+
+func TestWriterReplaysIntoFreshPositions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	w, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	positions := engine.NewPositionManager()
+	positions.SetJournalCallback(w.Record)
+	if err := positions.Deposit("alice", 50000); err != nil {
+		t.Fatal(err)
+	}
+	if err := positions.Withdraw("alice", 20000); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed := engine.NewPositionManager()
+	n, err := Replay(path, market.NewManager(), engine.NewMarketOrderbooks(), replayed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || replayed.GetBalance("alice") != 30000 {
+		t.Fatalf("replayed %d entries to a balance of %d, want 2 and 30000", n, replayed.GetBalance("alice"))
+	}
+}
+
+func TestResetAndMissingJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	if n, err := Replay(path, market.NewManager(), engine.NewMarketOrderbooks(), engine.NewPositionManager()); n != 0 || err != nil {
+		t.Fatalf("missing journal: applied %d, err %v", n, err)
+	}
+
+	w, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	w.Record(engine.JournalEntry{Type: engine.JournalDeposit, UserID: "alice", Amount: 10000})
+	if err := w.Reset(); err != nil {
+		t.Fatal(err)
+	}
+	// Appends after a reset start the file over
+	w.Record(engine.JournalEntry{Type: engine.JournalDeposit, UserID: "bob", Amount: 10000})
+
+	positions := engine.NewPositionManager()
+	if n, err := Replay(path, market.NewManager(), engine.NewMarketOrderbooks(), positions); n != 1 || err != nil {
+		t.Fatalf("after reset: applied %d, err %v; want 1", n, err)
+	}
+	if positions.GetBalance("alice") != 0 || positions.GetBalance("bob") != 10000 {
+		t.Fatalf("balances alice %d bob %d, want 0 and 10000", positions.GetBalance("alice"), positions.GetBalance("bob"))
+	}
+}
+
+func TestReplayRestoresMarketsAndTradeHistory(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal.jsonl")
+	w, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	markets := market.NewManager()
+	markets.SetJournalCallback(w.RecordMarket)
+	books := engine.NewMarketOrderbooks()
+	books.SetGlobalJournalCallback(w.Record)
+	positions := engine.NewPositionManager()
+	positions.SetJournalCallback(w.Record)
+
+	mkt, err := markets.Create(market.CreateMarketRequest{Question: "Will it rain?", ResolvesAt: time.Now().Add(time.Hour), CreatorID: "alice"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, user := range []string{"alice", "bob"} {
+		if err := positions.Deposit(user, 100000); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, order := range []*engine.Order{
+		engine.NewOrder("alice", mkt.ID, engine.OutcomeYES, engine.SideBuy, 6000, 5),
+		engine.NewOrder("bob", mkt.ID, engine.OutcomeNO, engine.SideBuy, 4000, 5),
+	} {
+		trades, err := books.GetOrderbook(mkt.ID, order.OutcomeID).PlaceOrder(order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, trade := range trades {
+			if err := positions.ExecuteTrade(trade); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := markets.Lock(mkt.ID); err != nil {
+		t.Fatal(err)
+	}
+	settle := func(marketID string, outcome market.Outcome) []market.SettlementEntry {
+		for _, user := range []string{"alice", "bob"} {
+			positions.PayoutWinningShares(user, marketID, engine.OutcomeID(outcome))
+		}
+		return nil
+	}
+	if _, _, err := markets.ResolveAndSettle(market.ResolveRequest{MarketID: mkt.ID, Outcome: market.OutcomeYes}, settle); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	replayedMarkets := market.NewManager()
+	replayedBooks := engine.NewMarketOrderbooks()
+	replayedPositions := engine.NewPositionManager()
+	if _, err := Replay(path, replayedMarkets, replayedBooks, replayedPositions); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := exportJSON(t, replayedMarkets.Export()), exportJSON(t, markets.Export()); got != want {
+		t.Fatalf("replayed markets differ:\n got %s\nwant %s", got, want)
+	}
+	if got, want := exportJSON(t, replayedPositions.Export()), exportJSON(t, positions.Export()); got != want {
+		t.Fatalf("replayed positions differ:\n got %s\nwant %s", got, want)
+	}
+	if !replayedBooks.HasTraded(mkt.ID) {
+		t.Fatal("replayed books have no trade history for the market")
+	}
+}
+
+// exportJSON encodes a snapshot for comparison
+func exportJSON(t *testing.T, v interface{}) string {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
@@ -8,4 +8,20 @@ var (
 	ErrMarketNotLocked   = errors.New("market must be locked before resolution")
 	ErrAlreadyResolved   = errors.New("market already resolved")
 	ErrInvalidOutcome    = errors.New("outcome must be YES or NO")
+	ErrInvalidOutcomeSet = errors.New("outcomes must be at least two distinct, non-empty values")
+	ErrTooManyOutcomes   = errors.New("market exceeds the maximum number of outcomes")
+	ErrMaxOutcomesTooLow = errors.New("maximum number of outcomes must allow a YES/NO market")
+	ErrNotBinary         = errors.New("only YES/NO markets can trade and settle; categorical outcomes are not supported yet")
+	ErrBadEmptyPolicy    = errors.New("empty market policy must be keep, void or flag")
+	ErrUnknownStatus     = errors.New("unknown market status")
+	ErrEmptyQuestion     = errors.New("question is required")
+
+	ErrResolutionPending   = errors.New("market resolution is pending its dispute window")
+	ErrNotResolving        = errors.New("market has no resolution pending")
+	ErrAlreadyDisputed     = errors.New("market resolution is already disputed")
+	ErrDisputeWindowOpen   = errors.New("dispute window has not ended")
+	ErrDisputeWindowClosed = errors.New("dispute window has ended")
+
+	ErrMarketFinal    = errors.New("market is resolved or voided and cannot be reopened")
+	ErrResolvesAtPast = errors.New("new resolution time must be in the future")
 )
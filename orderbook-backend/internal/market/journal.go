@@ -0,0 +1,37 @@
+package market
+
+// SetJournalCallback sets the callback that receives a copy of a market
+// after every change to it, under the manager's lock. record is the
+// market's settlement record once it has one, and nil before.
+func (m *Manager) SetJournalCallback(fn func(mkt *Market, record *SettlementRecord)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onJournal = fn
+}
+
+// journal reports a market that has just been created or changed (must hold lock)
+func (m *Manager) journal(market *Market) {
+	if m.onJournal == nil {
+		return
+	}
+	var record *SettlementRecord
+	if r, ok := m.settlements[market.ID]; ok {
+		copied := *r
+		record = &copied
+	}
+	m.onJournal(market.snapshot(), record)
+}
+
+// Replay applies a journaled market change: the market as it was after the
+// change, and its settlement record if it had one. It is not journaled again.
+func (m *Manager) Replay(mkt *Market, record *SettlementRecord) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	copied := *mkt
+	m.markets[mkt.ID] = &copied
+	if record != nil {
+		copiedRecord := *record
+		m.settlements[mkt.ID] = &copiedRecord
+	}
+}
@@ -7,21 +7,84 @@ import (
 	"time"
 )
 
+// EmptyMarketPolicy decides what happens to a market that locks with
+// nothing at stake
+type EmptyMarketPolicy string
+
+const (
+	EmptyMarketKeep EmptyMarketPolicy = "keep" // Wait for a manual resolution (default)
+	EmptyMarketVoid EmptyMarketPolicy = "void" // Void it automatically
+	EmptyMarketFlag EmptyMarketPolicy = "flag" // Flag it for operator attention
+)
+
+// ParseEmptyMarketPolicy validates an empty market policy name
+func ParseEmptyMarketPolicy(s string) (EmptyMarketPolicy, error) {
+	switch p := EmptyMarketPolicy(s); p {
+	case EmptyMarketKeep, EmptyMarketVoid, EmptyMarketFlag:
+		return p, nil
+	default:
+		return "", ErrBadEmptyPolicy
+	}
+}
+
 // LifecycleManager handles automatic market status transitions
 type LifecycleManager struct {
 	marketManager *Manager
 	stopCh        chan struct{}
 	wg            sync.WaitGroup
+
+	drainWindow    time.Duration // Drain this long before ResolvesAt (0 = never)
+	onStatusChange func(*Market) // Called after each automatic transition
+
+	// Handling of markets still unresolved with zero open interest after
+	// ResolvesAt + emptyGrace
+	emptyPolicy  EmptyMarketPolicy
+	emptyGrace   time.Duration
+	openInterest func(marketID string) uint64
+
+	// Pays out markets whose dispute window passes undisputed
+	settle SettleFunc
+
+	// Resolves locked markets automatically (nil = manual resolution only)
+	oracle Oracle
 }
 
-// NewLifecycleManager creates a new lifecycle manager
-func NewLifecycleManager(mm *Manager) *LifecycleManager {
+// NewLifecycleManager creates a new lifecycle manager. Locked markets are
+// resolved through oracle as soon as it knows their outcome; pass nil to
+// resolve every market manually.
+func NewLifecycleManager(mm *Manager, oracle Oracle) *LifecycleManager {
 	return &LifecycleManager{
 		marketManager: mm,
 		stopCh:        make(chan struct{}),
+		oracle:        oracle,
 	}
 }
 
+// SetDrainWindow makes markets enter draining this long before ResolvesAt
+func (lm *LifecycleManager) SetDrainWindow(d time.Duration) {
+	lm.drainWindow = d
+}
+
+// SetStatusCallback sets the callback for automatic status transitions
+func (lm *LifecycleManager) SetStatusCallback(fn func(*Market)) {
+	lm.onStatusChange = fn
+}
+
+// SetEmptyMarketPolicy sets how locked markets with zero open interest are
+// handled once grace has passed since ResolvesAt. openInterest reports the
+// shares outstanding in a market.
+func (lm *LifecycleManager) SetEmptyMarketPolicy(policy EmptyMarketPolicy, grace time.Duration, openInterest func(marketID string) uint64) {
+	lm.emptyPolicy = policy
+	lm.emptyGrace = grace
+	lm.openInterest = openInterest
+}
+
+// SetSettleFunc sets how markets are paid out when their resolution
+// becomes final at the end of the dispute window
+func (lm *LifecycleManager) SetSettleFunc(fn SettleFunc) {
+	lm.settle = fn
+}
+
 // Start begins the lifecycle management goroutine
 func (lm *LifecycleManager) Start(ctx context.Context) {
 	lm.wg.Add(1)
@@ -53,22 +116,115 @@ func (lm *LifecycleManager) run(ctx context.Context) {
 	}
 }
 
-// checkAndLockMarkets locks any markets that have passed their resolution time
+// checkAndLockMarkets drains markets nearing their resolution time, locks
+// any that have passed it and finalizes resolutions past their dispute window
 func (lm *LifecycleManager) checkAndLockMarkets() {
 	now := time.Now()
 	markets := lm.marketManager.List()
 
 	for _, market := range markets {
-		if market.Status == StatusTrading && now.After(market.ResolvesAt) {
+		switch {
+		case market.Status == StatusResolving && market.DisputedBy == "" && !now.Before(*market.DisputeEndsAt):
+			if _, record, err := lm.marketManager.Finalize(market.ID, lm.settle); err != nil {
+				log.Printf("Failed to finalize market %s: %v", market.ID, err)
+			} else {
+				log.Printf("Market %s resolved %s (dispute window passed, %d payouts)", market.ID, *market.Outcome, len(record.Entries))
+				lm.notify(market.ID)
+			}
+
+		case (market.Status == StatusTrading || market.Status == StatusDraining) && now.After(market.ResolvesAt):
 			if err := lm.marketManager.Lock(market.ID); err != nil {
 				log.Printf("Failed to lock market %s: %v", market.ID, err)
 			} else {
 				log.Printf("Market %s auto-locked (resolution time passed)", market.ID)
+				lm.notify(market.ID)
+			}
+
+		case market.Status == StatusLocked:
+			if lm.resolveByOracle(market) {
+				continue
+			}
+			if now.After(market.ResolvesAt.Add(lm.emptyGrace)) {
+				lm.handleEmptyMarket(market)
+			}
+
+		case market.Status == StatusTrading && lm.drainWindow > 0 && now.After(market.ResolvesAt.Add(-lm.drainWindow)):
+			if err := lm.marketManager.Drain(market.ID); err != nil {
+				log.Printf("Failed to drain market %s: %v", market.ID, err)
+			} else {
+				log.Printf("Market %s draining (locks at %s)", market.ID, market.ResolvesAt.Format(time.RFC3339))
+				lm.notify(market.ID)
 			}
 		}
 	}
 }
 
+// resolveByOracle resolves a locked market with the oracle's outcome, if it
+// has one yet, and reports whether it did
+func (lm *LifecycleManager) resolveByOracle(market *Market) bool {
+	if lm.oracle == nil {
+		return false
+	}
+	outcome, ok, err := lm.oracle.Resolve(market)
+	if err != nil {
+		log.Printf("Oracle failed for market %s: %v", market.ID, err)
+		return false
+	}
+	if !ok {
+		return false
+	}
+
+	_, record, err := lm.marketManager.ResolveAndSettle(ResolveRequest{MarketID: market.ID, Outcome: outcome}, lm.settle)
+	if err != nil {
+		log.Printf("Failed to resolve market %s from oracle: %v", market.ID, err)
+		return false
+	}
+	if record == nil {
+		log.Printf("Market %s resolving %s from oracle (dispute window open)", market.ID, outcome)
+	} else {
+		log.Printf("Market %s resolved %s from oracle (%d payouts)", market.ID, outcome, len(record.Entries))
+	}
+	lm.notify(market.ID)
+	return true
+}
+
+// handleEmptyMarket applies the empty market policy to a locked market past
+// its grace period, if nothing is at stake in it
+func (lm *LifecycleManager) handleEmptyMarket(market *Market) {
+	if lm.openInterest == nil || market.NeedsAttention || lm.openInterest(market.ID) > 0 {
+		return
+	}
+
+	switch lm.emptyPolicy {
+	case EmptyMarketVoid:
+		if err := lm.marketManager.Void(market.ID); err != nil {
+			log.Printf("Failed to void market %s: %v", market.ID, err)
+			return
+		}
+		log.Printf("Market %s auto-voided (no open interest)", market.ID)
+		lm.notify(market.ID)
+
+	case EmptyMarketFlag:
+		if err := lm.marketManager.Flag(market.ID); err != nil {
+			log.Printf("Failed to flag market %s: %v", market.ID, err)
+			return
+		}
+		log.Printf("Market %s flagged for attention (locked with no open interest)", market.ID)
+		lm.notify(market.ID)
+	}
+}
+
+// notify reports a status transition to the callback, if set, with the
+// market as it is after the transition
+func (lm *LifecycleManager) notify(marketID string) {
+	if lm.onStatusChange == nil {
+		return
+	}
+	if market, ok := lm.marketManager.Get(marketID); ok {
+		lm.onStatusChange(market)
+	}
+}
+
 // ForceTransition allows manual status transition (for admin/testing)
 func (lm *LifecycleManager) ForceTransition(marketID string, targetStatus MarketStatus) error {
 	lm.marketManager.mu.Lock()
@@ -81,10 +237,14 @@ func (lm *LifecycleManager) ForceTransition(marketID string, targetStatus Market
 
 	// Validate transition
 	switch targetStatus {
-	case StatusLocked:
+	case StatusDraining:
 		if market.Status != StatusTrading {
 			return ErrInvalidTransition
 		}
+	case StatusLocked:
+		if market.Status != StatusTrading && market.Status != StatusDraining {
+			return ErrInvalidTransition
+		}
 	case StatusResolved:
 		if market.Status != StatusLocked {
 			return ErrMarketNotLocked
@@ -94,5 +254,6 @@ func (lm *LifecycleManager) ForceTransition(marketID string, targetStatus Market
 	}
 
 	market.Status = targetStatus
+	lm.marketManager.journal(market)
 	return nil
 }
@@ -0,0 +1,67 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+// newLockedMarkets creates markets that reached ResolvesAt the given time ago
+// and runs the lifecycle once to lock them
+func newLockedMarkets(t *testing.T, m *Manager, lm *LifecycleManager, ago ...time.Duration) []string {
+	t.Helper()
+	ids := make([]string, len(ago))
+	for i, d := range ago {
+		mkt, err := m.Create(CreateMarketRequest{Question: "q?", ResolvesAt: time.Now().Add(-d), CreatorID: "c"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = mkt.ID
+	}
+	lm.checkAndLockMarkets()
+	for _, id := range ids {
+		if status, _ := m.Status(id); status != StatusLocked {
+			t.Fatalf("market %s: status = %v, want locked", id, status)
+		}
+	}
+	return ids
+}
+
+func TestEmptyMarketVoidsAfterGrace(t *testing.T) {
+	m := NewManager()
+	lm := NewLifecycleManager(m, nil)
+	held := map[string]uint64{}
+	lm.SetEmptyMarketPolicy(EmptyMarketVoid, time.Hour, func(id string) uint64 { return held[id] })
+
+	ids := newLockedMarkets(t, m, lm, 2*time.Hour, 2*time.Hour, 10*time.Minute)
+	empty, traded, recent := ids[0], ids[1], ids[2]
+	held[traded] = 5
+
+	lm.checkAndLockMarkets()
+	for _, tc := range []struct {
+		id   string
+		want MarketStatus
+	}{
+		{empty, StatusVoided},
+		{traded, StatusLocked},
+		{recent, StatusLocked}, // Still within its grace period
+	} {
+		if status, _ := m.Status(tc.id); status != tc.want {
+			t.Errorf("market %s: status = %v, want %v", tc.id, status, tc.want)
+		}
+	}
+}
+
+func TestEmptyMarketFlaggedForAttention(t *testing.T) {
+	m := NewManager()
+	lm := NewLifecycleManager(m, nil)
+	lm.SetEmptyMarketPolicy(EmptyMarketFlag, time.Hour, func(string) uint64 { return 0 })
+
+	id := newLockedMarkets(t, m, lm, 2*time.Hour)[0]
+	lm.checkAndLockMarkets()
+	lm.checkAndLockMarkets()
+
+	mkt, _ := m.Get(id)
+	if mkt.Status != StatusLocked || !mkt.NeedsAttention {
+		t.Fatalf("status %v, needs attention %v: want a flagged locked market", mkt.Status, mkt.NeedsAttention)
+	}
+}
@@ -1,21 +1,32 @@
 package market
 
 import (
+	"math/rand"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
-// MarketStatus represents the lifecycle stage of a prediction market
+// MarketStatus represents the lifecycle stage of a prediction market.
+// The numeric values are part of the API (serialized as status_code) and
+// must not be reordered; new statuses are appended.
 type MarketStatus int
 
 const (
-	StatusTrading  MarketStatus = iota // Accepting orders
-	StatusLocked                       // No more orders, awaiting resolution
-	StatusResolved                     // Outcome determined, payouts ready
+	StatusTrading   MarketStatus = iota // Accepting orders
+	StatusLocked                        // No more orders, awaiting resolution
+	StatusResolved                      // Outcome determined, payouts ready
+	StatusDraining                      // Only reduce-only orders, about to lock
+	StatusVoided                        // Closed without an outcome (nothing was at stake)
+	StatusResolving                     // Outcome proposed, final once the dispute window passes
 )
 
+// AllStatuses lists every market status in code order
+var AllStatuses = []MarketStatus{StatusTrading, StatusLocked, StatusResolved, StatusDraining, StatusVoided, StatusResolving}
+
 func (s MarketStatus) String() string {
 	switch s {
 	case StatusTrading:
@@ -24,11 +35,27 @@ func (s MarketStatus) String() string {
 		return "locked"
 	case StatusResolved:
 		return "resolved"
+	case StatusDraining:
+		return "draining"
+	case StatusVoided:
+		return "voided"
+	case StatusResolving:
+		return "resolving"
 	default:
 		return "unknown"
 	}
 }
 
+// ParseMarketStatus looks up a market status by its name
+func ParseMarketStatus(s string) (MarketStatus, error) {
+	for _, status := range AllStatuses {
+		if status.String() == s {
+			return status, nil
+		}
+	}
+	return 0, ErrUnknownStatus
+}
+
 // Outcome represents the possible outcomes of a binary market
 type Outcome string
 
@@ -37,30 +64,83 @@ const (
 	OutcomeNo  Outcome = "NO"
 )
 
+// BinaryOutcomes is the outcome set used when a market doesn't specify one
+var BinaryOutcomes = []Outcome{OutcomeYes, OutcomeNo}
+
+// DefaultMaxOutcomes bounds categorical markets. Every outcome gets its own
+// orderbook (two heaps, an order index and a 1000-trade history ring), so a
+// market's memory footprint grows linearly with its outcome count.
+const DefaultMaxOutcomes = 16
+
 // Market represents a binary prediction market
 type Market struct {
 	ID          string       `json:"id"`
 	Question    string       `json:"question"`
 	Description string       `json:"description,omitempty"`
+	Outcomes    []Outcome    `json:"outcomes"`
 	Status      MarketStatus `json:"status"`
-	Outcome     *Outcome     `json:"outcome,omitempty"` // nil until resolved
+	Outcome     *Outcome     `json:"outcome,omitempty"` // nil until resolved (proposed while resolving)
 	CreatedAt   time.Time    `json:"created_at"`
 	ResolvesAt  time.Time    `json:"resolves_at"` // When trading locks
 	ResolvedAt  *time.Time   `json:"resolved_at,omitempty"`
 	CreatorID   string       `json:"creator_id"`
+
+	// Resolvers may resolve the market besides admins (defaults to the creator)
+	Resolvers []string `json:"resolvers,omitempty"`
+
+	// NeedsAttention flags a market the lifecycle manager left for an operator
+	NeedsAttention bool `json:"needs_attention,omitempty"`
+
+	// FeeFreeUntil is the end of the fee-free bootstrap window (zero if none)
+	FeeFreeUntil time.Time `json:"fee_free_until"`
+
+	// A proposed resolution becomes final at DisputeEndsAt unless disputed;
+	// DisputedBy is set while a dispute awaits a new resolution
+	DisputeEndsAt *time.Time `json:"dispute_ends_at,omitempty"`
+	DisputedBy    string     `json:"disputed_by,omitempty"`
+}
+
+// CanResolve reports whether an identity is on the market's resolver list.
+// Identities are compared case-insensitively since they are usually addresses.
+func (m *Market) CanResolve(identity string) bool {
+	if identity == "" {
+		return false
+	}
+	for _, r := range m.Resolvers {
+		if strings.EqualFold(r, identity) {
+			return true
+		}
+	}
+	return false
+}
+
+// snapshot copies the market for use outside the manager's lock. Writers
+// replace pointer and slice fields rather than writing through them, so a
+// shallow copy is enough (must hold lock).
+func (m *Market) snapshot() *Market {
+	copied := *m
+	return &copied
 }
 
 // MarketJSON is the JSON representation of a market
 type MarketJSON struct {
-	ID          string  `json:"id"`
-	Question    string  `json:"question"`
-	Description string  `json:"description,omitempty"`
-	Status      string  `json:"status"`
-	Outcome     *string `json:"outcome,omitempty"`
-	CreatedAt   string  `json:"created_at"`
-	ResolvesAt  string  `json:"resolves_at"`
-	ResolvedAt  *string `json:"resolved_at,omitempty"`
-	CreatorID   string  `json:"creator_id"`
+	ID          string   `json:"id"`
+	Question    string   `json:"question"`
+	Description string   `json:"description,omitempty"`
+	Outcomes    []string `json:"outcomes"`
+	Status      string   `json:"status"`
+	StatusCode  int      `json:"status_code"` // Numeric MarketStatus, stable across releases
+	Outcome     *string  `json:"outcome,omitempty"`
+	CreatedAt   string   `json:"created_at"`
+	ResolvesAt  string   `json:"resolves_at"`
+	ResolvedAt  *string  `json:"resolved_at,omitempty"`
+	CreatorID   string   `json:"creator_id"`
+	Resolvers   []string `json:"resolvers,omitempty"`
+
+	NeedsAttention bool    `json:"needs_attention,omitempty"`
+	FeeFreeUntil   *string `json:"fee_free_until,omitempty"`
+	DisputeEndsAt  *string `json:"dispute_ends_at,omitempty"`
+	DisputedBy     string  `json:"disputed_by,omitempty"`
 }
 
 // ToJSON converts a Market to its JSON representation
@@ -69,10 +149,19 @@ func (m *Market) ToJSON() MarketJSON {
 		ID:          m.ID,
 		Question:    m.Question,
 		Description: m.Description,
+		Outcomes:    make([]string, len(m.Outcomes)),
 		Status:      m.Status.String(),
+		StatusCode:  int(m.Status),
 		CreatedAt:   m.CreatedAt.Format(time.RFC3339),
 		ResolvesAt:  m.ResolvesAt.Format(time.RFC3339),
 		CreatorID:   m.CreatorID,
+		Resolvers:   m.Resolvers,
+
+		NeedsAttention: m.NeedsAttention,
+		DisputedBy:     m.DisputedBy,
+	}
+	for i, o := range m.Outcomes {
+		mj.Outcomes[i] = string(o)
 	}
 	if m.Outcome != nil {
 		s := string(*m.Outcome)
@@ -82,28 +171,106 @@ func (m *Market) ToJSON() MarketJSON {
 		s := m.ResolvedAt.Format(time.RFC3339)
 		mj.ResolvedAt = &s
 	}
+	if !m.FeeFreeUntil.IsZero() {
+		s := m.FeeFreeUntil.Format(time.RFC3339)
+		mj.FeeFreeUntil = &s
+	}
+	if m.DisputeEndsAt != nil {
+		s := m.DisputeEndsAt.Format(time.RFC3339)
+		mj.DisputeEndsAt = &s
+	}
 	return mj
 }
 
+// IDGenerator produces IDs for new markets. It is always called with the
+// manager's lock held, so it needn't be safe for concurrent use.
+type IDGenerator func() string
+
+// RandomIDs generates random UUIDs (the default)
+func RandomIDs() IDGenerator {
+	return func() string { return uuid.New().String() }
+}
+
+// SeededIDs generates a reproducible sequence of UUIDs from a seed, for
+// fixtures and staged environments. Not for production use.
+func SeededIDs(seed int64) IDGenerator {
+	r := rand.New(rand.NewSource(seed))
+	return func() string {
+		id, err := uuid.NewRandomFromReader(r)
+		if err != nil {
+			panic(err) // math/rand never fails to read
+		}
+		return id.String()
+	}
+}
+
 // Manager manages all prediction markets
 type Manager struct {
-	mu      sync.RWMutex
-	markets map[string]*Market
+	mu          sync.RWMutex
+	markets     map[string]*Market
+	settlements map[string]*SettlementRecord // marketID -> payouts at resolution
+	maxOutcomes int
+	newID       IDGenerator
+
+	// How long a proposed resolution can be disputed (0 = final at once)
+	disputeWindow time.Duration
+
+	// Reports whether a market has traded; edits are refused once it has
+	hasTraded func(marketID string) bool
+
+	// Callback for the write-ahead journal (see journal.go)
+	onJournal func(*Market, *SettlementRecord)
 }
 
 // NewManager creates a new market manager
 func NewManager() *Manager {
 	return &Manager{
-		markets: make(map[string]*Market),
+		markets:     make(map[string]*Market),
+		settlements: make(map[string]*SettlementRecord),
+		maxOutcomes: DefaultMaxOutcomes,
+		newID:       RandomIDs(),
 	}
 }
 
+// SetIDGenerator replaces how new market IDs are generated
+func (m *Manager) SetIDGenerator(gen IDGenerator) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.newID = gen
+}
+
+// SetMaxOutcomes sets the maximum number of outcomes a market may have
+// (0 = unlimited). A bound below two would refuse every YES/NO market.
+func (m *Manager) SetMaxOutcomes(n int) error {
+	if n != 0 && n < len(BinaryOutcomes) {
+		return ErrMaxOutcomesTooLow
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.maxOutcomes = n
+	return nil
+}
+
+// SetTradeCheck sets how Update finds out whether a market has traded
+func (m *Manager) SetTradeCheck(fn func(marketID string) bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.hasTraded = fn
+}
+
 // CreateMarketRequest is the request to create a new market
 type CreateMarketRequest struct {
 	Question    string    `json:"question"`
 	Description string    `json:"description,omitempty"`
+	Outcomes    []Outcome `json:"outcomes,omitempty"` // Defaults to YES/NO
 	ResolvesAt  time.Time `json:"resolves_at"`
 	CreatorID   string    `json:"creator_id"`
+
+	// FeeFreeWindow waives trading fees for this long after creation
+	FeeFreeWindow time.Duration `json:"fee_free_window,omitempty"`
+
+	// Resolvers overrides who may resolve the market (defaults to the creator)
+	Resolvers []string `json:"resolvers,omitempty"`
 }
 
 // Create creates a new prediction market
@@ -111,40 +278,192 @@ func (m *Manager) Create(req CreateMarketRequest) (*Market, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	outcomes := req.Outcomes
+	if len(outcomes) == 0 {
+		outcomes = BinaryOutcomes
+	}
+	if err := validateOutcomes(outcomes, m.maxOutcomes); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
 	market := &Market{
-		ID:          uuid.New().String(),
+		ID:          m.newID(),
 		Question:    req.Question,
 		Description: req.Description,
+		Outcomes:    append([]Outcome(nil), outcomes...),
 		Status:      StatusTrading,
-		CreatedAt:   time.Now(),
+		CreatedAt:   now,
 		ResolvesAt:  req.ResolvesAt,
 		CreatorID:   req.CreatorID,
 	}
+	if req.FeeFreeWindow > 0 {
+		market.FeeFreeUntil = now.Add(req.FeeFreeWindow)
+	}
+	if len(req.Resolvers) > 0 {
+		market.Resolvers = append([]string(nil), req.Resolvers...)
+	} else if req.CreatorID != "" {
+		market.Resolvers = []string{req.CreatorID}
+	}
 
 	m.markets[market.ID] = market
-	return market, nil
+	m.journal(market)
+	return market.snapshot(), nil
 }
 
-// Get retrieves a market by ID
+// UpdateMarketRequest holds the fields to change on a market; nil fields
+// are left as they are
+type UpdateMarketRequest struct {
+	Question    *string    `json:"question,omitempty"`
+	Description *string    `json:"description,omitempty"`
+	ResolvesAt  *time.Time `json:"resolves_at,omitempty"`
+}
+
+// Update edits a market's question, description or resolution time. This
+// is only allowed while it is trading and before its first trade.
+func (m *Manager) Update(marketID string, req UpdateMarketRequest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[marketID]
+	if !ok {
+		return ErrMarketNotFound
+	}
+	if market.Status != StatusTrading || (m.hasTraded != nil && m.hasTraded(marketID)) {
+		return ErrInvalidTransition
+	}
+	if req.Question != nil && *req.Question == "" {
+		return ErrEmptyQuestion
+	}
+	if req.ResolvesAt != nil && !req.ResolvesAt.After(time.Now()) {
+		return ErrResolvesAtPast
+	}
+
+	if req.Question != nil {
+		market.Question = *req.Question
+	}
+	if req.Description != nil {
+		market.Description = *req.Description
+	}
+	if req.ResolvesAt != nil {
+		market.ResolvesAt = *req.ResolvesAt
+	}
+	m.journal(market)
+	return nil
+}
+
+// validateOutcomes checks an outcome set is non-trivial, unique and bounded.
+// The bound is checked first so an over-large set is refused as such.
+// Orders, resolution and settlement only handle YES and NO, so any other
+// set within it is refused until categorical markets are supported end to end.
+func validateOutcomes(outcomes []Outcome, max int) error {
+	if max > 0 && len(outcomes) > max {
+		return ErrTooManyOutcomes
+	}
+	seen := make(map[Outcome]bool, len(outcomes))
+	for _, o := range outcomes {
+		if o == "" || seen[o] {
+			return ErrInvalidOutcomeSet
+		}
+		seen[o] = true
+	}
+	if len(seen) < 2 {
+		return ErrInvalidOutcomeSet
+	}
+	if len(seen) != len(BinaryOutcomes) || !seen[OutcomeYes] || !seen[OutcomeNo] {
+		return ErrNotBinary
+	}
+	return nil
+}
+
+// Get retrieves a copy of a market by ID
 func (m *Manager) Get(id string) (*Market, bool) {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	market, ok := m.markets[id]
-	return market, ok
+	if !ok {
+		return nil, false
+	}
+	return market.snapshot(), true
+}
+
+// Status returns a market's current status, read under the lock so it is
+// safe against concurrent lifecycle transitions
+func (m *Manager) Status(id string) (MarketStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	market, ok := m.markets[id]
+	if !ok {
+		return 0, false
+	}
+	return market.Status, true
+}
+
+// CanResolve reports whether an identity is on a market's resolver list,
+// read under the lock. found is false if the market doesn't exist.
+func (m *Manager) CanResolve(id, identity string) (ok, found bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	market, found := m.markets[id]
+	if !found {
+		return false, false
+	}
+	return market.CanResolve(identity), true
 }
 
-// List returns all markets
+// List returns copies of all markets
 func (m *Manager) List() []*Market {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 
 	markets := make([]*Market, 0, len(m.markets))
 	for _, market := range m.markets {
-		markets = append(markets, market)
+		markets = append(markets, market.snapshot())
 	}
 	return markets
 }
 
+// ListFilter selects a page of markets for ListPage
+type ListFilter struct {
+	Status    *MarketStatus // nil = any status
+	CreatorID string        // Empty = any creator
+	Limit     int           // 0 = no limit
+	Offset    int
+}
+
+// ListPage returns the markets matching a filter, newest first, along with
+// how many match in total
+func (m *Manager) ListPage(f ListFilter) ([]*Market, int) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	var matched []*Market
+	for _, market := range m.markets {
+		if f.Status != nil && market.Status != *f.Status {
+			continue
+		}
+		if f.CreatorID != "" && market.CreatorID != f.CreatorID {
+			continue
+		}
+		matched = append(matched, market.snapshot())
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		a, b := matched[i], matched[j]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.After(b.CreatedAt)
+		}
+		return a.ID < b.ID
+	})
+
+	total := len(matched)
+	start := min(max(f.Offset, 0), total)
+	end := total
+	if f.Limit > 0 {
+		end = min(start+f.Limit, total)
+	}
+	return matched[start:end], total
+}
+
 // Lock transitions a market to locked status
 func (m *Manager) Lock(id string) error {
 	m.mu.Lock()
@@ -154,10 +473,92 @@ func (m *Manager) Lock(id string) error {
 	if !ok {
 		return ErrMarketNotFound
 	}
-	if market.Status != StatusTrading {
+	if market.Status != StatusTrading && market.Status != StatusDraining {
 		return ErrInvalidTransition
 	}
 
 	market.Status = StatusLocked
+	m.journal(market)
+	return nil
+}
+
+// Drain transitions a trading market to draining, where only reduce-only
+// orders are accepted until it locks
+func (m *Manager) Drain(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[id]
+	if !ok {
+		return ErrMarketNotFound
+	}
+	if market.Status != StatusTrading {
+		return ErrInvalidTransition
+	}
+
+	market.Status = StatusDraining
+	m.journal(market)
+	return nil
+}
+
+// Reopen returns a locked market to trading with a new resolution time, for
+// markets locked early because ResolvesAt was set wrong
+func (m *Manager) Reopen(id string, newResolvesAt time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[id]
+	if !ok {
+		return ErrMarketNotFound
+	}
+	switch market.Status {
+	case StatusLocked:
+	case StatusResolved, StatusVoided:
+		return ErrMarketFinal
+	case StatusResolving:
+		return ErrResolutionPending
+	default:
+		return ErrInvalidTransition
+	}
+	if !newResolvesAt.After(time.Now()) {
+		return ErrResolvesAtPast
+	}
+
+	market.Status = StatusTrading
+	market.ResolvesAt = newResolvesAt
+	market.NeedsAttention = false
+	m.journal(market)
+	return nil
+}
+
+// Void closes a locked, unresolved market without an outcome
+func (m *Manager) Void(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[id]
+	if !ok {
+		return ErrMarketNotFound
+	}
+	if market.Status != StatusLocked {
+		return ErrInvalidTransition
+	}
+
+	market.Status = StatusVoided
+	m.journal(market)
+	return nil
+}
+
+// Flag marks a market as needing operator attention
+func (m *Manager) Flag(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[id]
+	if !ok {
+		return ErrMarketNotFound
+	}
+	market.NeedsAttention = true
+	m.journal(market)
 	return nil
 }
@@ -0,0 +1,351 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newTestMarket(t *testing.T, m *Manager, outcomes ...Outcome) (*Market, error) {
+	t.Helper()
+	return m.Create(CreateMarketRequest{
+		Question:   "Will it rain?",
+		Outcomes:   outcomes,
+		ResolvesAt: time.Now().Add(time.Hour),
+		CreatorID:  "creator",
+	})
+}
+
+// categorical returns n distinct outcome labels
+func categorical(n int) []Outcome {
+	outcomes := make([]Outcome, n)
+	for i := range outcomes {
+		outcomes[i] = Outcome(fmt.Sprintf("OUTCOME_%d", i))
+	}
+	return outcomes
+}
+
+func TestCreateEnforcesMaxOutcomes(t *testing.T) {
+	m := NewManager()
+	if err := m.SetMaxOutcomes(4); err != nil {
+		t.Fatal(err)
+	}
+
+	// A set at the max passes the bound and is refused only as categorical
+	if _, err := newTestMarket(t, m, categorical(4)...); err != ErrNotBinary {
+		t.Fatalf("market at the max: err = %v, want %v", err, ErrNotBinary)
+	}
+	if _, err := newTestMarket(t, m, categorical(5)...); err != ErrTooManyOutcomes {
+		t.Fatalf("market over the max: err = %v, want %v", err, ErrTooManyOutcomes)
+	}
+	if _, err := newTestMarket(t, m, categorical(DefaultMaxOutcomes+1)...); err != ErrTooManyOutcomes {
+		t.Fatalf("market over the default max: err = %v, want %v", err, ErrTooManyOutcomes)
+	}
+
+	// Unlimited lets any size through to the categorical check
+	if err := m.SetMaxOutcomes(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := newTestMarket(t, m, categorical(DefaultMaxOutcomes+1)...); err != ErrNotBinary {
+		t.Fatalf("unbounded market: err = %v, want %v", err, ErrNotBinary)
+	}
+}
+
+func TestMaxOutcomesMustAllowBinaryMarkets(t *testing.T) {
+	m := NewManager()
+	if err := m.SetMaxOutcomes(1); err != ErrMaxOutcomesTooLow {
+		t.Fatalf("max of 1: err = %v, want %v", err, ErrMaxOutcomesTooLow)
+	}
+	if err := m.SetMaxOutcomes(2); err != nil {
+		t.Fatalf("max of 2: %v", err)
+	}
+	if _, err := newTestMarket(t, m, OutcomeYes, OutcomeNo); err != nil {
+		t.Fatalf("YES/NO market at a max of 2: %v", err)
+	}
+}
+
+func TestCreateRejectsCategoricalOutcomes(t *testing.T) {
+	m := NewManager()
+	for _, outcomes := range [][]Outcome{
+		{"RED", "GREEN", "BLUE"},
+		{"UP", "DOWN"},
+		{OutcomeYes, OutcomeNo, "MAYBE"},
+	} {
+		if _, err := newTestMarket(t, m, outcomes...); err != ErrNotBinary {
+			t.Errorf("%v: err = %v, want %v", outcomes, err, ErrNotBinary)
+		}
+	}
+	if _, err := newTestMarket(t, m, OutcomeNo, OutcomeYes); err != nil {
+		t.Fatalf("NO/YES market: %v", err)
+	}
+}
+
+// Run with -race: lifecycle transitions and readers share markets
+func TestStatusReadsDuringTransitions(t *testing.T) {
+	m := NewManager()
+	lm := NewLifecycleManager(m, nil)
+	lm.SetStatusCallback(func(mkt *Market) { _ = mkt.ToJSON() })
+
+	ids := make([]string, 20)
+	for i := range ids {
+		mkt, err := m.Create(CreateMarketRequest{Question: "q?", ResolvesAt: time.Now().Add(-time.Second), CreatorID: "c"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = mkt.ID
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		lm.checkAndLockMarkets()
+	}()
+	for _, id := range ids {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				if _, ok := m.Status(id); !ok {
+					t.Error("market missing")
+				}
+				if mkt, ok := m.Get(id); ok {
+					_ = mkt.ToJSON()
+				}
+				for _, mkt := range m.List() {
+					_ = mkt.Status
+				}
+			}
+		}(id)
+	}
+	wg.Wait()
+
+	for _, id := range ids {
+		if status, _ := m.Status(id); status != StatusLocked {
+			t.Fatalf("status = %v, want locked", status)
+		}
+	}
+}
+
+func TestMarketJSONCarriesStatusCodeAndName(t *testing.T) {
+	for code, status := range AllStatuses {
+		mkt := &Market{Status: status, Outcomes: BinaryOutcomes}
+		data, err := json.Marshal(mkt.ToJSON())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got struct {
+			Status     string `json:"status"`
+			StatusCode int    `json:"status_code"`
+		}
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.StatusCode != code || got.Status != status.String() {
+			t.Errorf("%v: status %q code %d, want %q code %d", status, got.Status, got.StatusCode, status.String(), code)
+		}
+		if parsed, err := ParseMarketStatus(got.Status); err != nil || parsed != MarketStatus(got.StatusCode) {
+			t.Errorf("%v: name %q parses to %v (%v), code %d", status, got.Status, parsed, err, got.StatusCode)
+		}
+	}
+}
+
+func TestInjectedIDGenerator(t *testing.T) {
+	m := NewManager()
+	n := 0
+	m.SetIDGenerator(func() string {
+		n++
+		return fmt.Sprintf("market-%d", n)
+	})
+	for i := 1; i <= 3; i++ {
+		mkt, err := newTestMarket(t, m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("market-%d", i); mkt.ID != want {
+			t.Fatalf("ID = %q, want %q", mkt.ID, want)
+		}
+		if _, ok := m.Get(mkt.ID); !ok {
+			t.Fatalf("market %s not found by its ID", mkt.ID)
+		}
+	}
+}
+
+func TestSeededIDsAreReproducible(t *testing.T) {
+	a, b, c := SeededIDs(42), SeededIDs(42), SeededIDs(43)
+	for i := 0; i < 5; i++ {
+		x, y, z := a(), b(), c()
+		if x != y {
+			t.Fatalf("same seed: %q != %q", x, y)
+		}
+		if x == z {
+			t.Fatalf("different seeds both produced %q", x)
+		}
+	}
+}
+
+func TestReopen(t *testing.T) {
+	lock := func(m *Manager, id string) error { return m.Lock(id) }
+	resolve := func(m *Manager, id string) error {
+		if err := m.Lock(id); err != nil {
+			return err
+		}
+		_, err := m.Resolve(ResolveRequest{MarketID: id, Outcome: OutcomeYes})
+		return err
+	}
+	later := time.Now().Add(2 * time.Hour)
+
+	for _, tc := range []struct {
+		name       string
+		prepare    func(m *Manager, id string) error // From trading
+		resolvesAt time.Time
+		want       error
+	}{
+		{"locked", lock, later, nil},
+		{"resolved", resolve, later, ErrMarketFinal},
+		{"voided", func(m *Manager, id string) error {
+			if err := m.Lock(id); err != nil {
+				return err
+			}
+			return m.Void(id)
+		}, later, ErrMarketFinal},
+		{"resolving", func(m *Manager, id string) error {
+			m.SetDisputeWindow(time.Hour)
+			return resolve(m, id)
+		}, later, ErrResolutionPending},
+		{"trading", func(*Manager, string) error { return nil }, later, ErrInvalidTransition},
+		{"draining", func(m *Manager, id string) error { return m.Drain(id) }, later, ErrInvalidTransition},
+		{"resolution time in the past", lock, time.Now().Add(-time.Minute), ErrResolvesAtPast},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewManager()
+			mkt, err := newTestMarket(t, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.prepare(m, mkt.ID); err != nil {
+				t.Fatal(err)
+			}
+			before, _ := m.Get(mkt.ID)
+
+			if err := m.Reopen(mkt.ID, tc.resolvesAt); err != tc.want {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			after, _ := m.Get(mkt.ID)
+			if tc.want != nil {
+				if after.Status != before.Status || !after.ResolvesAt.Equal(before.ResolvesAt) {
+					t.Fatalf("rejected reopen changed the market: %v at %v", after.Status, after.ResolvesAt)
+				}
+				return
+			}
+			if after.Status != StatusTrading || !after.ResolvesAt.Equal(tc.resolvesAt) {
+				t.Fatalf("status %v at %v, want trading at %v", after.Status, after.ResolvesAt, tc.resolvesAt)
+			}
+		})
+	}
+
+	if err := NewManager().Reopen("missing", later); err != ErrMarketNotFound {
+		t.Fatalf("unknown market: err = %v, want %v", err, ErrMarketNotFound)
+	}
+}
+
+func TestListPage(t *testing.T) {
+	m := NewManager()
+	base := time.Now().Add(-time.Hour)
+	var ids []string // Oldest first
+	for i, creator := range []string{"a", "b", "a", "b", "a"} {
+		mkt, err := m.Create(CreateMarketRequest{Question: "q?", ResolvesAt: time.Now().Add(time.Hour), CreatorID: creator})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m.mu.Lock()
+		m.markets[mkt.ID].CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		m.mu.Unlock()
+		ids = append(ids, mkt.ID)
+	}
+	if err := m.Lock(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	locked := StatusLocked
+
+	pageIDs := func(markets []*Market) []string {
+		out := []string{}
+		for _, mkt := range markets {
+			out = append(out, mkt.ID)
+		}
+		return out
+	}
+	for _, tc := range []struct {
+		name      string
+		filter    ListFilter
+		want      []string
+		wantTotal int
+	}{
+		{"all, newest first", ListFilter{}, []string{ids[4], ids[3], ids[2], ids[1], ids[0]}, 5},
+		{"first page", ListFilter{Limit: 2}, []string{ids[4], ids[3]}, 5},
+		{"second page", ListFilter{Limit: 2, Offset: 2}, []string{ids[2], ids[1]}, 5},
+		{"offset past the end", ListFilter{Limit: 2, Offset: 10}, []string{}, 5},
+		{"by status", ListFilter{Status: &locked}, []string{ids[1]}, 1},
+		{"by creator", ListFilter{CreatorID: "a"}, []string{ids[4], ids[2], ids[0]}, 3},
+	} {
+		markets, total := m.ListPage(tc.filter)
+		if got := pageIDs(markets); fmt.Sprint(got) != fmt.Sprint(tc.want) || total != tc.wantTotal {
+			t.Errorf("%s: got %v of %d, want %v of %d", tc.name, got, total, tc.want, tc.wantTotal)
+		}
+	}
+}
+
+func TestUpdateBeforeFirstTrade(t *testing.T) {
+	question := "Will it snow?"
+	empty := ""
+	later := time.Now().Add(3 * time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	for _, tc := range []struct {
+		name   string
+		locked bool
+		traded bool
+		req    UpdateMarketRequest
+		want   error
+	}{
+		{"untouched", false, false, UpdateMarketRequest{Question: &question, ResolvesAt: &later}, nil},
+		{"traded", false, true, UpdateMarketRequest{Question: &question}, ErrInvalidTransition},
+		{"locked", true, false, UpdateMarketRequest{Question: &question}, ErrInvalidTransition},
+		{"empty question", false, false, UpdateMarketRequest{Question: &empty}, ErrEmptyQuestion},
+		{"resolution time in the past", false, false, UpdateMarketRequest{ResolvesAt: &past}, ErrResolvesAtPast},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewManager()
+			m.SetTradeCheck(func(string) bool { return tc.traded })
+			mkt, err := newTestMarket(t, m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tc.locked {
+				if err := m.Lock(mkt.ID); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			if err := m.Update(mkt.ID, tc.req); err != tc.want {
+				t.Fatalf("err = %v, want %v", err, tc.want)
+			}
+			after, _ := m.Get(mkt.ID)
+			if tc.want != nil {
+				if after.Question != mkt.Question || !after.ResolvesAt.Equal(mkt.ResolvesAt) {
+					t.Fatalf("rejected update changed the market: %q at %v", after.Question, after.ResolvesAt)
+				}
+				return
+			}
+			if after.Question != question || !after.ResolvesAt.Equal(later) {
+				t.Fatalf("market is %q at %v, want %q at %v", after.Question, after.ResolvesAt, question, later)
+			}
+		})
+	}
+
+	if err := NewManager().Update("missing", UpdateMarketRequest{}); err != ErrMarketNotFound {
+		t.Fatalf("unknown market: err = %v, want %v", err, ErrMarketNotFound)
+	}
+}
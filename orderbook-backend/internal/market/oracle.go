@@ -0,0 +1,75 @@
+package market
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Oracle reports the outcome of a locked market. ok is false while the
+// outcome is still undetermined.
+type Oracle interface {
+	Resolve(market *Market) (outcome Outcome, ok bool, err error)
+}
+
+// HTTPOracle asks an HTTP endpoint for each market's outcome. The endpoint
+// answers with YES, NO or UNDETERMINED, either as plain text or as
+// {"outcome": "..."}.
+type HTTPOracle struct {
+	urlTemplate string // "{id}" is replaced with the market ID
+	client      *http.Client
+}
+
+// NewHTTPOracle creates an oracle that GETs urlTemplate with "{id}" replaced
+// by the market ID, giving up on a request after timeout
+func NewHTTPOracle(urlTemplate string, timeout time.Duration) *HTTPOracle {
+	return &HTTPOracle{
+		urlTemplate: urlTemplate,
+		client:      &http.Client{Timeout: timeout},
+	}
+}
+
+// Resolve fetches a market's outcome from the endpoint
+func (o *HTTPOracle) Resolve(market *Market) (Outcome, bool, error) {
+	target := strings.ReplaceAll(o.urlTemplate, "{id}", url.PathEscape(market.ID))
+	resp, err := o.client.Get(target)
+	if err != nil {
+		return "", false, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if err != nil {
+		return "", false, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", false, fmt.Errorf("oracle returned %s", resp.Status)
+	}
+	return parseOracleOutcome(body)
+}
+
+// parseOracleOutcome reads an oracle answer given as text or JSON
+func parseOracleOutcome(body []byte) (Outcome, bool, error) {
+	answer := strings.Trim(strings.TrimSpace(string(body)), `"`)
+	var parsed struct {
+		Outcome string `json:"outcome"`
+	}
+	if json.Unmarshal(body, &parsed) == nil {
+		answer = parsed.Outcome
+	}
+
+	switch strings.ToUpper(strings.TrimSpace(answer)) {
+	case "YES":
+		return OutcomeYes, true, nil
+	case "NO":
+		return OutcomeNo, true, nil
+	case "UNDETERMINED", "":
+		return "", false, nil
+	default:
+		return "", false, fmt.Errorf("unrecognized oracle answer %q", answer)
+	}
+}
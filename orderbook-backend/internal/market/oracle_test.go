@@ -0,0 +1,98 @@
+package market
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakeOracle answers every market with a fixed result
+type fakeOracle struct {
+	outcome Outcome
+	ok      bool
+	err     error
+}
+
+func (o fakeOracle) Resolve(*Market) (Outcome, bool, error) { return o.outcome, o.ok, o.err }
+
+func TestOracleResolvesLockedMarkets(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		oracle  fakeOracle
+		want    MarketStatus
+		outcome Outcome
+	}{
+		{"YES", fakeOracle{outcome: OutcomeYes, ok: true}, StatusResolved, OutcomeYes},
+		{"NO", fakeOracle{outcome: OutcomeNo, ok: true}, StatusResolved, OutcomeNo},
+		{"undetermined", fakeOracle{}, StatusLocked, ""},
+		{"error", fakeOracle{err: errors.New("feed down")}, StatusLocked, ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewManager()
+			lm := NewLifecycleManager(m, tc.oracle)
+			var settled []Outcome
+			lm.SetSettleFunc(func(_ string, outcome Outcome) []SettlementEntry {
+				settled = append(settled, outcome)
+				return nil
+			})
+
+			// Locking happens on one pass and asking the oracle on the next
+			id := newLockedMarkets(t, m, lm, time.Minute)[0]
+			lm.checkAndLockMarkets()
+
+			mkt, _ := m.Get(id)
+			if mkt.Status != tc.want {
+				t.Fatalf("status = %v, want %v", mkt.Status, tc.want)
+			}
+			if tc.outcome == "" {
+				if mkt.Outcome != nil || len(settled) != 0 {
+					t.Fatalf("outcome %v, settled %v: want neither", mkt.Outcome, settled)
+				}
+				return
+			}
+			if *mkt.Outcome != tc.outcome || len(settled) != 1 || settled[0] != tc.outcome {
+				t.Fatalf("outcome %v, settled %v: want %s once", *mkt.Outcome, settled, tc.outcome)
+			}
+		})
+	}
+}
+
+func TestHTTPOracleParsesAnswers(t *testing.T) {
+	answers := map[string]struct {
+		status int
+		body   string
+	}{
+		"text-yes":  {http.StatusOK, "YES\n"},
+		"json-no":   {http.StatusOK, `{"outcome":"no"}`},
+		"quoted":    {http.StatusOK, `"UNDETERMINED"`},
+		"garbage":   {http.StatusOK, "MAYBE"},
+		"not-found": {http.StatusNotFound, "no such market"},
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		answer := answers[r.URL.Path[len("/outcome/"):]]
+		w.WriteHeader(answer.status)
+		w.Write([]byte(answer.body))
+	}))
+	defer server.Close()
+	oracle := NewHTTPOracle(server.URL+"/outcome/{id}", time.Second)
+
+	for _, tc := range []struct {
+		id      string
+		outcome Outcome
+		ok      bool
+		wantErr bool
+	}{
+		{"text-yes", OutcomeYes, true, false},
+		{"json-no", OutcomeNo, true, false},
+		{"quoted", "", false, false},
+		{"garbage", "", false, true},
+		{"not-found", "", false, true},
+	} {
+		outcome, ok, err := oracle.Resolve(&Market{ID: tc.id})
+		if outcome != tc.outcome || ok != tc.ok || (err != nil) != tc.wantErr {
+			t.Errorf("%s: got %q, %v, %v; want %q, %v, error %v", tc.id, outcome, ok, err, tc.outcome, tc.ok, tc.wantErr)
+		}
+	}
+}
@@ -18,34 +18,163 @@ type Payout struct {
 	AmountUSD uint64 `json:"amount_usd"` // Payout in USDC (6 decimals)
 }
 
+// SettlementEntry is one user's share of a market's settlement
+type SettlementEntry struct {
+	UserID string `json:"user_id"`
+	Shares uint64 `json:"shares"` // Winning shares held at resolution
+	Payout uint64 `json:"payout"` // In basis points (10000 = 1 USDC)
+}
+
+// SettlementRecord preserves who was paid what when a market resolved, since
+// payouts zero the positions they are computed from
+type SettlementRecord struct {
+	MarketID  string            `json:"market_id"`
+	Outcome   Outcome           `json:"outcome"`
+	SettledAt time.Time         `json:"settled_at"`
+	Entries   []SettlementEntry `json:"entries"`
+	Total     uint64            `json:"total"`
+}
+
+// SettleFunc pays out a market for the winning outcome and reports the payouts
+type SettleFunc func(marketID string, outcome Outcome) []SettlementEntry
+
+// SetDisputeWindow sets how long a resolution stays open to dispute before
+// it is final and paid out. Zero makes resolutions final at once.
+func (m *Manager) SetDisputeWindow(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.disputeWindow = d
+}
+
 // Resolve resolves a market with the given outcome
 func (m *Manager) Resolve(req ResolveRequest) (*Market, error) {
+	mkt, _, err := m.ResolveAndSettle(req, nil)
+	return mkt, err
+}
+
+// ResolveAndSettle resolves a market and runs settle under the same lock, so
+// a resolved market always has its settlement record. A nil settle records
+// an empty settlement. With a dispute window set the outcome is only
+// proposed: the market enters resolving, no settlement record is returned,
+// and Finalize settles it once the window passes. A disputed market is
+// resolved again the same way.
+func (m *Manager) ResolveAndSettle(req ResolveRequest, settle SettleFunc) (*Market, *SettlementRecord, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	market, ok := m.markets[req.MarketID]
 	if !ok {
-		return nil, ErrMarketNotFound
+		return nil, nil, ErrMarketNotFound
 	}
 
-	if market.Status != StatusLocked {
-		return nil, ErrMarketNotLocked
-	}
-
-	if market.Outcome != nil {
-		return nil, ErrAlreadyResolved
+	switch {
+	case market.Status == StatusResolved:
+		return nil, nil, ErrAlreadyResolved
+	case market.Status == StatusResolving && market.DisputedBy == "":
+		return nil, nil, ErrResolutionPending
+	case market.Status != StatusLocked && market.Status != StatusResolving:
+		return nil, nil, ErrMarketNotLocked
 	}
 
 	if req.Outcome != OutcomeYes && req.Outcome != OutcomeNo {
-		return nil, ErrInvalidOutcome
+		return nil, nil, ErrInvalidOutcome
 	}
 
 	now := time.Now()
 	market.Outcome = &req.Outcome
+	market.DisputedBy = ""
+	if m.disputeWindow > 0 {
+		ends := now.Add(m.disputeWindow)
+		market.DisputeEndsAt = &ends
+		market.Status = StatusResolving
+		m.journal(market)
+		return market.snapshot(), nil, nil
+	}
+	record := m.settle(market, settle, now)
+	return market.snapshot(), record, nil
+}
+
+// Finalize makes a market's proposed resolution final once its dispute
+// window has passed undisputed, and runs settle like ResolveAndSettle
+func (m *Manager) Finalize(marketID string, settle SettleFunc) (*Market, *SettlementRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[marketID]
+	if !ok {
+		return nil, nil, ErrMarketNotFound
+	}
+	if market.Status != StatusResolving {
+		return nil, nil, ErrNotResolving
+	}
+	if market.DisputedBy != "" {
+		return nil, nil, ErrAlreadyDisputed
+	}
+
+	now := time.Now()
+	if now.Before(*market.DisputeEndsAt) {
+		return nil, nil, ErrDisputeWindowOpen
+	}
+	record := m.settle(market, settle, now)
+	return market.snapshot(), record, nil
+}
+
+// Dispute challenges a market's proposed resolution within its dispute
+// window. The market stays resolving until it is resolved again.
+func (m *Manager) Dispute(marketID, disputedBy string) (*Market, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	market, ok := m.markets[marketID]
+	if !ok {
+		return nil, ErrMarketNotFound
+	}
+	if market.Status != StatusResolving {
+		return nil, ErrNotResolving
+	}
+	if market.DisputedBy != "" {
+		return nil, ErrAlreadyDisputed
+	}
+	if !time.Now().Before(*market.DisputeEndsAt) {
+		return nil, ErrDisputeWindowClosed
+	}
+
+	market.DisputedBy = disputedBy
+	m.journal(market)
+	return market.snapshot(), nil
+}
+
+// settle marks a market resolved with its current outcome and records its
+// settlement (must hold lock)
+func (m *Manager) settle(market *Market, settle SettleFunc, now time.Time) *SettlementRecord {
 	market.ResolvedAt = &now
 	market.Status = StatusResolved
 
-	return market, nil
+	record := &SettlementRecord{
+		MarketID:  market.ID,
+		Outcome:   *market.Outcome,
+		SettledAt: now,
+		Entries:   []SettlementEntry{},
+	}
+	if settle != nil {
+		if entries := settle(market.ID, *market.Outcome); entries != nil {
+			record.Entries = entries
+		}
+	}
+	for _, e := range record.Entries {
+		record.Total += e.Payout
+	}
+	m.settlements[market.ID] = record
+	m.journal(market)
+	return record
+}
+
+// Settlement returns the settlement record of a resolved market
+func (m *Manager) Settlement(marketID string) (*SettlementRecord, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	record, ok := m.settlements[marketID]
+	return record, ok
 }
 
 // CalculatePayouts calculates payouts for all users with positions in a resolved market
@@ -0,0 +1,104 @@
+package market
+
+import (
+	"testing"
+	"time"
+)
+
+// endDisputeWindow moves a resolving market's dispute window into the past
+func endDisputeWindow(m *Manager, marketID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	ended := time.Now().Add(-time.Second)
+	m.markets[marketID].DisputeEndsAt = &ended
+}
+
+// newResolvingMarket locks a market and proposes YES for it, counting how
+// often the lifecycle settles it
+func newResolvingMarket(t *testing.T) (*Manager, *LifecycleManager, string, *int) {
+	t.Helper()
+	m := NewManager()
+	m.SetDisputeWindow(time.Hour)
+	lm := NewLifecycleManager(m, nil)
+	settled := new(int)
+	lm.SetSettleFunc(func(string, Outcome) []SettlementEntry {
+		*settled++
+		return []SettlementEntry{{UserID: "winner", Payout: 10000}}
+	})
+
+	id := newLockedMarkets(t, m, lm, time.Minute)[0]
+	mkt, record, err := m.ResolveAndSettle(ResolveRequest{MarketID: id, Outcome: OutcomeYes}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mkt.Status != StatusResolving || record != nil {
+		t.Fatalf("status %v, record %v: want resolving with no settlement yet", mkt.Status, record)
+	}
+	return m, lm, id, settled
+}
+
+func TestCleanDisputeWindowPaysOut(t *testing.T) {
+	m, lm, id, settled := newResolvingMarket(t)
+
+	// Nothing is final while the window is open
+	lm.checkAndLockMarkets()
+	if status, _ := m.Status(id); status != StatusResolving || *settled != 0 {
+		t.Fatalf("inside the window: status %v, settled %d times", status, *settled)
+	}
+	if _, _, err := m.Finalize(id, nil); err != ErrDisputeWindowOpen {
+		t.Fatalf("finalize early: err = %v, want %v", err, ErrDisputeWindowOpen)
+	}
+	if _, _, err := m.ResolveAndSettle(ResolveRequest{MarketID: id, Outcome: OutcomeNo}, nil); err != ErrResolutionPending {
+		t.Fatalf("resolve again undisputed: err = %v, want %v", err, ErrResolutionPending)
+	}
+
+	endDisputeWindow(m, id)
+	lm.checkAndLockMarkets()
+	if status, _ := m.Status(id); status != StatusResolved || *settled != 1 {
+		t.Fatalf("after the window: status %v, settled %d times, want resolved once", status, *settled)
+	}
+	if record, ok := m.Settlement(id); !ok || record.Outcome != OutcomeYes || record.Total != 10000 {
+		t.Fatalf("settlement = %+v, want YES paying 10000", record)
+	}
+	if _, err := m.Dispute(id, "late"); err != ErrNotResolving {
+		t.Fatalf("dispute after finalizing: err = %v, want %v", err, ErrNotResolving)
+	}
+}
+
+func TestDisputeBlocksPayout(t *testing.T) {
+	m, lm, id, settled := newResolvingMarket(t)
+
+	if _, err := m.Dispute(id, "challenger"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.Dispute(id, "another"); err != ErrAlreadyDisputed {
+		t.Fatalf("second dispute: err = %v, want %v", err, ErrAlreadyDisputed)
+	}
+
+	// The window passing doesn't finalize a disputed resolution
+	endDisputeWindow(m, id)
+	lm.checkAndLockMarkets()
+	if status, _ := m.Status(id); status != StatusResolving || *settled != 0 {
+		t.Fatalf("disputed: status %v, settled %d times, want resolving and unpaid", status, *settled)
+	}
+	if _, ok := m.Settlement(id); ok {
+		t.Fatal("disputed market has a settlement record")
+	}
+
+	// Re-resolving opens a fresh window
+	mkt, _, err := m.ResolveAndSettle(ResolveRequest{MarketID: id, Outcome: OutcomeNo}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mkt.Status != StatusResolving || mkt.DisputedBy != "" || *mkt.Outcome != OutcomeNo || !mkt.DisputeEndsAt.After(time.Now()) {
+		t.Fatalf("re-resolved market = %+v, want NO resolving in a new window", mkt)
+	}
+}
+
+func TestDisputeAfterWindowRejected(t *testing.T) {
+	m, _, id, _ := newResolvingMarket(t)
+	endDisputeWindow(m, id)
+	if _, err := m.Dispute(id, "late"); err != ErrDisputeWindowClosed {
+		t.Fatalf("err = %v, want %v", err, ErrDisputeWindowClosed)
+	}
+}
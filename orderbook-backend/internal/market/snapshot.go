@@ -0,0 +1,48 @@
+package market
+
+import "sort"
+
+// Snapshot is the state of the market manager, for restoring after a restart
+type Snapshot struct {
+	Markets     []*Market                    `json:"markets"`
+	Settlements map[string]*SettlementRecord `json:"settlements,omitempty"`
+}
+
+// Export captures every market and settlement record
+func (m *Manager) Export() Snapshot {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	snap := Snapshot{
+		Markets:     make([]*Market, 0, len(m.markets)),
+		Settlements: make(map[string]*SettlementRecord, len(m.settlements)),
+	}
+	for _, mkt := range m.markets {
+		snap.Markets = append(snap.Markets, mkt.snapshot())
+	}
+	sort.Slice(snap.Markets, func(i, j int) bool {
+		return snap.Markets[i].CreatedAt.Before(snap.Markets[j].CreatedAt)
+	})
+	for marketID, record := range m.settlements {
+		copied := *record
+		snap.Settlements[marketID] = &copied
+	}
+	return snap
+}
+
+// Import replaces the manager's markets and settlement records with a snapshot
+func (m *Manager) Import(snap Snapshot) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.markets = make(map[string]*Market, len(snap.Markets))
+	for _, mkt := range snap.Markets {
+		copied := *mkt
+		m.markets[mkt.ID] = &copied
+	}
+	m.settlements = make(map[string]*SettlementRecord, len(snap.Settlements))
+	for marketID, record := range snap.Settlements {
+		copied := *record
+		m.settlements[marketID] = &copied
+	}
+}
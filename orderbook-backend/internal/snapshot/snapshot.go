@@ -0,0 +1,81 @@
+// Package snapshot saves the in-memory trading state to a file and restores
+// it on startup, so a restart does not wipe markets, orders and positions.
+package snapshot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+)
+
+// version is bumped whenever the document layout changes incompatibly
+const version = 1
+
+// State is the document written to the snapshot file
+type State struct {
+	Version    int                       `json:"version"`
+	SavedAt    time.Time                 `json:"saved_at"`
+	Markets    market.Snapshot           `json:"markets"`
+	Orderbooks engine.OrderbooksSnapshot `json:"orderbooks"`
+	Positions  engine.PositionsSnapshot  `json:"positions"`
+}
+
+// Capture collects the current state of the markets, orderbooks and positions
+func Capture(markets *market.Manager, books *engine.MarketOrderbooks, positions *engine.PositionManager) *State {
+	return &State{
+		Version:    version,
+		SavedAt:    time.Now().UTC(),
+		Markets:    markets.Export(),
+		Orderbooks: books.Export(),
+		Positions:  positions.Export(),
+	}
+}
+
+// Restore loads a captured state into freshly created managers
+func (s *State) Restore(markets *market.Manager, books *engine.MarketOrderbooks, positions *engine.PositionManager) error {
+	if s.Version != version {
+		return fmt.Errorf("unsupported snapshot version %d", s.Version)
+	}
+	markets.Import(s.Markets)
+	positions.Import(s.Positions)
+	return books.Import(s.Orderbooks)
+}
+
+// Save atomically writes a state to path
+func Save(path string, s *State) error {
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0o700); err != nil {
+			return err
+		}
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Load reads a state saved by Save. A missing file is reported as
+// os.ErrNotExist.
+func Load(path string) (*State, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var s State
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
@@ -0,0 +1,80 @@
+package snapshot
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/engine"
+	"orderbook-backend/internal/market"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	markets := market.NewManager()
+	books := engine.NewMarketOrderbooks()
+	positions := engine.NewPositionManager()
+
+	mkt, err := markets.Create(market.CreateMarketRequest{
+		Question:   "Will it rain?",
+		ResolvesAt: time.Now().Add(time.Hour),
+		CreatorID:  "alice",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := positions.Deposit("alice", 100000); err != nil {
+		t.Fatal(err)
+	}
+	if err := positions.MintShares("alice", mkt.ID, 5); err != nil {
+		t.Fatal(err)
+	}
+	for _, order := range []*engine.Order{
+		engine.NewOrder("alice", mkt.ID, engine.OutcomeYES, engine.SideSell, 6500, 5),
+		engine.NewOrder("alice", mkt.ID, engine.OutcomeYES, engine.SideBuy, 4000, 3),
+	} {
+		if _, err := books.GetOrderbook(mkt.ID, order.OutcomeID).PlaceOrder(order); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "state", "snapshot.json")
+	saved := Capture(markets, books, positions)
+	if err := Save(path, saved); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restoredMarkets := market.NewManager()
+	restoredBooks := engine.NewMarketOrderbooks()
+	restoredPositions := engine.NewPositionManager()
+	if err := loaded.Restore(restoredMarkets, restoredBooks, restoredPositions); err != nil {
+		t.Fatal(err)
+	}
+
+	again := Capture(restoredMarkets, restoredBooks, restoredPositions)
+	again.SavedAt = saved.SavedAt
+	want, _ := json.Marshal(saved)
+	got, _ := json.Marshal(again)
+	if string(got) != string(want) {
+		t.Fatalf("export -> import changed the state:\n got %s\nwant %s", got, want)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("err = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestRestoreRejectsOtherVersions(t *testing.T) {
+	s := &State{Version: version + 1}
+	if err := s.Restore(market.NewManager(), engine.NewMarketOrderbooks(), engine.NewPositionManager()); err == nil {
+		t.Fatal("restored a snapshot of another version")
+	}
+}
@@ -2,101 +2,179 @@ package state
 
 import (
 	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"sync"
 
 	"orderbook-backend/internal/yellow"
 )
 
-// Allocations tracks the fund allocations within a state channel
+// Allocations tracks the fund allocations within a state channel, per token.
+// Amounts are in basis points of a token unit (10000 = 1 USDC), the same
+// units the position manager keeps balances in. Trades don't move channel
+// funds as they happen; a session's trading is netted into its allocations
+// when it closes (see Net).
 type Allocations struct {
 	mu        sync.RWMutex
 	channelID string
-	token     string
-	balances  map[string]uint64 // participant address -> balance
+	balances  map[string]map[string]uint64 // token address -> participant address -> balance
 	version   uint64
 }
 
-// NewAllocations creates a new allocations tracker
+// NewAllocations creates a tracker for a channel holding a single token
 func NewAllocations(channelID string, token string, initial map[string]uint64) *Allocations {
-	balances := make(map[string]uint64)
-	for k, v := range initial {
-		balances[k] = v
+	return NewMultiTokenAllocations(channelID, map[string]map[string]uint64{token: initial})
+}
+
+// NewMultiTokenAllocations creates a tracker from initial balances keyed by
+// token, then participant
+func NewMultiTokenAllocations(channelID string, initial map[string]map[string]uint64) *Allocations {
+	balances := make(map[string]map[string]uint64)
+	for token, byParticipant := range initial {
+		balances[token] = make(map[string]uint64)
+		for k, v := range byParticipant {
+			balances[token][k] = v
+		}
 	}
 	return &Allocations{
 		channelID: channelID,
-		token:     token,
 		balances:  balances,
 		version:   0,
 	}
 }
 
-// GetBalance returns the balance for a participant
-func (a *Allocations) GetBalance(participant string) uint64 {
+// FromCheckpoints rebuilds the funds committed to funding sessions from their
+// checkpointed signed states, so channel backing survives a restart. Market
+// sessions' allocations value positions rather than deposits and are skipped.
+func FromCheckpoints(checkpoints []yellow.Checkpoint) (*Allocations, error) {
+	a := NewMultiTokenAllocations("", nil)
+	for _, cp := range checkpoints {
+		if cp.MarketID != "" {
+			continue
+		}
+		allocs, err := yellow.NormalizeAllocations(cp.Allocations)
+		if err != nil {
+			return nil, fmt.Errorf("checkpoint %s: %w", cp.ChannelID, err)
+		}
+		for _, alloc := range allocs {
+			amount, err := ParseAmount(alloc.Amount)
+			if err != nil {
+				return nil, fmt.Errorf("checkpoint %s: %w", cp.ChannelID, err)
+			}
+			a.tokenBalances(alloc.Token)[alloc.Participant] += amount
+		}
+	}
+	return a, nil
+}
+
+// GetBalance returns a participant's balance of one token
+func (a *Allocations) GetBalance(token, participant string) uint64 {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	return a.balances[participant]
+	return a.balances[token][participant]
 }
 
-// GetBalances returns all balances
-func (a *Allocations) GetBalances() map[string]uint64 {
+// GetBalances returns all balances, keyed by token, then participant
+func (a *Allocations) GetBalances() map[string]map[string]uint64 {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
-	result := make(map[string]uint64)
-	for k, v := range a.balances {
-		result[k] = v
+	return a.copyBalances()
+}
+
+// copyBalances returns a deep copy of the balances (must hold lock)
+func (a *Allocations) copyBalances() map[string]map[string]uint64 {
+	result := make(map[string]map[string]uint64)
+	for token, byParticipant := range a.balances {
+		result[token] = make(map[string]uint64)
+		for k, v := range byParticipant {
+			result[token][k] = v
+		}
 	}
 	return result
 }
 
-// Transfer moves funds from one participant to another
-func (a *Allocations) Transfer(from, to string, amount uint64) error {
-	a.mu.Lock()
-	defer a.mu.Unlock()
-
-	if a.balances[from] < amount {
-		return ErrInsufficientBalance
+// tokenBalances returns the balances of one token, creating them if needed
+// (must hold lock)
+func (a *Allocations) tokenBalances(token string) map[string]uint64 {
+	balances, ok := a.balances[token]
+	if !ok {
+		balances = make(map[string]uint64)
+		a.balances[token] = balances
 	}
+	return balances
+}
 
-	a.balances[from] -= amount
-	a.balances[to] += amount
+// Credit adds funds of a token a participant committed to the channel
+func (a *Allocations) Credit(token, participant string, amount uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.tokenBalances(token)[participant] += amount
 	a.version++
-
-	return nil
 }
 
-// ApplyTrade updates allocations based on a trade
-// buyer pays seller `price * quantity`
-func (a *Allocations) ApplyTrade(buyerAddr, sellerAddr string, price, quantity uint64) error {
+// Transfer moves funds of a token from one participant to another
+func (a *Allocations) Transfer(token, from, to string, amount uint64) error {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	// Calculate total cost (price is in basis points, quantity is units)
-	// cost = price * quantity / 10000 (if using basis points for 0-1 range)
-	cost := (price * quantity) / 10000
-
-	if a.balances[buyerAddr] < cost {
+	balances := a.tokenBalances(token)
+	if balances[from] < amount {
 		return ErrInsufficientBalance
 	}
 
-	a.balances[buyerAddr] -= cost
-	a.balances[sellerAddr] += cost
+	balances[from] -= amount
+	balances[to] += amount
 	a.version++
 
 	return nil
 }
 
-// ToYellowAllocations converts to Yellow Network allocation format
+// Net returns a copy of the allocations with signed deltas, keyed by token
+// then participant, added to the balances. Netting a participant's gains and
+// losses across markets this way leaves one amount per token. It fails if
+// any balance would go negative; the receiver is not changed.
+func (a *Allocations) Net(deltas map[string]map[string]int64) (*Allocations, error) {
+	a.mu.RLock()
+	balances := a.copyBalances()
+	channelID, version := a.channelID, a.version
+	a.mu.RUnlock()
+
+	for token, byParticipant := range deltas {
+		if balances[token] == nil {
+			balances[token] = make(map[string]uint64)
+		}
+		for participant, delta := range byParticipant {
+			balance := int64(balances[token][participant]) + delta
+			if balance < 0 {
+				return nil, fmt.Errorf("%w: %s would owe %d", ErrInsufficientBalance, participant, -balance)
+			}
+			balances[token][participant] = uint64(balance)
+		}
+	}
+
+	return &Allocations{channelID: channelID, balances: balances, version: version + 1}, nil
+}
+
+// ToYellowAllocations converts to Yellow Network allocation format, one
+// entry per token and participant with a nonzero balance
 func (a *Allocations) ToYellowAllocations() []yellow.Allocation {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	allocs := make([]yellow.Allocation, 0, len(a.balances))
-	for participant, amount := range a.balances {
-		allocs = append(allocs, yellow.Allocation{
-			Participant: participant,
-			Token:       a.token,
-			Amount:      formatAmount(amount),
-		})
+	var allocs []yellow.Allocation
+	for token, byParticipant := range a.balances {
+		for participant, amount := range byParticipant {
+			if amount == 0 {
+				continue
+			}
+			allocs = append(allocs, yellow.Allocation{
+				Participant: participant,
+				Token:       token,
+				Amount:      FormatAmount(amount),
+			})
+		}
 	}
 	return allocs
 }
@@ -110,25 +188,18 @@ func (a *Allocations) GetVersion() uint64 {
 
 // Snapshot returns a JSON-serializable snapshot of the allocations
 type AllocationSnapshot struct {
-	ChannelID string            `json:"channel_id"`
-	Token     string            `json:"token"`
-	Balances  map[string]uint64 `json:"balances"`
-	Version   uint64            `json:"version"`
+	ChannelID string                       `json:"channel_id"`
+	Balances  map[string]map[string]uint64 `json:"balances"` // token -> participant -> balance
+	Version   uint64                       `json:"version"`
 }
 
 func (a *Allocations) Snapshot() AllocationSnapshot {
 	a.mu.RLock()
 	defer a.mu.RUnlock()
 
-	balances := make(map[string]uint64)
-	for k, v := range a.balances {
-		balances[k] = v
-	}
-
 	return AllocationSnapshot{
 		ChannelID: a.channelID,
-		Token:     a.token,
-		Balances:  balances,
+		Balances:  a.copyBalances(),
 		Version:   a.version,
 	}
 }
@@ -138,8 +209,32 @@ func (a *Allocations) ToJSON() ([]byte, error) {
 	return json.Marshal(a.Snapshot())
 }
 
-func formatAmount(amount uint64) string {
-	return json.Number(string(rune(amount))).String()
+// FormatAmount formats an amount in basis points as a decimal number of
+// token units
+func FormatAmount(amount uint64) string {
+	whole, frac := amount/10000, amount%10000
+	if frac == 0 {
+		return strconv.FormatUint(whole, 10)
+	}
+	return fmt.Sprintf("%d.%s", whole, strings.TrimRight(fmt.Sprintf("%04d", frac), "0"))
+}
+
+// ParseAmount parses a decimal number of token units, as formatted by
+// FormatAmount, into basis points
+func ParseAmount(s string) (uint64, error) {
+	whole, frac, _ := strings.Cut(s, ".")
+	if len(frac) > 4 || (frac != "" && strings.TrimLeft(frac, "0123456789") != "") {
+		return 0, fmt.Errorf("invalid amount %q: at most 4 decimal places", s)
+	}
+	w, err := strconv.ParseUint(whole, 10, 64)
+	if err != nil || w > math.MaxUint64/10000 {
+		return 0, fmt.Errorf("invalid amount %q", s)
+	}
+	var f uint64
+	if frac != "" {
+		f, _ = strconv.ParseUint(frac+strings.Repeat("0", 4-len(frac)), 10, 64)
+	}
+	return w*10000 + f, nil
 }
 
 // Errors
@@ -0,0 +1,139 @@
+package state
+
+import (
+	"errors"
+	"sort"
+	"testing"
+
+	"orderbook-backend/internal/yellow"
+)
+
+const (
+	token = "0x0000000000000000000000000000000000000000"
+	usdt  = "0xdac17f958d2ee523a2206206994597c13d831ec7"
+	alice = "0x1111111111111111111111111111111111111111"
+	bob   = "0x2222222222222222222222222222222222222222"
+)
+
+func TestParseAmountRoundTrips(t *testing.T) {
+	for _, bps := range []uint64{0, 1, 10, 2500, 10000, 12345, 1000000005} {
+		s := FormatAmount(bps)
+		got, err := ParseAmount(s)
+		if err != nil || got != bps {
+			t.Errorf("%d -> %q -> %d (%v)", bps, s, got, err)
+		}
+	}
+	for _, s := range []string{"", "-1", "1.23456", "1.2x", "abc", "1e3"} {
+		if _, err := ParseAmount(s); err == nil {
+			t.Errorf("%q: want an error", s)
+		}
+	}
+}
+
+func TestFromCheckpointsRestoresFundingSessions(t *testing.T) {
+	a, err := FromCheckpoints([]yellow.Checkpoint{
+		{ChannelID: "0xfund1", Allocations: []yellow.Allocation{
+			{Participant: alice, Token: token, Amount: "10"},
+			{Participant: bob, Token: token, Amount: "2.5"},
+		}},
+		{ChannelID: "0xfund2", Allocations: []yellow.Allocation{
+			{Participant: alice, Token: token, Amount: "5"},
+		}},
+		// Market sessions value positions, not deposits
+		{ChannelID: "0xmarket", MarketID: "m1", Allocations: []yellow.Allocation{
+			{Participant: alice, Token: token, Amount: "100"},
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := a.GetBalance(token, alice); got != 150000 {
+		t.Errorf("alice = %d, want 150000", got)
+	}
+	if got := a.GetBalance(token, bob); got != 25000 {
+		t.Errorf("bob = %d, want 25000", got)
+	}
+
+	if _, err := FromCheckpoints([]yellow.Checkpoint{{ChannelID: "0xbad", Allocations: []yellow.Allocation{
+		{Participant: "house", Token: token, Amount: "1"},
+	}}}); err == nil {
+		t.Error("invalid participant: want an error")
+	}
+}
+
+func TestMultiTokenTransfersAreIndependent(t *testing.T) {
+	a := NewMultiTokenAllocations("0xchannel", map[string]map[string]uint64{
+		token: {alice: 100000},
+		usdt:  {bob: 50000},
+	})
+
+	if err := a.Transfer(token, alice, bob, 30000); err != nil {
+		t.Fatal(err)
+	}
+	if err := a.Transfer(usdt, bob, alice, 50000); err != nil {
+		t.Fatal(err)
+	}
+	// Alice's token balance doesn't fund a USDT transfer
+	if err := a.Transfer(usdt, alice, bob, 50001); err != ErrInsufficientBalance {
+		t.Fatalf("overdraft: err = %v, want %v", err, ErrInsufficientBalance)
+	}
+
+	for _, tc := range []struct {
+		token, participant string
+		want               uint64
+	}{
+		{token, alice, 70000},
+		{token, bob, 30000},
+		{usdt, alice, 50000},
+		{usdt, bob, 0},
+	} {
+		if got := a.GetBalance(tc.token, tc.participant); got != tc.want {
+			t.Errorf("%s of %s = %d, want %d", tc.participant, tc.token, got, tc.want)
+		}
+	}
+
+	// Bob's emptied USDT balance is left out
+	var got []string
+	for _, alloc := range a.ToYellowAllocations() {
+		got = append(got, alloc.Token+" "+alloc.Participant+" "+alloc.Amount)
+	}
+	sort.Strings(got)
+	want := []string{token + " " + alice + " 7", token + " " + bob + " 3", usdt + " " + alice + " 5"}
+	if len(got) != len(want) {
+		t.Fatalf("allocations = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("allocation %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestSingleTokenConstructor(t *testing.T) {
+	a := NewAllocations("0xchannel", token, map[string]uint64{alice: 10000})
+	if got := a.GetBalance(token, alice); got != 10000 {
+		t.Fatalf("balance = %d, want 10000", got)
+	}
+	if got := a.GetBalance(usdt, alice); got != 0 {
+		t.Fatalf("other token balance = %d, want 0", got)
+	}
+}
+
+func TestNetAddsSignedDeltas(t *testing.T) {
+	a := NewAllocations("0xchannel", token, map[string]uint64{alice: 100000, bob: 100000})
+
+	netted, err := a.Net(map[string]map[string]int64{token: {alice: 30000, bob: -30000}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := [2]uint64{netted.GetBalance(token, alice), netted.GetBalance(token, bob)}; got != [2]uint64{130000, 70000} {
+		t.Fatalf("netted alice/bob = %v, want 130000/70000", got)
+	}
+
+	if _, err := a.Net(map[string]map[string]int64{token: {bob: -100001}}); !errors.Is(err, ErrInsufficientBalance) {
+		t.Fatalf("net past zero: err = %v, want %v", err, ErrInsufficientBalance)
+	}
+	if got := [2]uint64{a.GetBalance(token, alice), a.GetBalance(token, bob)}; got != [2]uint64{100000, 100000} {
+		t.Fatalf("receiver changed to %v", got)
+	}
+}
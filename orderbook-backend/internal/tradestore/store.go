@@ -0,0 +1,240 @@
+package tradestore
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"orderbook-backend/internal/engine"
+)
+
+const filePattern = "trades-%06d.jsonl"
+
+// Store durably appends every trade to rotating JSON-lines files.
+// Writes happen on a background goroutine so the matching path only pays
+// for a channel send.
+type Store struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex // guards file rotation against concurrent readers
+	file    *os.File
+	writer  *bufio.Writer
+	index   int
+	written int64
+
+	queue chan *engine.Trade
+	done  chan struct{}
+}
+
+// Open opens (or creates) a trade store in dir, rotating files once they
+// exceed maxBytes. Appending continues in the newest existing file.
+func Open(dir string, maxBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create trade store dir: %w", err)
+	}
+
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Store{
+		dir:      dir,
+		maxBytes: maxBytes,
+		index:    1,
+		queue:    make(chan *engine.Trade, 4096),
+		done:     make(chan struct{}),
+	}
+	if len(files) > 0 {
+		s.index = files[len(files)-1].index
+	}
+	if err := s.openFile(); err != nil {
+		return nil, err
+	}
+
+	go s.run()
+	return s, nil
+}
+
+// Append queues a trade for persistence. It only blocks if the write queue
+// is full, so trades are never dropped.
+func (s *Store) Append(trade *engine.Trade) {
+	s.queue <- trade
+}
+
+// Close flushes queued trades and closes the current file
+func (s *Store) Close() error {
+	close(s.queue)
+	<-s.done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	return s.file.Close()
+}
+
+// run drains the queue, flushing whenever it runs empty
+func (s *Store) run() {
+	defer close(s.done)
+
+	for trade := range s.queue {
+		s.mu.Lock()
+		if err := s.write(trade); err != nil {
+			log.Printf("Failed to persist trade %s: %v", trade.ID, err)
+		}
+		if len(s.queue) == 0 {
+			if err := s.writer.Flush(); err != nil {
+				log.Printf("Failed to flush trade store: %v", err)
+			}
+		}
+		s.mu.Unlock()
+	}
+}
+
+// write appends one trade, rotating first if the file is full (must hold lock)
+func (s *Store) write(trade *engine.Trade) error {
+	line, err := json.Marshal(trade)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if s.maxBytes > 0 && s.written > 0 && s.written+int64(len(line)) > s.maxBytes {
+		if err := s.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := s.writer.Write(line)
+	s.written += int64(n)
+	return err
+}
+
+// rotate closes the current file and starts the next one (must hold lock)
+func (s *Store) rotate() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	s.index++
+	return s.openFile()
+}
+
+// openFile opens the file for the current index in append mode (must hold lock)
+func (s *Store) openFile() error {
+	path := filepath.Join(s.dir, fmt.Sprintf(filePattern, s.index))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open trade file: %w", err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	s.file = f
+	s.writer = bufio.NewWriter(f)
+	s.written = info.Size()
+	return nil
+}
+
+// Query returns persisted trades for a market within [since, until], oldest
+// first. An empty marketID matches every market; zero times are unbounded.
+func (s *Store) Query(marketID string, since, until time.Time) ([]*engine.Trade, error) {
+	// Flush buffered trades so the reader sees everything written so far
+	s.mu.Lock()
+	err := s.writer.Flush()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return ReadDir(s.dir, marketID, since, until)
+}
+
+// ReadDir reads trades from a trade store directory without opening it for writing
+func ReadDir(dir, marketID string, since, until time.Time) ([]*engine.Trade, error) {
+	files, err := listFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var trades []*engine.Trade
+	for _, f := range files {
+		if err := readFile(f.path, func(trade *engine.Trade) {
+			if marketID != "" && trade.MarketID != marketID {
+				return
+			}
+			if !since.IsZero() && trade.Timestamp.Before(since) {
+				return
+			}
+			if !until.IsZero() && trade.Timestamp.After(until) {
+				return
+			}
+			trades = append(trades, trade)
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return trades, nil
+}
+
+func readFile(path string, fn func(*engine.Trade)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var trade engine.Trade
+		if err := json.Unmarshal(scanner.Bytes(), &trade); err != nil {
+			// A torn final line from a crash is skipped rather than failing the read
+			log.Printf("Skipping malformed trade record in %s: %v", path, err)
+			continue
+		}
+		fn(&trade)
+	}
+	return scanner.Err()
+}
+
+type storeFile struct {
+	path  string
+	index int
+}
+
+// listFiles returns the store's files ordered by rotation index
+func listFiles(dir string) ([]storeFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []storeFile
+	for _, e := range entries {
+		var index int
+		if _, err := fmt.Sscanf(e.Name(), filePattern, &index); err != nil {
+			continue
+		}
+		files = append(files, storeFile{path: filepath.Join(dir, e.Name()), index: index})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].index < files[j].index })
+	return files, nil
+}
@@ -0,0 +1,100 @@
+package tradestore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"orderbook-backend/internal/engine"
+)
+
+func TestStoreKeepsTradesPastHistoryCap(t *testing.T) {
+	dir := t.TempDir()
+	store, err := Open(dir, 1024) // Small files so the tape rotates
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const capacity, total = 10, 50
+	history := engine.NewTradeHistory(capacity)
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < total; i++ {
+		trade := &engine.Trade{
+			ID:        fmt.Sprintf("t%02d", i),
+			MarketID:  []string{"m1", "m2"}[i%2],
+			OutcomeID: engine.OutcomeYES,
+			Price:     5000,
+			Quantity:  1,
+			Timestamp: start.Add(time.Duration(i) * time.Minute),
+		}
+		history.Add(trade)
+		store.Append(trade)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(history.Recent(total)); n != capacity {
+		t.Fatalf("in-memory history holds %d trades, want %d", n, capacity)
+	}
+	files, err := listFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) < 2 {
+		t.Fatalf("%d files written, want the tape to rotate", len(files))
+	}
+
+	all, err := ReadDir(dir, "", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != total || all[0].ID != "t00" || all[total-1].ID != "t49" {
+		t.Fatalf("read back %d trades, want all %d oldest first", len(all), total)
+	}
+
+	// The oldest trades, long gone from memory, filtered by market and time
+	older, err := ReadDir(dir, "m1", start, start.Add(9*time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"t00", "t02", "t04", "t06", "t08"}
+	if len(older) != len(want) {
+		t.Fatalf("got %d trades, want %d", len(older), len(want))
+	}
+	for i, trade := range older {
+		if trade.ID != want[i] {
+			t.Errorf("trade %d = %s, want %s", i, trade.ID, want[i])
+		}
+	}
+}
+
+func TestOpenResumesNewestFile(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 2; i++ {
+		store, err := Open(dir, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Append(&engine.Trade{ID: fmt.Sprintf("t%d", i), MarketID: "m1"})
+		if err := store.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%d files, want appends to continue in one", len(entries))
+	}
+	trades, err := ReadDir(dir, "m1", time.Time{}, time.Time{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 2 {
+		t.Fatalf("read %d trades, want 2", len(trades))
+	}
+}
@@ -0,0 +1,76 @@
+package yellow
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+var (
+	ErrInvalidAddress   = errors.New("invalid Ethereum address")
+	ErrInvalidChannelID = errors.New("invalid channel ID: must be 32 bytes of hex")
+)
+
+// NormalizeAddress validates a hex Ethereum address and returns its EIP-55
+// checksummed form. All-lowercase and all-uppercase input is accepted as-is;
+// mixed-case input must carry a correct checksum, since a mismatch usually
+// means a typo.
+func NormalizeAddress(addr string) (string, error) {
+	if !common.IsHexAddress(addr) || !strings.HasPrefix(strings.ToLower(addr), "0x") {
+		return "", fmt.Errorf("%w: %q", ErrInvalidAddress, addr)
+	}
+
+	checksummed := common.HexToAddress(addr).Hex()
+	hexPart := addr[2:]
+	if hexPart != strings.ToLower(hexPart) && hexPart != strings.ToUpper(hexPart) && hexPart != checksummed[2:] {
+		return "", fmt.Errorf("%w: %q has a bad checksum", ErrInvalidAddress, addr)
+	}
+	return checksummed, nil
+}
+
+// NormalizeAddresses normalizes a list of addresses, failing on the first invalid one
+func NormalizeAddresses(addrs []string) ([]string, error) {
+	out := make([]string, len(addrs))
+	for i, addr := range addrs {
+		normalized, err := NormalizeAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		out[i] = normalized
+	}
+	return out, nil
+}
+
+// NormalizeAllocations validates the participant and token address of each
+// allocation and returns a copy with both checksummed
+func NormalizeAllocations(allocs []Allocation) ([]Allocation, error) {
+	out := make([]Allocation, len(allocs))
+	for i, alloc := range allocs {
+		participant, err := NormalizeAddress(alloc.Participant)
+		if err != nil {
+			return nil, fmt.Errorf("allocation %d participant: %w", i, err)
+		}
+		token, err := NormalizeAddress(alloc.Token)
+		if err != nil {
+			return nil, fmt.Errorf("allocation %d token: %w", i, err)
+		}
+		out[i] = Allocation{Participant: participant, Token: token, Amount: alloc.Amount}
+	}
+	return out, nil
+}
+
+// ParseChannelID decodes a 0x-prefixed hex channel ID into its 32 bytes
+func ParseChannelID(id string) ([32]byte, error) {
+	var out [32]byte
+	hexPart, ok := strings.CutPrefix(strings.ToLower(id), "0x")
+	if !ok || len(hexPart) != 64 {
+		return out, fmt.Errorf("%w: %q", ErrInvalidChannelID, id)
+	}
+	if _, err := hex.Decode(out[:], []byte(hexPart)); err != nil {
+		return out, fmt.Errorf("%w: %q", ErrInvalidChannelID, id)
+	}
+	return out, nil
+}
@@ -0,0 +1,67 @@
+package yellow
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	const lower = "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"
+	checksummed := common.HexToAddress(lower).Hex()
+	// Flipping the case of one letter breaks the checksum
+	i := strings.IndexAny(checksummed[2:], "abcdefABCDEF") + 2
+	flipped := checksummed[:i] + swapCase(checksummed[i:i+1]) + checksummed[i+1:]
+
+	for _, tc := range []struct {
+		name, addr, want string
+	}{
+		{"checksummed", checksummed, checksummed},
+		{"lowercase", lower, checksummed},
+		{"uppercase", "0x" + strings.ToUpper(lower[2:]), checksummed},
+		{"bad checksum", flipped, ""},
+		{"non-hex", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beazz", ""},
+		{"not an address", "house", ""},
+		{"too short", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea", ""},
+		{"too long", "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00", ""},
+		{"no prefix", "5aaeb6053f3e94c9b9a09f33669435e7ef1beaed", ""},
+	} {
+		got, err := NormalizeAddress(tc.addr)
+		if tc.want == "" {
+			if !errors.Is(err, ErrInvalidAddress) {
+				t.Errorf("%s: err = %v, want %v", tc.name, err, ErrInvalidAddress)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("%s: got %q (%v), want %q", tc.name, got, err, tc.want)
+		}
+	}
+}
+
+func swapCase(s string) string {
+	if s == strings.ToLower(s) {
+		return strings.ToUpper(s)
+	}
+	return strings.ToLower(s)
+}
+
+func TestParseChannelID(t *testing.T) {
+	id, err := ParseChannelID("0x" + strings.Repeat("AB", 31) + "c1")
+	if err != nil || id[0] != 0xab || id[31] != 0xc1 {
+		t.Fatalf("got %x (%v), want ab...c1", id, err)
+	}
+	for _, bad := range []string{
+		"",
+		strings.Repeat("ab", 32),               // No prefix
+		"0x" + strings.Repeat("ab", 31),        // 31 bytes
+		"0x" + strings.Repeat("ab", 33),        // 33 bytes
+		"0x" + strings.Repeat("ab", 31) + "zz", // Not hex
+	} {
+		if _, err := ParseChannelID(bad); !errors.Is(err, ErrInvalidChannelID) {
+			t.Errorf("%q: err = %v, want %v", bad, err, ErrInvalidChannelID)
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package yellow
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+)
+
+// mockClearNode is a WebSocket server that answers JSON-RPC requests with a
+// handler. A nil response from the handler drops the request unanswered.
+type mockClearNode struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	requests []*Request
+	conns    []*websocket.Conn
+}
+
+func newMockClearNode(t *testing.T, handle func(req *Request) *Response) *mockClearNode {
+	t.Helper()
+	m := &mockClearNode{}
+	upgrader := websocket.Upgrader{}
+	m.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		m.mu.Lock()
+		m.conns = append(m.conns, conn)
+		m.mu.Unlock()
+		defer conn.Close()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			var req Request
+			if err := json.Unmarshal(data, &req); err != nil {
+				continue
+			}
+			m.mu.Lock()
+			m.requests = append(m.requests, &req)
+			m.mu.Unlock()
+
+			if resp := handle(&req); resp != nil {
+				resp.JSONRPC, resp.ID = "2.0", req.ID
+				out, _ := json.Marshal(resp)
+				m.mu.Lock()
+				err := conn.WriteMessage(websocket.TextMessage, out)
+				m.mu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}))
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+// url returns the server's WebSocket URL
+func (m *mockClearNode) url() string {
+	return "ws" + strings.TrimPrefix(m.server.URL, "http")
+}
+
+// received returns the requests received so far with the given method
+func (m *mockClearNode) received(method string) []*Request {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []*Request
+	for _, req := range m.requests {
+		if req.Method == method {
+			out = append(out, req)
+		}
+	}
+	return out
+}
+
+// notify pushes a notification to every connection the server has accepted
+func (m *mockClearNode) notify(t *testing.T, method string, params interface{}) {
+	t.Helper()
+	data, err := json.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(Notification{JSONRPC: "2.0", Method: method, Params: data})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, conn := range m.conns {
+		if err := conn.WriteMessage(websocket.TextMessage, out); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// drop closes every connection the server has accepted
+func (m *mockClearNode) drop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, conn := range m.conns {
+		conn.Close()
+	}
+}
+
+// connections returns how many connections the server has accepted
+func (m *mockClearNode) connections() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.conns)
+}
+
+// newMockClient connects a client (no keepalive, no reconnect) to a mock ClearNode
+func newMockClient(t *testing.T, m *mockClearNode) *Client {
+	t.Helper()
+	c := NewClient(m.url(), nil)
+	c.SetPingInterval(0)
+	c.SetReconnect(false)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// okResult is a successful response with a JSON result
+func okResult(v interface{}) *Response {
+	data, _ := json.Marshal(v)
+	return &Response{Result: data}
+}
@@ -3,14 +3,32 @@ package yellow
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"log/slog"
 	"sync"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+var (
+	ErrNotConnected   = errors.New("not connected")
+	ErrRequestTimeout = errors.New("request timeout")
+
+	// ErrDuplicateRequestID is returned when a request's ID is already
+	// awaiting a response, so the two responses couldn't be told apart
+	ErrDuplicateRequestID = errors.New("request ID already in flight")
+)
+
+// Auth session identity presented to the ClearNode. The application name
+// doubles as the EIP-712 domain name.
+const (
+	AuthApplication = "OrderbookTrade"
+	AuthScope       = "orderbook.app"
+)
+
 // Client manages the WebSocket connection to Yellow ClearNode
 type Client struct {
 	mu     sync.RWMutex
@@ -21,30 +39,87 @@ type Client struct {
 	sessionKey    string // Session key address
 	jwtToken      string // JWT token from auth
 	authenticated bool
+	authMode      AuthSignMode // How the auth challenge is signed
+
+	// Auth session renewal; authMu serializes auth flows so a refresh
+	// never overlaps the re-authentication of a reconnect
+	authMu        sync.Mutex
+	authTTL       time.Duration
+	authExpiresAt time.Time
+	refreshMargin time.Duration
+	refreshTimer  *time.Timer
 
 	// Pending requests waiting for response
 	pending   map[int64]chan *Response
+	sentAt    map[int64]time.Time // Send times of traced requests
 	pendingMu sync.Mutex
 
+	logger *slog.Logger
+
+	// Frame logging (off by default)
+	trace    bool
+	traceLog *log.Logger
+
 	// Callbacks
-	onMessage func(*Response)
-	onError   func(error)
+	onMessage      func(*Response)
+	onError        func(error)
+	notifyHandlers map[string]func(json.RawMessage) // Keyed by notification method
 
 	// Control
 	done   chan struct{}
 	closed bool
+
+	// Automatic reconnection after an unexpected disconnect
+	reconnect    bool
+	reconnecting bool // A reconnect loop is running
+
+	// Keepalive pings
+	pingInterval    time.Duration
+	maxPingFailures int
 }
 
 // NewClient creates a new Yellow Network client
 func NewClient(url string, signer *Signer) *Client {
 	return &Client{
-		url:     url,
-		signer:  signer,
-		pending: make(map[int64]chan *Response),
-		done:    make(chan struct{}),
+		url:       url,
+		signer:    signer,
+		pending:   make(map[int64]chan *Response),
+		sentAt:    make(map[int64]time.Time),
+		done:      make(chan struct{}),
+		authMode:  AuthSignEIP712,
+		reconnect: true,
+		logger:    slog.Default(),
+
+		pingInterval:    DefaultPingInterval,
+		maxPingFailures: DefaultMaxPingFailures,
+
+		authTTL:       DefaultAuthTTL,
+		refreshMargin: DefaultAuthRefreshMargin,
 	}
 }
 
+// SetLogger sets the client's logger. Requests log with their context, so
+// the API request that caused a ClearNode call is tagged on its log lines.
+func (c *Client) SetLogger(logger *slog.Logger) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.logger = logger
+}
+
+// log returns the client's logger
+func (c *Client) log() *slog.Logger {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.logger
+}
+
+// SetAuthSignMode sets how the auth challenge is signed (default EIP-712)
+func (c *Client) SetAuthSignMode(mode AuthSignMode) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authMode = mode
+}
+
 // Connect establishes the WebSocket connection
 func (c *Client) Connect(ctx context.Context) error {
 	c.mu.Lock()
@@ -64,17 +139,26 @@ func (c *Client) Connect(ctx context.Context) error {
 	}
 
 	c.conn = conn
-	c.closed = false
+	silence := c.startKeepalive(conn)
 
 	// Start message reader
-	go c.readLoop()
+	go c.readLoop(conn, silence)
 
 	return nil
 }
 
-// Authenticate performs the auth flow with the ClearNode using EIP-712
+// Authenticate performs the auth flow with the ClearNode using EIP-712. The
+// auth session is renewed automatically shortly before it expires.
 func (c *Client) Authenticate(ctx context.Context) error {
-	log.Println("Starting Yellow Network authentication...")
+	c.authMu.Lock()
+	defer c.authMu.Unlock()
+	return c.authenticate(ctx)
+}
+
+// authenticate runs the auth flow (must hold authMu)
+func (c *Client) authenticate(ctx context.Context) error {
+	logger := c.log()
+	logger.InfoContext(ctx, "yellow authentication starting")
 
 	// Step 1: Generate session keypair
 	_, sessionAddr, err := GenerateSessionKey()
@@ -82,9 +166,12 @@ func (c *Client) Authenticate(ctx context.Context) error {
 		return fmt.Errorf("failed to generate session key: %w", err)
 	}
 	sessionKey := sessionAddr.Hex()
-	log.Printf("  Generated session key: %s", sessionKey)
+	logger.DebugContext(ctx, "yellow session key generated", "session_key", sessionKey)
 
 	// Step 2: Prepare auth parameters
+	c.mu.RLock()
+	ttl := c.authTTL
+	c.mu.RUnlock()
 	authParams := AuthRequestParams{
 		Address:    c.signer.AddressHex(),
 		SessionKey: sessionKey,
@@ -94,13 +181,12 @@ func (c *Client) Authenticate(ctx context.Context) error {
 				Amount: "1000000000", // Large allowance for testing
 			},
 		},
-		ExpiresAt:   time.Now().Unix() + 3600, // 1 hour
-		Scope:       "orderbook.app",
-		Application: "OrderbookTrade",
+		ExpiresAt:   time.Now().Add(ttl).Unix(),
+		Scope:       AuthScope,
+		Application: AuthApplication,
 	}
 
 	// Step 3: Send auth_request
-	log.Println("  Sending auth_request...")
 	authReq, err := NewAuthRequest(authParams)
 	if err != nil {
 		return fmt.Errorf("failed to create auth request: %w", err)
@@ -112,7 +198,7 @@ func (c *Client) Authenticate(ctx context.Context) error {
 	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("auth request error: %s", resp.Error.Message)
+		return fmt.Errorf("auth request error: %w", resp.Error)
 	}
 
 	var authResult AuthRequestResult
@@ -120,11 +206,14 @@ func (c *Client) Authenticate(ctx context.Context) error {
 		return fmt.Errorf("failed to parse auth result: %w", err)
 	}
 
-	log.Printf("  Received challenge: %s", authResult.ChallengeMessage)
+	logger.DebugContext(ctx, "yellow auth challenge received", "challenge", authResult.ChallengeMessage)
 
-	// Step 4: Sign the challenge using EIP-712
-	log.Println("  Signing challenge with EIP-712...")
-	signature, err := c.signer.SignEIP712Auth(
+	// Step 4: Sign the challenge in the configured format
+	c.mu.RLock()
+	mode := c.authMode
+	c.mu.RUnlock()
+	signature, err := c.signer.SignAuthChallenge(
+		mode,
 		authResult.ChallengeMessage,
 		authParams,
 		authParams.Application,
@@ -133,10 +222,9 @@ func (c *Client) Authenticate(ctx context.Context) error {
 		return fmt.Errorf("failed to sign challenge: %w", err)
 	}
 
-	log.Printf("  Generated signature: %s", signature[:20]+"...")
+	logger.DebugContext(ctx, "yellow auth challenge signed", "mode", string(mode))
 
 	// Step 5: Send auth_verify
-	log.Println("  Sending auth_verify...")
 	verifyParams := AuthVerifyParams{
 		Address:          authParams.Address,
 		SessionKey:       authParams.SessionKey,
@@ -159,7 +247,7 @@ func (c *Client) Authenticate(ctx context.Context) error {
 	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("auth verify error: %s", resp.Error.Message)
+		return fmt.Errorf("auth verify error (sign mode %s): %w", mode, resp.Error)
 	}
 
 	var verifyResult AuthVerifyResult
@@ -167,40 +255,60 @@ func (c *Client) Authenticate(ctx context.Context) error {
 		return fmt.Errorf("failed to parse verify result: %w", err)
 	}
 
+	expiresAt := authExpiry(verifyResult, authParams.ExpiresAt)
+
 	c.mu.Lock()
 	c.sessionKey = verifyResult.SessionKey
 	c.jwtToken = verifyResult.JWTToken
 	c.authenticated = true
+	if !c.closed {
+		c.scheduleRefresh(expiresAt)
+	}
 	c.mu.Unlock()
 
-	log.Printf("✓ Authenticated successfully!")
-	log.Printf("  Session Key: %s", verifyResult.SessionKey)
-	if verifyResult.JWTToken != "" {
-		log.Printf("  JWT Token: %s...", verifyResult.JWTToken[:20])
-	}
-	log.Printf("  Expires At: %s", time.Unix(verifyResult.ExpiresAt, 0).Format(time.RFC3339))
+	logger.InfoContext(ctx, "yellow authenticated",
+		"session_key", verifyResult.SessionKey,
+		"expires_at", expiresAt.Format(time.RFC3339),
+	)
 
 	return nil
 }
 
-// SendRequest sends a JSON-RPC request and waits for response
+// SendRequest sends a JSON-RPC request and waits for response. Each call is
+// logged with ctx, so it carries the request ID of the API call behind it.
 func (c *Client) SendRequest(ctx context.Context, req *Request) (*Response, error) {
-	c.mu.RLock()
-	if c.conn == nil {
-		c.mu.RUnlock()
-		return nil, fmt.Errorf("not connected")
+	start := time.Now()
+	resp, err := c.roundTrip(ctx, req)
+
+	logger := c.log()
+	attrs := []any{"method", req.Method, "id", req.ID, "duration_ms", float64(time.Since(start).Microseconds()) / 1000}
+	switch {
+	case err != nil:
+		logger.WarnContext(ctx, "yellow request failed", append(attrs, "error", err)...)
+	case resp.Error != nil:
+		logger.WarnContext(ctx, "yellow request rejected", append(attrs, "error", resp.Error)...)
+	default:
+		logger.DebugContext(ctx, "yellow request", attrs...)
 	}
-	c.mu.RUnlock()
+	return resp, err
+}
 
+// roundTrip sends a request and waits for its response
+func (c *Client) roundTrip(ctx context.Context, req *Request) (*Response, error) {
 	// Create response channel
 	respChan := make(chan *Response, 1)
 	c.pendingMu.Lock()
+	if _, inFlight := c.pending[req.ID]; inFlight {
+		c.pendingMu.Unlock()
+		return nil, ErrDuplicateRequestID
+	}
 	c.pending[req.ID] = respChan
 	c.pendingMu.Unlock()
 
 	defer func() {
 		c.pendingMu.Lock()
 		delete(c.pending, req.ID)
+		delete(c.sentAt, req.ID)
 		c.pendingMu.Unlock()
 	}()
 
@@ -210,7 +318,13 @@ func (c *Client) SendRequest(ctx context.Context, req *Request) (*Response, erro
 		return nil, err
 	}
 
+	c.traceRequest(req, data)
+
 	c.mu.Lock()
+	if c.conn == nil {
+		c.mu.Unlock()
+		return nil, ErrNotConnected
+	}
 	err = c.conn.WriteMessage(websocket.TextMessage, data)
 	c.mu.Unlock()
 
@@ -221,66 +335,51 @@ func (c *Client) SendRequest(ctx context.Context, req *Request) (*Response, erro
 	// Wait for response
 	select {
 	case resp := <-respChan:
+		if resp == nil {
+			return nil, ErrConnectionLost
+		}
 		return resp, nil
 	case <-ctx.Done():
 		return nil, ctx.Err()
 	case <-time.After(30 * time.Second):
-		return nil, fmt.Errorf("request timeout")
+		return nil, ErrRequestTimeout
 	}
 }
 
-// readLoop reads messages from the WebSocket
-func (c *Client) readLoop() {
-	defer func() {
-		c.mu.Lock()
-		c.closed = true
-		if c.conn != nil {
-			c.conn.Close()
-		}
-		c.mu.Unlock()
-	}()
-
+// readLoop reads messages from one connection until it fails. With a
+// silence limit, each frame pushes the read deadline back by that much.
+func (c *Client) readLoop(conn *websocket.Conn, silence time.Duration) {
 	for {
-		select {
-		case <-c.done:
-			return
-		default:
-		}
-
-		c.mu.RLock()
-		conn := c.conn
-		c.mu.RUnlock()
-
-		if conn == nil {
-			return
-		}
-
 		_, message, err := conn.ReadMessage()
 		if err != nil {
-			if !websocket.IsCloseError(err, websocket.CloseNormalClosure) {
-				if c.onError != nil {
-					c.onError(err)
-				}
-			}
+			c.handleDisconnect(conn, err)
 			return
 		}
+		if silence > 0 {
+			conn.SetReadDeadline(time.Now().Add(silence))
+		}
 
 		resp, err := ParseResponse(message)
 		if err != nil {
-			log.Printf("Failed to parse response: %v", err)
+			c.log().Warn("yellow: failed to parse response", "error", err)
 			continue
 		}
 
 		// Check if this is a response to a pending request
 		c.pendingMu.Lock()
-		if ch, ok := c.pending[resp.ID]; ok {
+		ch, pending := c.pending[resp.ID]
+		c.pendingMu.Unlock()
+
+		c.traceResponse(resp, message, pending)
+		if pending {
 			ch <- resp
-			c.pendingMu.Unlock()
 			continue
 		}
-		c.pendingMu.Unlock()
 
 		// Otherwise, it's an unsolicited message (notification)
+		if n, ok := ParseNotification(message); ok && c.dispatchNotification(n) {
+			continue
+		}
 		if c.onMessage != nil {
 			c.onMessage(resp)
 		}
@@ -315,6 +414,7 @@ func (c *Client) Close() error {
 
 	close(c.done)
 	c.closed = true
+	c.stopRefresh()
 
 	if c.conn != nil {
 		return c.conn.Close()
@@ -335,7 +435,7 @@ func (c *Client) Ping(ctx context.Context) error {
 	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("ping error: %s", resp.Error.Message)
+		return fmt.Errorf("ping error: %w", resp.Error)
 	}
 
 	return nil
@@ -0,0 +1,87 @@
+package yellow
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestConcurrentRequestsGetTheirOwnResponses(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		return okResult(req.Params) // Echo the params back
+	})
+	client := newMockClient(t, node)
+
+	const callers = 50
+	ids := make([]int64, callers)
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			req, err := NewRequest("echo", map[string]int{"caller": i})
+			if err != nil {
+				errs <- err
+				return
+			}
+			ids[i] = req.ID
+			resp, err := client.SendRequest(context.Background(), req)
+			if err != nil {
+				errs <- err
+				return
+			}
+			var got map[string]int
+			if err := json.Unmarshal(resp.Result, &got); err != nil || got["caller"] != i {
+				t.Errorf("caller %d got the response %s", i, resp.Result)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+
+	seen := make(map[int64]bool)
+	for _, id := range ids {
+		if seen[id] {
+			t.Fatalf("request ID %d was issued twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestDuplicateRequestIDIsRefused(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		return nil // Never answer, so the first request stays in flight
+	})
+	client := newMockClient(t, node)
+	req, err := NewRequest("hold", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := client.SendRequest(ctx, req)
+		done <- err
+	}()
+	for deadline := time.Now().Add(time.Second); len(node.received("hold")) == 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("first request never arrived")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if _, err := client.SendRequest(context.Background(), req); err != ErrDuplicateRequestID {
+		t.Fatalf("second send of ID %d: err = %v, want %v", req.ID, err, ErrDuplicateRequestID)
+	}
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("first request: err = %v, want %v", err, context.Canceled)
+	}
+}
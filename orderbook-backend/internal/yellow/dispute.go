@@ -0,0 +1,200 @@
+package yellow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+var ErrNoSignedState = errors.New("no signed state to dispute with")
+
+// AdjudicatorABI is the part of the Nitrolite custody/adjudicator contract
+// used to dispute a channel: challenge(channelId, candidate, proofs) puts
+// the candidate state on chain and starts the challenge period.
+const AdjudicatorABI = `[{
+	"type": "function",
+	"name": "challenge",
+	"stateMutability": "nonpayable",
+	"inputs": [
+		{"name": "channelId", "type": "bytes32"},
+		{"name": "candidate", "type": "tuple", "components": [
+			{"name": "intent", "type": "uint8"},
+			{"name": "version", "type": "uint256"},
+			{"name": "data", "type": "bytes"},
+			{"name": "allocations", "type": "tuple[]", "components": [
+				{"name": "destination", "type": "address"},
+				{"name": "token", "type": "address"},
+				{"name": "amount", "type": "uint256"}
+			]},
+			{"name": "sigs", "type": "tuple[]", "components": [
+				{"name": "v", "type": "uint8"},
+				{"name": "r", "type": "bytes32"},
+				{"name": "s", "type": "bytes32"}
+			]}
+		]},
+		{"name": "proofs", "type": "tuple[]", "components": [
+			{"name": "intent", "type": "uint8"},
+			{"name": "version", "type": "uint256"},
+			{"name": "data", "type": "bytes"},
+			{"name": "allocations", "type": "tuple[]", "components": [
+				{"name": "destination", "type": "address"},
+				{"name": "token", "type": "address"},
+				{"name": "amount", "type": "uint256"}
+			]},
+			{"name": "sigs", "type": "tuple[]", "components": [
+				{"name": "v", "type": "uint8"},
+				{"name": "r", "type": "bytes32"},
+				{"name": "s", "type": "bytes32"}
+			]}
+		]}
+	],
+	"outputs": []
+}]`
+
+// StateIntentOperate marks an ordinary operating state (as opposed to the
+// initial funding or a resize)
+const StateIntentOperate uint8 = 0
+
+// ChainState is a channel state in the adjudicator's ABI layout
+type ChainState struct {
+	Intent      uint8
+	Version     *big.Int
+	Data        []byte
+	Allocations []ChainAllocation
+	Sigs        []ChainSignature
+}
+
+// ChainAllocation is an allocation in the adjudicator's ABI layout
+type ChainAllocation struct {
+	Destination common.Address
+	Token       common.Address
+	Amount      *big.Int
+}
+
+// ChainSignature is a signature split into v, r and s
+type ChainSignature struct {
+	V uint8
+	R [32]byte
+	S [32]byte
+}
+
+// ChainBackend is what the disputer needs from an Ethereum client.
+// *ethclient.Client satisfies it.
+type ChainBackend interface {
+	bind.ContractBackend
+	ChainID(ctx context.Context) (*big.Int, error)
+}
+
+// Disputer submits the latest signed state of a channel to the on-chain
+// adjudicator when the ClearNode won't close it cooperatively
+type Disputer struct {
+	backend     ChainBackend
+	signer      *Signer
+	adjudicator common.Address
+	contract    *bind.BoundContract
+}
+
+// NewDisputer creates a disputer that sends transactions from the signer's
+// address to the adjudicator contract
+func NewDisputer(backend ChainBackend, signer *Signer, adjudicatorAddr string) (*Disputer, error) {
+	addr, err := NormalizeAddress(adjudicatorAddr)
+	if err != nil {
+		return nil, fmt.Errorf("adjudicator: %w", err)
+	}
+	parsed, err := abi.JSON(strings.NewReader(AdjudicatorABI))
+	if err != nil {
+		return nil, err
+	}
+	adjudicator := common.HexToAddress(addr)
+	return &Disputer{
+		backend:     backend,
+		signer:      signer,
+		adjudicator: adjudicator,
+		contract:    bind.NewBoundContract(adjudicator, parsed, backend, backend, backend),
+	}, nil
+}
+
+// Challenge submits a checkpointed state to the adjudicator and returns the
+// hash of the challenge transaction. It does not wait for it to be mined.
+func (d *Disputer) Challenge(ctx context.Context, cp Checkpoint) (common.Hash, error) {
+	if cp.Signature == "" {
+		return common.Hash{}, ErrNoSignedState
+	}
+	channelID, err := ParseChannelID(cp.ChannelID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	candidate, err := chainState(cp)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	chainID, err := d.backend.ChainID(ctx)
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("failed to get chain ID: %w", err)
+	}
+	opts, err := bind.NewKeyedTransactorWithChainID(d.signer.privateKey, chainID)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	opts.Context = ctx
+
+	tx, err := d.contract.Transact(opts, "challenge", channelID, candidate, []ChainState{})
+	if err != nil {
+		return common.Hash{}, fmt.Errorf("challenge transaction failed: %w", err)
+	}
+	return tx.Hash(), nil
+}
+
+// chainState converts a checkpoint into the adjudicator's state layout
+func chainState(cp Checkpoint) (ChainState, error) {
+	sig, err := splitSignature(cp.Signature)
+	if err != nil {
+		return ChainState{}, err
+	}
+
+	allocations := make([]ChainAllocation, len(cp.Allocations))
+	for i, alloc := range cp.Allocations {
+		participant, err := NormalizeAddress(alloc.Participant)
+		if err != nil {
+			return ChainState{}, fmt.Errorf("allocation %d participant: %w", i, err)
+		}
+		token, err := NormalizeAddress(alloc.Token)
+		if err != nil {
+			return ChainState{}, fmt.Errorf("allocation %d token: %w", i, err)
+		}
+		allocations[i] = ChainAllocation{
+			Destination: common.HexToAddress(participant),
+			Token:       common.HexToAddress(token),
+			Amount:      allocationAmount(alloc),
+		}
+	}
+
+	return ChainState{
+		Intent:      StateIntentOperate,
+		Version:     new(big.Int).SetUint64(cp.Version),
+		Data:        []byte{}, // App data is not part of the signed state
+		Allocations: allocations,
+		Sigs:        []ChainSignature{sig},
+	}, nil
+}
+
+// splitSignature splits a 65-byte hex signature into v, r and s
+func splitSignature(sigHex string) (ChainSignature, error) {
+	raw, err := hexutil.Decode(sigHex)
+	if err != nil || len(raw) != 65 {
+		return ChainSignature{}, fmt.Errorf("invalid state signature %q", sigHex)
+	}
+	var sig ChainSignature
+	copy(sig.R[:], raw[:32])
+	copy(sig.S[:], raw[32:64])
+	sig.V = raw[64]
+	return sig, nil
+}
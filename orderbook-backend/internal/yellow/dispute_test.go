@@ -0,0 +1,132 @@
+package yellow
+
+import (
+	"bytes"
+	"context"
+	"math/big"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+)
+
+// fakeChain is a ChainBackend that records the transactions sent to it.
+// Calls the disputer never makes hit the nil embedded backend and panic.
+type fakeChain struct {
+	bind.ContractBackend
+	chainID *big.Int
+
+	mu   sync.Mutex
+	sent []*types.Transaction
+}
+
+func (c *fakeChain) ChainID(ctx context.Context) (*big.Int, error) { return c.chainID, nil }
+
+func (c *fakeChain) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return &types.Header{Number: big.NewInt(1), BaseFee: big.NewInt(1_000_000_000)}, nil
+}
+
+func (c *fakeChain) PendingCodeAt(ctx context.Context, account common.Address) ([]byte, error) {
+	return []byte{0x00}, nil // Every address holds a contract
+}
+
+func (c *fakeChain) PendingNonceAt(ctx context.Context, account common.Address) (uint64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return uint64(len(c.sent)), nil
+}
+
+func (c *fakeChain) SuggestGasPrice(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(2_000_000_000), nil
+}
+
+func (c *fakeChain) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
+	return big.NewInt(1_000_000_000), nil
+}
+
+func (c *fakeChain) EstimateGas(ctx context.Context, call ethereum.CallMsg) (uint64, error) {
+	return 100_000, nil
+}
+
+func (c *fakeChain) SendTransaction(ctx context.Context, tx *types.Transaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sent = append(c.sent, tx)
+	return nil
+}
+
+func TestChallengeSubmitsSignedState(t *testing.T) {
+	ctx := context.Background()
+	signer := newTestSigner(t)
+	chain := &fakeChain{chainID: big.NewInt(1337)}
+	adjudicator := common.HexToAddress("0x5555555555555555555555555555555555555555")
+
+	disputer, err := NewDisputer(chain, signer, adjudicator.Hex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := disputer.Challenge(ctx, Checkpoint{ChannelID: testChannelID, Version: 3}); err != ErrNoSignedState {
+		t.Fatalf("unsigned checkpoint: err = %v, want %v", err, ErrNoSignedState)
+	}
+
+	const alice, token = "0x1111111111111111111111111111111111111111", "0x0000000000000000000000000000000000000000"
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocations := []Allocation{{Participant: alice, Token: token, Amount: "5"}}
+	sigHex, err := signer.SignStateHashHex(channelID, 3, allocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txHash, err := disputer.Challenge(ctx, Checkpoint{ChannelID: testChannelID, Version: 3, Allocations: allocations, Signature: sigHex})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chain.sent) != 1 {
+		t.Fatalf("%d transactions sent, want 1", len(chain.sent))
+	}
+	tx := chain.sent[0]
+	if tx.Hash() != txHash {
+		t.Fatalf("returned hash %v, sent %v", txHash, tx.Hash())
+	}
+	if tx.To() == nil || *tx.To() != adjudicator {
+		t.Fatalf("tx sent to %v, want the adjudicator %v", tx.To(), adjudicator)
+	}
+	if from, err := types.Sender(types.LatestSignerForChainID(chain.chainID), tx); err != nil || from != signer.Address() {
+		t.Fatalf("tx sent from %v (%v), want the operator %v", from, err, signer.Address())
+	}
+
+	// The call is challenge(channelId, candidate, no proofs), the candidate
+	// being the checkpointed state with its signature split into v, r and s
+	sig := hexutil.MustDecode(sigHex)
+	candidate := ChainState{
+		Intent:  StateIntentOperate,
+		Version: big.NewInt(3),
+		Data:    []byte{},
+		Allocations: []ChainAllocation{{
+			Destination: common.HexToAddress(alice),
+			Token:       common.HexToAddress(token),
+			Amount:      big.NewInt(5),
+		}},
+		Sigs: []ChainSignature{{V: sig[64], R: [32]byte(sig[:32]), S: [32]byte(sig[32:64])}},
+	}
+	parsed, err := abi.JSON(strings.NewReader(AdjudicatorABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := parsed.Pack("challenge", channelID, candidate, []ChainState{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(tx.Data(), want) {
+		t.Fatalf("calldata = %x, want %x", tx.Data(), want)
+	}
+}
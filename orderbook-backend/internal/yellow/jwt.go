@@ -1,18 +1,48 @@
 package yellow
 
 import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
 	"strings"
 	"time"
 )
 
+var (
+	ErrTokenSignature      = errors.New("invalid token signature")
+	ErrTokenVerifyDisabled = errors.New("no key configured to verify tokens")
+)
+
 // JWTClaims represents the Yellow Network JWT token claims
 type JWTClaims struct {
 	Address    string `json:"address"`
 	SessionKey string `json:"session_key"`
 	ExpiresAt  int64  `json:"expires_at"`
 	Scope      string `json:"scope"`
+
+	// Registered JWT expiry, used when expires_at is absent
+	Exp int64 `json:"exp"`
+}
+
+// Expiry returns when the token expires, or the zero time if it carries no
+// expiry claim
+func (c *JWTClaims) Expiry() time.Time {
+	switch {
+	case c.ExpiresAt > 0:
+		return time.Unix(c.ExpiresAt, 0)
+	case c.Exp > 0:
+		return time.Unix(c.Exp, 0)
+	default:
+		return time.Time{}
+	}
 }
 
 // UserSession represents an authenticated user session
@@ -23,8 +53,10 @@ type UserSession struct {
 	ExpiresAt  time.Time
 }
 
-// ParseJWT parses a Yellow Network JWT token (simplified version)
-// Note: In production, you should verify the signature against Yellow's public key
+// ParseJWT decodes the claims of a Yellow Network JWT token without checking
+// its signature. Only use it on tokens from a trusted source, such as the
+// one the ClearNode issued to this client; validate callers' tokens with a
+// TokenVerifier.
 func ParseJWT(tokenString string) (*JWTClaims, error) {
 	// JWT format: header.payload.signature
 	parts := strings.Split(tokenString, ".")
@@ -33,31 +65,60 @@ func ParseJWT(tokenString string) (*JWTClaims, error) {
 	}
 
 	// Decode payload (base64url)
-	payload := parts[1]
-
-	// Add padding if needed
-	switch len(payload) % 4 {
-	case 2:
-		payload += "=="
-	case 3:
-		payload += "="
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("invalid JWT payload: %w", err)
 	}
 
-	// For now, we'll just return a basic claims structure
-	// In production, decode the base64 and verify signature
-	claims := &JWTClaims{
-		// These would be extracted from the actual JWT
-		// For now, we accept the token as-is if it exists
+	claims := &JWTClaims{}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return nil, fmt.Errorf("invalid JWT claims: %w", err)
 	}
 
 	return claims, nil
 }
 
-// ValidateToken validates a Yellow JWT token
-func ValidateToken(tokenString string) (*UserSession, error) {
+// TokenVerifier validates JWTs signed by the ClearNode, using its public
+// key (ES256 for an ECDSA P-256 key, RS256 for an RSA key)
+type TokenVerifier struct {
+	key crypto.PublicKey
+}
+
+// NewTokenVerifier creates a verifier from a PEM-encoded public key
+func NewTokenVerifier(pemKey []byte) (*TokenVerifier, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM public key found")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key: %w", err)
+	}
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		if k.Curve.Params().BitSize != 256 {
+			return nil, fmt.Errorf("unsupported ECDSA curve %s", k.Curve.Params().Name)
+		}
+	case *rsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported public key type %T", key)
+	}
+	return &TokenVerifier{key: key}, nil
+}
+
+// Validate checks a token's signature, then its claims: it must name a valid
+// address and carry an expiry that hasn't passed. A nil verifier rejects
+// every token.
+func (v *TokenVerifier) Validate(tokenString string) (*UserSession, error) {
+	if v == nil {
+		return nil, ErrTokenVerifyDisabled
+	}
 	if tokenString == "" {
 		return nil, fmt.Errorf("empty token")
 	}
+	if err := v.verify(tokenString); err != nil {
+		return nil, err
+	}
 
 	// Parse the token
 	claims, err := ParseJWT(tokenString)
@@ -65,30 +126,70 @@ func ValidateToken(tokenString string) (*UserSession, error) {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
 
-	// Check expiration (if available)
-	if claims.ExpiresAt > 0 {
-		expiresAt := time.Unix(claims.ExpiresAt, 0)
-		if time.Now().After(expiresAt) {
-			return nil, fmt.Errorf("token expired")
-		}
+	address, err := NormalizeAddress(claims.Address)
+	if err != nil {
+		return nil, fmt.Errorf("token address: %w", err)
+	}
 
-		return &UserSession{
-			Address:    claims.Address,
-			SessionKey: claims.SessionKey,
-			JWTToken:   tokenString,
-			ExpiresAt:  expiresAt,
-		}, nil
+	expiresAt := claims.Expiry()
+	if expiresAt.IsZero() {
+		return nil, fmt.Errorf("token has no expiry")
+	}
+	if !time.Now().Before(expiresAt) {
+		return nil, fmt.Errorf("token expired")
 	}
 
-	// If no expiration info, create session without expiry check
 	return &UserSession{
-		Address:    claims.Address,
+		Address:    address,
 		SessionKey: claims.SessionKey,
 		JWTToken:   tokenString,
-		ExpiresAt:  time.Now().Add(1 * time.Hour),
+		ExpiresAt:  expiresAt,
 	}, nil
 }
 
+// verify checks the signature over header.payload against the key. The
+// header's alg must match the key type, so "none" or HMAC tokens are refused.
+func (v *TokenVerifier) verify(tokenString string) error {
+	parts := strings.Split(tokenString, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("invalid JWT format")
+	}
+	rawHeader, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("invalid JWT header: %w", err)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(rawHeader, &header); err != nil {
+		return fmt.Errorf("invalid JWT header: %w", err)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return ErrTokenSignature
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+
+	switch key := v.key.(type) {
+	case *ecdsa.PublicKey:
+		if header.Alg != "ES256" || len(sig) != 64 {
+			return ErrTokenSignature
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(key, digest[:], r, s) {
+			return ErrTokenSignature
+		}
+	case *rsa.PublicKey:
+		if header.Alg != "RS256" || rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return ErrTokenSignature
+		}
+	default:
+		return ErrTokenSignature
+	}
+	return nil
+}
+
 // YellowAuthMessage represents the WebSocket auth message from frontend
 type YellowAuthMessage struct {
 	Type       string `json:"type"`
@@ -0,0 +1,173 @@
+package yellow
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testAddress = "0x1111111111111111111111111111111111111111"
+
+func newTestVerifier(t *testing.T) (*TokenVerifier, *ecdsa.PrivateKey) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, err := NewTokenVerifier(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v, key
+}
+
+func signTestToken(t *testing.T, key *ecdsa.PrivateKey, alg string, claims map[string]interface{}) string {
+	t.Helper()
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"` + alg + `","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig := make([]byte, 64)
+	r.FillBytes(sig[:32])
+	s.FillBytes(sig[32:])
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func TestTokenVerifierAcceptsSignedToken(t *testing.T) {
+	v, key := newTestVerifier(t)
+	token := signTestToken(t, key, "ES256", map[string]interface{}{
+		"address":    strings.ToLower(testAddress),
+		"expires_at": time.Now().Add(time.Hour).Unix(),
+	})
+
+	session, err := v.Validate(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if session.Address != testAddress {
+		t.Fatalf("address = %s, want %s", session.Address, testAddress)
+	}
+}
+
+func TestTokenVerifierRejectsForgedTokens(t *testing.T) {
+	v, key := newTestVerifier(t)
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claims := map[string]interface{}{
+		"address":    testAddress,
+		"expires_at": time.Now().Add(time.Hour).Unix(),
+	}
+	good := signTestToken(t, key, "ES256", claims)
+	parts := strings.Split(good, ".")
+	tampered, _ := json.Marshal(map[string]interface{}{
+		"address":    "0x2222222222222222222222222222222222222222",
+		"expires_at": claims["expires_at"],
+	})
+
+	for name, token := range map[string]string{
+		"other key":        signTestToken(t, other, "ES256", claims),
+		"alg none":         base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." + parts[1] + ".",
+		"wrong alg":        signTestToken(t, key, "HS256", claims),
+		"tampered payload": parts[0] + "." + base64.RawURLEncoding.EncodeToString(tampered) + "." + parts[2],
+	} {
+		if _, err := v.Validate(token); err != ErrTokenSignature {
+			t.Errorf("%s: err = %v, want %v", name, err, ErrTokenSignature)
+		}
+	}
+}
+
+func TestTokenVerifierRejectsExpiredToken(t *testing.T) {
+	v, key := newTestVerifier(t)
+	token := signTestToken(t, key, "ES256", map[string]interface{}{
+		"address":    testAddress,
+		"expires_at": time.Now().Add(-time.Minute).Unix(),
+	})
+	if _, err := v.Validate(token); err == nil {
+		t.Fatal("expired token accepted")
+	}
+}
+
+func TestNilTokenVerifierRejectsEveryToken(t *testing.T) {
+	_, key := newTestVerifier(t)
+	var v *TokenVerifier
+	token := signTestToken(t, key, "ES256", map[string]interface{}{
+		"address":    testAddress,
+		"expires_at": time.Now().Add(time.Hour).Unix(),
+	})
+	if _, err := v.Validate(token); err != ErrTokenVerifyDisabled {
+		t.Fatalf("err = %v, want %v", err, ErrTokenVerifyDisabled)
+	}
+}
+
+func TestParseJWTClaims(t *testing.T) {
+	_, key := newTestVerifier(t)
+	expiresAt := time.Now().Add(time.Hour).Unix()
+	token := signTestToken(t, key, "ES256", map[string]interface{}{
+		"address":     testAddress,
+		"session_key": "0x2222222222222222222222222222222222222222",
+		"expires_at":  expiresAt,
+		"scope":       "app.orderbook",
+	})
+	claims, err := ParseJWT(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := JWTClaims{Address: testAddress, SessionKey: "0x2222222222222222222222222222222222222222", ExpiresAt: expiresAt, Scope: "app.orderbook"}
+	if *claims != want {
+		t.Fatalf("claims = %+v, want %+v", *claims, want)
+	}
+
+	// The registered exp claim stands in for a missing expires_at
+	claims, err = ParseJWT(signTestToken(t, key, "ES256", map[string]interface{}{"exp": expiresAt}))
+	if err != nil || !claims.Expiry().Equal(time.Unix(expiresAt, 0)) {
+		t.Fatalf("exp only: expiry %v (%v), want %v", claims.Expiry(), err, time.Unix(expiresAt, 0))
+	}
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"ES256"}`))
+	for name, bad := range map[string]string{
+		"two parts":  header + ".e30",
+		"not base64": header + ".!!!.sig",
+		"not JSON":   header + "." + base64.RawURLEncoding.EncodeToString([]byte("address")) + ".sig",
+		"wrong type": header + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"expires_at":"soon"}`)) + ".sig",
+		"empty":      "",
+		"no payload": header + "..sig",
+	} {
+		if _, err := ParseJWT(bad); err == nil {
+			t.Errorf("%s: parsed", name)
+		}
+	}
+}
+
+func TestTokenVerifierRequiresAddressAndExpiry(t *testing.T) {
+	v, key := newTestVerifier(t)
+	for name, claims := range map[string]map[string]interface{}{
+		"no expiry":   {"address": testAddress},
+		"no address":  {"expires_at": time.Now().Add(time.Hour).Unix()},
+		"bad address": {"address": "alice", "expires_at": time.Now().Add(time.Hour).Unix()},
+	} {
+		if _, err := v.Validate(signTestToken(t, key, "ES256", claims)); err == nil {
+			t.Errorf("%s: accepted", name)
+		}
+	}
+}
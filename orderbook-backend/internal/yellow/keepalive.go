@@ -0,0 +1,93 @@
+package yellow
+
+import (
+	"context"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// Keepalive defaults
+const (
+	DefaultPingInterval    = 30 * time.Second
+	DefaultMaxPingFailures = 3
+)
+
+// SetPingInterval sets how often an idle-proof ping is sent on connections
+// opened afterwards (default 30s, 0 disables keepalive). A connection that
+// receives nothing, not even a pong, for two intervals is treated as dead.
+func (c *Client) SetPingInterval(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.pingInterval = d
+}
+
+// SetMaxPingFailures sets how many consecutive failed pings drop the
+// connection (default 3)
+func (c *Client) SetMaxPingFailures(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxPingFailures = n
+}
+
+// startKeepalive arms the read deadline on a new connection and starts its
+// ping loop. It returns how long the connection may stay silent, 0 if
+// keepalive is off (must hold lock).
+func (c *Client) startKeepalive(conn *websocket.Conn) time.Duration {
+	interval := c.pingInterval
+	if interval <= 0 {
+		return 0
+	}
+
+	// Any frame from the server, pongs included, proves the link is alive
+	deadline := 2 * interval
+	conn.SetReadDeadline(time.Now().Add(deadline))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(deadline))
+	})
+
+	go c.keepalive(conn, interval, max(c.maxPingFailures, 1))
+	return deadline
+}
+
+// keepalive pings the ClearNode every interval while conn is the client's
+// connection, closing it after maxFailures pings in a row fail
+func (c *Client) keepalive(conn *websocket.Conn, interval time.Duration, maxFailures int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+
+		c.mu.Lock()
+		if c.conn != conn {
+			c.mu.Unlock()
+			return
+		}
+		err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(interval))
+		c.mu.Unlock()
+
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), interval)
+			err = c.Ping(ctx)
+			cancel()
+		}
+		if err == nil {
+			failures = 0
+			continue
+		}
+
+		failures++
+		c.log().Warn("yellow: keepalive ping failed", "failures", failures, "max_failures", maxFailures, "error", err)
+		if failures >= maxFailures {
+			// The read loop sees the closed connection and handles the disconnect
+			conn.Close()
+			return
+		}
+	}
+}
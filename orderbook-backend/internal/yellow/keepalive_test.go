@@ -0,0 +1,65 @@
+package yellow
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestKeepaliveDropsStalledConnection(t *testing.T) {
+	// Unanswered pings: the server reads (and pongs) but never replies
+	unanswered := newMockClearNode(t, func(req *Request) *Response { return nil }).url()
+
+	// Dead link: the server accepts and then never reads, so no pong comes back
+	release := make(chan struct{})
+	silent := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upgrader := websocket.Upgrader{}
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		<-release
+	}))
+	t.Cleanup(func() {
+		close(release)
+		silent.Close()
+	})
+
+	for name, url := range map[string]string{
+		"unanswered pings": unanswered,
+		"no pong":          "ws" + strings.TrimPrefix(silent.URL, "http"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			c := NewClient(url, nil)
+			c.SetPingInterval(50 * time.Millisecond)
+			c.SetMaxPingFailures(2)
+			c.SetReconnect(false)
+			lost := make(chan error, 1)
+			c.SetErrorHandler(func(err error) {
+				select {
+				case lost <- err:
+				default:
+				}
+			})
+			if err := c.Connect(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { c.Close() })
+
+			select {
+			case <-lost:
+			case <-time.After(2 * time.Second):
+				t.Fatal("stalled connection was never dropped")
+			}
+			if err := c.Ping(context.Background()); err != ErrNotConnected {
+				t.Fatalf("ping after the drop: err = %v, want %v", err, ErrNotConnected)
+			}
+		})
+	}
+}
@@ -2,6 +2,8 @@ package yellow
 
 import (
 	"encoding/json"
+	"fmt"
+	"sync/atomic"
 )
 
 // JSON-RPC 2.0 request/response structures for ERC-7824
@@ -29,6 +31,43 @@ type RPCError struct {
 	Data    json.RawMessage `json:"data,omitempty"`
 }
 
+// Error formats the error including the ClearNode's detail payload, which
+// usually carries the actual rejection reason
+func (e *RPCError) Error() string {
+	reason := e.Reason()
+	if reason == "" {
+		return fmt.Sprintf("%s (code %d)", e.Message, e.Code)
+	}
+	return fmt.Sprintf("%s (code %d): %s", e.Message, e.Code, reason)
+}
+
+// HasData reports whether the error carries a detail payload
+func (e *RPCError) HasData() bool {
+	return len(e.Data) != 0 && string(e.Data) != "null"
+}
+
+// Reason returns the detail payload as text: a string payload unquoted,
+// anything else as raw JSON, or "" if there is none
+func (e *RPCError) Reason() string {
+	if !e.HasData() {
+		return ""
+	}
+	var reason string
+	if err := json.Unmarshal(e.Data, &reason); err != nil {
+		return string(e.Data) // Structured detail: show it verbatim
+	}
+	return reason
+}
+
+// DecodeData parses the detail payload into v, e.g. a struct describing
+// which allocation was rejected
+func (e *RPCError) DecodeData(v interface{}) error {
+	if !e.HasData() {
+		return fmt.Errorf("rpc error %d has no data", e.Code)
+	}
+	return json.Unmarshal(e.Data, v)
+}
+
 // --- Method-specific params and results ---
 
 // PingParams for the ping method
@@ -137,11 +176,13 @@ type StateUpdate struct {
 
 // --- Message builders ---
 
+// requestID numbers requests across all clients; it is only advanced
+// atomically, so concurrent callers never share an ID
 var requestID int64
 
 // NewRequest creates a new JSON-RPC request
 func NewRequest(method string, params interface{}) (*Request, error) {
-	requestID++
+	id := atomic.AddInt64(&requestID, 1)
 
 	paramsBytes, err := json.Marshal(params)
 	if err != nil {
@@ -150,7 +191,7 @@ func NewRequest(method string, params interface{}) (*Request, error) {
 
 	return &Request{
 		JSONRPC: "2.0",
-		ID:      requestID,
+		ID:      id,
 		Method:  method,
 		Params:  paramsBytes,
 	}, nil
@@ -181,7 +222,7 @@ func NewCreateAppSession(def AppDefinition, allocs []Allocation) (*Request, erro
 
 // NewCloseAppSession creates a close session request
 func NewCloseAppSession(channelID string, allocs []Allocation) (*Request, error) {
-	return NewRequest("close_app_session", CloseAppSessionParams{
+	return NewRequest(MethodCloseAppSession, CloseAppSessionParams{
 		ChannelID:   channelID,
 		Allocations: allocs,
 	})
@@ -189,7 +230,7 @@ func NewCloseAppSession(channelID string, allocs []Allocation) (*Request, error)
 
 // NewAppSessionMessage creates a state update message
 func NewAppSessionMessage(channelID string, state StateUpdate, sig string) (*Request, error) {
-	return NewRequest("app_session_message", AppSessionMessageParams{
+	return NewRequest(MethodAppSessionMessage, AppSessionMessageParams{
 		ChannelID: channelID,
 		StateData: state,
 		Signature: sig,
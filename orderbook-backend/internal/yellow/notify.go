@@ -0,0 +1,54 @@
+package yellow
+
+import (
+	"encoding/json"
+)
+
+// Methods the ClearNode pushes to participants without being asked
+const (
+	MethodAppSessionMessage = "app_session_message" // A counterparty's signed state update
+	MethodCloseAppSession   = "close_app_session"   // An app session was closed
+)
+
+// Notification is a server-initiated JSON-RPC message: it carries a method
+// and params but no ID, so it answers no pending request
+type Notification struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// ParseNotification parses a frame as a notification. ok is false for
+// frames without a method, such as responses.
+func ParseNotification(data []byte) (n *Notification, ok bool) {
+	var msg Notification
+	if err := json.Unmarshal(data, &msg); err != nil || msg.Method == "" {
+		return nil, false
+	}
+	return &msg, true
+}
+
+// OnNotification sets the handler for notifications of one method,
+// replacing any previous one. Handlers run on the read loop, so they
+// must not block on requests to the ClearNode.
+func (c *Client) OnNotification(method string, fn func(params json.RawMessage)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.notifyHandlers == nil {
+		c.notifyHandlers = make(map[string]func(json.RawMessage))
+	}
+	c.notifyHandlers[method] = fn
+}
+
+// dispatchNotification passes a notification to its method's handler and
+// reports whether there was one
+func (c *Client) dispatchNotification(n *Notification) bool {
+	c.mu.RLock()
+	fn, ok := c.notifyHandlers[n.Method]
+	c.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	fn(n.Params)
+	return true
+}
@@ -0,0 +1,100 @@
+package yellow
+
+import (
+	"context"
+	"time"
+)
+
+// Auth lifetime defaults
+const (
+	DefaultAuthTTL           = time.Hour
+	DefaultAuthRefreshMargin = 5 * time.Minute
+	authRetryInterval        = 30 * time.Second // After a failed refresh, while the old token lasts
+)
+
+// SetAuthTTL sets the session lifetime requested when authenticating
+// (default 1h)
+func (c *Client) SetAuthTTL(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.authTTL = d
+}
+
+// SetAuthRefreshMargin sets how long before the auth session expires it is
+// renewed (default 5m). A margin longer than the session refreshes halfway.
+func (c *Client) SetAuthRefreshMargin(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshMargin = d
+}
+
+// scheduleRefresh arms the timer that re-authenticates ahead of expiresAt,
+// replacing any earlier one (must hold lock)
+func (c *Client) scheduleRefresh(expiresAt time.Time) {
+	c.stopRefresh()
+	c.authExpiresAt = expiresAt
+
+	remaining := time.Until(expiresAt)
+	delay := remaining - c.refreshMargin
+	if delay < remaining/2 {
+		delay = remaining / 2
+	}
+	c.refreshTimer = time.AfterFunc(delay, c.refreshAuth)
+}
+
+// stopRefresh cancels a scheduled refresh (must hold lock)
+func (c *Client) stopRefresh() {
+	if c.refreshTimer != nil {
+		c.refreshTimer.Stop()
+		c.refreshTimer = nil
+	}
+}
+
+// refreshAuth runs the auth flow again on the current connection. The old
+// token stays in use until the new one is issued, so IsAuthenticated stays
+// true throughout. While a reconnect is under way it does nothing: the
+// reconnect authenticates afresh and schedules the next refresh itself.
+func (c *Client) refreshAuth() {
+	c.mu.RLock()
+	skip := c.closed || c.reconnecting || c.conn == nil || !c.authenticated
+	c.mu.RUnlock()
+	if skip || !c.authMu.TryLock() {
+		return
+	}
+	defer c.authMu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+	defer cancel()
+	err := c.authenticate(ctx)
+	if err == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed || c.reconnecting {
+		return
+	}
+	remaining := time.Until(c.authExpiresAt)
+	if remaining <= 0 {
+		c.authenticated = false
+		c.logger.Error("yellow: auth refresh failed and the session expired", "error", err)
+		return
+	}
+	c.logger.Warn("yellow: auth refresh failed, retrying", "error", err, "expires_in", remaining.String())
+	c.refreshTimer = time.AfterFunc(min(authRetryInterval, remaining/2), c.refreshAuth)
+}
+
+// authExpiry returns when an auth session ends: the expiry the ClearNode
+// reported, else the token's, else the one requested
+func authExpiry(result AuthVerifyResult, requested int64) time.Time {
+	if result.ExpiresAt > 0 {
+		return time.Unix(result.ExpiresAt, 0)
+	}
+	if claims, err := ParseJWT(result.JWTToken); err == nil {
+		if expiry := claims.Expiry(); !expiry.IsZero() {
+			return expiry
+		}
+	}
+	return time.Unix(requested, 0)
+}
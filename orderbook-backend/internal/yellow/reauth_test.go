@@ -0,0 +1,60 @@
+package yellow
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestAuthRefreshesBeforeExpiry(t *testing.T) {
+	var issued atomic.Int32
+	node := newMockClearNode(t, func(req *Request) *Response {
+		switch req.Method {
+		case "auth_request":
+			return okResult(AuthRequestResult{ChallengeMessage: "challenge"})
+		case "auth_verify":
+			// Each session lasts about 2s, so it is renewed within a second
+			return okResult(AuthVerifyResult{
+				SessionKey: "0xsession",
+				JWTToken:   fmt.Sprintf("jwt-%d", issued.Add(1)),
+				ExpiresAt:  time.Now().Add(2 * time.Second).Unix(),
+			})
+		}
+		return okResult("pong")
+	})
+	c := NewClient(node.url(), newTestSigner(t))
+	c.SetPingInterval(0)
+	c.SetReconnect(false)
+	c.SetAuthRefreshMargin(time.Second)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	if err := c.Authenticate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// The session is renewed twice, and never lapses in between
+	deadline := time.Now().Add(4 * time.Second)
+	for issued.Load() < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("%d auth sessions issued, want the first and two renewals", issued.Load())
+		}
+		if !c.IsAuthenticated() {
+			t.Fatal("client unauthenticated while renewing")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if !eventually(t, time.Second, func() bool {
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.jwtToken == "jwt-3"
+	}) {
+		t.Fatal("client did not switch to the renewed token")
+	}
+	if !c.IsAuthenticated() {
+		t.Fatal("client unauthenticated after renewing")
+	}
+}
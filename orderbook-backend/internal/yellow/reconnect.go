@@ -0,0 +1,132 @@
+package yellow
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+var ErrConnectionLost = errors.New("connection to ClearNode lost")
+
+// Reconnect backoff: doubles from the minimum up to the cap, and each wait
+// is jittered to between half and all of the current backoff
+const (
+	reconnectMinBackoff = 500 * time.Millisecond
+	reconnectMaxBackoff = 30 * time.Second
+	reconnectTimeout    = 30 * time.Second // Per attempt, dial and auth
+)
+
+// SetReconnect turns automatic reconnection after an unexpected disconnect
+// on or off (on by default)
+func (c *Client) SetReconnect(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.reconnect = enabled
+}
+
+// handleDisconnect cleans up after conn stopped reading. Requests still
+// waiting for a response fail with ErrConnectionLost, and unless the client
+// was closed a reconnect loop is started.
+func (c *Client) handleDisconnect(conn *websocket.Conn, err error) {
+	c.mu.Lock()
+	if c.conn == conn {
+		c.conn = nil
+	}
+	reauth := c.authenticated
+	c.authenticated = false
+	c.stopRefresh() // Re-authenticating after the reconnect schedules a new one
+	closed := c.closed
+	start := c.reconnect && !closed && !c.reconnecting
+	if start {
+		c.reconnecting = true
+	}
+	c.mu.Unlock()
+
+	conn.Close()
+	c.failPending()
+
+	if closed {
+		return
+	}
+	if !websocket.IsCloseError(err, websocket.CloseNormalClosure) && c.onError != nil {
+		c.onError(err)
+	}
+	if start {
+		c.log().Warn("yellow: connection lost, reconnecting", "error", err)
+		go c.reconnectLoop(reauth)
+	}
+}
+
+// failPending wakes every request waiting for a response with no response,
+// which SendRequest reports as ErrConnectionLost
+func (c *Client) failPending() {
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	for _, ch := range c.pending {
+		select {
+		case ch <- nil:
+		default:
+		}
+	}
+}
+
+// reconnectLoop re-dials the ClearNode with exponential backoff until it
+// succeeds or the client is closed, re-authenticating if the lost
+// connection was authenticated
+func (c *Client) reconnectLoop(reauth bool) {
+	defer func() {
+		c.mu.Lock()
+		c.reconnecting = false
+		c.mu.Unlock()
+	}()
+
+	backoff := reconnectMinBackoff
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-c.done:
+			return
+		case <-time.After(jitter(backoff)):
+		}
+
+		err := c.redial(reauth)
+		if err == nil {
+			c.log().Info("yellow: reconnected", "attempts", attempt)
+			return
+		}
+		c.log().Warn("yellow: reconnect attempt failed", "attempt", attempt, "error", err)
+		backoff = min(backoff*2, reconnectMaxBackoff)
+	}
+}
+
+// redial connects and, if reauth is set, authenticates. A connection that
+// fails to authenticate is dropped again.
+func (c *Client) redial(reauth bool) error {
+	ctx, cancel := context.WithTimeout(context.Background(), reconnectTimeout)
+	defer cancel()
+
+	if err := c.Connect(ctx); err != nil {
+		return err
+	}
+	if !reauth {
+		return nil
+	}
+	if err := c.Authenticate(ctx); err != nil {
+		c.mu.Lock()
+		if c.conn != nil {
+			c.conn.Close()
+			c.conn = nil
+		}
+		c.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// jitter returns a random wait between half of d and d
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + rand.N(d-half+1)
+}
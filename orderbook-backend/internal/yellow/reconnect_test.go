@@ -0,0 +1,96 @@
+package yellow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// eventually polls cond until it holds or the timeout passes
+func eventually(t *testing.T, timeout time.Duration, cond func() bool) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return true
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return cond()
+}
+
+// hangingNode answers pings and leaves every other request unanswered
+func hangingNode(t *testing.T) *mockClearNode {
+	return newMockClearNode(t, func(req *Request) *Response {
+		if req.Method == "ping" {
+			return okResult("pong")
+		}
+		return nil
+	})
+}
+
+func TestReconnectAfterDrop(t *testing.T) {
+	node := hangingNode(t)
+	c := NewClient(node.url(), nil)
+	c.SetPingInterval(0)
+	if err := c.Connect(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+
+	req, err := NewRequest("hang", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(chan error, 1)
+	go func() {
+		_, err := c.SendRequest(context.Background(), req)
+		result <- err
+	}()
+	if !eventually(t, time.Second, func() bool { return len(node.received("hang")) == 1 }) {
+		t.Fatal("request never reached the server")
+	}
+
+	// The in-flight request fails, and the client dials back in
+	node.drop()
+	select {
+	case err := <-result:
+		if !errors.Is(err, ErrConnectionLost) {
+			t.Fatalf("in-flight request: err = %v, want %v", err, ErrConnectionLost)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("in-flight request still waiting after the drop")
+	}
+	if !eventually(t, 5*time.Second, func() bool { return node.connections() == 2 }) {
+		t.Fatalf("%d connections, want a reconnect", node.connections())
+	}
+	if !eventually(t, time.Second, func() bool { return c.Ping(context.Background()) == nil }) {
+		t.Fatal("ping fails after reconnecting")
+	}
+}
+
+func TestNoReconnectWhenDisabled(t *testing.T) {
+	node := hangingNode(t)
+	c := newMockClient(t, node)
+
+	node.drop()
+	if !eventually(t, time.Second, func() bool { return errors.Is(c.Ping(context.Background()), ErrNotConnected) }) {
+		t.Fatal("client still connected after the drop")
+	}
+	// Past the longest first backoff
+	time.Sleep(reconnectMinBackoff + 100*time.Millisecond)
+	if n := node.connections(); n != 1 {
+		t.Fatalf("%d connections with reconnect off, want 1", n)
+	}
+}
+
+func TestJitterStaysWithinBackoff(t *testing.T) {
+	for _, d := range []time.Duration{reconnectMinBackoff, time.Second, reconnectMaxBackoff} {
+		for i := 0; i < 100; i++ {
+			if got := jitter(d); got < d/2 || got > d {
+				t.Fatalf("jitter(%s) = %s, want between %s and %s", d, got, d/2, d)
+			}
+		}
+	}
+}
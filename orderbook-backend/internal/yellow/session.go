@@ -2,11 +2,41 @@ package yellow
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/binary"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
 )
 
+// ErrInvalidStateSignature is returned for a state update that no
+// participant of the session signed
+var ErrInvalidStateSignature = errors.New("state update not signed by a session participant")
+
+// ErrStaleState is returned for a state update whose version is not newer
+// than the session's last accepted state
+var ErrStaleState = errors.New("stale state update")
+
+// CloseOptions controls how a cooperative close is retried. Transport
+// failures (timeouts, dropped sends) are retried with exponential backoff;
+// an error result from the ClearNode is final.
+type CloseOptions struct {
+	Timeout time.Duration // Per-attempt timeout
+	Retries int           // Extra attempts after the first
+	Backoff time.Duration // Delay before the first retry, doubled each time
+}
+
+// DefaultCloseOptions are used unless SetCloseOptions is called
+var DefaultCloseOptions = CloseOptions{
+	Timeout: 10 * time.Second,
+	Retries: 2,
+	Backoff: 500 * time.Millisecond,
+}
+
 // Session manages an app session lifecycle with Yellow Network
 type Session struct {
 	mu          sync.RWMutex
@@ -15,7 +45,13 @@ type Session struct {
 	channelID   string
 	version     uint64
 	allocations []Allocation
+	appData     string // App data of the last accepted state
+	signature   string // Signature over the last accepted state (ours, or a participant's)
 	active      bool
+	closeOpts   CloseOptions
+
+	// Participants: the operator, then traders as they join
+	members []string
 }
 
 // SessionManager manages multiple sessions
@@ -24,15 +60,45 @@ type SessionManager struct {
 	client   *Client
 	signer   *Signer
 	sessions map[string]*Session
+
+	// One session per market, keyed by market ID. openMu serializes opening
+	// market sessions, so concurrent first trades can't open two channels.
+	markets map[string]string
+	openMu  sync.Mutex
+
+	closeOpts CloseOptions
 }
 
 // NewSessionManager creates a new session manager
 func NewSessionManager(client *Client, signer *Signer) *SessionManager {
-	return &SessionManager{
-		client:   client,
-		signer:   signer,
-		sessions: make(map[string]*Session),
+	m := &SessionManager{
+		client:    client,
+		signer:    signer,
+		sessions:  make(map[string]*Session),
+		markets:   make(map[string]string),
+		closeOpts: DefaultCloseOptions,
+	}
+	if client != nil {
+		client.OnNotification(MethodAppSessionMessage, m.notified(m.HandleStateUpdate))
+		client.OnNotification(MethodCloseAppSession, m.notified(m.HandleSessionClosed))
 	}
+	return m
+}
+
+// notified adapts a notification handler, logging the updates it rejects
+func (m *SessionManager) notified(handle func(json.RawMessage) error) func(json.RawMessage) {
+	return func(params json.RawMessage) {
+		if err := handle(params); err != nil {
+			m.client.log().Warn("yellow: ignored session notification", "error", err)
+		}
+	}
+}
+
+// SetCloseOptions sets the timeout/retry policy for sessions created afterwards
+func (m *SessionManager) SetCloseOptions(opts CloseOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closeOpts = opts
 }
 
 // CreateSession creates a new app session
@@ -46,6 +112,16 @@ func (m *SessionManager) CreateSession(
 		return nil, fmt.Errorf("client not authenticated")
 	}
 
+	// Malformed addresses would otherwise be silently zero-padded or truncated
+	participants, err := NormalizeAddresses(participants)
+	if err != nil {
+		return nil, fmt.Errorf("invalid participant: %w", err)
+	}
+	allocations, err = NormalizeAllocations(allocations)
+	if err != nil {
+		return nil, err
+	}
+
 	// Build app definition
 	weights := make([]int, len(participants))
 	for i := range weights {
@@ -72,7 +148,7 @@ func (m *SessionManager) CreateSession(
 	}
 
 	if resp.Error != nil {
-		return nil, fmt.Errorf("create session error: %s", resp.Error.Message)
+		return nil, fmt.Errorf("create session error: %w", resp.Error)
 	}
 
 	var result CreateAppSessionResult
@@ -80,22 +156,71 @@ func (m *SessionManager) CreateSession(
 		return nil, fmt.Errorf("failed to parse result: %w", err)
 	}
 
+	m.mu.Lock()
 	session := &Session{
 		client:      m.client,
 		signer:      m.signer,
 		channelID:   result.ChannelID,
 		version:     0,
 		allocations: allocations,
+		members:     participants,
 		active:      true,
+		closeOpts:   m.closeOpts,
 	}
-
-	m.mu.Lock()
 	m.sessions[result.ChannelID] = session
 	m.mu.Unlock()
 
 	return session, nil
 }
 
+// OpenMarketSession creates the app session for a market. Its participants
+// are the operator plus the given traders; later traders are added with
+// Session.Join rather than by creating a new session. If the market already
+// has a session it is returned unchanged.
+func (m *SessionManager) OpenMarketSession(
+	ctx context.Context,
+	marketID string,
+	traders []string,
+	allocations []Allocation,
+	adjudicatorAddr string,
+) (*Session, error) {
+	m.openMu.Lock()
+	defer m.openMu.Unlock()
+
+	if session, ok := m.MarketSession(marketID); ok {
+		return session, nil
+	}
+
+	participants := append([]string{m.signer.AddressHex()}, traders...)
+	participants, err := NormalizeAddresses(participants)
+	if err != nil {
+		return nil, fmt.Errorf("invalid participant: %w", err)
+	}
+	participants = dedupe(participants)
+
+	session, err := m.CreateSession(ctx, participants, allocations, adjudicatorAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.markets[marketID] = session.GetChannelID()
+	m.mu.Unlock()
+	return session, nil
+}
+
+// MarketSession returns the app session of a market
+func (m *SessionManager) MarketSession(marketID string) (*Session, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	channelID, ok := m.markets[marketID]
+	if !ok {
+		return nil, false
+	}
+	session, ok := m.sessions[channelID]
+	return session, ok
+}
+
 // GetSession returns a session by channel ID
 func (m *SessionManager) GetSession(channelID string) (*Session, bool) {
 	m.mu.RLock()
@@ -104,22 +229,97 @@ func (m *SessionManager) GetSession(channelID string) (*Session, bool) {
 	return session, ok
 }
 
-// CloseSession closes an app session
+// SessionMarket returns the market a session was opened for, if any
+func (m *SessionManager) SessionMarket(channelID string) (string, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for marketID, id := range m.markets {
+		if id == channelID {
+			return marketID, true
+		}
+	}
+	return "", false
+}
+
+// CloseSession closes an app session on its last accepted state
 func (m *SessionManager) CloseSession(ctx context.Context, channelID string) error {
+	return m.CloseSessionWithAllocations(ctx, channelID, nil)
+}
+
+// CloseSessionWithAllocations closes an app session with the given final
+// allocations, or on its last accepted state if allocations is nil
+func (m *SessionManager) CloseSessionWithAllocations(ctx context.Context, channelID string, allocations []Allocation) error {
+	session, ok := m.remove(channelID)
+	if !ok {
+		return fmt.Errorf("session not found: %s", channelID)
+	}
+
+	if allocations == nil {
+		return session.Close(ctx)
+	}
+	return session.CloseWithAllocations(ctx, allocations)
+}
+
+// HandleStateUpdate applies the params of an app_session_message
+// notification, a counterparty's signed state, to its session
+func (m *SessionManager) HandleStateUpdate(params json.RawMessage) error {
+	var msg AppSessionMessageParams
+	if err := json.Unmarshal(params, &msg); err != nil {
+		return fmt.Errorf("invalid state update: %w", err)
+	}
+
+	session, ok := m.GetSession(msg.ChannelID)
+	if !ok {
+		return fmt.Errorf("session not found: %s", msg.ChannelID)
+	}
+	return session.ApplyState(msg.StateData, msg.Signature)
+}
+
+// HandleSessionClosed applies the params of a close_app_session
+// notification: the session is closed on the given final allocations and
+// forgotten, as if CloseSession had been called
+func (m *SessionManager) HandleSessionClosed(params json.RawMessage) error {
+	var msg CloseAppSessionParams
+	if err := json.Unmarshal(params, &msg); err != nil {
+		return fmt.Errorf("invalid session close: %w", err)
+	}
+	allocations, err := NormalizeAllocations(msg.Allocations)
+	if err != nil {
+		return err
+	}
+
+	session, ok := m.remove(msg.ChannelID)
+	if !ok {
+		return fmt.Errorf("session not found: %s", msg.ChannelID)
+	}
+	session.closed(allocations)
+	return nil
+}
+
+// remove forgets a session and its market, returning the session
+func (m *SessionManager) remove(channelID string) (*Session, bool) {
 	m.mu.Lock()
+	defer m.mu.Unlock()
 	session, ok := m.sessions[channelID]
 	if !ok {
-		m.mu.Unlock()
-		return fmt.Errorf("session not found: %s", channelID)
+		return nil, false
 	}
 	delete(m.sessions, channelID)
-	m.mu.Unlock()
-
-	return session.Close(ctx)
+	for marketID, id := range m.markets {
+		if id == channelID {
+			delete(m.markets, marketID)
+		}
+	}
+	return session, true
 }
 
 // UpdateState updates the session state with new allocations
 func (s *Session) UpdateState(ctx context.Context, allocations []Allocation, appData string) error {
+	allocations, err := NormalizeAllocations(allocations)
+	if err != nil {
+		return err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -127,6 +327,14 @@ func (s *Session) UpdateState(ctx context.Context, allocations []Allocation, app
 		return fmt.Errorf("session is not active")
 	}
 
+	if s.signer == nil {
+		return fmt.Errorf("session has no signer")
+	}
+	channelID, err := ParseChannelID(s.channelID)
+	if err != nil {
+		return err
+	}
+
 	s.version++
 
 	state := StateUpdate{
@@ -135,19 +343,11 @@ func (s *Session) UpdateState(ctx context.Context, allocations []Allocation, app
 		AppData:     appData,
 	}
 
-	// Sign the state using the signer
-	var sig string
-	if s.signer != nil {
-		// Convert channel ID to bytes32
-		var channelIDBytes [32]byte
-		copy(channelIDBytes[:], []byte(s.channelID))
-
-		var err error
-		sig, err = s.signer.SignStateHashHex(channelIDBytes, s.version, allocations)
-		if err != nil {
-			s.version--
-			return fmt.Errorf("failed to sign state: %w", err)
-		}
+	// Sign the new state over the channel ID, version and allocations
+	sig, err := s.signer.SignStateHashHex(channelID, s.version, allocations)
+	if err != nil {
+		s.version--
+		return fmt.Errorf("failed to sign state: %w", err)
 	}
 
 	req, err := NewAppSessionMessage(s.channelID, state, sig)
@@ -164,10 +364,57 @@ func (s *Session) UpdateState(ctx context.Context, allocations []Allocation, app
 
 	if resp.Error != nil {
 		s.version--
-		return fmt.Errorf("update state error: %s", resp.Error.Message)
+		return fmt.Errorf("update state error: %w", resp.Error)
+	}
+
+	s.allocations = allocations
+	s.appData = appData
+	s.signature = sig
+	return nil
+}
+
+// ApplyState accepts a state update sent by a counterparty. Its version must
+// be newer than the last accepted state's, and the signature must recover to
+// one of the session's participants.
+func (s *Session) ApplyState(update StateUpdate, sig string) error {
+	allocations, err := NormalizeAllocations(update.Allocations)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.active {
+		return fmt.Errorf("session is not active")
+	}
+	if update.Version <= s.version {
+		return ErrStaleState
+	}
+	channelID, err := ParseChannelID(s.channelID)
+	if err != nil {
+		return err
+	}
+
+	signed := false
+	for _, member := range s.members {
+		ok, err := VerifyStateSignature(channelID, update.Version, allocations, sig, common.HexToAddress(member))
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidStateSignature, err)
+		}
+		if ok {
+			signed = true
+			break
+		}
+	}
+	if !signed {
+		return ErrInvalidStateSignature
 	}
 
+	s.version = update.Version
 	s.allocations = allocations
+	s.appData = update.AppData
+	s.signature = sig
 	return nil
 }
 
@@ -176,28 +423,85 @@ func (s *Session) Close(ctx context.Context) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.close(ctx, s.allocations)
+}
+
+// CloseWithAllocations closes the session with final allocations that
+// differ from its last state, e.g. once positions have been settled
+func (s *Session) CloseWithAllocations(ctx context.Context, allocations []Allocation) error {
+	allocations, err := NormalizeAllocations(allocations)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.close(ctx, allocations)
+}
+
+// close sends the close request and records the final allocations (must hold lock)
+func (s *Session) close(ctx context.Context, allocations []Allocation) error {
 	if !s.active {
 		return nil
 	}
 
-	req, err := NewCloseAppSession(s.channelID, s.allocations)
+	req, err := NewCloseAppSession(s.channelID, allocations)
 	if err != nil {
 		return err
 	}
 
-	resp, err := s.client.SendRequest(ctx, req)
+	resp, err := s.sendWithRetry(ctx, req)
 	if err != nil {
 		return fmt.Errorf("close session failed: %w", err)
 	}
 
 	if resp.Error != nil {
-		return fmt.Errorf("close session error: %s", resp.Error.Message)
+		return fmt.Errorf("close session error: %w", resp.Error)
 	}
 
+	s.allocations = allocations
 	s.active = false
 	return nil
 }
 
+// closed records a close made elsewhere, e.g. by a counterparty
+func (s *Session) closed(allocations []Allocation) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.allocations = allocations
+	s.active = false
+}
+
+// sendWithRetry sends a request using the session's close policy. Only
+// transport errors are retried; a response (even an error one) is returned.
+func (s *Session) sendWithRetry(ctx context.Context, req *Request) (*Response, error) {
+	timeout := s.closeOpts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultCloseOptions.Timeout
+	}
+	backoff := s.closeOpts.Backoff
+
+	for attempt := 0; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		resp, err := s.client.SendRequest(attemptCtx, req)
+		cancel()
+
+		if err == nil {
+			return resp, nil
+		}
+		if ctx.Err() != nil || attempt >= s.closeOpts.Retries {
+			return nil, err
+		}
+
+		select {
+		case <-time.After(backoff):
+		case <-ctx.Done():
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
 // GetChannelID returns the session's channel ID
 func (s *Session) GetChannelID() string {
 	s.mu.RLock()
@@ -205,6 +509,13 @@ func (s *Session) GetChannelID() string {
 	return s.channelID
 }
 
+// Version returns the version of the last accepted state
+func (s *Session) Version() uint64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.version
+}
+
 // GetAllocations returns the current allocations
 func (s *Session) GetAllocations() []Allocation {
 	s.mu.RLock()
@@ -214,6 +525,37 @@ func (s *Session) GetAllocations() []Allocation {
 	return result
 }
 
+// Participants returns the session's current participants
+func (s *Session) Participants() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	result := make([]string, len(s.members))
+	copy(result, s.members)
+	return result
+}
+
+// Join adds traders who are not yet participants and returns the ones added.
+// Their funds enter the channel by resizing it; the next state update then
+// carries their allocations.
+func (s *Session) Join(addresses []string) ([]string, error) {
+	addresses, err := NormalizeAddresses(addresses)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var joined []string
+	for _, addr := range addresses {
+		if !contains(s.members, addr) {
+			s.members = append(s.members, addr)
+			joined = append(joined, addr)
+		}
+	}
+	return joined, nil
+}
+
 // IsActive returns whether the session is active
 func (s *Session) IsActive() bool {
 	s.mu.RLock()
@@ -221,14 +563,33 @@ func (s *Session) IsActive() bool {
 	return s.active
 }
 
-// generateNonce generates a unique nonce for session creation
-func generateNonce() int64 {
-	return nonce()
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
 }
 
-var nonceCounter int64
+// dedupe drops repeated entries, keeping the first of each
+func dedupe(list []string) []string {
+	out := list[:0]
+	for _, v := range list {
+		if !contains(out, v) {
+			out = append(out, v)
+		}
+	}
+	return out
+}
 
-func nonce() int64 {
-	nonceCounter++
-	return nonceCounter
+// generateNonce returns a random positive nonce for session creation, so
+// nonces don't repeat across restarts or between server processes
+func generateNonce() int64 {
+	var b [8]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// The clock still differs between restarts
+		return time.Now().UnixNano()
+	}
+	return int64(binary.BigEndian.Uint64(b[:]) >> 1)
 }
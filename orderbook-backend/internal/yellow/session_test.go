@@ -0,0 +1,453 @@
+package yellow
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testCloseOptions retries quickly so tests stay fast
+var testCloseOptions = CloseOptions{Timeout: 100 * time.Millisecond, Retries: 2, Backoff: 10 * time.Millisecond}
+
+func TestCloseRetriesAfterTimeout(t *testing.T) {
+	var attempts atomic.Int32
+	node := newMockClearNode(t, func(req *Request) *Response {
+		if req.Method == MethodCloseAppSession && attempts.Add(1) == 1 {
+			return nil // The first close times out
+		}
+		return okResult(map[string]string{"status": "closed"})
+	})
+	session := &Session{client: newMockClient(t, node), channelID: "0xchannel", active: true, closeOpts: testCloseOptions}
+
+	if err := session.Close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := len(node.received(MethodCloseAppSession)); n != 2 {
+		t.Fatalf("%d close attempts, want 2", n)
+	}
+	if session.IsActive() {
+		t.Fatal("session still active after close")
+	}
+}
+
+func TestCloseDoesNotRetryRPCError(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		return &Response{Error: &RPCError{Code: -32000, Message: "allocations do not match"}}
+	})
+	session := &Session{client: newMockClient(t, node), channelID: "0xchannel", active: true, closeOpts: testCloseOptions}
+
+	err := session.Close(context.Background())
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("err = %v, want the RPC error", err)
+	}
+	if n := len(node.received(MethodCloseAppSession)); n != 1 {
+		t.Fatalf("%d close attempts, want 1", n)
+	}
+	if !session.IsActive() {
+		t.Fatal("session closed despite the error")
+	}
+}
+
+func TestCloseGivesUpAfterRetries(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response { return nil })
+	session := &Session{client: newMockClient(t, node), channelID: "0xchannel", active: true, closeOpts: testCloseOptions}
+
+	if err := session.Close(context.Background()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want a timeout", err)
+	}
+	if n := len(node.received(MethodCloseAppSession)); n != 1+testCloseOptions.Retries {
+		t.Fatalf("%d close attempts, want %d", n, 1+testCloseOptions.Retries)
+	}
+}
+
+func TestUpdateStateSignsState(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		return okResult(map[string]string{"status": "accepted"})
+	})
+	signer := newTestSigner(t)
+	session := &Session{
+		client:    newMockClient(t, node),
+		signer:    signer,
+		channelID: testChannelID,
+		version:   4,
+		active:    true,
+	}
+	allocs := []Allocation{{Participant: "0x1111111111111111111111111111111111111111", Token: "0x0000000000000000000000000000000000000000", Amount: "5"}}
+	if err := session.UpdateState(context.Background(), allocs, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := node.received(MethodAppSessionMessage)
+	if len(sent) != 1 {
+		t.Fatalf("%d state updates sent, want 1", len(sent))
+	}
+	var params AppSessionMessageParams
+	if err := json.Unmarshal(sent[0].Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := hex.DecodeString(strings.TrimPrefix(params.Signature, "0x"))
+	if err != nil || len(sig) != 65 {
+		t.Fatalf("signature %q: %d bytes (%v), want 65", params.Signature, len(sig), err)
+	}
+
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash, err := StateTypedDataHash(channelID, 5, params.StateData.Allocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok, err := verifyHash(hash, params.Signature, signer.Address()); err != nil || !ok {
+		t.Fatalf("signature does not recover to the signer (ok %v, err %v)", ok, err)
+	}
+	if params.StateData.Version != 5 {
+		t.Fatalf("version = %d, want 5", params.StateData.Version)
+	}
+
+	session.channelID = "0x1234"
+	if err := session.UpdateState(context.Background(), allocs, ""); !errors.Is(err, ErrInvalidChannelID) {
+		t.Fatalf("short channel ID: err = %v, want %v", err, ErrInvalidChannelID)
+	}
+	if session.Version() != 5 {
+		t.Fatalf("version = %d after an unsigned update, want 5", session.Version())
+	}
+}
+
+func TestNewSessionManagerSignsWithItsSigner(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		if req.Method == "create_app_session" {
+			return okResult(CreateAppSessionResult{ChannelID: testChannelID, Status: "open"})
+		}
+		return okResult(map[string]string{"status": "accepted"})
+	})
+	client := newMockClient(t, node)
+	client.authenticated = true
+	signer := newTestSigner(t)
+	m := NewSessionManager(client, signer)
+
+	// Sessions the manager creates sign their states with its signer
+	const alice, token = "0x1111111111111111111111111111111111111111", "0x0000000000000000000000000000000000000000"
+	session, err := m.CreateSession(context.Background(), []string{signer.AddressHex(), alice}, nil, "0xadjudicator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := []Allocation{{Participant: alice, Token: token, Amount: "5"}}
+	if err := session.UpdateState(context.Background(), allocs, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	sent := node.received(MethodAppSessionMessage)
+	if len(sent) != 1 {
+		t.Fatalf("%d state updates sent, want 1", len(sent))
+	}
+	var params AppSessionMessageParams
+	if err := json.Unmarshal(sent[0].Params, &params); err != nil {
+		t.Fatal(err)
+	}
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := VerifyStateSignature(channelID, params.StateData.Version, params.StateData.Allocations, params.Signature, signer.Address())
+	if err != nil || !ok {
+		t.Fatalf("state signature does not verify against the signer's address (ok %v, err %v)", ok, err)
+	}
+}
+
+func TestApplyStateRequiresParticipantSignature(t *testing.T) {
+	operator, counterparty, outsider := newTestSigner(t), newTestSigner(t), newTestSigner(t)
+	session := &Session{
+		channelID: testChannelID,
+		version:   1,
+		members:   []string{operator.AddressHex(), counterparty.AddressHex()},
+		active:    true,
+	}
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocs := []Allocation{{Participant: counterparty.AddressHex(), Token: "0x0000000000000000000000000000000000000000", Amount: "5"}}
+
+	sig, err := outsider.SignStateHashHex(channelID, 2, allocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.ApplyState(StateUpdate{Version: 2, Allocations: allocs}, sig); !errors.Is(err, ErrInvalidStateSignature) {
+		t.Fatalf("outsider's state: err = %v, want %v", err, ErrInvalidStateSignature)
+	}
+	if session.Version() != 1 {
+		t.Fatalf("version = %d after a rejected state, want 1", session.Version())
+	}
+
+	sig, err = counterparty.SignStateHashHex(channelID, 2, allocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := session.ApplyState(StateUpdate{Version: 2, Allocations: allocs}, sig); err != nil {
+		t.Fatalf("participant's state: %v", err)
+	}
+	if cp := session.Checkpoint(); cp.Version != 2 || cp.Signature != sig || !reflect.DeepEqual(cp.Allocations, allocs) {
+		t.Fatalf("checkpoint = %+v, want the participant's version 2 state", cp)
+	}
+}
+
+func TestApplyStateRejectsStaleVersions(t *testing.T) {
+	counterparty := newTestSigner(t)
+	session := &Session{
+		channelID: testChannelID,
+		version:   5,
+		members:   []string{counterparty.AddressHex()},
+		active:    true,
+	}
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	apply := func(version uint64, amount string) error {
+		allocs := []Allocation{{Participant: counterparty.AddressHex(), Token: "0x0000000000000000000000000000000000000000", Amount: amount}}
+		sig, err := counterparty.SignStateHashHex(channelID, version, allocs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return session.ApplyState(StateUpdate{Version: version, Allocations: allocs}, sig)
+	}
+
+	for _, version := range []uint64{4, 5} {
+		if err := apply(version, "1"); err != ErrStaleState {
+			t.Fatalf("version %d after 5: err = %v, want %v", version, err, ErrStaleState)
+		}
+	}
+	if err := apply(7, "7"); err != nil {
+		t.Fatal(err)
+	}
+	// Versions may skip ahead, but a late update can't roll the state back
+	if err := apply(6, "6"); err != ErrStaleState {
+		t.Fatalf("version 6 after 7: err = %v, want %v", err, ErrStaleState)
+	}
+	if cp := session.Checkpoint(); cp.Version != 7 || cp.Allocations[0].Amount != "7" {
+		t.Fatalf("checkpoint = %+v, want the version 7 state", cp)
+	}
+}
+
+func TestUpdateStateSurfacesRPCErrorData(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		return &Response{Error: &RPCError{
+			Code:    -32602,
+			Message: "invalid allocations",
+			Data:    json.RawMessage(`{"participant":"0x1111111111111111111111111111111111111111","reason":"exceeds deposit"}`),
+		}}
+	})
+	session := &Session{
+		client:    newMockClient(t, node),
+		signer:    newTestSigner(t),
+		channelID: testChannelID,
+		version:   4,
+		active:    true,
+	}
+
+	err := session.UpdateState(context.Background(), []Allocation{
+		{Participant: "0x1111111111111111111111111111111111111111", Token: "0x0000000000000000000000000000000000000000", Amount: "5"},
+	}, "")
+	var rpcErr *RPCError
+	if !errors.As(err, &rpcErr) {
+		t.Fatalf("err = %v, want the RPC error", err)
+	}
+	if rpcErr.Code != -32602 {
+		t.Fatalf("code = %d, want -32602", rpcErr.Code)
+	}
+	var detail struct {
+		Participant string `json:"participant"`
+		Reason      string `json:"reason"`
+	}
+	if err := rpcErr.DecodeData(&detail); err != nil {
+		t.Fatal(err)
+	}
+	if detail.Participant != "0x1111111111111111111111111111111111111111" || detail.Reason != "exceeds deposit" {
+		t.Fatalf("detail = %+v", detail)
+	}
+	if !strings.Contains(err.Error(), "exceeds deposit") {
+		t.Fatalf("error %q does not mention the detail", err)
+	}
+	if session.Version() != 4 {
+		t.Fatalf("version = %d after a rejected update, want 4", session.Version())
+	}
+}
+
+func TestMarketSessionIsCreatedOnce(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		if req.Method == "create_app_session" {
+			return okResult(CreateAppSessionResult{ChannelID: testChannelID, Status: "open"})
+		}
+		return okResult(map[string]string{"status": "accepted"})
+	})
+	client := newMockClient(t, node)
+	client.authenticated = true
+	signer := newTestSigner(t)
+	m := NewSessionManager(client, signer)
+
+	const alice, bob = "0x1111111111111111111111111111111111111111", "0x2222222222222222222222222222222222222222"
+	token := "0x0000000000000000000000000000000000000000"
+	session, err := m.OpenMarketSession(context.Background(), "m1", []string{alice}, []Allocation{{Participant: alice, Token: token, Amount: "5"}}, "0xadjudicator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Later trades reuse the session: new traders join and the state is updated
+	again, err := m.OpenMarketSession(context.Background(), "m1", []string{alice, bob}, nil, "0xadjudicator")
+	if err != nil || again != session {
+		t.Fatalf("second open returned %p (%v), want the existing session %p", again, err, session)
+	}
+	if got, _ := m.MarketSession("m1"); got != session {
+		t.Fatal("market session not registered")
+	}
+	joined, err := session.Join([]string{alice, bob})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(joined, []string{bob}) {
+		t.Fatalf("joined = %v, want only bob", joined)
+	}
+	for i, amounts := range [][2]string{{"5", "5"}, {"3", "7"}} {
+		allocs := []Allocation{{Participant: alice, Token: token, Amount: amounts[0]}, {Participant: bob, Token: token, Amount: amounts[1]}}
+		if err := session.UpdateState(context.Background(), allocs, ""); err != nil {
+			t.Fatalf("update %d: %v", i+1, err)
+		}
+	}
+
+	if n := len(node.received("create_app_session")); n != 1 {
+		t.Fatalf("%d create_app_session requests, want 1", n)
+	}
+	if n := len(node.received(MethodAppSessionMessage)); n != 2 {
+		t.Fatalf("%d state updates, want 2", n)
+	}
+	operator, err := NormalizeAddress(signer.AddressHex())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := session.Participants(), []string{operator, alice, bob}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("participants = %v, want %v", got, want)
+	}
+	if session.Version() != 2 {
+		t.Fatalf("version = %d, want 2", session.Version())
+	}
+}
+
+func TestConcurrentFirstTradesShareOneSession(t *testing.T) {
+	var created atomic.Int32
+	node := newMockClearNode(t, func(req *Request) *Response {
+		if req.Method == "create_app_session" {
+			return okResult(CreateAppSessionResult{ChannelID: fmt.Sprintf("0x%064x", created.Add(1)), Status: "open"})
+		}
+		return okResult(map[string]string{"status": "accepted"})
+	})
+	client := newMockClient(t, node)
+	client.authenticated = true
+	m := NewSessionManager(client, newTestSigner(t))
+
+	const trades = 10
+	sessions := make([]*Session, trades)
+	var wg sync.WaitGroup
+	for i := 0; i < trades; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			trader := fmt.Sprintf("0x%040x", i+1)
+			session, err := m.OpenMarketSession(context.Background(), "m1", []string{trader}, []Allocation{}, "0xadjudicator")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			sessions[i] = session
+		}(i)
+	}
+	wg.Wait()
+
+	if n := len(node.received("create_app_session")); n != 1 {
+		t.Fatalf("%d create_app_session requests, want 1", n)
+	}
+	for i, session := range sessions {
+		if session != sessions[0] {
+			t.Fatalf("trade %d got channel %s, want the market's one channel %s", i, session.GetChannelID(), sessions[0].GetChannelID())
+		}
+	}
+	if got, ok := m.MarketSession("m1"); !ok || got != sessions[0] {
+		t.Fatal("market not mapped to its channel")
+	}
+}
+
+func TestNotificationsUpdateSessions(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response {
+		if req.Method == "create_app_session" {
+			return okResult(CreateAppSessionResult{ChannelID: testChannelID, Status: "open"})
+		}
+		return okResult(map[string]string{"status": "accepted"})
+	})
+	client := newMockClient(t, node)
+	client.authenticated = true
+	m := NewSessionManager(client, newTestSigner(t))
+	counterparty := newTestSigner(t)
+	session, err := m.OpenMarketSession(context.Background(), "m1", []string{counterparty.AddressHex()}, []Allocation{}, "0xadjudicator")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A counterparty's signed state is pushed and applied
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := "0x0000000000000000000000000000000000000000"
+	allocs := []Allocation{{Participant: counterparty.AddressHex(), Token: token, Amount: "5"}}
+	sig, err := counterparty.SignStateHashHex(channelID, 1, allocs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	node.notify(t, MethodAppSessionMessage, AppSessionMessageParams{
+		ChannelID: testChannelID,
+		StateData: StateUpdate{Version: 1, Allocations: allocs},
+		Signature: sig,
+	})
+	if !eventually(t, time.Second, func() bool { return session.Version() == 1 }) {
+		t.Fatalf("version = %d, want the pushed state's 1", session.Version())
+	}
+	if cp := session.Checkpoint(); !reflect.DeepEqual(cp.Allocations, allocs) || cp.Signature != sig {
+		t.Fatalf("checkpoint = %+v, want the pushed state", cp)
+	}
+
+	// A close ends the session on its final allocations
+	final := []Allocation{{Participant: counterparty.AddressHex(), Token: token, Amount: "3"}}
+	node.notify(t, MethodCloseAppSession, CloseAppSessionParams{ChannelID: testChannelID, Allocations: final})
+	if !eventually(t, time.Second, func() bool { return !session.IsActive() }) {
+		t.Fatal("session still active after the close notification")
+	}
+	if _, ok := m.MarketSession("m1"); ok {
+		t.Fatal("closed session is still the market's session")
+	}
+	if got := session.Checkpoint().Allocations; !reflect.DeepEqual(got, final) {
+		t.Fatalf("allocations = %+v, want the final %+v", got, final)
+	}
+}
+
+func TestGenerateNonceIsUnique(t *testing.T) {
+	seen := make(map[int64]bool)
+	for i := 0; i < 1000; i++ {
+		nonce := generateNonce()
+		if nonce <= 0 {
+			t.Fatalf("nonce %d is not positive", nonce)
+		}
+		if seen[nonce] {
+			t.Fatalf("nonce %d repeated after %d calls", nonce, i)
+		}
+		seen[nonce] = true
+	}
+}
@@ -0,0 +1,153 @@
+package yellow
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Checkpoint is the latest signed state of a session, saved to disk when the
+// session couldn't be closed so it can be recovered or disputed later
+type Checkpoint struct {
+	ChannelID   string       `json:"channel_id"`
+	MarketID    string       `json:"market_id,omitempty"` // Empty for funding sessions
+	Version     uint64       `json:"version"`
+	Allocations []Allocation `json:"allocations"`
+	AppData     string       `json:"app_data"`
+	Signature   string       `json:"signature"`
+	SavedAt     time.Time    `json:"saved_at"`
+}
+
+// ShutdownOptions controls what happens to active sessions on shutdown
+type ShutdownOptions struct {
+	Close         bool   // Cooperatively close sessions after the final state push
+	CheckpointDir string // Where sessions that aren't closed are persisted
+}
+
+// Checkpoint returns the session's latest signed state
+func (s *Session) Checkpoint() Checkpoint {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return Checkpoint{
+		ChannelID:   s.channelID,
+		Version:     s.version,
+		Allocations: append([]Allocation(nil), s.allocations...),
+		AppData:     s.appData,
+		Signature:   s.signature,
+		SavedAt:     time.Now(),
+	}
+}
+
+// Shutdown pushes a final state for every active session, then either closes
+// it or checkpoints it to disk. ctx bounds the network work: a session whose
+// push or close fails or runs out of time is checkpointed rather than lost.
+// It returns an error if any session could be neither closed nor saved.
+func (m *SessionManager) Shutdown(ctx context.Context, opts ShutdownOptions) error {
+	m.mu.RLock()
+	sessions := make([]*Session, 0, len(m.sessions))
+	for _, session := range m.sessions {
+		if session.IsActive() {
+			sessions = append(sessions, session)
+		}
+	}
+	m.mu.RUnlock()
+
+	logger := m.client.log()
+	var failed []string
+	for _, session := range sessions {
+		last := session.Checkpoint()
+		channelID := last.ChannelID
+
+		// Re-push the last state so the ClearNode holds our final signature
+		err := session.UpdateState(ctx, last.Allocations, last.AppData)
+		if err == nil && opts.Close {
+			if err = m.CloseSession(ctx, channelID); err == nil {
+				logger.InfoContext(ctx, "closed session on shutdown", "channel_id", channelID)
+				continue
+			}
+		}
+		if err != nil {
+			logger.WarnContext(ctx, "session not settled on shutdown", "channel_id", channelID, "error", err)
+		}
+
+		cp := session.Checkpoint()
+		cp.MarketID, _ = m.SessionMarket(channelID)
+		if err := WriteCheckpoint(opts.CheckpointDir, cp); err != nil {
+			logger.ErrorContext(ctx, "failed to checkpoint session", "channel_id", channelID, "error", err)
+			failed = append(failed, channelID)
+			continue
+		}
+		logger.InfoContext(ctx, "checkpointed session", "channel_id", channelID, "dir", opts.CheckpointDir)
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("sessions lost on shutdown: %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// WriteCheckpoint atomically saves a checkpoint as <dir>/<channel>.json
+func WriteCheckpoint(dir string, cp Checkpoint) error {
+	if dir == "" {
+		return fmt.Errorf("no checkpoint directory configured")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, checkpointFile(cp.ChannelID))
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// ReadCheckpoints loads every checkpoint saved in dir
+func ReadCheckpoints(dir string) ([]Checkpoint, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	checkpoints := make([]Checkpoint, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		var cp Checkpoint
+		if err := json.Unmarshal(data, &cp); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		checkpoints = append(checkpoints, cp)
+	}
+	return checkpoints, nil
+}
+
+// ReadCheckpoint loads the checkpoint saved in dir for one channel
+func ReadCheckpoint(dir, channelID string) (Checkpoint, error) {
+	var cp Checkpoint
+	data, err := os.ReadFile(filepath.Join(dir, checkpointFile(channelID)))
+	if err != nil {
+		return cp, err
+	}
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return cp, fmt.Errorf("checkpoint %s: %w", channelID, err)
+	}
+	return cp, nil
+}
+
+// checkpointFile maps a channel ID to a safe file name
+func checkpointFile(channelID string) string {
+	return strings.NewReplacer("/", "_", "\\", "_", "..", "_").Replace(channelID) + ".json"
+}
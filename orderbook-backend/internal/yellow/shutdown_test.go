@@ -0,0 +1,89 @@
+package yellow
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testChannelID = "0x00000000000000000000000000000000000000000000000000000000000000c1"
+
+// newShutdownManager tracks one active session, at version 3, on a mock ClearNode
+func newShutdownManager(t *testing.T, node *mockClearNode) *SessionManager {
+	t.Helper()
+	signer := newTestSigner(t)
+	m := NewSessionManager(newMockClient(t, node), signer)
+	m.sessions[testChannelID] = &Session{
+		client:    m.client,
+		signer:    signer,
+		channelID: testChannelID,
+		version:   3,
+		allocations: []Allocation{
+			{Participant: signer.AddressHex(), Token: "0x0000000000000000000000000000000000000000", Amount: "10"},
+		},
+		active:    true,
+		closeOpts: testCloseOptions,
+	}
+	return m
+}
+
+func TestShutdownClosesActiveSession(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response { return okResult(map[string]string{"status": "ok"}) })
+	m := newShutdownManager(t, node)
+	session, _ := m.GetSession(testChannelID)
+	dir := t.TempDir()
+
+	if err := m.Shutdown(context.Background(), ShutdownOptions{Close: true, CheckpointDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(node.received(MethodAppSessionMessage)); n != 1 {
+		t.Errorf("%d final state pushes, want 1", n)
+	}
+	if n := len(node.received(MethodCloseAppSession)); n != 1 {
+		t.Errorf("%d closes, want 1", n)
+	}
+	if session.IsActive() {
+		t.Error("session still active")
+	}
+	if cps, _ := ReadCheckpoints(dir); len(cps) != 0 {
+		t.Errorf("closed session was checkpointed: %+v", cps)
+	}
+}
+
+func TestShutdownCheckpointsUnresponsiveSession(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response { return nil })
+	m := newShutdownManager(t, node)
+	m.markets["m1"] = testChannelID
+	dir := t.TempDir()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := m.Shutdown(ctx, ShutdownOptions{Close: true, CheckpointDir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(node.received(MethodCloseAppSession)); n != 0 {
+		t.Errorf("%d closes after a failed push, want 0", n)
+	}
+
+	cp, err := ReadCheckpoint(dir, testChannelID)
+	if err != nil {
+		t.Fatalf("session not persisted: %v", err)
+	}
+	// The unacknowledged push is rolled back, so the last signed state is kept
+	if cp.ChannelID != testChannelID || cp.MarketID != "m1" || cp.Version != 3 || len(cp.Allocations) != 1 {
+		t.Errorf("checkpoint = %+v, want version 3 of market m1's %s with its allocation", cp, testChannelID)
+	}
+}
+
+func TestShutdownReportsSessionsItCannotSave(t *testing.T) {
+	node := newMockClearNode(t, func(req *Request) *Response { return nil })
+	m := newShutdownManager(t, node)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := m.Shutdown(ctx, ShutdownOptions{})
+	if err == nil || !strings.Contains(err.Error(), testChannelID) {
+		t.Fatalf("err = %v, want the lost channel reported", err)
+	}
+}
@@ -8,10 +8,113 @@ import (
 
 	"github.com/ethereum/go-ethereum/accounts"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/signer/core/apitypes"
 )
 
+// EIP-712 definitions for the auth challenge. Clients signing client-side
+// must use exactly these, so they are exported rather than inlined.
+const (
+	AuthDomainVersion = "1"
+	AuthPrimaryType   = "AuthVerify"
+)
+
+// AuthTypes are the typed-data type definitions for the auth challenge
+var AuthTypes = apitypes.Types{
+	"EIP712Domain": []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+	},
+	"AuthVerify": []apitypes.Type{
+		{Name: "address", Type: "address"},
+		{Name: "session_key", Type: "address"},
+		{Name: "challenge_message", Type: "string"},
+		{Name: "allowances", Type: "Allowance[]"},
+		{Name: "expires_at", Type: "uint256"},
+		{Name: "scope", Type: "string"},
+		{Name: "application", Type: "string"},
+	},
+	"Allowance": []apitypes.Type{
+		{Name: "asset", Type: "string"},
+		{Name: "amount", Type: "string"},
+	},
+}
+
+// EIP-712 definitions for channel states, as verified by the Nitrolite
+// custody contract (ERC-7824)
+const (
+	StateDomainName    = "Nitrolite:Custody"
+	StateDomainVersion = "0.3.0"
+	StatePrimaryType   = "AllowStateHash"
+)
+
+// StateTypes are the typed-data type definitions for a channel state. The
+// primary type's encoding is the contract's STATE_TYPEHASH:
+// AllowStateHash(bytes32 channelId,uint8 intent,uint256 version,bytes data,Allocation[] allocations)Allocation(address destination,address token,uint256 amount)
+var StateTypes = apitypes.Types{
+	"EIP712Domain": []apitypes.Type{
+		{Name: "name", Type: "string"},
+		{Name: "version", Type: "string"},
+		{Name: "chainId", Type: "uint256"},
+		{Name: "verifyingContract", Type: "address"},
+	},
+	"AllowStateHash": []apitypes.Type{
+		{Name: "channelId", Type: "bytes32"},
+		{Name: "intent", Type: "uint8"},
+		{Name: "version", Type: "uint256"},
+		{Name: "data", Type: "bytes"},
+		{Name: "allocations", Type: "Allocation[]"},
+	},
+	"Allocation": []apitypes.Type{
+		{Name: "destination", Type: "address"},
+		{Name: "token", Type: "address"},
+		{Name: "amount", Type: "uint256"},
+	},
+}
+
+// StateDomain is the domain states are signed in: the custody contract and
+// the chain it is deployed on. It defaults to the Sepolia deployment; set it
+// with NewStateDomain at startup, before any state is signed.
+var StateDomain = NewStateDomain(11155111, "0x33eA68432d7657CA49Db36f378A95c6c71d3BDF1")
+
+// NewStateDomain returns the state signing domain of a custody contract
+func NewStateDomain(chainID int64, custodyAddr string) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:              StateDomainName,
+		Version:           StateDomainVersion,
+		ChainId:           math.NewHexOrDecimal256(chainID),
+		VerifyingContract: common.HexToAddress(custodyAddr).Hex(),
+	}
+}
+
+// AuthSignMode selects how the auth challenge is signed. ClearNode versions
+// differ in which format they verify.
+type AuthSignMode string
+
+const (
+	AuthSignEIP712   AuthSignMode = "eip712"        // Typed AuthVerify struct (default)
+	AuthSignPersonal AuthSignMode = "personal_sign" // EIP-191 signature over the raw challenge
+)
+
+// ParseAuthSignMode validates a configured auth signing mode
+func ParseAuthSignMode(mode string) (AuthSignMode, error) {
+	switch m := AuthSignMode(mode); m {
+	case AuthSignEIP712, AuthSignPersonal:
+		return m, nil
+	default:
+		return "", fmt.Errorf("unknown auth sign mode %q (want %q or %q)", mode, AuthSignEIP712, AuthSignPersonal)
+	}
+}
+
+// AuthDomain returns the EIP-712 domain for an application name
+func AuthDomain(name string) apitypes.TypedDataDomain {
+	return apitypes.TypedDataDomain{
+		Name:    name,
+		Version: AuthDomainVersion,
+	}
+}
+
 // Signer handles EIP-712 typed data signing for state channel messages
 type Signer struct {
 	privateKey *ecdsa.PrivateKey
@@ -78,38 +181,55 @@ func (s *Signer) SignMessageHex(message []byte) (string, error) {
 	return "0x" + hex.EncodeToString(sig), nil
 }
 
+// SignAuthChallenge signs the auth challenge in the given mode
+func (s *Signer) SignAuthChallenge(
+	mode AuthSignMode,
+	challenge string,
+	params AuthRequestParams,
+	domainName string,
+) (string, error) {
+	switch mode {
+	case AuthSignPersonal:
+		return s.SignMessageHex([]byte(challenge))
+	case AuthSignEIP712, "":
+		return s.SignEIP712Auth(challenge, params, domainName)
+	default:
+		return "", fmt.Errorf("unknown auth sign mode %q", mode)
+	}
+}
+
 // SignEIP712Auth signs the Yellow Network auth challenge using EIP-712
 func (s *Signer) SignEIP712Auth(
 	challenge string,
 	params AuthRequestParams,
 	domainName string,
 ) (string, error) {
+	hash, err := AuthTypedDataHash(challenge, params, domainName)
+	if err != nil {
+		return "", err
+	}
+
+	// Sign the hash
+	sig, err := crypto.Sign(hash, s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign: %w", err)
+	}
+
+	// Adjust v value for Ethereum (27 or 28)
+	if sig[64] < 27 {
+		sig[64] += 27
+	}
+
+	return "0x" + hex.EncodeToString(sig), nil
+}
+
+// AuthTypedDataHash returns the EIP-712 digest signed for an auth challenge
+func AuthTypedDataHash(challenge string, params AuthRequestParams, domainName string) ([]byte, error) {
 	// Build EIP-712 TypedData
 	typedData := apitypes.TypedData{
-		Types: apitypes.Types{
-			"EIP712Domain": []apitypes.Type{
-				{Name: "name", Type: "string"},
-				{Name: "version", Type: "string"},
-			},
-			"AuthVerify": []apitypes.Type{
-				{Name: "address", Type: "address"},
-				{Name: "session_key", Type: "address"},
-				{Name: "challenge_message", Type: "string"},
-				{Name: "allowances", Type: "Allowance[]"},
-				{Name: "expires_at", Type: "uint256"},
-				{Name: "scope", Type: "string"},
-				{Name: "application", Type: "string"},
-			},
-			"Allowance": []apitypes.Type{
-				{Name: "asset", Type: "string"},
-				{Name: "amount", Type: "string"},
-			},
-		},
-		PrimaryType: "AuthVerify",
-		Domain: apitypes.TypedDataDomain{
-			Name:    domainName,
-			Version: "1",
-		},
+		Types:       AuthTypes,
+		PrimaryType: AuthPrimaryType,
+		Domain:      AuthDomain(domainName),
 		Message: apitypes.TypedDataMessage{
 			"address":           params.Address,
 			"session_key":       params.SessionKey,
@@ -121,33 +241,24 @@ func (s *Signer) SignEIP712Auth(
 		},
 	}
 
-	// Calculate the hash to sign
+	return typedDataDigest(typedData)
+}
+
+// typedDataDigest returns the EIP-712 digest of typed data
+func typedDataDigest(typedData apitypes.TypedData) ([]byte, error) {
 	domainSeparator, err := typedData.HashStruct("EIP712Domain", typedData.Domain.Map())
 	if err != nil {
-		return "", fmt.Errorf("failed to hash domain: %w", err)
+		return nil, fmt.Errorf("failed to hash domain: %w", err)
 	}
 
 	typedDataHash, err := typedData.HashStruct(typedData.PrimaryType, typedData.Message)
 	if err != nil {
-		return "", fmt.Errorf("failed to hash message: %w", err)
+		return nil, fmt.Errorf("failed to hash message: %w", err)
 	}
 
 	// Final hash: keccak256("\x19\x01" + domainSeparator + typedDataHash)
 	rawData := []byte(fmt.Sprintf("\x19\x01%s%s", string(domainSeparator), string(typedDataHash)))
-	hash := crypto.Keccak256(rawData)
-
-	// Sign the hash
-	sig, err := crypto.Sign(hash, s.privateKey)
-	if err != nil {
-		return "", fmt.Errorf("failed to sign: %w", err)
-	}
-
-	// Adjust v value for Ethereum (27 or 28)
-	if sig[64] < 27 {
-		sig[64] += 27
-	}
-
-	return "0x" + hex.EncodeToString(sig), nil
+	return crypto.Keccak256(rawData), nil
 }
 
 // convertAllowancesToTypedData converts allowances to TypedData format
@@ -173,14 +284,16 @@ func GenerateSessionKey() (*ecdsa.PrivateKey, common.Address, error) {
 	return privateKey, address, nil
 }
 
-// SignStateHash signs a state channel state hash (EIP-712 style)
+// SignStateHash signs a channel state's EIP-712 hash
 func (s *Signer) SignStateHash(
 	channelID [32]byte,
 	version uint64,
 	allocations []Allocation,
 ) ([]byte, error) {
-	// Build the state hash according to Nitrolite protocol
-	stateHash := buildStateHash(channelID, version, allocations)
+	stateHash, err := buildStateHash(channelID, version, allocations)
+	if err != nil {
+		return nil, err
+	}
 
 	sig, err := crypto.Sign(stateHash, s.privateKey)
 	if err != nil {
@@ -208,27 +321,70 @@ func (s *Signer) SignStateHashHex(
 }
 
 // buildStateHash constructs the hash to sign for a state update
-func buildStateHash(channelID [32]byte, version uint64, allocations []Allocation) []byte {
-	// Simplified state hash - in production should match Nitrolite's exact format
-	// STATE_TYPEHASH = keccak256("AllowStateHash(bytes32 channelId,uint8 intent,uint256 version,bytes data,Allocation[] allocations)Allocation(address destination,address token,uint256 amount)")
-
-	// For now, create a simple hash of the key fields
-	data := append(channelID[:], big.NewInt(int64(version)).Bytes()...)
-
-	for _, alloc := range allocations {
-		data = append(data, common.HexToAddress(alloc.Participant).Bytes()...)
-		data = append(data, common.HexToAddress(alloc.Token).Bytes()...)
-		// Parse amount as big.Int
-		amount := new(big.Int)
-		amount.SetString(alloc.Amount, 10)
-		data = append(data, common.LeftPadBytes(amount.Bytes(), 32)...)
+func buildStateHash(channelID [32]byte, version uint64, allocations []Allocation) ([]byte, error) {
+	return StateTypedDataHash(channelID, version, allocations)
+}
+
+// StateTypedDataHash returns the EIP-712 digest of an operating state in
+// StateDomain. App data is kept off-chain, so the signed state's data is empty.
+func StateTypedDataHash(channelID [32]byte, version uint64, allocations []Allocation) ([]byte, error) {
+	allocs := make([]interface{}, len(allocations))
+	for i, alloc := range allocations {
+		allocs[i] = map[string]interface{}{
+			"destination": common.HexToAddress(alloc.Participant).Hex(),
+			"token":       common.HexToAddress(alloc.Token).Hex(),
+			"amount":      allocationAmount(alloc).String(),
+		}
 	}
 
-	return crypto.Keccak256(data)
+	typedData := apitypes.TypedData{
+		Types:       StateTypes,
+		PrimaryType: StatePrimaryType,
+		Domain:      StateDomain,
+		Message: apitypes.TypedDataMessage{
+			"channelId":   channelID[:],
+			"intent":      fmt.Sprintf("%d", StateIntentOperate),
+			"version":     fmt.Sprintf("%d", version),
+			"data":        []byte{},
+			"allocations": allocs,
+		},
+	}
+	return typedDataDigest(typedData)
+}
+
+// allocationAmount parses an allocation's amount as an integer number of
+// token units; anything else counts as zero
+func allocationAmount(alloc Allocation) *big.Int {
+	amount, ok := new(big.Int).SetString(alloc.Amount, 10)
+	if !ok {
+		return new(big.Int)
+	}
+	return amount
 }
 
 // VerifySignature verifies a signature against a message and address
 func VerifySignature(message []byte, sigHex string, expectedAddr common.Address) (bool, error) {
+	return verifyHash(accounts.TextHash(message), sigHex, expectedAddr)
+}
+
+// VerifyStateSignature verifies that a state signature was made by
+// expectedAddr over the state's EIP-712 hash
+func VerifyStateSignature(
+	channelID [32]byte,
+	version uint64,
+	allocations []Allocation,
+	sigHex string,
+	expectedAddr common.Address,
+) (bool, error) {
+	hash, err := buildStateHash(channelID, version, allocations)
+	if err != nil {
+		return false, err
+	}
+	return verifyHash(hash, sigHex, expectedAddr)
+}
+
+// verifyHash recovers the signer of a hash and compares it to expectedAddr
+func verifyHash(hash []byte, sigHex string, expectedAddr common.Address) (bool, error) {
 	if len(sigHex) >= 2 && sigHex[:2] == "0x" {
 		sigHex = sigHex[2:]
 	}
@@ -247,7 +403,6 @@ func VerifySignature(message []byte, sigHex string, expectedAddr common.Address)
 		sig[64] -= 27
 	}
 
-	hash := accounts.TextHash(message)
 	pubKey, err := crypto.SigToPub(hash, sig)
 	if err != nil {
 		return false, err
@@ -0,0 +1,139 @@
+package yellow
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func newTestSigner(t *testing.T) *Signer {
+	t.Helper()
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	signer, err := NewSigner(hex.EncodeToString(key.D.FillBytes(make([]byte, 32))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signer
+}
+
+func TestSignAuthChallengeRecoversToSigner(t *testing.T) {
+	signer := newTestSigner(t)
+	const challenge = "4a1b6c2e-challenge"
+	params := AuthRequestParams{
+		Address:     signer.AddressHex(),
+		SessionKey:  signer.AddressHex(),
+		Allowances:  []AuthAllowance{{Asset: "usdc", Amount: "100"}},
+		ExpiresAt:   1767225600,
+		Scope:       "app.orderbook",
+		Application: "orderbook",
+	}
+	typedHash, err := AuthTypedDataHash(challenge, params, params.Application)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tc := range []struct {
+		mode AuthSignMode
+		hash []byte
+	}{
+		{AuthSignEIP712, typedHash},
+		{AuthSignPersonal, accounts.TextHash([]byte(challenge))},
+	} {
+		sig, err := signer.SignAuthChallenge(tc.mode, challenge, params, params.Application)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.mode, err)
+		}
+		ok, err := verifyHash(tc.hash, sig, signer.Address())
+		if err != nil || !ok {
+			t.Errorf("%s: signature does not recover to the signer (ok %v, err %v)", tc.mode, ok, err)
+		}
+	}
+
+	if _, err := signer.SignAuthChallenge("raw", challenge, params, params.Application); err == nil {
+		t.Error("unknown mode: want an error")
+	}
+}
+
+func TestStateTypedDataHash(t *testing.T) {
+	saved := StateDomain
+	StateDomain = NewStateDomain(11155111, "0x33eA68432d7657CA49Db36f378A95c6c71d3BDF1")
+	t.Cleanup(func() { StateDomain = saved })
+
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const token = "0x0000000000000000000000000000000000000000"
+	allocations := []Allocation{
+		{Participant: "0x1111111111111111111111111111111111111111", Token: token, Amount: "5"},
+		{Participant: "0x2222222222222222222222222222222222222222", Token: token, Amount: "7"},
+	}
+
+	// keccak256("\x19\x01" || domainSeparator || hashStruct(state)), with
+	// STATE_TYPEHASH 0xb02e61f8dbbfba070321cdff64845b04358ee41db88ba372ef47e352446f4b9c
+	// and domain separator 0xf97fce226963d6b72ee2b2c49a34f74752fbfe681e218c1900d5ea2b78bf3bec
+	const want = "1eb29e7aec9d595e860ea1e565a205cd0a8f77a6c6bc217184dfc4463c3d8330"
+	hash, err := StateTypedDataHash(channelID, 3, allocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(hash); got != want {
+		t.Fatalf("state hash = %s, want %s", got, want)
+	}
+
+	// Every signed field is covered
+	if bumped, _ := StateTypedDataHash(channelID, 4, allocations); hex.EncodeToString(bumped) == want {
+		t.Fatal("changing the version did not change the hash")
+	}
+	allocations[1].Amount = "8"
+	if tampered, _ := StateTypedDataHash(channelID, 3, allocations); hex.EncodeToString(tampered) == want {
+		t.Fatal("changing an allocation did not change the hash")
+	}
+}
+
+func TestVerifyStateSignature(t *testing.T) {
+	signer, other := newTestSigner(t), newTestSigner(t)
+	channelID, err := ParseChannelID(testChannelID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	allocations := []Allocation{{Participant: "0x1111111111111111111111111111111111111111", Token: "0x0000000000000000000000000000000000000000", Amount: "5"}}
+	sig, err := signer.SignStateHashHex(channelID, 3, allocations)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ok, err := VerifyStateSignature(channelID, 3, allocations, sig, signer.Address()); err != nil || !ok {
+		t.Fatalf("valid signature: ok %v, err %v", ok, err)
+	}
+	if ok, _ := VerifyStateSignature(channelID, 3, allocations, sig, other.Address()); ok {
+		t.Fatal("signature verified for a different signer")
+	}
+	tampered := []Allocation{{Participant: allocations[0].Participant, Token: allocations[0].Token, Amount: "50"}}
+	if ok, _ := VerifyStateSignature(channelID, 3, tampered, sig, signer.Address()); ok {
+		t.Fatal("signature verified over a tampered allocation")
+	}
+}
+
+func TestRPCErrorSurfacesData(t *testing.T) {
+	for _, tc := range []struct {
+		data json.RawMessage
+		want string
+	}{
+		{nil, "auth failed (code -32000)"},
+		{json.RawMessage(`null`), "auth failed (code -32000)"},
+		{json.RawMessage(`"invalid signature"`), "auth failed (code -32000): invalid signature"},
+		{json.RawMessage(`{"reason":"expired"}`), `auth failed (code -32000): {"reason":"expired"}`},
+	} {
+		err := &RPCError{Code: -32000, Message: "auth failed", Data: tc.data}
+		if got := err.Error(); got != tc.want {
+			t.Errorf("data %s: got %q, want %q", tc.data, got, tc.want)
+		}
+	}
+}
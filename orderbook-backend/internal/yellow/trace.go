@@ -0,0 +1,107 @@
+package yellow
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+// redactedFields are JSON keys whose values are credentials and must never
+// reach the logs
+var redactedFields = map[string]bool{
+	"signature":  true,
+	"signatures": true,
+	"sig":        true,
+	"jwt_token":  true,
+	"jwt":        true,
+}
+
+const redacted = "[REDACTED]"
+
+// SetTrace enables verbose logging of every JSON-RPC frame sent to and
+// received from the ClearNode. Credentials are redacted. A nil logger uses
+// the standard logger.
+func (c *Client) SetTrace(enabled bool, logger *log.Logger) {
+	if logger == nil {
+		logger = log.Default()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.trace = enabled
+	c.traceLog = logger
+}
+
+// tracing reports whether frame logging is on and returns the logger
+func (c *Client) tracing() (*log.Logger, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.traceLog, c.trace
+}
+
+// traceRequest logs an outgoing request and remembers when it was sent
+func (c *Client) traceRequest(req *Request, frame []byte) {
+	logger, ok := c.tracing()
+	if !ok {
+		return
+	}
+	c.pendingMu.Lock()
+	c.sentAt[req.ID] = time.Now()
+	c.pendingMu.Unlock()
+	logger.Printf("yellow → %s id=%d %s", req.Method, req.ID, redactFrame(frame))
+}
+
+// traceResponse logs an incoming response with its round-trip time, or a
+// notification if it answers no pending request
+func (c *Client) traceResponse(resp *Response, frame []byte, pending bool) {
+	logger, ok := c.tracing()
+	if !ok {
+		return
+	}
+	if !pending {
+		logger.Printf("yellow ← notification %s", redactFrame(frame))
+		return
+	}
+
+	c.pendingMu.Lock()
+	sent, known := c.sentAt[resp.ID]
+	delete(c.sentAt, resp.ID)
+	c.pendingMu.Unlock()
+
+	elapsed := "?"
+	if known {
+		elapsed = time.Since(sent).Round(time.Microsecond).String()
+	}
+	logger.Printf("yellow ← response id=%d in %s %s", resp.ID, elapsed, redactFrame(frame))
+}
+
+// redactFrame returns a frame with credential fields replaced at any depth.
+// Frames that aren't valid JSON are not logged verbatim.
+func redactFrame(frame []byte) string {
+	var v interface{}
+	if err := json.Unmarshal(frame, &v); err != nil {
+		return "<unparseable frame>"
+	}
+	out, err := json.Marshal(redactValue(v))
+	if err != nil {
+		return "<unparseable frame>"
+	}
+	return string(out)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, child := range t {
+			if redactedFields[k] {
+				t[k] = redacted
+				continue
+			}
+			t[k] = redactValue(child)
+		}
+	case []interface{}:
+		for i, child := range t {
+			t[i] = redactValue(child)
+		}
+	}
+	return v
+}
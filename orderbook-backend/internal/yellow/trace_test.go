@@ -0,0 +1,73 @@
+package yellow
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// syncBuffer is a log destination safe to read while the client writes
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestTracePingLogsRedactedPair(t *testing.T) {
+	const secret = "0xfeedfacecafebeef"
+	m := newMockClearNode(t, func(req *Request) *Response {
+		return okResult(map[string]string{"pong": "ok", "signature": secret})
+	})
+	c := newMockClient(t, m)
+
+	var out syncBuffer
+	c.SetTrace(true, log.New(&out, "", 0))
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want a request and a response:\n%s", len(lines), out.String())
+	}
+	if !strings.HasPrefix(lines[0], "yellow → ping id=") {
+		t.Errorf("request line = %q", lines[0])
+	}
+	if !strings.HasPrefix(lines[1], "yellow ← response id=") {
+		t.Errorf("response line = %q", lines[1])
+	}
+	if strings.Contains(out.String(), secret) {
+		t.Errorf("signature logged:\n%s", out.String())
+	}
+	if !strings.Contains(lines[1], `"signature":"`+redacted+`"`) {
+		t.Errorf("response line does not redact the signature: %q", lines[1])
+	}
+}
+
+func TestTraceDisabledLogsNothing(t *testing.T) {
+	m := newMockClearNode(t, func(req *Request) *Response { return okResult("pong") })
+	c := newMockClient(t, m)
+
+	var out syncBuffer
+	c.SetTrace(false, log.New(&out, "", 0))
+	if err := c.Ping(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "" {
+		t.Errorf("logged with tracing off:\n%s", out.String())
+	}
+}